@@ -0,0 +1,272 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// DefaultPath is the location of the application configuration file
+const DefaultPath = "config.json"
+
+// Config represents the application configuration stored in config.json
+type Config struct {
+	AppName        string               `json:"app_name"`
+	Version        string               `json:"version"`
+	Description    string               `json:"description"`
+	Ollama         OllamaConfig         `json:"ollama"`
+	GUI            GUIConfig            `json:"gui"`
+	KnowledgeBase  KnowledgeBaseConfig  `json:"knowledge_base"`
+	Prompts        PromptsConfig        `json:"prompts"`
+	Intent         IntentConfig         `json:"intent"`
+	Feedback       FeedbackConfig       `json:"feedback"`
+	Sessions       SessionsConfig       `json:"sessions"`
+	FileProcessing FileProcessingConfig `json:"file_processing"`
+	WindowsAPI     WindowsAPIConfig     `json:"windows_api"`
+	Logging        LoggingConfig        `json:"logging"`
+}
+
+// OllamaConfig holds the Ollama server and model selection settings
+type OllamaConfig struct {
+	BaseURL               string   `json:"base_url"`
+	Model                 string   `json:"model"`
+	TimeoutSeconds        int      `json:"timeout_seconds"`
+	Stream                bool     `json:"stream"`
+	FallbackModels        []string `json:"fallback_models"`          // Tried in order when the selected model fails
+	HealthCheckTTLSeconds int      `json:"health_check_ttl_seconds"` // How long a server health check result is reused
+
+	// Generation holds the default generation options and ModelPresets the per-model overrides
+	Generation   models.GenerationOptions            `json:"generation"`
+	ModelPresets map[string]models.GenerationOptions `json:"model_presets,omitempty"`
+}
+
+// GUIConfig holds the window settings
+type GUIConfig struct {
+	WindowWidth  int    `json:"window_width"`
+	WindowHeight int    `json:"window_height"`
+	Theme        string `json:"theme"`
+}
+
+// KnowledgeBaseConfig holds the knowledge base locations and limits. When no profiles are
+// configured, ErrorCodesFile and TextFilesDirectory form a single "default" profile.
+type KnowledgeBaseConfig struct {
+	ErrorCodesFile     string                    `json:"error_codes_file"`
+	TextFilesDirectory string                    `json:"text_files_directory"`
+	MaxPDFSizeMB       int                       `json:"max_pdf_size_mb"` // Also applies to Word documents
+	MaxImageSizeMB     int                       `json:"max_image_size_mb"`
+	MaxTextSizeMB      int                       `json:"max_text_size_mb"`          // Text, log, HTML and diagram files
+	TicketProjects     []string                  `json:"ticket_projects,omitempty"` // Jira projects of the default profile
+	Profiles           []models.KnowledgeProfile `json:"profiles,omitempty"`
+	ActiveProfile      string                    `json:"active_profile,omitempty"`
+}
+
+// ProfileList returns the configured knowledge profiles, or the default profile when there are none
+func (k KnowledgeBaseConfig) ProfileList() []models.KnowledgeProfile {
+	if len(k.Profiles) > 0 {
+		return k.Profiles
+	}
+	return []models.KnowledgeProfile{{
+		Name:           "default",
+		Root:           k.TextFilesDirectory,
+		ErrorCodesFile: k.ErrorCodesFile,
+		TicketProjects: k.TicketProjects,
+	}}
+}
+
+// Profile returns the knowledge profile with the given name
+func (k KnowledgeBaseConfig) Profile(name string) (models.KnowledgeProfile, bool) {
+	for _, profile := range k.ProfileList() {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return models.KnowledgeProfile{}, false
+}
+
+// Active returns the active knowledge profile, or the first profile when none is selected
+func (k KnowledgeBaseConfig) Active() models.KnowledgeProfile {
+	if profile, ok := k.Profile(k.ActiveProfile); ok {
+		return profile
+	}
+	return k.ProfileList()[0]
+}
+
+// IndexPolicy returns the size limits and file formats used when indexing documents and uploads
+func (c *Config) IndexPolicy() models.IndexPolicy {
+	const mb = 1 << 20
+	return models.IndexPolicy{
+		MaxTextBytes:   int64(c.KnowledgeBase.MaxTextSizeMB) * mb,
+		MaxPDFBytes:    int64(c.KnowledgeBase.MaxPDFSizeMB) * mb,
+		MaxImageBytes:  int64(c.KnowledgeBase.MaxImageSizeMB) * mb,
+		ImageFormats:   c.FileProcessing.SupportedImageFormats,
+		PDFFormats:     c.FileProcessing.SupportedPDFFormats,
+		DiagramFormats: c.FileProcessing.SupportedDiagramFormats,
+	}
+}
+
+// PromptsConfig holds the prompt template location and the values shared by all templates
+type PromptsConfig struct {
+	Directory string `json:"directory"`
+	LabName   string `json:"lab_name"`
+}
+
+// IntentConfig holds the question classifier settings
+type IntentConfig struct {
+	UseModel       bool    `json:"use_model"`       // Ask the model when the keyword rules are unsure
+	ModelThreshold float64 `json:"model_threshold"` // Rule confidence below which the model is asked
+}
+
+// FeedbackConfig holds the locations of the answer feedback store and the queue of
+// knowledge base changes proposed from resolved answers
+type FeedbackConfig struct {
+	File          string `json:"file"`
+	ProposalsFile string `json:"proposals_file"`
+}
+
+// SessionsConfig holds the location of the saved chat sessions and whether copies of their
+// uploaded files are kept with them
+type SessionsConfig struct {
+	Directory   string `json:"directory"`
+	KeepUploads bool   `json:"keep_uploads"`
+}
+
+// FileProcessingConfig holds the supported file formats and temp file handling
+type FileProcessingConfig struct {
+	SupportedImageFormats   []string `json:"supported_image_formats"`
+	SupportedPDFFormats     []string `json:"supported_pdf_formats"`
+	SupportedDiagramFormats []string `json:"supported_diagram_formats"`
+	TempDirectory           string   `json:"temp_directory"`
+	CleanupOnExit           bool     `json:"cleanup_on_exit"`
+}
+
+// WindowsAPIConfig holds the Windows native processing settings
+type WindowsAPIConfig struct {
+	UseNativeImageProcessing bool `json:"use_native_image_processing"`
+	EnableOCR                bool `json:"enable_ocr"`
+	ImageMetadataExtraction  bool `json:"image_metadata_extraction"`
+}
+
+// LoggingConfig holds the log file settings
+type LoggingConfig struct {
+	Level        string `json:"level"`
+	LogFile      string `json:"log_file"`
+	MaxLogSizeMB int    `json:"max_log_size_mb"`
+	MaxLogFiles  int    `json:"max_log_files"`
+}
+
+// Default returns the built-in configuration used when config.json is missing
+func Default() *Config {
+	return &Config{
+		AppName:     "BeanBot",
+		Version:     "1.0.0",
+		Description: "iTest Troubleshooting Assistant",
+		Ollama: OllamaConfig{
+			BaseURL:               "http://localhost:11434",
+			Model:                 "llama3.2:1b",
+			TimeoutSeconds:        120,
+			HealthCheckTTLSeconds: 30,
+			Generation: models.GenerationOptions{
+				Temperature: floatPtr(0.7),
+				TopP:        floatPtr(0.9),
+				NumPredict:  intPtr(1000),
+			},
+		},
+		GUI: GUIConfig{
+			WindowWidth:  450,
+			WindowHeight: 700,
+			Theme:        "default",
+		},
+		KnowledgeBase: KnowledgeBaseConfig{
+			ErrorCodesFile:     "testData/lsie_errors.json",
+			TextFilesDirectory: "testData/",
+			MaxPDFSizeMB:       50,
+			MaxImageSizeMB:     10,
+			MaxTextSizeMB:      20,
+		},
+		Prompts: PromptsConfig{
+			Directory: "prompts/",
+		},
+		Intent: IntentConfig{
+			ModelThreshold: 0.5,
+		},
+		Feedback: FeedbackConfig{
+			File:          "data/feedback.jsonl",
+			ProposalsFile: "data/proposals.json",
+		},
+		Sessions: SessionsConfig{
+			Directory: "data/sessions/",
+		},
+		FileProcessing: FileProcessingConfig{
+			SupportedImageFormats:   []string{".png", ".jpg", ".jpeg", ".bmp"},
+			SupportedPDFFormats:     []string{".pdf"},
+			SupportedDiagramFormats: []string{".drawio"},
+			TempDirectory:           "temp/",
+			CleanupOnExit:           true,
+		},
+		Logging: LoggingConfig{
+			Level:        "info",
+			LogFile:      "beanbot.log",
+			MaxLogSizeMB: 10,
+			MaxLogFiles:  5,
+		},
+	}
+}
+
+// Load reads the configuration file, falling back to defaults for missing values
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Save writes the configuration to path, replacing the previous file atomically
+func (c *Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// floatPtr returns a pointer to a float64 value
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+// intPtr returns a pointer to an int value
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,256 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+
+	"github.com/beanspout/2025-beanbot/internal/session"
+)
+
+// Format is a file format answers can be exported to
+type Format string
+
+// Supported export formats
+const (
+	Markdown Format = "Markdown"
+	HTML     Format = "HTML"
+	PDF      Format = "PDF"
+)
+
+// Formats lists the export formats in the order they are offered
+var Formats = []Format{Markdown, HTML, PDF}
+
+// Extension returns the file extension for the format
+func (f Format) Extension() string {
+	switch f {
+	case HTML:
+		return ".html"
+	case PDF:
+		return ".pdf"
+	}
+	return ".md"
+}
+
+// timeLayout formats timestamps in reports
+const timeLayout = "2006-01-02 15:04 MST"
+
+// Report is the content of an export: a whole conversation or a single answer from it
+type Report struct {
+	Title      string
+	Profile    string // Knowledge profile the conversation was started with
+	Turns      []session.Turn
+	ExportedAt time.Time
+}
+
+// SessionReport creates a report of every answer in a conversation
+func SessionReport(s *session.Session) Report {
+	return Report{
+		Title:      s.Title,
+		Profile:    s.Profile,
+		Turns:      s.Turns,
+		ExportedAt: time.Now(),
+	}
+}
+
+// AnswerReport creates a report of the answer at index in a conversation
+func AnswerReport(s *session.Session, index int) Report {
+	turn := s.Turns[index]
+	return Report{
+		Title:      "BeanBot answer: " + session.TitleFor(turn.Question),
+		Profile:    s.Profile,
+		Turns:      []session.Turn{turn},
+		ExportedAt: time.Now(),
+	}
+}
+
+// Render produces the report in a format
+func (r Report) Render(format Format) ([]byte, error) {
+	switch format {
+	case Markdown:
+		return []byte(r.Markdown()), nil
+	case HTML:
+		page, err := r.HTML()
+		return []byte(page), err
+	case PDF:
+		return r.PDF(), nil
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// FileName suggests a file name for the report in a format
+func (r Report) FileName(format Format) string {
+	var slug strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(r.Title) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			slug.WriteRune(c)
+			dash = false
+		} else if !dash && slug.Len() > 0 {
+			slug.WriteByte('-')
+			dash = true
+		}
+		if slug.Len() >= 48 {
+			break
+		}
+	}
+	name := strings.Trim(slug.String(), "-")
+	if name == "" {
+		name = "beanbot"
+	}
+	return fmt.Sprintf("%s-%s%s", name, r.ExportedAt.Format("20060102-1504"), format.Extension())
+}
+
+// Markdown renders the report as Markdown; the other formats are produced from it
+func (r Report) Markdown() string {
+	var out strings.Builder
+	out.WriteString(fmt.Sprintf("# %s\n\n", r.Title))
+	details := []string{"Exported " + r.ExportedAt.Format(timeLayout)}
+	if r.Profile != "" {
+		details = append([]string{"Knowledge base: " + r.Profile}, details...)
+	}
+	out.WriteString(fmt.Sprintf("*%s*\n", strings.Join(details, " · ")))
+
+	for i, turn := range r.Turns {
+		out.WriteString("\n---\n\n")
+		if len(r.Turns) > 1 {
+			out.WriteString(fmt.Sprintf("## Question %d\n\n", i+1))
+		} else {
+			out.WriteString("## Question\n\n")
+		}
+		for _, line := range strings.Split(strings.TrimSpace(turn.Question), "\n") {
+			out.WriteString("> " + line + "\n")
+		}
+		out.WriteString("\n")
+		writeDetails(&out, turn)
+		out.WriteString("\n### Answer\n\n")
+		out.WriteString(closeCodeBlock(nestHeadings(strings.TrimSpace(turn.Reply()), 3)))
+		out.WriteString("\n\n### Sources\n\n")
+		writeSources(&out, turn)
+	}
+	return out.String()
+}
+
+// writeDetails lists when and how a question was answered
+func writeDetails(out *strings.Builder, turn session.Turn) {
+	out.WriteString(fmt.Sprintf("- **Asked:** %s\n", turn.AskedAt.Format(timeLayout)))
+	if !turn.AnsweredAt.IsZero() {
+		out.WriteString(fmt.Sprintf("- **Answered:** %s\n", turn.AnsweredAt.Format(timeLayout)))
+	}
+	if turn.Model != "" {
+		out.WriteString(fmt.Sprintf("- **Model:** %s\n", turn.Model))
+	}
+	if turn.Options != nil {
+		if options := turn.Options.String(); options != "" {
+			out.WriteString(fmt.Sprintf("- **Generation options:** %s\n", options))
+		}
+	}
+	if turn.Intent != "" {
+		out.WriteString(fmt.Sprintf("- **Question type:** %s\n", turn.Intent))
+	}
+	if len(turn.ErrorCodes) > 0 {
+		out.WriteString(fmt.Sprintf("- **Error codes:** %s\n", strings.Join(turn.ErrorCodes, ", ")))
+	}
+	if len(turn.Attachments) > 0 {
+		out.WriteString(fmt.Sprintf("- **Attachments:** %s\n", strings.Join(turn.Attachments, ", ")))
+	}
+}
+
+// writeSources lists the knowledge sources of an answer with their locations, cited ones first
+func writeSources(out *strings.Builder, turn session.Turn) {
+	if len(turn.References) == 0 {
+		// Sessions saved before references were recorded only have the source names
+		for _, source := range turn.Sources {
+			out.WriteString(fmt.Sprintf("- %s\n", source))
+		}
+		if len(turn.Sources) == 0 {
+			out.WriteString("- *No knowledge base documents were referenced.*\n")
+		}
+		return
+	}
+
+	for _, reference := range turn.References {
+		if reference.Cited() {
+			out.WriteString(fmt.Sprintf("- **[%d]** %s%s\n", reference.Footnote, reference.Source, formatPath(reference.Path)))
+		}
+	}
+	for _, reference := range turn.References {
+		if !reference.Cited() {
+			out.WriteString(fmt.Sprintf("- %s%s *(provided, not cited)*\n", reference.Source, formatPath(reference.Path)))
+		}
+	}
+}
+
+// nestHeadings moves the headings of an answer below the report heading level so they stay
+// within the answer's section
+func nestHeadings(markdown string, level int) string {
+	lines := strings.Split(markdown, "\n")
+	inCode := false
+	for i, line := range lines {
+		parsed := classifyLine(line)
+		switch {
+		case parsed.kind == lineFence:
+			inCode = !inCode
+		case !inCode && parsed.kind == lineHeading:
+			lines[i] = strings.Repeat("#", min(parsed.level+level, 6)) + " " + parsed.text
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// closeCodeBlock ends an answer cut off inside a code block, as when the model stopped at its
+// token limit, so the sources after it are not shown as code
+func closeCodeBlock(markdown string) string {
+	inCode := false
+	for _, line := range strings.Split(markdown, "\n") {
+		if classifyLine(line).kind == lineFence {
+			inCode = !inCode
+		}
+	}
+	if inCode {
+		return markdown + "\n```"
+	}
+	return markdown
+}
+
+// formatPath formats a source's file location for the sources list
+func formatPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " — `" + path + "`"
+}
+
+// htmlStyle is embedded in exported HTML so the file needs nothing else to display
+const htmlStyle = `body { font-family: "Segoe UI", Helvetica, Arial, sans-serif; max-width: 860px; margin: 2em auto; padding: 0 1em; color: #222; line-height: 1.5; }
+h1 { border-bottom: 2px solid #6f4e37; padding-bottom: .3em; }
+h2 { color: #6f4e37; margin-top: 1.5em; }
+blockquote { margin: 0; padding: .5em 1em; background: #f6f1ec; border-left: 4px solid #6f4e37; }
+code { font-family: Consolas, "Courier New", monospace; background: #f2f2f2; padding: 0 .2em; }
+pre { background: #f2f2f2; padding: .8em; overflow-x: auto; }
+pre code { padding: 0; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: .3em .6em; text-align: left; }
+hr { border: 0; border-top: 1px solid #ddd; margin: 2em 0; }`
+
+// HTML renders the report as a self-contained HTML page
+func (r Report) HTML() (string, error) {
+	var body bytes.Buffer
+	markdown := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	if err := markdown.Convert([]byte(r.Markdown()), &body); err != nil {
+		return "", fmt.Errorf("failed to render HTML: %w", err)
+	}
+
+	var page strings.Builder
+	page.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	page.WriteString(fmt.Sprintf("<title>%s</title>\n", html.EscapeString(r.Title)))
+	page.WriteString("<style>\n" + htmlStyle + "\n</style>\n</head>\n<body>\n")
+	page.Write(body.Bytes())
+	page.WriteString("</body>\n</html>\n")
+	return page.String(), nil
+}
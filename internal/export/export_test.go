@@ -0,0 +1,150 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+	"github.com/beanspout/2025-beanbot/internal/session"
+)
+
+// testReport is a single answer with every detail a turn can record
+func testReport() Report {
+	temperature := 0.2
+	return Report{
+		Title:      "Rack 3 trips",
+		Profile:    "Lab A",
+		ExportedAt: time.Date(2025, 3, 2, 9, 30, 0, 0, time.UTC),
+		Turns: []session.Turn{{
+			Question:   "Why does rack 3 trip?\nIt shows E-12.",
+			Answer:     "Displayed answer with the sources section",
+			Body:       "# Cause\n\nOver **voltage** on `ch4` [1].\n\n```\n# not a heading\n```",
+			Model:      "llama3.2:1b",
+			Options:    &models.GenerationOptions{Temperature: &temperature},
+			Intent:     "troubleshoot",
+			ErrorCodes: []string{"E-12"},
+			References: []session.Reference{
+				{Passage: "P1", Source: "Error Code E-12", Path: "errors.json", Footnote: 1},
+				{Passage: "P2", Source: "manual.pdf"},
+			},
+			AskedAt:    time.Date(2025, 3, 2, 9, 5, 0, 0, time.UTC),
+			AnsweredAt: time.Date(2025, 3, 2, 9, 6, 0, 0, time.UTC),
+		}},
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	want := "# Rack 3 trips\n\n" +
+		"*Knowledge base: Lab A · Exported 2025-03-02 09:30 UTC*\n\n" +
+		"---\n\n" +
+		"## Question\n\n" +
+		"> Why does rack 3 trip?\n> It shows E-12.\n\n" +
+		"- **Asked:** 2025-03-02 09:05 UTC\n" +
+		"- **Answered:** 2025-03-02 09:06 UTC\n" +
+		"- **Model:** llama3.2:1b\n" +
+		"- **Generation options:** temperature=0.2\n" +
+		"- **Question type:** troubleshoot\n" +
+		"- **Error codes:** E-12\n\n" +
+		"### Answer\n\n" +
+		"#### Cause\n\nOver **voltage** on `ch4` [1].\n\n```\n# not a heading\n```\n\n" +
+		"### Sources\n\n" +
+		"- **[1]** Error Code E-12 — `errors.json`\n" +
+		"- manual.pdf *(provided, not cited)*\n"
+	if got := testReport().Markdown(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarkdownConversation(t *testing.T) {
+	report := Report{
+		Title:      "Conversation",
+		ExportedAt: time.Date(2025, 3, 2, 9, 30, 0, 0, time.UTC),
+		Turns: []session.Turn{
+			{Question: "Saved before references", Answer: "Old answer", Sources: []string{"notes.txt"}},
+			{Question: "Hello", Answer: "Hi!"},
+		},
+	}
+	got := report.Markdown()
+	for _, want := range []string{
+		"*Exported 2025-03-02 09:30 UTC*\n",
+		"## Question 1\n\n> Saved before references\n",
+		"### Answer\n\nOld answer\n\n### Sources\n\n- notes.txt\n",
+		"## Question 2\n\n> Hello\n",
+		"- *No knowledge base documents were referenced.*\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Knowledge base:") || strings.Contains(got, "**Model:**") || strings.Contains(got, "**Answered:**") {
+		t.Errorf("details that were not recorded are listed:\n%s", got)
+	}
+}
+
+func TestNestHeadings(t *testing.T) {
+	got := nestHeadings("# One\n### Three\n###### Six\n#not a heading\n```\n# code\n```\n## Two", 3)
+	want := "#### One\n###### Three\n###### Six\n#not a heading\n```\n# code\n```\n##### Two"
+	if got != want {
+		t.Errorf("nestHeadings() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	report := testReport()
+	report.Title = "Rack <3> & fuses"
+	page, err := report.HTML()
+	if err != nil {
+		t.Fatalf("HTML: %v", err)
+	}
+	if !strings.HasPrefix(page, "<!DOCTYPE html>\n") || !strings.HasSuffix(page, "</body>\n</html>\n") {
+		t.Errorf("not a complete page:\n%s", page)
+	}
+	for _, want := range []string{
+		"<title>Rack &lt;3&gt; &amp; fuses</title>",
+		"<style>\n" + htmlStyle,
+		"<blockquote>\n<p>Why does rack 3 trip?\nIt shows E-12.</p>\n</blockquote>",
+		"<h4>Cause</h4>",
+		"<p>Over <strong>voltage</strong> on <code>ch4</code> [1].</p>",
+		"<pre><code># not a heading\n</code></pre>",
+		"<li><strong>[1]</strong> Error Code E-12 — <code>errors.json</code></li>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("missing %q in:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "<3>") {
+		t.Errorf("the title is not escaped:\n%s", page)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		title  string
+		format Format
+		want   string
+	}{
+		{"BeanBot answer: Why does rack 3 trip?", PDF, "beanbot-answer-why-does-rack-3-trip-20250302-0930.pdf"},
+		{"Überprüfung -- E-12!", Markdown, "berpr-fung-e-12-20250302-0930.md"},
+		{strings.Repeat("fuse ", 20), HTML, strings.Repeat("fuse-", 9) + "fus-20250302-0930.html"}, // Cut at 48 characters
+		{"???", HTML, "beanbot-20250302-0930.html"},
+	}
+	for _, tt := range tests {
+		report := Report{Title: tt.title, ExportedAt: time.Date(2025, 3, 2, 9, 30, 0, 0, time.UTC)}
+		if got := report.FileName(tt.format); got != tt.want {
+			t.Errorf("FileName(%q, %s) = %q, want %q", tt.title, tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	report := testReport()
+	for _, format := range Formats {
+		if data, err := report.Render(format); err != nil || len(data) == 0 {
+			t.Errorf("Render(%s) = %d bytes, %v", format, len(data), err)
+		}
+	}
+	if _, err := report.Render("DOCX"); err == nil {
+		t.Error("rendering an unsupported format gave no error")
+	}
+}
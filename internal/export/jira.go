@@ -0,0 +1,103 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+)
+
+// jiraEscaper escapes characters that Jira markup would read as links or macros
+var jiraEscaper = strings.NewReplacer("[", `\[`, "]", `\]`, "{", `\{`, "}", `\}`)
+
+// Jira renders the report as Jira wiki markup for pasting into tickets and comments
+func (r Report) Jira() string {
+	lines := strings.Split(r.Markdown(), "\n")
+	var out []string
+	inCode := false
+	for i, line := range lines {
+		if inCode {
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				out = append(out, "{noformat}")
+				inCode = false
+			} else {
+				out = append(out, line)
+			}
+			continue
+		}
+
+		parsed := classifyLine(line)
+		switch parsed.kind {
+		case lineBlank:
+			out = append(out, "")
+		case lineFence:
+			out = append(out, "{noformat}")
+			inCode = true
+		case lineHeading:
+			heading := strongPattern.ReplaceAllString(parsed.text, "$1$2")
+			out = append(out, fmt.Sprintf("h%d. %s", parsed.level, jiraInline(heading)))
+		case lineRule:
+			out = append(out, "----")
+		case lineQuote:
+			out = append(out, "bq. "+jiraInline(parsed.text))
+		case lineBullet:
+			out = append(out, strings.Repeat("*", parsed.level+1)+" "+jiraInline(parsed.text))
+		case lineNumbered:
+			out = append(out, strings.Repeat("#", parsed.level+1)+" "+jiraInline(parsed.text))
+		case lineTableDivider:
+			// The header row was already written with Jira's header cell markers
+		case lineTableRow:
+			separator := "|"
+			if i+1 < len(lines) && classifyLine(lines[i+1]).kind == lineTableDivider {
+				separator = "||"
+			}
+			var cells []string
+			for _, cell := range tableCells(parsed.text) {
+				cells = append(cells, jiraInline(cell))
+			}
+			out = append(out, separator+strings.Join(cells, separator)+separator)
+		default:
+			out = append(out, jiraInline(parsed.text))
+		}
+	}
+	if inCode {
+		out = append(out, "{noformat}")
+	}
+	return strings.TrimSpace(strings.Join(out, "\n")) + "\n"
+}
+
+// jiraInline converts inline Markdown formatting to Jira markup
+func jiraInline(text string) string {
+	var out strings.Builder
+	parts := strings.Split(text, "`")
+	for i, part := range parts {
+		switch {
+		case i%2 == 1 && i < len(parts)-1:
+			out.WriteString("{{" + part + "}}")
+		case i%2 == 1:
+			// An unmatched backtick is kept as written
+			out.WriteString("`" + jiraText(part))
+		default:
+			out.WriteString(jiraText(part))
+		}
+	}
+	return out.String()
+}
+
+// jiraText converts links and emphasis in text outside inline code
+func jiraText(text string) string {
+	var out strings.Builder
+	last := 0
+	for _, match := range linkPattern.FindAllStringSubmatchIndex(text, -1) {
+		out.WriteString(jiraEmphasis(text[last:match[0]]))
+		out.WriteString("[" + text[match[2]:match[3]] + "|" + text[match[4]:match[5]] + "]")
+		last = match[1]
+	}
+	out.WriteString(jiraEmphasis(text[last:]))
+	return out.String()
+}
+
+// jiraEmphasis escapes text and converts Markdown bold and italics to Jira's
+func jiraEmphasis(text string) string {
+	text = jiraEscaper.Replace(text)
+	text = italicPattern.ReplaceAllString(text, "${1}_${2}_")
+	return strongPattern.ReplaceAllString(text, "*$1$2*")
+}
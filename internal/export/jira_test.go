@@ -0,0 +1,58 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beanspout/2025-beanbot/internal/session"
+)
+
+func TestJira(t *testing.T) {
+	want := "h1. Rack 3 trips\n\n" +
+		"_Knowledge base: Lab A · Exported 2025-03-02 09:30 UTC_\n\n" +
+		"----\n\n" +
+		"h2. Question\n\n" +
+		"bq. Why does rack 3 trip?\nbq. It shows E-12.\n\n" +
+		"* *Asked:* 2025-03-02 09:05 UTC\n" +
+		"* *Answered:* 2025-03-02 09:06 UTC\n" +
+		"* *Model:* llama3.2:1b\n" +
+		"* *Generation options:* temperature=0.2\n" +
+		"* *Question type:* troubleshoot\n" +
+		"* *Error codes:* E-12\n\n" +
+		"h3. Answer\n\n" +
+		"h4. Cause\n\nOver *voltage* on {{ch4}} \\[1\\].\n\n{noformat}\n# not a heading\n{noformat}\n\n" +
+		"h3. Sources\n\n" +
+		"* *\\[1\\]* Error Code E-12 — {{errors.json}}\n" +
+		"* manual.pdf _(provided, not cited)_\n"
+	if got := testReport().Jira(); got != want {
+		t.Errorf("Jira() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestJiraAnswerMarkup(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"link", "See [the manual](https://wiki/manual) now", "See [the manual|https://wiki/manual] now"},
+		{"macros escaped", "Set {rack} to [on]", "Set \\{rack\\} to \\[on\\]"},
+		{"code keeps markup", "Run `fix --all [now]` *twice*", "Run {{fix --all [now]}} _twice_"},
+		{"unmatched backtick", "Quote ` left", "Quote ` left"},
+		{"numbered and nested lists", "1. Power down\n  - Wait **30 s**\n2) Reseat", "# Power down\n** Wait *30 s*\n# Reseat"},
+		{"table", "| Channel | Volts |\n| --- | ---: |\n| 12 | 4.5 |", "||Channel||Volts||\n|12|4.5|"},
+		{"heading emphasis", "## **Fix** it", "h5. Fix it"},
+		{"unclosed code block", "```sh\nreboot", "{noformat}\nreboot\n{noformat}\n\nh3. Sources"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Report{Title: "Answer", ExportedAt: time.Now(), Turns: []session.Turn{{Question: "Q", Body: tt.body}}}
+			got := report.Jira()
+			answer := got[strings.Index(got, "h3. Answer\n\n")+len("h3. Answer\n\n"):]
+			if !strings.HasPrefix(answer, tt.want) {
+				t.Errorf("answer %q rendered as\n%s\nwant it to start with\n%s", tt.body, answer, tt.want)
+			}
+		})
+	}
+}
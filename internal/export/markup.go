@@ -0,0 +1,102 @@
+package export
+
+import (
+	"regexp"
+	"strings"
+)
+
+// lineKind is the Markdown construct a line starts
+type lineKind int
+
+const (
+	lineText lineKind = iota
+	lineBlank
+	lineHeading
+	lineRule
+	lineQuote
+	lineBullet
+	lineNumbered
+	lineTableRow
+	lineTableDivider
+	lineFence
+)
+
+// mdLine is one line of Markdown, classified for the PDF and Jira renderers. They only need the
+// constructs BeanBot answers use; HTML is rendered with a full Markdown parser instead.
+type mdLine struct {
+	kind   lineKind
+	level  int    // Heading level, or the nesting depth of a list item
+	marker string // Number of a numbered item, or the language of a code fence
+	text   string
+}
+
+var (
+	numberedPattern = regexp.MustCompile(`^(\d+)[.)]\s+(.*)$`)
+	dividerPattern  = regexp.MustCompile(`^\|?\s*:?-{3,}:?\s*(\|\s*:?-{3,}:?\s*)*\|?$`)
+)
+
+// classifyLine works out which construct a line outside a code block starts
+func classifyLine(line string) mdLine {
+	trimmed := strings.TrimSpace(line)
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+	switch {
+	case trimmed == "":
+		return mdLine{kind: lineBlank}
+	case strings.HasPrefix(trimmed, "```"):
+		return mdLine{kind: lineFence, marker: strings.TrimSpace(strings.TrimPrefix(trimmed, "```"))}
+	case strings.HasPrefix(trimmed, "#"):
+		level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+		if level <= 6 && (len(trimmed) == level || trimmed[level] == ' ') {
+			return mdLine{kind: lineHeading, level: level, text: strings.TrimSpace(trimmed[level:])}
+		}
+	case isRule(trimmed):
+		return mdLine{kind: lineRule}
+	case strings.HasPrefix(trimmed, ">"):
+		return mdLine{kind: lineQuote, text: strings.TrimSpace(strings.TrimLeft(trimmed, "> "))}
+	case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") || strings.HasPrefix(trimmed, "+ "):
+		return mdLine{kind: lineBullet, level: indent / 2, text: strings.TrimSpace(trimmed[2:])}
+	case dividerPattern.MatchString(trimmed) && strings.Contains(trimmed, "|"):
+		return mdLine{kind: lineTableDivider}
+	case strings.HasPrefix(trimmed, "|"):
+		return mdLine{kind: lineTableRow, text: trimmed}
+	}
+	if match := numberedPattern.FindStringSubmatch(trimmed); match != nil {
+		return mdLine{kind: lineNumbered, level: indent / 2, marker: match[1], text: match[2]}
+	}
+	return mdLine{kind: lineText, text: trimmed}
+}
+
+// isRule reports whether a line is a thematic break such as --- or ***
+func isRule(trimmed string) bool {
+	compact := strings.ReplaceAll(trimmed, " ", "")
+	if len(compact) < 3 {
+		return false
+	}
+	return strings.Trim(compact, "-") == "" || strings.Trim(compact, "*") == "" || strings.Trim(compact, "_") == ""
+}
+
+// tableCells splits a table row into its trimmed cells
+func tableCells(row string) []string {
+	row = strings.TrimSpace(row)
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	cells := strings.Split(row, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return cells
+}
+
+var (
+	linkPattern   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strongPattern = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	italicPattern = regexp.MustCompile(`(^|[^*\w])\*([^*\s](?:[^*]*[^*\s])?)\*`)
+)
+
+// plainInline removes inline Markdown formatting, keeping link targets after their text
+func plainInline(text string) string {
+	text = linkPattern.ReplaceAllString(text, "$1 ($2)")
+	text = strongPattern.ReplaceAllString(text, "$1$2")
+	text = italicPattern.ReplaceAllString(text, "$1$2")
+	return strings.ReplaceAll(text, "`", "")
+}
@@ -0,0 +1,312 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Page geometry in points: A4 with equal margins
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	pageMargin   = 50.0
+	contentWidth = pageWidth - 2*pageMargin
+)
+
+// pdfFont is one of the standard PDF fonts, which every viewer has and which need no embedding
+type pdfFont int
+
+const (
+	fontRegular pdfFont = iota
+	fontBold
+	fontItalic
+	fontMono
+)
+
+// baseFonts are the PDF names of the fonts, in pdfFont order
+var baseFonts = []string{"Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Courier"}
+
+// helveticaWidths are the advance widths of printable ASCII in Helvetica, in thousandths of the font size
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+// winAnsiSpecials maps characters outside Latin-1 to their WinAnsiEncoding codes
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, '‰': 0x89,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+	'→': '>', '←': '<', '✓': '+', '✔': '+', '✅': '+', '❌': 'x', '⚠': '!',
+}
+
+// PDF renders the report as a PDF document. Text is set in the standard fonts, so characters
+// they cannot show, such as emoji, are left out.
+func (r Report) PDF() []byte {
+	layout := &pdfLayout{}
+	layout.newPage()
+
+	inCode := false
+	for _, line := range strings.Split(r.Markdown(), "\n") {
+		if inCode {
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				inCode = false
+				layout.space(4)
+			} else {
+				layout.paragraph(fontMono, 8.5, 10, "", strings.ReplaceAll(line, "\t", "    "))
+			}
+			continue
+		}
+
+		parsed := classifyLine(line)
+		switch parsed.kind {
+		case lineBlank:
+			layout.space(5)
+		case lineFence:
+			inCode = true
+			layout.space(4)
+		case lineHeading:
+			sizes := []float64{18, 15, 12.5, 11}
+			size := sizes[min(parsed.level, len(sizes))-1]
+			layout.space(size * 0.6)
+			layout.paragraph(fontBold, size, 0, "", plainInline(parsed.text))
+			layout.space(2)
+		case lineRule:
+			layout.rule()
+		case lineQuote:
+			layout.paragraph(fontItalic, 10.5, 12, "", plainInline(parsed.text))
+		case lineBullet:
+			layout.paragraph(fontRegular, 10.5, 14*float64(parsed.level), "•", plainInline(parsed.text))
+		case lineNumbered:
+			layout.paragraph(fontRegular, 10.5, 14*float64(parsed.level), parsed.marker+".", plainInline(parsed.text))
+		case lineTableDivider:
+			// The divider only marks the row above it as the header
+		case lineTableRow:
+			layout.paragraph(fontRegular, 9.5, 0, "", plainInline(strings.Join(tableCells(parsed.text), "   |   ")))
+		default:
+			layout.paragraph(fontRegular, 10.5, 0, "", plainInline(parsed.text))
+		}
+	}
+	return layout.document(r.Title)
+}
+
+// pdfLayout places lines of text on pages from the top down
+type pdfLayout struct {
+	pages []*bytes.Buffer // Content stream of each page
+	y     float64         // Baseline of the next line on the current page
+}
+
+// newPage starts a page
+func (l *pdfLayout) newPage() {
+	l.pages = append(l.pages, &bytes.Buffer{})
+	l.y = pageHeight - pageMargin
+}
+
+// space adds vertical space, unless at the top of a page
+func (l *pdfLayout) space(points float64) {
+	if l.y < pageHeight-pageMargin {
+		l.y -= points
+	}
+}
+
+// rule draws a horizontal line across the text area
+func (l *pdfLayout) rule() {
+	l.space(6)
+	if l.y-8 < pageMargin {
+		l.newPage()
+		return
+	}
+	fmt.Fprintf(l.pages[len(l.pages)-1], "0.75 G 0.5 w %.1f %.1f m %.1f %.1f l S 0 G\n", pageMargin, l.y, pageWidth-pageMargin, l.y)
+	l.y -= 12
+}
+
+// paragraph wraps text to the text area, indented and with an optional marker hanging before it
+func (l *pdfLayout) paragraph(font pdfFont, size, indent float64, marker, text string) {
+	encoded := encodeWinAnsi(text)
+	if font != fontMono {
+		// Collapse the gaps left by dropped symbols; code keeps its indentation
+		encoded = strings.Join(strings.Fields(encoded), " ")
+	}
+	x := pageMargin + indent
+	if marker != "" {
+		x += 14
+	}
+	lines := wrapText(encoded, font, size, pageWidth-pageMargin-x)
+	if marker != "" && len(lines) > 0 {
+		l.line(font, size, x-14, encodeWinAnsi(marker))
+		l.y += size * 1.35 // The text starts on the marker's line
+	}
+	for _, wrapped := range lines {
+		l.line(font, size, x, wrapped)
+	}
+}
+
+// line writes one line of encoded text at x on the current line, moving to a new page when full
+func (l *pdfLayout) line(font pdfFont, size, x float64, encoded string) {
+	if l.y-size < pageMargin {
+		l.newPage()
+	}
+	l.y -= size
+	fmt.Fprintf(l.pages[len(l.pages)-1], "BT /F%d %.1f Tf %.1f %.1f Td (%s) Tj ET\n", font+1, size, x, l.y, escapePDFString(encoded))
+	l.y -= size * 0.35
+}
+
+// document assembles the pages into a PDF file with a footer on each page
+func (l *pdfLayout) document(title string) []byte {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1 and 2 are the catalog and page tree, followed by the fonts, then each page and its content
+	firstPage := 3 + len(baseFonts)
+	var kids []string
+	for i := range l.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+2*i))
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(l.pages)))
+	var fonts []string
+	for i, name := range baseFonts {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+		fonts = append(fonts, fmt.Sprintf("/F%d %d 0 R", i+1, 3+i))
+	}
+	resources := fmt.Sprintf("<< /Font << %s >> >>", strings.Join(fonts, " "))
+
+	footerTitle := encodeWinAnsi(title)
+	for i, page := range l.pages {
+		fmt.Fprintf(page, "0.45 g BT /F1 8 Tf %.1f 30 Td (%s) Tj ET\n", pageMargin, escapePDFString(truncateText(footerTitle, fontRegular, 8, contentWidth-80)))
+		number := fmt.Sprintf("Page %d of %d", i+1, len(l.pages))
+		fmt.Fprintf(page, "BT /F1 8 Tf %.1f 30 Td (%s) Tj ET 0 g\n", pageWidth-pageMargin-textWidth(number, fontRegular, 8), number)
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources %s /Contents %d 0 R >>",
+			pageWidth, pageHeight, resources, firstPage+2*i+1))
+		var compressed bytes.Buffer
+		writer := zlib.NewWriter(&compressed)
+		writer.Write(page.Bytes())
+		writer.Close()
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", compressed.Len(), compressed.String()))
+	}
+	object(fmt.Sprintf("<< /Title (%s) /Producer (BeanBot) >>", escapePDFString(footerTitle)))
+	info := len(offsets)
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, info, xref)
+	return out.Bytes()
+}
+
+// encodeWinAnsi converts text to the WinAnsiEncoding used by the standard fonts. Symbols without
+// an equivalent are dropped and other characters replaced with a question mark.
+func encodeWinAnsi(text string) string {
+	var out strings.Builder
+	for _, c := range text {
+		switch {
+		case c == '\t':
+			out.WriteString("    ")
+		case c >= 0x20 && c < 0x7f, c >= 0xa0 && c <= 0xff:
+			out.WriteByte(byte(c))
+		case winAnsiSpecials[c] != 0:
+			out.WriteByte(winAnsiSpecials[c])
+		case unicode.Is(unicode.So, c), unicode.Is(unicode.Mn, c), unicode.Is(unicode.Cf, c), unicode.IsControl(c):
+		default:
+			out.WriteByte('?')
+		}
+	}
+	return out.String()
+}
+
+// escapePDFString escapes encoded text for a PDF string literal
+func escapePDFString(encoded string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(encoded)
+}
+
+// charWidth returns the width of an encoded character in thousandths of the font size
+func charWidth(c byte, font pdfFont) float64 {
+	if font == fontMono {
+		return 600
+	}
+	width := 556.0
+	switch {
+	case c >= 0x20 && c < 0x7f:
+		width = float64(helveticaWidths[c-0x20])
+	case c == 0x85 || c == 0x97 || c == 0x89:
+		width = 1000
+	case c == 0x95:
+		width = 350
+	}
+	if font == fontBold {
+		// Helvetica-Bold is slightly wider; overestimating only wraps a little early
+		width *= 1.1
+	}
+	return width
+}
+
+// textWidth returns the width of encoded text in points
+func textWidth(encoded string, font pdfFont, size float64) float64 {
+	total := 0.0
+	for i := 0; i < len(encoded); i++ {
+		total += charWidth(encoded[i], font)
+	}
+	return total * size / 1000
+}
+
+// wrapText breaks encoded text into lines no wider than width, splitting long words if needed
+func wrapText(encoded string, font pdfFont, size, width float64) []string {
+	if encoded == "" {
+		return nil
+	}
+	var lines []string
+	current := ""
+	for _, word := range strings.Split(encoded, " ") {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if textWidth(candidate, font, size) <= width {
+			current = candidate
+			continue
+		}
+		if current != "" {
+			lines = append(lines, current)
+		}
+		for textWidth(word, font, size) > width {
+			cut := len(truncateText(word, font, size, width))
+			if cut == 0 {
+				cut = 1
+			}
+			lines = append(lines, word[:cut])
+			word = word[cut:]
+		}
+		current = word
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// truncateText returns the longest prefix of encoded text that fits in width
+func truncateText(encoded string, font pdfFont, size, width float64) string {
+	total := 0.0
+	for i := 0; i < len(encoded); i++ {
+		total += charWidth(encoded[i], font) * size / 1000
+		if total > width {
+			return encoded[:i]
+		}
+	}
+	return encoded
+}
@@ -0,0 +1,208 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beanspout/2025-beanbot/internal/session"
+)
+
+var (
+	streamPattern = regexp.MustCompile(`/Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+	textPattern   = regexp.MustCompile(`\((.*)\) Tj`)
+)
+
+// checkPDFStructure checks that every object the cross-reference table lists starts at its offset
+// and that startxref points at the table, returning the number of objects
+func checkPDFStructure(t *testing.T, data []byte) int {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("missing the PDF header or end marker")
+	}
+
+	match := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if match == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n0 ")) {
+		t.Fatalf("startxref %d does not point at the cross-reference table", xref)
+	}
+	lines := strings.Split(string(data[xref:]), "\n")
+	var count int
+	fmt.Sscanf(lines[1], "0 %d", &count)
+	if lines[2] != "0000000000 65535 f " {
+		t.Errorf("the first entry is %q, want the free list head", lines[2])
+	}
+	for number := 1; number < count; number++ {
+		entry := lines[2+number]
+		offset, err := strconv.Atoi(strings.TrimSuffix(entry, " 00000 n "))
+		if err != nil || len(entry) != 19 {
+			t.Fatalf("entry %d is %q", number, entry)
+		}
+		if want := fmt.Sprintf("%d 0 obj\n", number); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Errorf("object %d is not at offset %d", number, offset)
+		}
+	}
+	if trailer := lines[2+count]; trailer != "trailer" || !strings.HasPrefix(lines[3+count], fmt.Sprintf("<< /Size %d /Root 1 0 R /Info ", count)) {
+		t.Errorf("trailer %q %q", trailer, lines[3+count])
+	}
+	return count
+}
+
+// pdfPages returns the decompressed content stream of each page
+func pdfPages(t *testing.T, data []byte) []string {
+	t.Helper()
+	var pages []string
+	for _, match := range streamPattern.FindAllSubmatchIndex(data, -1) {
+		length, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+		if !bytes.HasPrefix(data[match[1]+length:], []byte("\nendstream")) {
+			t.Fatalf("stream length %d does not end at endstream", length)
+		}
+		reader, err := zlib.NewReader(bytes.NewReader(data[match[1] : match[1]+length]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, string(content))
+	}
+	return pages
+}
+
+// pdfText returns the text shown on a page, one string per line
+func pdfText(page string) []string {
+	var lines []string
+	for _, match := range textPattern.FindAllStringSubmatch(page, -1) {
+		lines = append(lines, match[1])
+	}
+	return lines
+}
+
+func TestPDF(t *testing.T) {
+	data := testReport().PDF()
+	fonts := len(baseFonts)
+	// Catalog, page tree, fonts, a page and its content, and the document information
+	if count := checkPDFStructure(t, data); count != 1+2+fonts+2+1 {
+		t.Errorf("%d cross-reference entries for one page", count)
+	}
+	if !bytes.Contains(data, []byte("/Count 1 >>")) || !bytes.Contains(data, []byte("/Title (Rack 3 trips) /Producer (BeanBot)")) {
+		t.Errorf("missing the page count or title")
+	}
+
+	pages := pdfPages(t, data)
+	if len(pages) != 1 {
+		t.Fatalf("%d pages, want 1", len(pages))
+	}
+	text := strings.Join(pdfText(pages[0]), "\n")
+	for _, want := range []string{
+		"Rack 3 trips",
+		"Why does rack 3 trip?",
+		"Model: llama3.2:1b",
+		"Over voltage on ch4 [1].",
+		"# not a heading",
+		"[1] Error Code E-12 \x97 errors.json",
+		"Page 1 of 1",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q in:\n%s", want, text)
+		}
+	}
+}
+
+func TestPDFPageBreaks(t *testing.T) {
+	var answer strings.Builder
+	for i := 1; i <= 150; i++ {
+		fmt.Fprintf(&answer, "- Step %d: check the fuse on channel %d and write the reading into the rack log before moving on\n", i, i)
+	}
+	answer.WriteString("\n" + strings.Repeat("x", 400))
+	report := Report{Title: "Long answer", ExportedAt: time.Now(), Turns: []session.Turn{{Question: "How?", Body: answer.String()}}}
+
+	data := report.PDF()
+	checkPDFStructure(t, data)
+	pages := pdfPages(t, data)
+	if len(pages) < 3 {
+		t.Fatalf("%d pages for 150 long steps, want them spread over several pages", len(pages))
+	}
+	if !bytes.Contains(data, []byte(fmt.Sprintf("/Count %d >>", len(pages)))) {
+		t.Errorf("the page tree does not count %d pages", len(pages))
+	}
+
+	var all []string
+	positions := regexp.MustCompile(`Td \(`)
+	for i, page := range pages {
+		lines := pdfText(page)
+		if want := fmt.Sprintf("Page %d of %d", i+1, len(pages)); lines[len(lines)-1] != want {
+			t.Errorf("page %d footer %q, want %q", i+1, lines[len(lines)-1], want)
+		}
+		for _, match := range regexp.MustCompile(`([\d.]+) ([\d.]+) Td`).FindAllStringSubmatch(page, -1) {
+			x, _ := strconv.ParseFloat(match[1], 64)
+			y, _ := strconv.ParseFloat(match[2], 64)
+			if x < pageMargin || x > pageWidth-pageMargin || (y < pageMargin && y != 30) || y > pageHeight-pageMargin {
+				t.Errorf("page %d has text outside the margins at %g, %g", i+1, x, y)
+			}
+		}
+		if len(positions.FindAllString(page, -1)) != len(lines) {
+			t.Errorf("page %d has text that could not be read back", i+1)
+		}
+		all = append(all, lines...)
+	}
+	text := strings.Join(all, "\n")
+	for _, want := range []string{"Step 1: check", "Step 150: check", "channel 150 and write"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q", want)
+		}
+	}
+	// A word wider than the page is split over lines
+	if !strings.Contains(text, strings.Repeat("x", 80)+"\n"+"xx") || strings.Contains(text, strings.Repeat("x", 400)) {
+		t.Errorf("the long word is not split")
+	}
+}
+
+func TestEncodeWinAnsi(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Check the fuse", "Check the fuse"},
+		{"Größe 5 °C", "Gr\xf6\xdfe 5 \xb0C"},
+		{"“quoted” – 5 €", "\x93quoted\x94 \x96 5 \x80"},
+		{"→ ✅ done ⚠️", "> + done !"},
+		{"Fuse 🔧 replaced", "Fuse  replaced"},
+		{"漢字 E-12", "?? E-12"},
+		{"tab\there", "tab    here"},
+	}
+	for _, tt := range tests {
+		if got := encodeWinAnsi(tt.text); got != tt.want {
+			t.Errorf("encodeWinAnsi(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPDFLeavesOutUnsupportedText(t *testing.T) {
+	report := Report{Title: "Fuse 🔧 (rack 3)", ExportedAt: time.Now(), Turns: []session.Turn{{
+		Question: "Why?",
+		Body:     "✅ Fuse 🔧 replaced on 漢字 rack (see \\manual)",
+	}}}
+	data := report.PDF()
+	checkPDFStructure(t, data)
+	text := strings.Join(pdfText(pdfPages(t, data)[0]), "\n")
+	if !strings.Contains(text, `+ Fuse replaced on ?? rack \(see \\manual\)`) {
+		t.Errorf("the answer is not encoded and escaped:\n%s", text)
+	}
+	if !bytes.Contains(data, []byte(`/Title (Fuse  \(rack 3\))`)) {
+		t.Errorf("the title is not encoded and escaped")
+	}
+	if strings.ContainsAny(text, "🔧✅漢") {
+		t.Errorf("characters outside WinAnsiEncoding were written:\n%s", text)
+	}
+}
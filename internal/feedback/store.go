@@ -0,0 +1,215 @@
+package feedback
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// Rating is the user's verdict on an answer
+type Rating string
+
+// Supported ratings
+const (
+	RatingUp   Rating = "up"
+	RatingDown Rating = "down"
+)
+
+// Entry is one piece of feedback together with everything needed to reproduce the answer
+type Entry struct {
+	ID              string                    `json:"id"`
+	Timestamp       time.Time                 `json:"timestamp"`
+	Rating          Rating                    `json:"rating"`
+	Comment         string                    `json:"comment,omitempty"`
+	CorrectFix      string                    `json:"correct_fix,omitempty"` // What actually solved the problem, if the answer was wrong
+	Resolved        bool                      `json:"resolved,omitempty"`    // The problem was solved and the fix was proposed for the knowledge base
+	Question        string                    `json:"question"`
+	Intent          string                    `json:"intent,omitempty"`
+	Sources         []string                  `json:"sources,omitempty"`       // Sources supplied to the model
+	CitedSources    []string                  `json:"cited_sources,omitempty"` // Sources the answer cited
+	Template        string                    `json:"template,omitempty"`
+	TemplateVersion string                    `json:"template_version,omitempty"`
+	Model           string                    `json:"model"`
+	RequestedModel  string                    `json:"requested_model,omitempty"`
+	Options         *models.GenerationOptions `json:"generation_options,omitempty"` // Effective options sent to Model; nil for offline answers
+	Offline         bool                      `json:"offline,omitempty"`
+	Answer          string                    `json:"answer"`
+}
+
+// Store appends feedback entries to a JSON Lines file
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewStore creates a feedback store backed by the file at path
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the location of the feedback file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Add records an entry, assigning its ID and timestamp if they are not set, and returns its ID
+func (s *Store) Add(entry Entry) (string, error) {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	if entry.ID == "" {
+		entry.ID = fmt.Sprintf("fb-%d", entry.Timestamp.UnixNano())
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal feedback: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create feedback directory: %w", err)
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", s.path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return "", fmt.Errorf("failed to write feedback to %s: %w", s.path, err)
+	}
+	return entry.ID, nil
+}
+
+// Load reads every entry in the store; a missing file means no feedback yet
+func (s *Store) Load() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", s.path, err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024) // Answers can be long
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse %s line %d: %w", s.path, lineNo, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.path, err)
+	}
+	return entries, nil
+}
+
+// Filter returns the entries with the given rating, or all entries when rating is empty
+func Filter(entries []Entry, rating Rating) []Entry {
+	if rating == "" {
+		return entries
+	}
+	var filtered []Entry
+	for _, entry := range entries {
+		if entry.Rating == rating {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// Export writes entries to path as CSV or JSON, chosen by the file extension
+func Export(entries []Entry, path string) error {
+	format := strings.ToLower(filepath.Ext(path))
+	if format != ".json" && format != ".csv" {
+		return fmt.Errorf("unsupported export format %q: use .csv or .json", filepath.Ext(path))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	switch format {
+	case ".json":
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if entries == nil {
+			entries = []Entry{}
+		}
+		if err := encoder.Encode(entries); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	case ".csv":
+		if err := writeCSV(file, entries); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+
+	return file.Close()
+}
+
+// writeCSV writes one row per entry with list fields joined by "; "
+func writeCSV(file *os.File, entries []Entry) error {
+	writer := csv.NewWriter(file)
+	header := []string{"id", "timestamp", "rating", "comment", "correct_fix", "question", "intent",
+		"resolved", "sources", "cited_sources", "template", "template_version", "model", "requested_model", "generation_options", "offline", "answer"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		var options string
+		if entry.Options != nil {
+			options = entry.Options.String()
+		}
+		row := []string{
+			entry.ID,
+			entry.Timestamp.Format(time.RFC3339),
+			string(entry.Rating),
+			entry.Comment,
+			entry.CorrectFix,
+			entry.Question,
+			entry.Intent,
+			fmt.Sprintf("%t", entry.Resolved),
+			strings.Join(entry.Sources, "; "),
+			strings.Join(entry.CitedSources, "; "),
+			entry.Template,
+			entry.TemplateVersion,
+			entry.Model,
+			entry.RequestedModel,
+			options,
+			fmt.Sprintf("%t", entry.Offline),
+			entry.Answer,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
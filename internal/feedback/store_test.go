@@ -0,0 +1,153 @@
+package feedback
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// Generation options recorded with upEntry
+var temperature, seed = 0.2, 42
+
+var (
+	upEntry = Entry{
+		ID: "fb-1", Timestamp: time.Date(2021, 3, 2, 9, 5, 0, 0, time.UTC), Rating: RatingUp,
+		Question: "What does E-12 mean?", Sources: []string{"Error Code E-12", "manual.pdf"}, Model: "llama3.2:1b",
+		Options: &models.GenerationOptions{Temperature: &temperature, Seed: &seed, Stop: []string{"###"}},
+		Answer:  "Over voltage, see \"Limits\",\nline two",
+	}
+	downEntry = Entry{
+		ID: "fb-2", Timestamp: time.Date(2021, 3, 3, 10, 0, 0, 0, time.UTC), Rating: RatingDown,
+		Comment: "Wrong rack", CorrectFix: "Replace the fuse", Resolved: true, Question: "Rack 3 trips", Model: "llama3.2:1b", Offline: true,
+	}
+)
+
+func TestStoreAddLoad(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "data", "feedback.jsonl"))
+	if entries, err := store.Load(); entries != nil || err != nil {
+		t.Errorf("a missing file loaded %v, %v", entries, err)
+	}
+
+	for _, entry := range []Entry{upEntry, downEntry} {
+		if id, err := store.Add(entry); err != nil || id != entry.ID {
+			t.Fatalf("Add() = %q, %v", id, err)
+		}
+	}
+	id, err := store.Add(Entry{Rating: RatingDown, Question: "No ID yet"})
+	if err != nil || !strings.HasPrefix(id, "fb-") {
+		t.Fatalf("Add() = %q, %v, want a generated ID", id, err)
+	}
+
+	entries, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(entries) != 3 || !reflect.DeepEqual(entries[:2], []Entry{upEntry, downEntry}) {
+		t.Errorf("Load() = %+v", entries)
+	}
+	if entries[2].ID != id || entries[2].Timestamp.IsZero() {
+		t.Errorf("the generated ID and timestamp were not stored: %+v", entries[2])
+	}
+
+	// Each entry is one line appended to the file
+	data, _ := os.ReadFile(store.Path())
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 3 {
+		t.Errorf("the store has %d lines, want 3", len(lines))
+	}
+}
+
+func TestStoreLoadErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feedback.jsonl")
+	if err := os.WriteFile(path, []byte("{\"id\": \"fb-1\"}\n\n  \nnot json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStore(path).Load(); err == nil || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("Load() error = %v, want the malformed line reported", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	entries := []Entry{upEntry, downEntry}
+	if got := Filter(entries, RatingDown); !reflect.DeepEqual(got, []Entry{downEntry}) {
+		t.Errorf("Filter(down) = %v", got)
+	}
+	if got := Filter(entries, ""); !reflect.DeepEqual(got, entries) {
+		t.Errorf("Filter(\"\") = %v, want every entry", got)
+	}
+	if got := Filter([]Entry{upEntry}, RatingDown); got != nil {
+		t.Errorf("Filter() = %v, want none", got)
+	}
+}
+
+func TestExportJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "negative.JSON")
+	if err := Export(Filter([]Entry{upEntry, downEntry}, RatingDown), path); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	var exported []Entry
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &exported); err != nil || !reflect.DeepEqual(exported, []Entry{downEntry}) {
+		t.Errorf("exported %+v, %v", exported, err)
+	}
+
+	empty := filepath.Join(dir, "empty.json")
+	if err := Export(nil, empty); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if data, _ := os.ReadFile(empty); strings.TrimSpace(string(data)) != "[]" {
+		t.Errorf("an empty export wrote %q, want []", data)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feedback.csv")
+	if err := Export([]Entry{upEntry, downEntry}, path); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("the export is not valid CSV: %v", err)
+	}
+
+	if len(rows) != 3 || rows[0][0] != "id" || rows[0][len(rows[0])-1] != "answer" {
+		t.Fatalf("rows = %v", rows)
+	}
+	column := make(map[string]int)
+	for i, name := range rows[0] {
+		column[name] = i
+	}
+	for name, want := range map[string]string{
+		"timestamp": "2021-03-02T09:05:00Z", "rating": "up", "sources": "Error Code E-12; manual.pdf",
+		"resolved": "false", "answer": upEntry.Answer, "generation_options": `temperature=0.2, seed=42, stop=["###"]`,
+	} {
+		if got := rows[1][column[name]]; got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if got := rows[2][column["correct_fix"]]; got != "Replace the fuse" || rows[2][column["offline"]] != "true" || rows[2][column["generation_options"]] != "" {
+		t.Errorf("second row = %v", rows[2])
+	}
+}
+
+func TestExportUnsupportedFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feedback.xlsx")
+	if err := Export([]Entry{upEntry}, path); err == nil {
+		t.Error("an .xlsx export was accepted")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("a file was created for an unsupported format")
+	}
+}
@@ -0,0 +1,301 @@
+package grounding
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+const (
+	// minClaimWords is the number of words a sentence needs to count as a claim
+	minClaimWords = 5
+	// maxListedUnsupported is the number of unsupported statements listed in the report
+	maxListedUnsupported = 5
+	// footnoteExcerptLength is the length of the passage excerpt shown in footnotes
+	footnoteExcerptLength = 120
+)
+
+var (
+	// citationPattern matches inline citations such as [P1] or [P1, P3]
+	citationPattern = regexp.MustCompile(`\[\s*(P\d+(?:\s*[,;]\s*P\d+)*)\s*\]`)
+	// idPattern matches a single passage ID inside a citation
+	idPattern = regexp.MustCompile(`P\d+`)
+	// listMarkerPattern matches list markers at the start of a line
+	listMarkerPattern = regexp.MustCompile(`^\s*(?:[-*+]|\d+\.)\s+`)
+)
+
+// stopWords are ignored when checking whether a statement shares terms with a passage
+var stopWords = map[string]bool{
+	"this": true, "that": true, "with": true, "from": true, "have": true, "will": true, "your": true,
+	"then": true, "than": true, "there": true, "which": true, "when": true, "should": true, "could": true,
+	"would": true, "also": true, "into": true, "they": true, "them": true, "been": true, "step": true,
+	"check": true, "ensure": true, "make": true, "sure": true, "following": true, "issue": true,
+}
+
+// Claim is a statement in an answer and the passages it cites
+type Claim struct {
+	Text      string   // Statement text without citations
+	Citations []string // Cited passage IDs that were in the context
+	Invalid   []string // Cited passage IDs that were not in the context
+	Weak      []string // Valid citations whose passage shares no key terms with the statement
+}
+
+// Supported reports whether the claim cites at least one passage that was in the context
+func (c Claim) Supported() bool {
+	return len(c.Citations) > 0
+}
+
+// Report is the result of checking an answer against the supplied passages
+type Report struct {
+	Claims    []Claim
+	Cited     []string // Valid passage IDs in order of first citation; footnote n is Cited[n-1]
+	Invalid   []string // Passage IDs cited but not supplied
+	Weak      []string // Passage IDs cited for a statement they share no terms with
+	passages  map[string]models.Passage
+	footnotes map[string]int
+}
+
+// Unsupported returns the claims that cite no supplied passage
+func (r *Report) Unsupported() []Claim {
+	var unsupported []Claim
+	for _, claim := range r.Claims {
+		if !claim.Supported() {
+			unsupported = append(unsupported, claim)
+		}
+	}
+	return unsupported
+}
+
+// IsCited reports whether the passage with the given ID was cited
+func (r *Report) IsCited(id string) bool {
+	_, ok := r.footnotes[id]
+	return ok
+}
+
+// Footnote returns the footnote number of a cited passage, or 0 if it was not cited
+func (r *Report) Footnote(id string) int {
+	return r.footnotes[id]
+}
+
+// Annotate verifies the inline passage citations in an answer. Valid citations are replaced
+// by footnote numbers, citations of passages that were not supplied are marked, and claims
+// with no supporting passage are flagged with ⚠️.
+func Annotate(answer string, passages []models.Passage) (string, *Report) {
+	report := &Report{
+		passages:  make(map[string]models.Passage, len(passages)),
+		footnotes: make(map[string]int),
+	}
+	for _, passage := range passages {
+		report.passages[passage.ID] = passage
+	}
+
+	lines := strings.Split(answer, "\n")
+	for i, line := range lines {
+		if !isProse(line) {
+			lines[i] = report.replaceCitations(line, nil)
+			continue
+		}
+
+		var annotated strings.Builder
+		for _, sentence := range splitSentences(line) {
+			claim := Claim{Text: strings.TrimSpace(citationPattern.ReplaceAllString(sentence, ""))}
+			for _, match := range citationPattern.FindAllStringSubmatch(sentence, -1) {
+				for _, id := range idPattern.FindAllString(match[1], -1) {
+					if passage, ok := report.passages[id]; ok {
+						claim.Citations = append(claim.Citations, id)
+						if !sharesTerms(claim.Text, passage.Text) {
+							claim.Weak = append(claim.Weak, id)
+							report.Weak = appendUnique(report.Weak, id)
+						}
+					} else {
+						claim.Invalid = append(claim.Invalid, id)
+						report.Invalid = appendUnique(report.Invalid, id)
+					}
+				}
+			}
+
+			text := report.replaceCitations(sentence, &claim)
+			if countWords(claim.Text) >= minClaimWords {
+				report.Claims = append(report.Claims, claim)
+				if !claim.Supported() {
+					text = strings.TrimRight(text, " ") + " ⚠️ "
+				}
+			}
+			annotated.WriteString(text)
+		}
+		lines[i] = strings.TrimRight(annotated.String(), " ")
+	}
+
+	return strings.Join(lines, "\n"), report
+}
+
+// replaceCitations rewrites the citations in text as footnote numbers
+func (r *Report) replaceCitations(text string, claim *Claim) string {
+	return citationPattern.ReplaceAllStringFunc(text, func(citation string) string {
+		var marks []string
+		for _, id := range idPattern.FindAllString(citation, -1) {
+			if _, ok := r.passages[id]; !ok {
+				marks = append(marks, id+"?")
+				if claim == nil {
+					r.Invalid = appendUnique(r.Invalid, id)
+				}
+				continue
+			}
+			if _, ok := r.footnotes[id]; !ok {
+				r.Cited = append(r.Cited, id)
+				r.footnotes[id] = len(r.Cited)
+			}
+			marks = append(marks, fmt.Sprintf("%d", r.footnotes[id]))
+		}
+		return "[" + strings.Join(marks, ", ") + "]"
+	})
+}
+
+// Render returns the citation footnotes and the grounding summary as markdown
+func (r *Report) Render() string {
+	var out strings.Builder
+
+	if len(r.Cited) > 0 {
+		out.WriteString("\n\n## **🔗 Citations:**\n\n")
+		for i, id := range r.Cited {
+			passage := r.passages[id]
+			out.WriteString(fmt.Sprintf("%d. **%s** — *\"%s\"*\n", i+1, passage.Source, excerpt(passage.Text)))
+		}
+	}
+
+	supported := len(r.Claims) - len(r.Unsupported())
+	out.WriteString("\n\n## **🧪 Grounding Check:**\n\n")
+	out.WriteString(fmt.Sprintf("- %d of %d statements cite the knowledge base\n", supported, len(r.Claims)))
+	if unsupported := r.Unsupported(); len(unsupported) > 0 {
+		out.WriteString(fmt.Sprintf("- ⚠️ %d statements have no supporting passage and may not come from your documentation:\n", len(unsupported)))
+		for i, claim := range unsupported {
+			if i >= maxListedUnsupported {
+				out.WriteString(fmt.Sprintf("  - *...and %d more*\n", len(unsupported)-maxListedUnsupported))
+				break
+			}
+			out.WriteString(fmt.Sprintf("  - \"%s\"\n", excerpt(stripMarkdown(claim.Text))))
+		}
+	}
+	if len(r.Invalid) > 0 {
+		out.WriteString(fmt.Sprintf("- ❌ Cited passages that were never provided: %s\n", strings.Join(r.Invalid, ", ")))
+	}
+	if len(r.Weak) > 0 {
+		var notes []string
+		for _, id := range r.Weak {
+			notes = append(notes, fmt.Sprintf("[%d]", r.footnotes[id]))
+		}
+		out.WriteString(fmt.Sprintf("- ❓ Citations that share no key terms with their statement: %s\n", strings.Join(notes, ", ")))
+	}
+	return out.String()
+}
+
+// isProse reports whether a line contains statements, as opposed to headings, rules or blank lines
+func isProse(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" &&
+		!strings.HasPrefix(trimmed, "#") &&
+		!strings.HasPrefix(trimmed, "---") &&
+		!strings.HasPrefix(trimmed, "|") &&
+		!strings.HasPrefix(trimmed, "```")
+}
+
+// splitSentences splits a line after sentence punctuation, keeping a citation that follows the
+// punctuation with the sentence it belongs to
+func splitSentences(line string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(line); i++ {
+		if line[i] != '.' && line[i] != '!' && line[i] != '?' {
+			continue
+		}
+		end := i + 1
+		if end < len(line) && line[end] != ' ' {
+			continue // Inside a number, version or file name
+		}
+		if isListNumber(line[start:end]) {
+			continue
+		}
+
+		// Attach citations that directly follow the punctuation
+		for {
+			rest := line[end:]
+			trimmed := strings.TrimLeft(rest, " ")
+			loc := citationPattern.FindStringIndex(trimmed)
+			if loc == nil || loc[0] != 0 {
+				break
+			}
+			end += len(rest) - len(trimmed) + loc[1]
+		}
+
+		// Keep the following space with this sentence so the line can be rebuilt unchanged
+		for end < len(line) && line[end] == ' ' {
+			end++
+		}
+		sentences = append(sentences, line[start:end])
+		start = end
+		i = end - 1
+	}
+	if start < len(line) {
+		sentences = append(sentences, line[start:])
+	}
+	return sentences
+}
+
+// isListNumber reports whether text is only a numbered list marker such as "1."
+func isListNumber(text string) bool {
+	trimmed := strings.TrimSpace(listMarkerPattern.ReplaceAllString(text+" ", ""))
+	return trimmed == ""
+}
+
+// sharesTerms reports whether the statement and passage share at least one key term
+func sharesTerms(statement, passage string) bool {
+	lowerPassage := strings.ToLower(passage)
+	terms := 0
+	for _, word := range strings.FieldsFunc(strings.ToLower(statement), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) < 4 || stopWords[word] {
+			continue
+		}
+		terms++
+		if strings.Contains(lowerPassage, word) {
+			return true
+		}
+	}
+	// Statements made only of common words cannot be judged
+	return terms == 0
+}
+
+// countWords counts the words of a statement, ignoring markdown markers
+func countWords(text string) int {
+	return len(strings.Fields(stripMarkdown(text)))
+}
+
+// stripMarkdown removes list markers and emphasis from a statement
+func stripMarkdown(text string) string {
+	text = listMarkerPattern.ReplaceAllString(text, "")
+	return strings.TrimSpace(strings.NewReplacer("**", "", "__", "", "`", "").Replace(text))
+}
+
+// excerpt shortens text to a single line for display
+func excerpt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) > footnoteExcerptLength {
+		return string(runes[:footnoteExcerptLength]) + "..."
+	}
+	return text
+}
+
+// appendUnique appends value if it is not already present
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,118 @@
+package grounding
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+var testPassages = []models.Passage{
+	{ID: "P1", Source: "Error Code E-12", Text: "E-12 means the channel voltage exceeded the rack limit. Replace the fuse."},
+	{ID: "P2", Source: "PDF: manual.pdf", Text: "Calibrate the thermocouples every six months."},
+}
+
+func TestAnnotate(t *testing.T) {
+	tests := []struct {
+		name        string
+		answer      string
+		annotated   string
+		cited       []string
+		invalid     []string
+		weak        []string
+		unsupported []string
+	}{
+		{
+			name:      "supported citations become footnotes",
+			answer:    "Replace the fuse when the channel voltage is high [P1]. Calibrate the thermocouples twice a year [P2, P1].",
+			annotated: "Replace the fuse when the channel voltage is high [1]. Calibrate the thermocouples twice a year [2, 1].",
+			cited:     []string{"P1", "P2"},
+			weak:      []string{"P1"}, // Cited for the calibration interval it does not mention
+		},
+		{
+			name:        "unknown passage ID",
+			answer:      "Reset the controller from the front panel [P7].",
+			annotated:   "Reset the controller from the front panel [P7?]. ⚠️",
+			invalid:     []string{"P7"},
+			unsupported: []string{"Reset the controller from the front panel ."},
+		},
+		{
+			name:        "no citations",
+			answer:      "Power cycle the rack and wait ten minutes. Then try again.",
+			annotated:   "Power cycle the rack and wait ten minutes. ⚠️ Then try again.",
+			unsupported: []string{"Power cycle the rack and wait ten minutes."},
+		},
+		{
+			name:      "citation without shared terms",
+			answer:    "Upgrade the network switch firmware first [P2].",
+			annotated: "Upgrade the network switch firmware first [1].",
+			cited:     []string{"P2"},
+			weak:      []string{"P2"},
+		},
+		{
+			name:      "headings and short sentences are not claims",
+			answer:    "## Fix [P1]\n\nSee below.\n1. Replace the fuse on the failed channel [P1].",
+			annotated: "## Fix [1]\n\nSee below.\n1. Replace the fuse on the failed channel [1].",
+			cited:     []string{"P1"},
+		},
+		{
+			name:      "numbers and file names do not end sentences",
+			answer:    "Set the limit to 4.2 V in config.yaml before restarting [P1].",
+			annotated: "Set the limit to 4.2 V in config.yaml before restarting [1].",
+			cited:     []string{"P1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotated, report := Annotate(tt.answer, testPassages)
+			if annotated != tt.annotated {
+				t.Errorf("Annotate() =\n%q\nwant\n%q", annotated, tt.annotated)
+			}
+			var unsupported []string
+			for _, claim := range report.Unsupported() {
+				unsupported = append(unsupported, claim.Text)
+			}
+			for _, check := range []struct {
+				name      string
+				got, want []string
+			}{
+				{"cited", report.Cited, tt.cited},
+				{"invalid", report.Invalid, tt.invalid},
+				{"weak", report.Weak, tt.weak},
+				{"unsupported", unsupported, tt.unsupported},
+			} {
+				if !reflect.DeepEqual(check.got, check.want) {
+					t.Errorf("%s = %q, want %q", check.name, check.got, check.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReportRender(t *testing.T) {
+	_, report := Annotate("Replace the fuse when the channel voltage is high [P1]. "+
+		"Upgrade the network switch firmware first [P2]. "+
+		"Reset the controller from the front panel [P9]. "+
+		"Power cycle the rack and wait ten minutes.", testPassages)
+	rendered := report.Render()
+
+	for _, want := range []string{
+		"1. **Error Code E-12** — *\"E-12 means the channel voltage exceeded the rack limit. Replace the fuse.\"*",
+		"2. **PDF: manual.pdf**",
+		"- 2 of 4 statements cite the knowledge base",
+		"- ⚠️ 2 statements have no supporting passage",
+		"  - \"Power cycle the rack and wait ten minutes.\"",
+		"- ❌ Cited passages that were never provided: P9",
+		"- ❓ Citations that share no key terms with their statement: [2]",
+	} {
+		if !strings.Contains(rendered, want) {
+			t.Errorf("missing %q in:\n%s", want, rendered)
+		}
+	}
+
+	_, empty := Annotate("", testPassages)
+	if rendered := empty.Render(); strings.Contains(rendered, "Citations:") || !strings.Contains(rendered, "0 of 0 statements") {
+		t.Errorf("an answer without statements rendered:\n%s", rendered)
+	}
+}
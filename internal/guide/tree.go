@@ -0,0 +1,202 @@
+package guide
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// Answer records the branch chosen at a decision node
+type Answer struct {
+	NodeID   string
+	Question string
+	Answer   string
+}
+
+// Session walks a technician through an error code's decision tree one question at a time
+type Session struct {
+	ErrorCode models.ErrorCode
+	Answers   []Answer
+
+	nodes map[string]models.DecisionNode
+	path  []string // IDs of the visited nodes; the last one is the current node
+}
+
+// NewSession starts a guided session at the first node of the error code's decision tree
+func NewSession(errorCode models.ErrorCode) (*Session, error) {
+	if errorCode.DecisionTree == nil {
+		return nil, fmt.Errorf("%s has no decision tree", errorCode.Code)
+	}
+	if problems := Validate(errorCode.DecisionTree); len(problems) > 0 {
+		return nil, fmt.Errorf("the decision tree for %s is invalid: %s", errorCode.Code, strings.Join(problems, "; "))
+	}
+
+	nodes := make(map[string]models.DecisionNode, len(errorCode.DecisionTree.Nodes))
+	for _, node := range errorCode.DecisionTree.Nodes {
+		nodes[node.ID] = node
+	}
+	return &Session{
+		ErrorCode: errorCode,
+		nodes:     nodes,
+		path:      []string{errorCode.DecisionTree.Start},
+	}, nil
+}
+
+// Current returns the node being asked, or the outcome once the session is finished
+func (s *Session) Current() models.DecisionNode {
+	return s.nodes[s.path[len(s.path)-1]]
+}
+
+// Step returns the 1-based number of the current node along the path taken
+func (s *Session) Step() int {
+	return len(s.path)
+}
+
+// Finished reports whether the session has reached an outcome
+func (s *Session) Finished() bool {
+	return len(s.Current().Branches) == 0
+}
+
+// Escalated reports whether the session ended by escalating the problem
+func (s *Session) Escalated() bool {
+	return s.Finished() && s.Current().Escalate
+}
+
+// Choose records the answer at index among the current node's branches and moves to its next node
+func (s *Session) Choose(index int) error {
+	node := s.Current()
+	if index < 0 || index >= len(node.Branches) {
+		return errors.New("no such answer")
+	}
+	branch := node.Branches[index]
+	s.Answers = append(s.Answers, Answer{NodeID: node.ID, Question: node.Question, Answer: branch.Answer})
+	s.path = append(s.path, branch.Next)
+	return nil
+}
+
+// Back returns to the previous question, discarding its answer. It reports false at the first question.
+func (s *Session) Back() bool {
+	if len(s.path) <= 1 {
+		return false
+	}
+	s.path = s.path[:len(s.path)-1]
+	s.Answers = s.Answers[:len(s.Answers)-1]
+	return true
+}
+
+// Summary renders the outcome and the recorded answers as markdown. An escalation summary
+// also carries the error details so it can be handed to the escalation team as is.
+func (s *Session) Summary() string {
+	node := s.Current()
+	var summary strings.Builder
+	summary.WriteString(fmt.Sprintf("## **🧭 Guided troubleshooting: %s**\n\n", s.ErrorCode.Code))
+
+	switch {
+	case !s.Finished():
+		summary.WriteString("**Outcome:** ⏸️ Not finished\n\n")
+	case node.Escalate:
+		summary.WriteString("**Outcome:** ⬆️ Escalate")
+		if s.ErrorCode.Escalation != nil {
+			summary.WriteString(fmt.Sprintf(" to **%s**", s.ErrorCode.Escalation))
+		}
+		summary.WriteString("\n\n")
+		summary.WriteString(fmt.Sprintf("- **Error:** %s - %s (%s severity)\n", s.ErrorCode.Code, s.ErrorCode.Description, s.ErrorCode.Severity))
+		if node.Resolution != "" {
+			summary.WriteString(fmt.Sprintf("- **Reason:** %s\n", node.Resolution))
+		}
+		summary.WriteString("\n")
+	default:
+		summary.WriteString(fmt.Sprintf("**Outcome:** ✅ %s\n\n", node.Resolution))
+	}
+
+	if len(s.Answers) > 0 {
+		summary.WriteString("### Checks performed\n\n")
+		for i, answer := range s.Answers {
+			summary.WriteString(fmt.Sprintf("%d. %s - **%s**\n", i+1, answer.Question, answer.Answer))
+		}
+	}
+	return summary.String()
+}
+
+// Validate returns the problems that would stop a decision tree from being walked: a missing
+// start node, duplicate or missing node IDs, branches to unknown nodes, questions without
+// branches or outcomes, loops, and nodes that cannot be reached from the start
+func Validate(tree *models.DecisionTree) []string {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	nodes := make(map[string]models.DecisionNode)
+	for i, node := range tree.Nodes {
+		if strings.TrimSpace(node.ID) == "" {
+			add("node #%d has no id", i+1)
+			continue
+		}
+		if _, exists := nodes[node.ID]; exists {
+			add("node %s is defined more than once", node.ID)
+		}
+		nodes[node.ID] = node
+	}
+	if _, ok := nodes[tree.Start]; !ok {
+		add("start node %q is not defined", tree.Start)
+		return problems
+	}
+
+	for _, node := range tree.Nodes {
+		if len(node.Branches) == 0 {
+			if strings.TrimSpace(node.Resolution) == "" && !node.Escalate {
+				add("node %s has no branches, resolution or escalation", node.ID)
+			}
+			continue
+		}
+		if strings.TrimSpace(node.Question) == "" {
+			add("node %s has branches but no question", node.ID)
+		}
+		if node.Escalate {
+			add("node %s has branches and escalates; only outcomes can escalate", node.ID)
+		}
+		for _, branch := range node.Branches {
+			if strings.TrimSpace(branch.Answer) == "" {
+				add("node %s has a branch without an answer", node.ID)
+			}
+			if _, ok := nodes[branch.Next]; !ok {
+				add("node %s answer %q leads to unknown node %q", node.ID, branch.Answer, branch.Next)
+			}
+		}
+	}
+
+	// Walk from the start to find loops and unreachable nodes
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var visit func(id string)
+	visit = func(id string) {
+		switch state[id] {
+		case visiting:
+			add("node %s can be reached again from itself, so the walk may never end", id)
+			return
+		case done:
+			return
+		}
+		state[id] = visiting
+		for _, branch := range nodes[id].Branches {
+			if _, ok := nodes[branch.Next]; ok {
+				visit(branch.Next)
+			}
+		}
+		state[id] = done
+	}
+	visit(tree.Start)
+	for _, node := range tree.Nodes {
+		if node.ID != "" && state[node.ID] == 0 {
+			add("node %s cannot be reached from the start node", node.ID)
+			state[node.ID] = done // Report duplicated IDs once
+		}
+	}
+	return problems
+}
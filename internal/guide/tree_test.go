@@ -0,0 +1,203 @@
+package guide
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// supplyTree checks the supply voltage, then the fuse, before escalating
+func supplyTree() *models.DecisionTree {
+	return &models.DecisionTree{
+		Start: "voltage",
+		Nodes: []models.DecisionNode{
+			{ID: "voltage", Question: "Measure the supply output voltage", Expected: "12 V", Branches: []models.Branch{
+				{Answer: "About 12 V", Next: "fuse"},
+				{Answer: "Below 11 V", Next: "replace-supply"},
+			}},
+			{ID: "fuse", Question: "Is the channel fuse intact?", Branches: []models.Branch{
+				{Answer: "Yes", Next: "escalate"},
+				{Answer: "No", Next: "replace-fuse"},
+			}},
+			{ID: "replace-supply", Resolution: "Replace the power supply"},
+			{ID: "replace-fuse", Resolution: "Replace the fuse"},
+			{ID: "escalate", Resolution: "Supply and fuse are fine", Escalate: true},
+		},
+	}
+}
+
+// overVoltage is an error code with the supply tree and an escalation path
+func overVoltage() models.ErrorCode {
+	return models.ErrorCode{
+		Code:         "E-12",
+		Description:  "Channel over voltage",
+		Severity:     "high",
+		Escalation:   &models.Escalation{Team: "Power electronics", Contact: "power@example.com"},
+		DecisionTree: supplyTree(),
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(tree *models.DecisionTree)
+		want   []string
+	}{
+		{"valid", func(tree *models.DecisionTree) {}, nil},
+		{"missing start", func(tree *models.DecisionTree) { tree.Start = "begin" }, []string{`start node "begin" is not defined`}},
+		{"duplicate and missing IDs", func(tree *models.DecisionTree) {
+			tree.Nodes = append(tree.Nodes, models.DecisionNode{ID: "replace-fuse", Resolution: "Again"}, models.DecisionNode{Resolution: "No ID"})
+		}, []string{"node replace-fuse is defined more than once", "node #7 has no id"}},
+		{"unknown node", func(tree *models.DecisionTree) { tree.Nodes[1].Branches[0].Next = "vendor" }, []string{
+			`node fuse answer "Yes" leads to unknown node "vendor"`,
+			"node escalate cannot be reached from the start node",
+		}},
+		{"unreachable node", func(tree *models.DecisionTree) {
+			tree.Nodes = append(tree.Nodes, models.DecisionNode{ID: "orphan", Resolution: "Never shown"})
+		}, []string{"node orphan cannot be reached from the start node"}},
+		{"loop back to the start", func(tree *models.DecisionTree) { tree.Nodes[1].Branches[1].Next = "voltage" }, []string{
+			"node voltage can be reached again from itself, so the walk may never end",
+			"node replace-fuse cannot be reached from the start node",
+		}},
+		{"node leading to itself", func(tree *models.DecisionTree) { tree.Nodes[1].Branches[0].Next = "fuse" }, []string{
+			"node fuse can be reached again from itself, so the walk may never end",
+			"node escalate cannot be reached from the start node",
+		}},
+		{"incomplete nodes", func(tree *models.DecisionTree) {
+			tree.Nodes[0].Question = " "
+			tree.Nodes[1].Escalate = true
+			tree.Nodes[1].Branches[1].Answer = ""
+			tree.Nodes[3].Resolution = ""
+		}, []string{
+			"node voltage has branches but no question",
+			"node fuse has branches and escalates; only outcomes can escalate",
+			"node fuse has a branch without an answer",
+			"node replace-fuse has no branches, resolution or escalation",
+		}},
+		{"escalation needs no reason", func(tree *models.DecisionTree) { tree.Nodes[4].Resolution = "" }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := supplyTree()
+			tt.change(tree)
+			if got := Validate(tree); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSession(t *testing.T) {
+	withoutTree := overVoltage()
+	withoutTree.DecisionTree = nil
+	if _, err := NewSession(withoutTree); err == nil || err.Error() != "E-12 has no decision tree" {
+		t.Errorf("NewSession() without a tree = %v", err)
+	}
+
+	looping := overVoltage()
+	looping.DecisionTree.Nodes[1].Branches[0].Next = "fuse"
+	if _, err := NewSession(looping); err == nil || !strings.Contains(err.Error(), "the decision tree for E-12 is invalid: node fuse can be reached again") {
+		t.Errorf("NewSession() with a loop = %v", err)
+	}
+}
+
+func TestSessionWalk(t *testing.T) {
+	session, err := NewSession(overVoltage())
+	if err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	if session.Current().ID != "voltage" || session.Step() != 1 || session.Finished() || session.Back() {
+		t.Fatalf("a new session is at %s, step %d", session.Current().ID, session.Step())
+	}
+	if err := session.Choose(2); err == nil || session.Choose(-1) == nil {
+		t.Error("Choose() accepted an answer the question does not have")
+	}
+
+	// Going back discards the answer given at the question
+	if err := session.Choose(1); err != nil || session.Current().ID != "replace-supply" || !session.Finished() || session.Escalated() {
+		t.Fatalf("after Below 11 V the session is at %s (%v)", session.Current().ID, err)
+	}
+	if !session.Back() || session.Current().ID != "voltage" || len(session.Answers) != 0 || session.Step() != 1 {
+		t.Fatalf("after Back the session is at %s with answers %+v", session.Current().ID, session.Answers)
+	}
+
+	for _, index := range []int{0, 0} {
+		if err := session.Choose(index); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if session.Current().ID != "escalate" || session.Step() != 3 || !session.Escalated() {
+		t.Fatalf("the session ended at %s, step %d, escalated %v", session.Current().ID, session.Step(), session.Escalated())
+	}
+	if err := session.Choose(0); err == nil {
+		t.Error("Choose() accepted an answer at an outcome")
+	}
+	want := []Answer{
+		{NodeID: "voltage", Question: "Measure the supply output voltage", Answer: "About 12 V"},
+		{NodeID: "fuse", Question: "Is the channel fuse intact?", Answer: "Yes"},
+	}
+	if !reflect.DeepEqual(session.Answers, want) {
+		t.Errorf("answers %+v, want %+v", session.Answers, want)
+	}
+}
+
+func TestSessionSummary(t *testing.T) {
+	walk := func(t *testing.T, errorCode models.ErrorCode, answers ...int) *Session {
+		t.Helper()
+		session, err := NewSession(errorCode)
+		if err != nil {
+			t.Fatalf("NewSession: %v", err)
+		}
+		for _, index := range answers {
+			if err := session.Choose(index); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return session
+	}
+	checks := "### Checks performed\n\n" +
+		"1. Measure the supply output voltage - **About 12 V**\n" +
+		"2. Is the channel fuse intact? - **Yes**\n"
+
+	t.Run("escalated", func(t *testing.T) {
+		want := "## **🧭 Guided troubleshooting: E-12**\n\n" +
+			"**Outcome:** ⬆️ Escalate to **Power electronics (power@example.com)**\n\n" +
+			"- **Error:** E-12 - Channel over voltage (high severity)\n" +
+			"- **Reason:** Supply and fuse are fine\n\n" + checks
+		if got := walk(t, overVoltage(), 0, 0).Summary(); got != want {
+			t.Errorf("Summary() =\n%s\nwant\n%s", got, want)
+		}
+	})
+
+	t.Run("escalated without a path or reason", func(t *testing.T) {
+		errorCode := overVoltage()
+		errorCode.Escalation = nil
+		errorCode.DecisionTree.Nodes[4].Resolution = ""
+		want := "## **🧭 Guided troubleshooting: E-12**\n\n" +
+			"**Outcome:** ⬆️ Escalate\n\n" +
+			"- **Error:** E-12 - Channel over voltage (high severity)\n\n" + checks
+		if got := walk(t, errorCode, 0, 0).Summary(); got != want {
+			t.Errorf("Summary() =\n%s\nwant\n%s", got, want)
+		}
+	})
+
+	t.Run("resolved", func(t *testing.T) {
+		want := "## **🧭 Guided troubleshooting: E-12**\n\n" +
+			"**Outcome:** ✅ Replace the fuse\n\n" +
+			"### Checks performed\n\n" +
+			"1. Measure the supply output voltage - **About 12 V**\n" +
+			"2. Is the channel fuse intact? - **No**\n"
+		if got := walk(t, overVoltage(), 0, 1).Summary(); got != want {
+			t.Errorf("Summary() =\n%s\nwant\n%s", got, want)
+		}
+	})
+
+	t.Run("not finished", func(t *testing.T) {
+		want := "## **🧭 Guided troubleshooting: E-12**\n\n**Outcome:** ⏸️ Not finished\n\n"
+		if got := walk(t, overVoltage()).Summary(); got != want {
+			t.Errorf("Summary() =\n%s\nwant\n%s", got, want)
+		}
+	})
+}
@@ -0,0 +1,328 @@
+package intent
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Intent is the kind of question being asked
+type Intent string
+
+// Supported intents
+const (
+	Troubleshoot Intent = "troubleshoot"  // Something is failing and needs fixing
+	ErrorLookup  Intent = "error_lookup"  // What a specific error code means
+	HowTo        Intent = "howto"         // How to do something, or what the documentation says
+	LogAnalysis  Intent = "log_analysis"  // Summarize or analyze log output
+	MeetingNotes Intent = "meeting_notes" // What was discussed or decided in a meeting
+	ChitChat     Intent = "chitchat"      // Greetings, thanks and questions about BeanBot
+)
+
+// All lists the supported intents in the order they are described to the model
+var All = []Intent{Troubleshoot, ErrorLookup, HowTo, LogAnalysis, MeetingNotes, ChitChat}
+
+// Label returns a readable name for the intent
+func (i Intent) Label() string {
+	switch i {
+	case Troubleshoot:
+		return "Troubleshooting"
+	case ErrorLookup:
+		return "Error code lookup"
+	case HowTo:
+		return "How-to / documentation"
+	case LogAnalysis:
+		return "Log analysis"
+	case MeetingNotes:
+		return "Meeting notes"
+	case ChitChat:
+		return "Chit-chat"
+	default:
+		return string(i)
+	}
+}
+
+// Signals is what the caller knows about the question besides its text
+type Signals struct {
+	ErrorCodes []string // Error codes known to the knowledge base
+	HasUploads bool     // The user has uploaded files in this conversation
+}
+
+// Result is the outcome of classifying a question
+type Result struct {
+	Intent     Intent
+	Confidence float64  // 0 to 1
+	Reasons    []string // Rule signals that matched, or the model's answer
+	ByModel    bool     // The model decided the intent
+}
+
+// String describes the result for display and logging
+func (r Result) String() string {
+	method := "rules"
+	if r.ByModel {
+		method = "model"
+	}
+	return fmt.Sprintf("%s (%s, %.0f%%)", r.Intent.Label(), method, r.Confidence*100)
+}
+
+// AskFunc sends a prompt to a language model and returns its answer
+type AskFunc func(prompt string) (string, error)
+
+// Classifier decides the intent of a question using keyword rules, optionally asking a
+// model when the rules are not confident enough
+type Classifier struct {
+	ask       AskFunc
+	threshold float64
+}
+
+// NewClassifier creates a rule-based classifier
+func NewClassifier() *Classifier {
+	return &Classifier{}
+}
+
+// SetModel enables the model pass for questions the rules classify below threshold confidence
+func (c *Classifier) SetModel(ask AskFunc, threshold float64) {
+	c.ask = ask
+	c.threshold = threshold
+}
+
+var (
+	// timestampPattern matches times such as 14:30:25 found in pasted log lines
+	timestampPattern = regexp.MustCompile(`\b\d{1,2}:\d{2}:\d{2}\b`)
+	// logLevelPattern matches log levels and exceptions in pasted log lines
+	logLevelPattern = regexp.MustCompile(`(?i)\b(error|warn|warning|fatal|exception|traceback)\b`)
+	// wordPattern splits a question into words for greeting detection
+	wordPattern = regexp.MustCompile(`[a-z']+`)
+)
+
+// rule adds weight to an intent when any of its phrases appear in the question
+type rule struct {
+	intent  Intent
+	weight  float64
+	phrases []string
+	prefix  bool // Match only at the start of the question
+}
+
+// rules are the keyword signals for each intent
+var rules = []rule{
+	{Troubleshoot, 1, []string{"error", "problem", "troubleshoot", "timeout", "connection", "device", "communication",
+		"system", "software", "hardware", "issue", "failure", "malfunction", "not working", "doesn't work",
+		"won't", "can't", "cannot", "unable", "broken", "crash", "crashes", "fails", "failed", "failing", "stuck", "fix"}, false},
+	{ErrorLookup, 1.5, []string{"error code", "what does error", "what is error", "meaning of", "what does code", "lookup"}, false},
+	{HowTo, 2, []string{"how do", "how to", "how can", "how should", "steps to", "procedure for", "guide to", "where can i find", "where do i", "is there a guide", "documentation"}, true},
+	{HowTo, 1, []string{"what is", "what are", "explain", "describe", "install", "configure", "set up", "setup", "grant", "update"}, false},
+	{LogAnalysis, 2, []string{"log", "logs", "stack trace", "traceback", "this output", "console output"}, false},
+	{LogAnalysis, 1, []string{"summarize", "summarise", "summary", "analyze", "analyse", "analysis"}, false},
+	{MeetingNotes, 2, []string{"meeting", "minutes", "retrospective", "standup", "stand-up", "action item", "action items"}, false},
+	{MeetingNotes, 1, []string{"discussed", "decided", "agreed", "attendees", "agenda"}, false},
+}
+
+// greetings are words that make a short question chit-chat
+var greetings = map[string]bool{
+	"hi": true, "hello": true, "hey": true, "thanks": true, "thank": true, "cheers": true,
+	"morning": true, "afternoon": true, "bye": true, "goodbye": true, "who": true, "joke": true,
+}
+
+// Classify returns the intent of the question
+func (c *Classifier) Classify(question string, signals Signals) Result {
+	result := classifyRules(question, signals)
+
+	if c.ask != nil && result.Confidence < c.threshold {
+		modelIntent, answer, err := c.classifyWithModel(question)
+		if err != nil {
+			log.Printf("[DEBUG] Intent model pass failed, keeping rule result %s: %v", result, err)
+			return result
+		}
+		log.Printf("[DEBUG] Intent model pass changed %s to %s", result, modelIntent)
+		return Result{Intent: modelIntent, Confidence: c.threshold, Reasons: []string{answer}, ByModel: true}
+	}
+	return result
+}
+
+// classifyRules scores each intent from keyword rules and question shape
+func classifyRules(question string, signals Signals) Result {
+	lower := strings.ToLower(strings.TrimSpace(question))
+	scores := make(map[Intent]float64)
+	reasons := make(map[Intent][]string)
+
+	add := func(intent Intent, weight float64, reason string) {
+		scores[intent] += weight
+		reasons[intent] = append(reasons[intent], reason)
+	}
+
+	for _, r := range rules {
+		for _, phrase := range r.phrases {
+			matched := containsWord(lower, phrase)
+			if r.prefix {
+				matched = strings.HasPrefix(lower, phrase)
+			}
+			if matched {
+				add(r.intent, r.weight, phrase)
+				break
+			}
+		}
+	}
+
+	// A known error code in a short question is a lookup; in a longer one it is a symptom
+	for _, code := range signals.ErrorCodes {
+		if code != "" && strings.Contains(lower, strings.ToLower(code)) {
+			if len(strings.Fields(lower)) <= 6 {
+				add(ErrorLookup, 2.5, "code "+code)
+			} else {
+				add(ErrorLookup, 1, "code "+code)
+				add(Troubleshoot, 1, "code "+code)
+			}
+			break
+		}
+	}
+
+	// Pasted log output has several lines with timestamps or log levels
+	logLines := 0
+	for _, line := range strings.Split(question, "\n") {
+		if timestampPattern.MatchString(line) || logLevelPattern.MatchString(line) {
+			logLines++
+		}
+	}
+	if logLines >= 3 {
+		add(LogAnalysis, 3, fmt.Sprintf("%d log lines", logLines))
+	}
+	if signals.HasUploads && scores[LogAnalysis] > 0 {
+		add(LogAnalysis, 1, "uploaded files")
+	}
+
+	// Short messages made of greetings or thanks are chit-chat
+	words := wordPattern.FindAllString(lower, -1)
+	if len(words) > 0 && len(words) <= 6 {
+		for _, word := range words {
+			if greetings[word] {
+				add(ChitChat, 3, word)
+				break
+			}
+		}
+	}
+	if strings.Contains(lower, "beanbot") || strings.Contains(lower, "who are you") || strings.Contains(lower, "what can you do") {
+		add(ChitChat, 3, "about BeanBot")
+	}
+
+	best, total := Intent(""), 0.0
+	for _, intent := range All {
+		total += scores[intent]
+		if best == "" || scores[intent] > scores[best] {
+			best = intent
+		}
+	}
+
+	if scores[best] == 0 {
+		// Nothing matched: treat it as a documentation question with low confidence
+		return Result{Intent: HowTo, Confidence: 0.3, Reasons: []string{"no signals"}}
+	}
+
+	// Confidence is the share of the total score, damped when there is little evidence
+	confidence := scores[best] / total
+	if scores[best] < 2 {
+		confidence *= 0.75
+	}
+	return Result{Intent: best, Confidence: confidence, Reasons: reasons[best]}
+}
+
+// containsWord reports whether phrase appears in text on word boundaries, so "log" does not match "login"
+func containsWord(text, phrase string) bool {
+	for offset := 0; ; {
+		idx := strings.Index(text[offset:], phrase)
+		if idx < 0 {
+			return false
+		}
+		start := offset + idx
+		end := start + len(phrase)
+		if (start == 0 || !isWordByte(text[start-1])) && (end == len(text) || !isWordByte(text[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+// isWordByte reports whether b is part of a word
+func isWordByte(b byte) bool {
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+}
+
+// classifyWithModel asks the model to choose an intent
+func (c *Classifier) classifyWithModel(question string) (Intent, string, error) {
+	var categories strings.Builder
+	for _, intent := range All {
+		categories.WriteString(fmt.Sprintf("- %s: %s\n", intent, intent.Label()))
+	}
+
+	prompt := fmt.Sprintf(`Classify the engineering support question into exactly one category.
+
+Categories:
+%s
+Question: %s
+
+Reply with only the category name.`, categories.String(), question)
+
+	answer, err := c.ask(prompt)
+	if err != nil {
+		return "", "", err
+	}
+
+	intent, ok := parseModelAnswer(answer)
+	if !ok {
+		return "", "", fmt.Errorf("model answered with an unknown category: %q", strings.TrimSpace(answer))
+	}
+	return intent, strings.TrimSpace(answer), nil
+}
+
+// separatorPattern matches the punctuation and spacing models put between the words of a category
+var separatorPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// normalizeCategory lower-cases text and reduces separators to single spaces, so that
+// "How-to", "error_lookup" and "Meeting notes" compare equal to the names in the prompt
+func normalizeCategory(text string) string {
+	return strings.TrimSpace(separatorPattern.ReplaceAllString(strings.ToLower(text), " "))
+}
+
+// categoryNames returns the normalized names the model may use for an intent: its ID, its
+// label and the short form of the label
+func categoryNames(intent Intent) []string {
+	names := []string{normalizeCategory(string(intent)), normalizeCategory(intent.Label())}
+	if short, _, found := strings.Cut(intent.Label(), "/"); found {
+		names = append(names, normalizeCategory(short))
+	}
+	return names
+}
+
+// parseModelAnswer returns the intent named in a model's answer. A first line that is exactly
+// a category name wins; otherwise the category mentioned earliest in the answer is used.
+func parseModelAnswer(answer string) (Intent, bool) {
+	first := ""
+	for _, line := range strings.Split(answer, "\n") {
+		if first = normalizeCategory(line); first != "" {
+			break
+		}
+	}
+	first = strings.TrimPrefix(first, "category ")
+	for _, intent := range All {
+		for _, name := range categoryNames(intent) {
+			if first == name {
+				return intent, true
+			}
+		}
+	}
+
+	text := " " + normalizeCategory(answer) + " "
+	best, bestAt, bestLen := Intent(""), -1, 0
+	for _, intent := range All {
+		for _, name := range categoryNames(intent) {
+			at := strings.Index(text, " "+name+" ")
+			if at < 0 {
+				continue
+			}
+			if bestAt < 0 || at < bestAt || (at == bestAt && len(name) > bestLen) {
+				best, bestAt, bestLen = intent, at, len(name)
+			}
+		}
+	}
+	return best, bestAt >= 0
+}
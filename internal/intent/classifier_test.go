@@ -0,0 +1,98 @@
+package intent
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestClassifyRules(t *testing.T) {
+	codes := Signals{ErrorCodes: []string{"E-1042"}}
+	pastedLog := "14:30:25 INFO start\n14:30:26 WARN channel 3 slow\n14:30:27 ERROR channel 3 timeout\nwhat happened?"
+
+	tests := []struct {
+		name       string
+		question   string
+		signals    Signals
+		want       Intent
+		confidence float64
+	}{
+		{"troubleshoot", "The cycler is stuck and the software keeps failing", Signals{}, Troubleshoot, 0.75},
+		{"error code phrase", "What does error code E-1042 mean?", codes, ErrorLookup, 4 / 5.0},
+		{"short question with a known code", "E-1042?", codes, ErrorLookup, 1},
+		{"how-to prefix", "How do I calibrate the thermocouples?", Signals{}, HowTo, 1},
+		{"how-to keyword", "Explain the calibration procedure", Signals{}, HowTo, 0.75},
+		{"pasted log", pastedLog, Signals{}, LogAnalysis, 3 / 4.0},
+		{"log request with uploads", "Summarize the attached log", Signals{HasUploads: true}, LogAnalysis, 1},
+		{"meeting", "What was decided in the standup on 2021-03-02?", Signals{}, MeetingNotes, 1},
+		{"greeting", "hi there", Signals{}, ChitChat, 1},
+		{"about the bot", "What can you do, BeanBot?", Signals{}, ChitChat, 1},
+		{"no signals", "Rack 3 tomorrow", Signals{}, HowTo, 0.3},
+		{"log does not match login", "login page", Signals{}, HowTo, 0.3},
+		// Ties go to the intent listed first in All
+		{"tie between how-to and meeting notes", "How do I book the meeting room", Signals{}, HowTo, 0.5},
+		{"tie between troubleshooting and logs", "Summarize the failure", Signals{}, Troubleshoot, 0.375},
+		{"known code in a longer question is also a symptom", "Channel 4 keeps tripping with E-1042 after the firmware was flashed", codes, Troubleshoot, 0.375},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := classifyRules(tt.question, tt.signals)
+			if result.Intent != tt.want {
+				t.Errorf("classifyRules(%q) = %s (reasons %v), want %s", tt.question, result.Intent, result.Reasons, tt.want)
+			}
+			if diff := result.Confidence - tt.confidence; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("confidence = %v, want %v", result.Confidence, tt.confidence)
+			}
+			if result.ByModel {
+				t.Error("the rules result was marked as decided by the model")
+			}
+		})
+	}
+}
+
+func TestClassifyModelPass(t *testing.T) {
+	tests := []struct {
+		name      string
+		question  string
+		answer    string
+		err       error
+		want      Intent
+		byModel   bool
+		askCalled bool
+	}{
+		{"confident rules skip the model", "How do I calibrate the thermocouples?", "", nil, HowTo, false, false},
+		{"model decides", "Rack 3 tomorrow", " Meeting_Notes\n", nil, MeetingNotes, true, true},
+		{"model fails", "Rack 3 tomorrow", "", errors.New("connection refused"), HowTo, false, true},
+		{"unknown category", "Rack 3 tomorrow", "weather", nil, HowTo, false, true},
+		{"label with hyphen", "Rack 3 tomorrow", "How-to", nil, HowTo, true, true},
+		{"id with a space", "Rack 3 tomorrow", "error lookup", nil, ErrorLookup, true, true},
+		{"label", "Rack 3 tomorrow", "Meeting notes", nil, MeetingNotes, true, true},
+		{"full label", "Rack 3 tomorrow", "Error code lookup.", nil, ErrorLookup, true, true},
+		{"formatted", "Rack 3 tomorrow", "**Chit-chat**", nil, ChitChat, true, true},
+		{"category prefix", "Rack 3 tomorrow", "Category: Log analysis", nil, LogAnalysis, true, true},
+		{"first line wins", "Rack 3 tomorrow", "meeting_notes\nNot troubleshoot, the rack was discussed.", nil, MeetingNotes, true, true},
+		{"earliest mention", "Rack 3 tomorrow", "These look like meeting notes rather than a troubleshoot request.", nil, MeetingNotes, true, true},
+		{"word boundaries", "Rack 3 tomorrow", "The troubleshooter is listed in the logbook", nil, HowTo, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			classifier := NewClassifier()
+			classifier.SetModel(func(prompt string) (string, error) {
+				called = true
+				if !strings.Contains(prompt, "Question: "+tt.question) || !strings.Contains(prompt, "- log_analysis: Log analysis") {
+					t.Errorf("unexpected prompt:\n%s", prompt)
+				}
+				return tt.answer, tt.err
+			}, 0.6)
+
+			result := classifier.Classify(tt.question, Signals{})
+			if result.Intent != tt.want || result.ByModel != tt.byModel || called != tt.askCalled {
+				t.Errorf("Classify() = %s, model asked %v, want %s by model %v, asked %v", result, called, tt.want, tt.byModel, tt.askCalled)
+			}
+			if result.ByModel && result.Confidence != 0.6 {
+				t.Errorf("a model result has confidence %v, want the threshold", result.Confidence)
+			}
+		})
+	}
+}
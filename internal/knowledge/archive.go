@@ -0,0 +1,335 @@
+package knowledge
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Archive limits, shared by an uploaded archive and the archives nested in it so a small upload
+// cannot expand into an unbounded amount of data
+const (
+	maxArchiveDepth     = 3         // Levels of archives within archives that are opened
+	maxArchiveMembers   = 2000      // Files read from an upload
+	maxArchiveBytes     = 512 << 20 // Uncompressed bytes read from an upload
+	maxCompressionRatio = 100       // Uncompressed bytes allowed per compressed byte
+	compressionSlack    = 1 << 20   // Uncompressed bytes allowed regardless of the ratio, for small files
+)
+
+// Archive formats read from uploads
+const (
+	archiveZip   = "zip"
+	archiveTar   = "tar"
+	archiveTarGz = "tar.gz"
+	archiveGzip  = "gz" // A single compressed file, such as a rotated log
+)
+
+// errArchiveLimit is returned when an archive expands beyond the archive limits
+var errArchiveLimit = errors.New("the archive expands beyond the size limits - it may be a zip bomb")
+
+// archiveFormat returns the archive format of a file name, "" for files that are not archives,
+// or an error for archive formats that cannot be read
+func archiveFormat(name string) (string, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return archiveZip, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveTarGz, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return archiveTar, nil
+	case strings.HasSuffix(lower, ".gz"):
+		return archiveGzip, nil
+	case strings.HasSuffix(lower, ".7z"), strings.HasSuffix(lower, ".rar"):
+		return "", &skipError{SkipUnsupported, fmt.Sprintf("%s archives are not supported - extract the files or re-pack them as .zip", filepath.Ext(lower))}
+	}
+	return "", nil
+}
+
+// archiveExtraction collects the files extracted from an uploaded archive
+type archiveExtraction struct {
+	name    string // File name of the uploaded archive
+	origin  string // Location of the uploaded archive
+	tempDir string // Where members are written for the extractors
+	members int    // Files read so far, including those skipped
+	bytes   int64  // Uncompressed bytes read so far
+	docs    []Document
+	skipped []SkippedFile // Paths are relative to the uploaded archive
+}
+
+// skip records a file in the archive that was not extracted
+func (ex *archiveExtraction) skip(display, category, reason string) {
+	ex.skipped = append(ex.skipped, SkippedFile{Path: strings.TrimPrefix(display, ex.name+"/"), Category: category, Reason: reason})
+}
+
+// processArchive extracts the files of an uploaded archive with the normal extractors and stores
+// each as an upload named after its path in the archive, replacing an earlier upload of the archive
+func (kb *KnowledgeDatabase) processArchive(filePath string, info os.FileInfo, format string) (Upload, error) {
+	name := filepath.Base(filePath)
+	tempDir, err := os.MkdirTemp("", "beanbot-archive-*")
+	if err != nil {
+		return Upload{}, fmt.Errorf("failed to create a temporary directory for %s: %w", name, err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	ex := &archiveExtraction{name: name, origin: filePath, tempDir: tempDir}
+	if err := kb.readArchive(ex, filePath, name, format, 1); err != nil {
+		log.Printf("Rejected archive %s: %v", name, err)
+		return Upload{}, fmt.Errorf("cannot use %s: %w", name, err)
+	}
+	if len(ex.docs) == 0 {
+		return Upload{}, fmt.Errorf("cannot use %s: none of its %d files contain usable text", name, len(ex.skipped))
+	}
+
+	key := uploadPrefix + name
+	kb.removeArchive(key)
+	archive := Upload{
+		ID:         key,
+		Name:       name,
+		Path:       filePath,
+		Size:       info.Size(),
+		Type:       DocArchive,
+		Extractor:  format,
+		Skipped:    ex.skipped,
+		UploadedAt: time.Now(),
+	}
+	text := 0
+	for _, doc := range ex.docs {
+		doc.Archive = key
+		doc.UploadedAt = archive.UploadedAt
+		doc.ID = kb.uploads.Add(doc)
+		archive.Members = append(archive.Members, kb.describeUpload(doc))
+		text += len(doc.Content)
+	}
+	archive.Summary = fmt.Sprintf("%d of %d files extracted · %s of text", len(ex.docs), len(ex.docs)+len(ex.skipped), FormatBytes(int64(text)))
+
+	kb.mu.Lock()
+	kb.archives[key] = archive
+	archive.Pinned = kb.pinned[key]
+	kb.projects = nil
+	kb.limitChecks = nil
+	kb.mu.Unlock()
+
+	log.Printf("Extracted %d files from %s, skipped %d", len(ex.docs), name, len(ex.skipped))
+	return archive, nil
+}
+
+// readArchive extracts the members of an archive; display is the archive's path within the upload
+func (kb *KnowledgeDatabase) readArchive(ex *archiveExtraction, filePath, display, format string, depth int) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", display, err)
+	}
+	defer file.Close()
+
+	if format == archiveZip {
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", display, err)
+		}
+		reader, err := zip.NewReader(file, info.Size())
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", display, err)
+		}
+		for _, member := range reader.File {
+			if member.FileInfo().IsDir() {
+				continue
+			}
+			// The zip reader fails if a member holds more data than its header declares
+			limit := int64(member.CompressedSize64)*maxCompressionRatio + compressionSlack
+			contents, err := member.Open()
+			if err != nil {
+				ex.skip(display+"/"+cleanMemberPath(member.Name), SkipUnreadable, err.Error())
+				continue
+			}
+			err = kb.extractMember(ex, display, member.Name, member.Modified, contents, limit, depth)
+			contents.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	compressed := &countingReader{r: file}
+	var contents io.Reader = compressed
+	if format != archiveTar {
+		gz, err := gzip.NewReader(compressed)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", display, err)
+		}
+		defer gz.Close()
+		contents = &ratioReader{r: gz, compressed: compressed}
+		if format == archiveGzip {
+			member := path.Base(display)
+			return kb.extractMember(ex, display, member[:len(member)-len(".gz")], gz.ModTime, contents, maxArchiveBytes, depth)
+		}
+	}
+
+	reader := tar.NewReader(contents)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", display, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := kb.extractMember(ex, display, header.Name, header.ModTime, reader, maxArchiveBytes, depth); err != nil {
+			return err
+		}
+	}
+}
+
+// extractMember writes a file from an archive to the temporary directory and extracts its text,
+// or opens it as a nested archive. Files that cannot be used are recorded as skipped; an error
+// means the whole archive cannot be used.
+func (kb *KnowledgeDatabase) extractMember(ex *archiveExtraction, display, memberName string, modTime time.Time, contents io.Reader, limit int64, depth int) error {
+	name := cleanMemberPath(memberName)
+	if name == "" {
+		return nil
+	}
+	if escapesArchive(memberName) {
+		ex.skip(display+"/"+strings.ReplaceAll(memberName, "\\", "/"), SkipExcluded, "the path leads outside the archive")
+		return nil
+	}
+	display += "/" + name
+	if isArchiveMetadata(name) {
+		ex.skip(display, SkipExcluded, "system metadata file")
+		return nil
+	}
+	ex.members++
+	if ex.members > maxArchiveMembers {
+		ex.skip(display, SkipExcluded, fmt.Sprintf("only the first %d files of an archive are read", maxArchiveMembers))
+		return nil
+	}
+
+	tempPath := filepath.Join(ex.tempDir, fmt.Sprintf("%d-%s", ex.members, path.Base(name)))
+	if remaining := maxArchiveBytes - ex.bytes; remaining < limit {
+		limit = remaining
+	}
+	written, err := writeLimited(tempPath, display, contents, limit)
+	ex.bytes += written
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempPath)
+
+	format, err := archiveFormat(name)
+	var skip *skipError
+	switch {
+	case errors.As(err, &skip):
+		ex.skip(display, skip.category, skip.reason)
+		return nil
+	case format != "" && depth >= maxArchiveDepth:
+		ex.skip(display, SkipExcluded, fmt.Sprintf("archives nested more than %d deep are not opened", maxArchiveDepth))
+		return nil
+	case format != "":
+		return kb.readArchive(ex, tempPath, display, format, depth+1)
+	}
+
+	info, err := os.Stat(tempPath)
+	if err != nil {
+		return fmt.Errorf("failed to extract %s: %w", display, err)
+	}
+	doc, err := kb.extractFile(tempPath, info, true)
+	if err != nil {
+		if errors.As(err, &skip) {
+			ex.skip(display, skip.category, skip.reason)
+		} else {
+			ex.skip(display, SkipUnreadable, err.Error())
+		}
+		return nil
+	}
+	doc.Name = display
+	doc.Path = uploadPrefix + display
+	doc.FullPath = filepath.Join(ex.origin, filepath.FromSlash(strings.TrimPrefix(display, ex.name+"/")))
+	if !modTime.IsZero() {
+		doc.ModTime = modTime
+	}
+	ex.docs = append(ex.docs, doc)
+	return nil
+}
+
+// cleanMemberPath turns a path inside an archive into a relative path with forward slashes that
+// cannot climb out of the archive, or "" for the archive root
+func cleanMemberPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+}
+
+// escapesArchive reports whether a path inside an archive uses ".." to climb above the archive root
+func escapesArchive(name string) bool {
+	cleaned := path.Clean(strings.TrimLeft(strings.ReplaceAll(name, "\\", "/"), "/"))
+	return cleaned == ".." || strings.HasPrefix(cleaned, "../")
+}
+
+// isArchiveMetadata reports whether an archive member is a file the operating system added
+func isArchiveMetadata(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, "._") ||
+		strings.EqualFold(base, ".DS_Store") || strings.EqualFold(base, "Thumbs.db")
+}
+
+// writeLimited copies at most limit bytes of an archive member to a new file, failing with
+// errArchiveLimit if there is more
+func writeLimited(filePath, display string, contents io.Reader, limit int64) (int64, error) {
+	out, err := os.Create(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", filePath, err)
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	written, err := io.Copy(out, io.LimitReader(contents, limit+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, fmt.Errorf("failed to extract %s: %w", display, err)
+	}
+	if written > limit {
+		return written, errArchiveLimit
+	}
+	return written, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ratioReader reads decompressed data, failing with errArchiveLimit once it has expanded more
+// than maxCompressionRatio times the compressed data read
+type ratioReader struct {
+	r          io.Reader
+	compressed *countingReader
+	read       int64
+}
+
+func (r *ratioReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.read > r.compressed.n*maxCompressionRatio+compressionSlack {
+		return n, errArchiveLimit
+	}
+	return n, err
+}
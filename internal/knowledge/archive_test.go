@@ -0,0 +1,203 @@
+package knowledge
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// archiveMember is a file written to a test archive
+type archiveMember struct {
+	name string
+	data []byte
+}
+
+// textMember is an archive member holding a line of text
+func textMember(name string) archiveMember {
+	return archiveMember{name, []byte("Cycler channel notes for " + name + "\n")}
+}
+
+// zipArchive builds a zip archive in memory
+func zipArchive(t *testing.T, members ...archiveMember) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, member := range members {
+		writer, err := archive.Create(member.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write(member.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// tarGzArchive builds a tar.gz archive in memory
+func tarGzArchive(t *testing.T, members ...archiveMember) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for _, member := range members {
+		if err := archive.WriteHeader(&tar.Header{Name: member.name, Mode: 0o644, Size: int64(len(member.data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := archive.Write(member.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// uploadArchive writes an archive to a file and uploads it to an empty knowledge base
+func uploadArchive(t *testing.T, name string, data []byte) (Upload, error) {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return newTestDatabase(t, t.TempDir()).ProcessUserUpload(filePath)
+}
+
+// skippedReasons maps the skipped files of an upload to their reasons
+func skippedReasons(upload Upload) map[string]string {
+	reasons := make(map[string]string)
+	for _, skipped := range upload.Skipped {
+		reasons[skipped.Path] = skipped.Reason
+	}
+	return reasons
+}
+
+func TestArchiveRejectsPathTraversal(t *testing.T) {
+	for _, format := range []string{"bundle.zip", "bundle.tar.gz"} {
+		t.Run(format, func(t *testing.T) {
+			members := []archiveMember{textMember("../../evil.txt"), textMember("logs/../../up.txt"), textMember("/abs/root.txt"), textMember("logs/ok.txt")}
+			data := zipArchive(t, members...)
+			if format == "bundle.tar.gz" {
+				data = tarGzArchive(t, members...)
+			}
+
+			upload, err := uploadArchive(t, format, data)
+			if err != nil {
+				t.Fatalf("ProcessUserUpload: %v", err)
+			}
+			var extracted []string
+			for _, member := range upload.Members {
+				extracted = append(extracted, member.Name)
+			}
+			if want := []string{format + "/abs/root.txt", format + "/logs/ok.txt"}; strings.Join(extracted, ",") != strings.Join(want, ",") {
+				t.Errorf("extracted %v, want %v", extracted, want)
+			}
+			reasons := skippedReasons(upload)
+			for _, name := range []string{"../../evil.txt", "logs/../../up.txt"} {
+				if reasons[name] != "the path leads outside the archive" {
+					t.Errorf("%s was not rejected: %v", name, reasons)
+				}
+			}
+		})
+	}
+}
+
+func TestEscapesArchive(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"logs/app.log", false},
+		{"/logs/app.log", false},
+		{"logs/../app.log", false},
+		{"..", true},
+		{"../app.log", true},
+		{"..\\app.log", true},
+		{"logs/../../app.log", true},
+		{"/../app.log", true},
+		{"..app.log", false},
+	}
+	for _, tt := range tests {
+		if got := escapesArchive(tt.name); got != tt.want {
+			t.Errorf("escapesArchive(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestArchiveMemberLimit(t *testing.T) {
+	members := make([]archiveMember, maxArchiveMembers+2)
+	for i := range members {
+		members[i] = textMember(fmt.Sprintf("logs/%04d.txt", i))
+	}
+	upload, err := uploadArchive(t, "many.zip", zipArchive(t, members...))
+	if err != nil {
+		t.Fatalf("ProcessUserUpload: %v", err)
+	}
+	if len(upload.Members) != maxArchiveMembers {
+		t.Errorf("extracted %d files, want %d", len(upload.Members), maxArchiveMembers)
+	}
+	if len(upload.Skipped) != 2 || !strings.Contains(upload.Skipped[0].Reason, "only the first") {
+		t.Errorf("skipped %v, want the last 2 files", upload.Skipped)
+	}
+}
+
+func TestArchiveCompressionRatio(t *testing.T) {
+	bomb := archiveMember{"zeros.txt", make([]byte, 4*compressionSlack)}
+	for _, format := range []string{"bomb.zip", "bomb.tar.gz"} {
+		t.Run(format, func(t *testing.T) {
+			data := zipArchive(t, bomb)
+			if format == "bomb.tar.gz" {
+				data = tarGzArchive(t, bomb)
+			}
+			if _, err := uploadArchive(t, format, data); !errors.Is(err, errArchiveLimit) {
+				t.Errorf("ProcessUserUpload error = %v, want %v", err, errArchiveLimit)
+			}
+		})
+	}
+}
+
+func TestArchiveTotalSizeLimit(t *testing.T) {
+	ex := &archiveExtraction{name: "big.zip", tempDir: t.TempDir(), bytes: maxArchiveBytes - 10}
+	kb := newTestDatabase(t, t.TempDir())
+	err := kb.extractMember(ex, "big.zip", "last.txt", time.Time{}, bytes.NewReader(make([]byte, 100)), compressionSlack, 1)
+	if !errors.Is(err, errArchiveLimit) {
+		t.Errorf("extractMember error = %v, want %v", err, errArchiveLimit)
+	}
+}
+
+func TestNestedArchiveDepth(t *testing.T) {
+	level4 := zipArchive(t, textMember("d.txt"))
+	level3 := zipArchive(t, textMember("c.txt"), archiveMember{"level4.zip", level4})
+	level2 := zipArchive(t, textMember("b.txt"), archiveMember{"level3.zip", level3})
+	upload, err := uploadArchive(t, "level1.zip", zipArchive(t, textMember("a.txt"), archiveMember{"level2.zip", level2}))
+	if err != nil {
+		t.Fatalf("ProcessUserUpload: %v", err)
+	}
+
+	var extracted []string
+	for _, member := range upload.Members {
+		extracted = append(extracted, member.Name)
+	}
+	want := []string{"level1.zip/a.txt", "level1.zip/level2.zip/b.txt", "level1.zip/level2.zip/level3.zip/c.txt"}
+	if strings.Join(extracted, ",") != strings.Join(want, ",") {
+		t.Errorf("extracted %v, want %v", extracted, want)
+	}
+	if reason := skippedReasons(upload)["level2.zip/level3.zip/level4.zip"]; !strings.Contains(reason, "nested more than 3 deep") {
+		t.Errorf("level4.zip was not skipped for its depth: %v", upload.Skipped)
+	}
+}
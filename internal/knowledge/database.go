@@ -1,12 +1,11 @@
 package knowledge
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/beanspout/2025-beanbot/internal/models"
 	"github.com/go-ole/go-ole"
@@ -16,113 +15,97 @@ import (
 
 // KnowledgeDatabase manages all troubleshooting data
 type KnowledgeDatabase struct {
-	data          *models.TroubleshootingData
-	textFiles     map[string]string
-	pdfContents   map[string]string
-	wordContents  map[string]string
-	imageContents map[string]string
-	filePaths     map[string]string // Maps filename to full relative path
-	// User uploaded files (temporary for current session)
-	userUploads map[string]string    // Maps uploaded filename to content
-	uploadPaths map[string]string    // Maps uploaded filename to temp path
-	uploadTime  map[string]time.Time // Maps uploaded filename to upload time
+	mu             sync.RWMutex // Guards archives, pinned, projects, limitChecks, orphaned, and data, which is replaced rather than modified in place
+	data           *models.TroubleshootingData
+	profile        models.KnowledgeProfile // Document root, error codes file and indexing patterns
+	policy         models.IndexPolicy      // Size limits and formats for documents and uploads
+	root           string                  // Cleaned profile root that source references are relative to
+	include        pathRules               // Parsed profile include patterns
+	exclude        pathRules               // Parsed profile exclude patterns and the root's ignore file
+	report         IndexReport             // Files skipped while indexing and why
+	errorCodesPath string                  // Structured error codes and common issues
+	learnedPath    string                  // Reviewed fixes learned from feedback, merged on top of the error codes file
+	orphaned       []string                // Error codes with learned fixes that the error codes file no longer defines
+	documents      *DocumentStore          // Indexed files under the profile root
+	uploads        *DocumentStore          // User uploaded files for the current conversation and pinned ones
+	archives       map[string]Upload       // Uploaded archives by path; their files are in uploads
+	pinned         map[string]bool         // Paths of uploads kept when a new conversation starts
+	projects       map[string]bool         // Ticket projects whose keys are linked; nil after documents or uploads change
+	limitChecks    map[string]string       // Uploads' tables checked against limits tables in other files, by upload ID; nil after changes
 }
 
-// NewKnowledgeDatabase creates and initializes the knowledge database
-func NewKnowledgeDatabase() (*KnowledgeDatabase, error) {
-	kb := &KnowledgeDatabase{
-		textFiles:     make(map[string]string),
-		pdfContents:   make(map[string]string),
-		wordContents:  make(map[string]string),
-		imageContents: make(map[string]string),
-		filePaths:     make(map[string]string),
-		userUploads:   make(map[string]string),
-		uploadPaths:   make(map[string]string),
-		uploadTime:    make(map[string]time.Time),
+// NewKnowledgeDatabase creates and initializes the knowledge database for a profile, indexing
+// the documents under its root that the policy allows
+func NewKnowledgeDatabase(profile models.KnowledgeProfile, policy models.IndexPolicy) (*KnowledgeDatabase, error) {
+	if strings.TrimSpace(profile.ErrorCodesFile) == "" {
+		return nil, fmt.Errorf("knowledge profile %q has no error_codes_file", profile.Name)
 	}
 
-	// Load JSON data
-	jsonData, err := os.ReadFile("testData/lsie_errors.json")
-	if err != nil {
-		return nil, fmt.Errorf("failed to read lsie_errors.json: %w", err)
+	kb := &KnowledgeDatabase{
+		profile:        profile,
+		policy:         policy,
+		root:           filepath.Clean(profile.Root),
+		documents:      NewDocumentStore(),
+		uploads:        NewDocumentStore(),
+		archives:       make(map[string]Upload),
+		pinned:         make(map[string]bool),
+		errorCodesPath: profile.ErrorCodesFile,
+		learnedPath:    LearnedPath(profile.ErrorCodesFile),
 	}
 
-	if err := json.Unmarshal(jsonData, &kb.data); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal JSON data: %w", err)
+	// Load JSON data merged with the reviewed fixes learned from feedback
+	if err := kb.loadErrorCodes(); err != nil {
+		return nil, err
 	}
 
-	// Load all text files from testData directory
-	kb.loadTextFiles("testData")
+	// Index the documents under the profile root
+	kb.indexDocuments()
 
 	return kb, nil
 }
 
-// GetData returns the troubleshooting data
+// GetData returns the troubleshooting data. The returned value is a snapshot and must not be modified.
 func (kb *KnowledgeDatabase) GetData() *models.TroubleshootingData {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
 	return kb.data
 }
 
-// GetTextFiles returns the loaded text files
-func (kb *KnowledgeDatabase) GetTextFiles() map[string]string {
-	return kb.textFiles
-}
-
-// GetPDFContents returns the loaded PDF contents
-func (kb *KnowledgeDatabase) GetPDFContents() map[string]string {
-	return kb.pdfContents
-}
-
-// GetWordContents returns the loaded Word document contents
-func (kb *KnowledgeDatabase) GetWordContents() map[string]string {
-	return kb.wordContents
-}
-
-// GetImageContents returns the loaded image OCR contents
-func (kb *KnowledgeDatabase) GetImageContents() map[string]string {
-	return kb.imageContents
-}
-
-// GetFilePaths returns the mapping of filename to relative path
-func (kb *KnowledgeDatabase) GetFilePaths() map[string]string {
-	return kb.filePaths
+// Profile returns the knowledge profile the database was loaded from
+func (kb *KnowledgeDatabase) Profile() models.KnowledgeProfile {
+	return kb.profile
 }
 
-// GetUserUploads returns the user uploaded file contents
-func (kb *KnowledgeDatabase) GetUserUploads() map[string]string {
-	return kb.userUploads
+// DocumentCount returns the number of indexed documents
+func (kb *KnowledgeDatabase) DocumentCount() int {
+	return kb.documents.Len()
 }
 
-// GetUploadPaths returns the mapping of uploaded filename to temp path
-func (kb *KnowledgeDatabase) GetUploadPaths() map[string]string {
-	return kb.uploadPaths
+// Documents returns the indexed documents in path order
+func (kb *KnowledgeDatabase) Documents() []Document {
+	return kb.documents.All()
 }
 
-// formatHierarchicalPath converts a full path to hierarchical folder/file format
-func (kb *KnowledgeDatabase) formatHierarchicalPath(fullPath string) string {
-	// Remove the testData prefix and clean up
-	relativePath := strings.TrimPrefix(fullPath, "testData/")
-	relativePath = strings.TrimPrefix(relativePath, "testData\\")
-
-	// Split path into components
-	parts := strings.Split(relativePath, "/")
-	if len(parts) == 1 {
-		// Handle Windows path separators
-		parts = strings.Split(relativePath, "\\")
+// Document returns an indexed document or upload by ID
+func (kb *KnowledgeDatabase) Document(id string) (Document, bool) {
+	if doc, ok := kb.documents.Get(id); ok {
+		return doc, true
 	}
-
-	if len(parts) == 1 {
-		// File is directly in testData
-		return parts[0]
-	} else if len(parts) == 2 {
-		// File is one level deep: parent/file
-		return fmt.Sprintf("%s/%s", parts[0], parts[1])
-	} else if len(parts) >= 3 {
-		// File is two or more levels deep: parent/file (skip testData grandparent)
-		// Show last folder and file
-		return fmt.Sprintf("%s/%s", parts[len(parts)-2], parts[len(parts)-1])
+	doc, ok := kb.uploads.Get(id)
+	if ok {
+		doc.Content += kb.uploadLimitChecks()[id]
 	}
+	return doc, ok
+}
 
-	return relativePath // fallback
+// RelativePath returns a path relative to the profile root with forward slashes, or the
+// path unchanged when it is outside the root
+func (kb *KnowledgeDatabase) RelativePath(fullPath string) string {
+	relative, err := filepath.Rel(kb.root, fullPath)
+	if err != nil || strings.HasPrefix(relative, "..") {
+		return filepath.ToSlash(fullPath)
+	}
+	return filepath.ToSlash(relative)
 }
 
 // ContainsAnyKeyword checks if input contains any of the keywords
@@ -297,116 +280,27 @@ func (kb *KnowledgeDatabase) extractHTMLContent(htmlContent string) string {
 	return content.String()
 }
 
-// loadTextFiles recursively loads all text files from a directory
-func (kb *KnowledgeDatabase) loadTextFiles(dirPath string) {
-	entries, err := os.ReadDir(dirPath)
-	if err != nil {
-		return
-	}
-
-	for _, entry := range entries {
-		fullPath := dirPath + "/" + entry.Name()
-		lowerName := strings.ToLower(entry.Name())
-
-		if entry.IsDir() {
-			// Recursively load from subdirectories
-			kb.loadTextFiles(fullPath)
-		} else if strings.HasSuffix(lowerName, ".log") || strings.HasSuffix(lowerName, ".logs") ||
-			(strings.Contains(lowerName, "log") && strings.HasSuffix(lowerName, ".txt")) {
-			// Load and parse log files
-			if data, err := os.ReadFile(fullPath); err == nil {
-				content := kb.parseLogFile(string(data), entry.Name())
-				if content != "" {
-					kb.textFiles[entry.Name()] = content
-					kb.filePaths[entry.Name()] = fullPath
-				}
-			}
-		} else if strings.HasSuffix(lowerName, ".txt") {
-			// Load text files (check if they might be log files)
-			if data, err := os.ReadFile(fullPath); err == nil {
-				rawContent := string(data)
-				if kb.isLogFile(rawContent) {
-					// Process as log file
-					content := kb.parseLogFile(rawContent, entry.Name())
-					kb.textFiles[entry.Name()] = content
-				} else {
-					// Regular text file
-					kb.textFiles[entry.Name()] = rawContent
-				}
-				kb.filePaths[entry.Name()] = fullPath
-			}
-		} else if strings.HasSuffix(lowerName, ".drawio") {
-			// Load DrawIO files and extract text content
-			if data, err := os.ReadFile(fullPath); err == nil {
-				content := kb.extractDrawIOContent(string(data))
-				if content != "" {
-					kb.textFiles[entry.Name()] = content
-					kb.filePaths[entry.Name()] = fullPath
-				}
-			}
-		} else if strings.HasSuffix(lowerName, ".html") {
-			// Load HTML files and extract text content
-			if data, err := os.ReadFile(fullPath); err == nil {
-				content := kb.extractHTMLContent(string(data))
-				if content != "" {
-					kb.textFiles[entry.Name()] = content
-					kb.filePaths[entry.Name()] = fullPath
-				}
-			}
-		} else if strings.HasSuffix(lowerName, ".pdf") {
-			// Extract text from PDF files
-			content := kb.extractPDFText(fullPath)
-			if content != "" {
-				kb.pdfContents[entry.Name()] = content
-				kb.filePaths[entry.Name()] = fullPath
-			} else {
-				kb.pdfContents[entry.Name()] = "Failed to extract text from PDF - " + entry.Name()
-				kb.filePaths[entry.Name()] = fullPath
-			}
-		} else if strings.HasSuffix(lowerName, ".docx") || strings.HasSuffix(lowerName, ".doc") {
-			// Extract text from Word documents (.docx only - .doc requires conversion)
-			if strings.HasSuffix(lowerName, ".docx") {
-				content := kb.extractWordContent(fullPath)
-				if content != "" {
-					kb.wordContents[entry.Name()] = content
-					kb.filePaths[entry.Name()] = fullPath
-				} else {
-					kb.wordContents[entry.Name()] = "Failed to extract text from Word document - " + entry.Name()
-					kb.filePaths[entry.Name()] = fullPath
-				}
-			} else {
-				// .doc files need to be converted to .docx first
-				kb.wordContents[entry.Name()] = "Legacy .doc format not supported - please convert to .docx format: " + entry.Name()
-				kb.filePaths[entry.Name()] = fullPath
-			}
-		} else if strings.HasSuffix(lowerName, ".png") || strings.HasSuffix(lowerName, ".jpg") ||
-			strings.HasSuffix(lowerName, ".jpeg") || strings.HasSuffix(lowerName, ".bmp") ||
-			strings.HasSuffix(lowerName, ".gif") || strings.HasSuffix(lowerName, ".tiff") {
-			// Extract text from images using Windows OCR
-			content := kb.extractImageContent(fullPath)
-			if content != "" {
-				kb.imageContents[entry.Name()] = content
-				kb.filePaths[entry.Name()] = fullPath
-			} else {
-				kb.imageContents[entry.Name()] = "Failed to process image - " + entry.Name()
-				kb.filePaths[entry.Name()] = fullPath
-			}
+// extractPDFText extracts text content from a PDF file and returns where each page starts in it
+func (kb *KnowledgeDatabase) extractPDFText(filePath string) (content string, pages []int, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			content, pages, err = "", nil, fmt.Errorf("malformed PDF: %v", recovered)
 		}
-	}
-}
+	}()
 
-// extractPDFText extracts text content from a PDF file
-func (kb *KnowledgeDatabase) extractPDFText(filePath string) string {
 	file, reader, err := pdf.Open(filePath)
 	if err != nil {
-		return ""
+		return "", nil, err
 	}
 	defer file.Close()
 
 	var textContent strings.Builder
 	numPages := reader.NumPage()
+	pages = make([]int, 0, numPages)
 
 	for pageNum := 1; pageNum <= numPages; pageNum++ {
+		pages = append(pages, textContent.Len())
 		page := reader.Page(pageNum)
 		if page.V.IsNull() {
 			continue
@@ -439,7 +333,7 @@ func (kb *KnowledgeDatabase) extractPDFText(filePath string) string {
 	}
 
 	result := textContent.String()
-	return result
+	return result, pages, nil
 }
 
 // extractWordContent extracts text content from Word documents (.docx)
@@ -447,7 +341,7 @@ func (kb *KnowledgeDatabase) extractWordContent(filePath string) string {
 	// Read the Word document
 	doc, err := docx.ReadDocxFile(filePath)
 	if err != nil {
-		return fmt.Sprintf("Error reading Word document %s: %v", filePath, err)
+		return ""
 	}
 	defer doc.Close()
 
@@ -477,14 +371,7 @@ func (kb *KnowledgeDatabase) extractWordContent(filePath string) string {
 		}
 	}
 
-	result := textContent.String()
-
-	// If no content extracted, provide a helpful message
-	if len(strings.TrimSpace(result)) == 0 {
-		return fmt.Sprintf("Word document %s processed but no readable text content found", filePath)
-	}
-
-	return result
+	return textContent.String()
 }
 
 // extractImageContent extracts text from images using Windows built-in OCR
@@ -525,119 +412,6 @@ func (kb *KnowledgeDatabase) extractImageContent(filePath string) string {
 	return content.String()
 }
 
-// ProcessUserUpload processes a user-uploaded file and adds it to the temporary knowledge base
-func (kb *KnowledgeDatabase) ProcessUserUpload(filePath string) error {
-	// Get the base filename
-	filename := filepath.Base(filePath)
-	lowerName := strings.ToLower(filename)
-
-	// Create a unique identifier to avoid conflicts
-	timestamp := time.Now()
-	uniqueFilename := fmt.Sprintf("upload_%d_%s", timestamp.Unix(), filename)
-
-	fmt.Printf("[DEBUG] ProcessUserUpload: Processing file %s as %s\n", filePath, uniqueFilename)
-
-	// Process based on file type
-	var content string
-	var err error
-
-	if strings.HasSuffix(lowerName, ".log") || strings.HasSuffix(lowerName, ".logs") ||
-		strings.Contains(lowerName, "log") && strings.HasSuffix(lowerName, ".txt") {
-		// Process log files with specialized parsing
-		data, readErr := os.ReadFile(filePath)
-		if readErr == nil {
-			content = kb.parseLogFile(string(data), filename)
-			fmt.Printf("[DEBUG] ProcessUserUpload: Processed log file, content length: %d\n", len(content))
-		} else {
-			err = readErr
-		}
-	} else if strings.HasSuffix(lowerName, ".txt") {
-		data, readErr := os.ReadFile(filePath)
-		if readErr == nil {
-			// Check if this txt file might be a log file based on content
-			rawContent := string(data)
-			if kb.isLogFile(rawContent) {
-				content = kb.parseLogFile(rawContent, filename)
-				fmt.Printf("[DEBUG] ProcessUserUpload: Detected and processed .txt file as log, content length: %d\n", len(content))
-			} else {
-				content = rawContent
-				fmt.Printf("[DEBUG] ProcessUserUpload: Loaded .txt file, content length: %d\n", len(content))
-			}
-		} else {
-			err = readErr
-		}
-	} else if strings.HasSuffix(lowerName, ".html") {
-		data, readErr := os.ReadFile(filePath)
-		if readErr == nil {
-			content = kb.extractHTMLContent(string(data))
-			fmt.Printf("[DEBUG] ProcessUserUpload: Processed .html file, content length: %d\n", len(content))
-		} else {
-			err = readErr
-		}
-	} else if strings.HasSuffix(lowerName, ".pdf") {
-		content = kb.extractPDFText(filePath)
-		if content == "" {
-			content = "Failed to extract text from uploaded PDF - " + filename
-		}
-		fmt.Printf("[DEBUG] ProcessUserUpload: Processed .pdf file, content length: %d\n", len(content))
-	} else if strings.HasSuffix(lowerName, ".docx") {
-		content = kb.extractWordContent(filePath)
-		if content == "" {
-			content = "Failed to extract text from uploaded Word document - " + filename
-		}
-		fmt.Printf("[DEBUG] ProcessUserUpload: Processed .docx file, content length: %d\n", len(content))
-	} else if strings.HasSuffix(lowerName, ".png") || strings.HasSuffix(lowerName, ".jpg") ||
-		strings.HasSuffix(lowerName, ".jpeg") || strings.HasSuffix(lowerName, ".bmp") ||
-		strings.HasSuffix(lowerName, ".gif") || strings.HasSuffix(lowerName, ".tiff") {
-		content = kb.extractImageContent(filePath)
-		if content == "" {
-			content = "Failed to process uploaded image - " + filename
-		}
-		fmt.Printf("[DEBUG] ProcessUserUpload: Processed image file, content length: %d\n", len(content))
-	} else {
-		// For unsupported file types, try to read as text
-		data, readErr := os.ReadFile(filePath)
-		if readErr == nil {
-			content = string(data)
-			fmt.Printf("[DEBUG] ProcessUserUpload: Loaded unknown file type as text, content length: %d\n", len(content))
-		} else {
-			return fmt.Errorf("unsupported file type: %s", filename)
-		}
-	}
-
-	if err != nil {
-		return fmt.Errorf("failed to process uploaded file %s: %w", filename, err)
-	}
-
-	// Store the processed content
-	kb.userUploads[uniqueFilename] = content
-	kb.uploadPaths[uniqueFilename] = filePath
-	kb.uploadTime[uniqueFilename] = timestamp
-
-	fmt.Printf("[DEBUG] ProcessUserUpload: Stored file %s with content length %d\n", uniqueFilename, len(content))
-	fmt.Printf("[DEBUG] ProcessUserUpload: Total uploaded files now: %d\n", len(kb.userUploads))
-
-	return nil
-}
-
-// ClearUserUploads removes all user-uploaded files from the temporary knowledge base
-func (kb *KnowledgeDatabase) ClearUserUploads() {
-	kb.userUploads = make(map[string]string)
-	kb.uploadPaths = make(map[string]string)
-	kb.uploadTime = make(map[string]time.Time)
-}
-
-// GetUploadedFilesList returns a list of currently uploaded files with timestamps
-func (kb *KnowledgeDatabase) GetUploadedFilesList() []string {
-	var files []string
-	for filename, timestamp := range kb.uploadTime {
-		// Remove the upload prefix for display
-		displayName := strings.TrimPrefix(filename, fmt.Sprintf("upload_%d_", timestamp.Unix()))
-		files = append(files, fmt.Sprintf("%s (uploaded %s)", displayName, timestamp.Format("15:04:05")))
-	}
-	return files
-}
-
 // isLogFile determines if a text file contains log-like content
 func (kb *KnowledgeDatabase) isLogFile(content string) bool {
 	lowerContent := strings.ToLower(content)
@@ -0,0 +1,55 @@
+package knowledge
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// newTestDatabase indexes the files of root with an empty error codes file kept outside it
+func newTestDatabase(t *testing.T, root string) *KnowledgeDatabase {
+	t.Helper()
+	return newProfileDatabase(t, models.KnowledgeProfile{Root: root})
+}
+
+// newProfileDatabase indexes a profile, giving it a name and an empty error codes file when
+// it has none
+func newProfileDatabase(t *testing.T, profile models.KnowledgeProfile) *KnowledgeDatabase {
+	t.Helper()
+	if profile.Name == "" {
+		profile.Name = "test"
+	}
+	if profile.ErrorCodesFile == "" {
+		profile.ErrorCodesFile = filepath.Join(t.TempDir(), "errors.json")
+		if err := os.WriteFile(profile.ErrorCodesFile, []byte(`{"error_codes": [], "common_issues": []}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	kb, err := NewKnowledgeDatabase(profile, models.IndexPolicy{})
+	if err != nil {
+		t.Fatalf("NewKnowledgeDatabase: %v", err)
+	}
+	return kb
+}
+
+// writeFiles writes files given by slash-separated paths relative to root
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		filePath := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNewKnowledgeDatabaseRequiresErrorCodesFile(t *testing.T) {
+	if _, err := NewKnowledgeDatabase(models.KnowledgeProfile{Name: "empty", Root: t.TempDir()}, models.IndexPolicy{}); err == nil {
+		t.Error("a profile without an error codes file should be rejected")
+	}
+}
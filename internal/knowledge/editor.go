@@ -0,0 +1,300 @@
+package knowledge
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/beanspout/2025-beanbot/internal/guide"
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// Severities accepted for error codes
+var Severities = []string{"low", "medium", "high", "critical"}
+
+// ValidationError lists every problem found in troubleshooting data
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d problems found:\n- %s", len(e.Problems), strings.Join(e.Problems, "\n- "))
+}
+
+// Validate checks that troubleshooting data is complete and consistent: codes, aliases and
+// issue names are present and unique, severities and likelihoods are known, patterns compile,
+// related codes exist, decision trees can be walked, and every record has steps or solutions
+func Validate(data *models.TroubleshootingData) error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// Codes and aliases share one namespace so a name always identifies a single error code
+	codes := make(map[string]bool)
+	for i, errorCode := range data.ErrorCodes {
+		name := strings.TrimSpace(errorCode.Code)
+		if name == "" {
+			add("error code #%d has no code", i+1)
+		} else if codes[strings.ToLower(name)] {
+			add("error code %s is defined more than once", name)
+		}
+		codes[strings.ToLower(name)] = true
+	}
+	for _, errorCode := range data.ErrorCodes {
+		for _, alias := range errorCode.Aliases {
+			if codes[strings.ToLower(alias)] {
+				add("%s has alias %s, which is already used by another error code", errorCode.Code, alias)
+			}
+			codes[strings.ToLower(alias)] = true
+		}
+	}
+
+	for i, errorCode := range data.ErrorCodes {
+		name := strings.TrimSpace(errorCode.Code)
+		if name == "" {
+			name = fmt.Sprintf("error code #%d", i+1)
+		}
+
+		if strings.TrimSpace(errorCode.Description) == "" {
+			add("%s has no description", name)
+		}
+		if !isOneOf(errorCode.Severity, Severities) {
+			add("%s has severity %q; use one of %s", name, errorCode.Severity, strings.Join(Severities, ", "))
+		}
+		if len(nonEmpty(errorCode.TroubleshootingSteps)) == 0 {
+			add("%s has no troubleshooting steps", name)
+		}
+		for _, pattern := range errorCode.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				add("%s has an invalid pattern %q: %v", name, pattern, err)
+			}
+		}
+		for _, cause := range errorCode.Causes {
+			if strings.TrimSpace(cause.Description) == "" {
+				add("%s has a cause without a description", name)
+			} else if !isOneOf(cause.Likelihood, Likelihoods) {
+				add("%s cause %q has likelihood %q; use one of %s", name, cause.Description, cause.Likelihood, strings.Join(Likelihoods, ", "))
+			}
+		}
+		for _, check := range errorCode.DiagnosticChecks {
+			if strings.TrimSpace(check.Check) == "" {
+				add("%s has a diagnostic check without a description", name)
+			}
+		}
+		if errorCode.Escalation != nil && strings.TrimSpace(errorCode.Escalation.Team) == "" {
+			add("%s has an escalation path without a team", name)
+		}
+		for _, link := range errorCode.Documentation {
+			if strings.TrimSpace(link.Location) == "" {
+				add("%s has a document link without a location", name)
+			}
+		}
+		for _, related := range errorCode.RelatedCodes {
+			if strings.EqualFold(related, name) {
+				add("%s lists itself as a related code", name)
+			} else if !codes[strings.ToLower(related)] {
+				add("%s lists related code %s, which is not defined", name, related)
+			}
+		}
+		if errorCode.DecisionTree != nil {
+			for _, problem := range guide.Validate(errorCode.DecisionTree) {
+				add("%s decision tree: %s", name, problem)
+			}
+		}
+	}
+
+	issues := make(map[string]bool)
+	for i, issue := range data.CommonIssues {
+		name := strings.TrimSpace(issue.Issue)
+		if name == "" {
+			name = fmt.Sprintf("common issue #%d", i+1)
+			add("%s has no name", name)
+		} else if issues[strings.ToLower(name)] {
+			add("common issue %q is defined more than once", name)
+		}
+		issues[strings.ToLower(name)] = true
+
+		if len(nonEmpty(issue.Solutions)) == 0 {
+			add("common issue %q has no solutions", name)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// ErrorCodesPath returns the path of the error codes file
+func (kb *KnowledgeDatabase) ErrorCodesPath() string {
+	return kb.errorCodesPath
+}
+
+// LoadBaseData reads the error codes file for editing, without the learned fixes merged in
+func (kb *KnowledgeDatabase) LoadBaseData() (*models.TroubleshootingData, error) {
+	return loadTroubleshootingFile(kb.errorCodesPath)
+}
+
+// SaveBaseData validates the data, copies the current error codes file to a .bak backup,
+// writes the new file atomically and reloads the knowledge base from it
+func (kb *KnowledgeDatabase) SaveBaseData(data *models.TroubleshootingData) error {
+	cleaned := Normalize(data)
+	if err := Validate(cleaned); err != nil {
+		return err
+	}
+
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	if err := backupFile(kb.errorCodesPath); err != nil {
+		return err
+	}
+	if err := writeJSONFile(kb.errorCodesPath, cleaned); err != nil {
+		return err
+	}
+
+	learned, err := loadTroubleshootingFile(kb.learnedPath)
+	if err != nil {
+		return err
+	}
+	kb.data, kb.orphaned = mergeLearned(cleaned, learned)
+	return nil
+}
+
+// OrphanedFixes returns the error codes that have fixes learned from feedback but are no longer
+// defined in the error codes file, because they were renamed or deleted. Their fixes are not used.
+func (kb *KnowledgeDatabase) OrphanedFixes() []string {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	return kb.orphaned
+}
+
+// loadErrorCodes loads the error codes file merged with the learned fixes. Files in an older
+// schema are migrated in memory and validation problems are logged rather than fatal, so a
+// hand-edited file still loads.
+func (kb *KnowledgeDatabase) loadErrorCodes() error {
+	base, notes, err := readTroubleshootingFile(kb.errorCodesPath)
+	if err != nil {
+		return err
+	}
+	for _, note := range notes {
+		log.Printf("Migrated %s: %s", kb.errorCodesPath, note)
+	}
+	if err := Validate(Normalize(base)); err != nil {
+		log.Printf("Warning: %s has %v", kb.errorCodesPath, err)
+	}
+
+	learned, err := loadTroubleshootingFile(kb.learnedPath)
+	if err != nil {
+		return err
+	}
+
+	data, orphaned := mergeLearned(base, learned)
+	if len(orphaned) > 0 {
+		log.Printf("Warning: %s has fixes for %s, which %s no longer defines; they are not used",
+			kb.learnedPath, strings.Join(orphaned, ", "), kb.errorCodesPath)
+	}
+
+	kb.mu.Lock()
+	kb.data, kb.orphaned = data, orphaned
+	kb.mu.Unlock()
+	return nil
+}
+
+// Normalize returns a copy of data with whitespace trimmed, severities in lower case and
+// empty list entries removed, as it is written by SaveBaseData
+func Normalize(data *models.TroubleshootingData) *models.TroubleshootingData {
+	cleaned := &models.TroubleshootingData{
+		SchemaVersion: SchemaVersion,
+		ErrorCodes:    make([]models.ErrorCode, 0, len(data.ErrorCodes)),
+		CommonIssues:  make([]models.CommonIssue, 0, len(data.CommonIssues)),
+	}
+	for _, errorCode := range data.ErrorCodes {
+		errorCode.Code = strings.TrimSpace(errorCode.Code)
+		errorCode.Description = strings.TrimSpace(errorCode.Description)
+		errorCode.Category = strings.TrimSpace(errorCode.Category)
+		errorCode.Severity = strings.ToLower(strings.TrimSpace(errorCode.Severity))
+		errorCode.TroubleshootingSteps = nonEmpty(errorCode.TroubleshootingSteps)
+		errorCode.RelatedComponents = nonEmpty(errorCode.RelatedComponents)
+		errorCode.DocumentationReference = strings.TrimSpace(errorCode.DocumentationReference)
+		errorCode.Aliases = nonEmpty(errorCode.Aliases)
+		errorCode.Patterns = nonEmpty(errorCode.Patterns)
+		errorCode.SafetyWarnings = nonEmpty(errorCode.SafetyWarnings)
+		errorCode.Equipment = nonEmpty(errorCode.Equipment)
+		errorCode.SoftwareVersions = nonEmpty(errorCode.SoftwareVersions)
+		errorCode.RelatedCodes = nonEmpty(errorCode.RelatedCodes)
+
+		causes := []models.Cause{}
+		for _, cause := range errorCode.Causes {
+			cause.Description = strings.TrimSpace(cause.Description)
+			cause.Likelihood = strings.ToLower(strings.TrimSpace(cause.Likelihood))
+			if cause.Description != "" || cause.Likelihood != "" {
+				causes = append(causes, cause)
+			}
+		}
+		errorCode.Causes = causes
+
+		checks := []models.DiagnosticCheck{}
+		for _, check := range errorCode.DiagnosticChecks {
+			check.Check = strings.TrimSpace(check.Check)
+			check.ExpectedResult = strings.TrimSpace(check.ExpectedResult)
+			if check.Check != "" || check.ExpectedResult != "" {
+				checks = append(checks, check)
+			}
+		}
+		errorCode.DiagnosticChecks = checks
+
+		links := []models.DocumentLink{}
+		for _, link := range errorCode.Documentation {
+			link.Title = strings.TrimSpace(link.Title)
+			link.Location = strings.TrimSpace(link.Location)
+			if link.Title != "" || link.Location != "" {
+				links = append(links, link)
+			}
+		}
+		errorCode.Documentation = links
+
+		if errorCode.Escalation != nil {
+			escalation := models.Escalation{
+				Team:    strings.TrimSpace(errorCode.Escalation.Team),
+				Contact: strings.TrimSpace(errorCode.Escalation.Contact),
+				When:    strings.TrimSpace(errorCode.Escalation.When),
+			}
+			errorCode.Escalation = nil
+			if escalation != (models.Escalation{}) {
+				errorCode.Escalation = &escalation
+			}
+		}
+		cleaned.ErrorCodes = append(cleaned.ErrorCodes, errorCode)
+	}
+	for _, issue := range data.CommonIssues {
+		issue.Issue = strings.TrimSpace(issue.Issue)
+		issue.Symptoms = nonEmpty(issue.Symptoms)
+		issue.Solutions = nonEmpty(issue.Solutions)
+		cleaned.CommonIssues = append(cleaned.CommonIssues, issue)
+	}
+	return cleaned
+}
+
+// isOneOf reports whether value is one of the allowed values, ignoring case
+func isOneOf(value string, allowed []string) bool {
+	for _, candidate := range allowed {
+		if strings.EqualFold(strings.TrimSpace(value), candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonEmpty returns the trimmed values that are not blank
+func nonEmpty(values []string) []string {
+	result := []string{}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
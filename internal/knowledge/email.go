@@ -0,0 +1,252 @@
+package knowledge
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Email extraction limits
+const (
+	maxEmailDepth      = 3        // Levels of forwarded messages and nested multiparts that are read
+	maxAttachmentBytes = 64 << 10 // Text of an attachment included in the document
+)
+
+// emailHeaders are the headers kept in an email's text, in order. Outlook messages saved as
+// text use Sent instead of Date.
+var emailHeaders = []string{"Subject", "From", "To", "Cc", "Date", "Sent"}
+
+// textAttachmentExtensions are attachments read as text whatever their declared content type
+var textAttachmentExtensions = []string{".txt", ".log", ".csv", ".tsv", ".md", ".json", ".xml", ".yaml", ".yml", ".ini", ".cfg"}
+
+// emailPart is the text found in a message and its attachments
+type emailPart struct {
+	plain       []string // text/plain bodies
+	html        []string // text/html bodies, used when there is no plain text
+	attachments []string // One line per attachment
+	texts       []string // Text of readable attachments and forwarded messages, with a heading
+}
+
+// extractEmail converts an RFC 822 message into its headers, body and attachments. Messages that
+// cannot be parsed, such as Outlook messages saved as plain text without headers, are kept as they are.
+func extractEmail(content string) string {
+	message, err := mail.ReadMessage(strings.NewReader(content))
+	if err != nil {
+		return content
+	}
+	var out strings.Builder
+	writeEmail(&out, message.Header, message.Body, 0)
+	return strings.TrimSpace(out.String())
+}
+
+// writeEmail writes a message's headers followed by its text
+func writeEmail(out *strings.Builder, header mail.Header, body io.Reader, depth int) {
+	for _, name := range emailHeaders {
+		if value := decodeHeader(header.Get(name)); value != "" {
+			fmt.Fprintf(out, "%s: %s\n", name, value)
+		}
+	}
+	out.WriteString("\n")
+
+	part := &emailPart{}
+	readEmailPart(part, header.Get("Content-Type"), header.Get("Content-Transfer-Encoding"), body, depth)
+	switch {
+	case len(part.plain) > 0:
+		out.WriteString(strings.Join(part.plain, "\n\n"))
+	case len(part.html) > 0:
+		out.WriteString(strings.Join(part.html, "\n\n"))
+	}
+	out.WriteString("\n")
+	if len(part.attachments) > 0 {
+		out.WriteString("\nAttachments:\n")
+		for _, attachment := range part.attachments {
+			out.WriteString("- " + attachment + "\n")
+		}
+	}
+	for _, text := range part.texts {
+		out.WriteString("\n" + text + "\n")
+	}
+}
+
+// readEmailPart collects the bodies and attachments of a message part, descending into
+// multipart parts and forwarded messages
+func readEmailPart(part *emailPart, contentType, encoding string, body io.Reader, depth int) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+	body = decodeTransfer(body, encoding)
+
+	if strings.HasPrefix(mediaType, "multipart/") && depth < maxEmailDepth {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			child, err := reader.NextPart()
+			if err != nil {
+				// The rest of a malformed message is dropped, keeping the parts read so far
+				return
+			}
+			if name := attachmentName(child.Header.Get("Content-Disposition"), child.Header.Get("Content-Type")); name != "" {
+				readAttachment(part, name, child.Header.Get("Content-Type"), child.Header.Get("Content-Transfer-Encoding"), child, depth)
+				continue
+			}
+			readEmailPart(part, child.Header.Get("Content-Type"), child.Header.Get("Content-Transfer-Encoding"), child, depth+1)
+		}
+	}
+
+	switch {
+	case mediaType == "message/rfc822" && depth < maxEmailDepth:
+		readForwarded(part, "Forwarded message", body, depth)
+	case mediaType == "text/plain":
+		if text := readText(body, params["charset"], -1); strings.TrimSpace(text) != "" {
+			part.plain = append(part.plain, strings.TrimSpace(text))
+		}
+	case mediaType == "text/html":
+		if text := htmlToText(readText(body, params["charset"], -1)); text != "" {
+			part.html = append(part.html, text)
+		}
+	}
+}
+
+// readAttachment lists an attachment with its type and size, keeping the text of text files and
+// forwarded messages
+func readAttachment(part *emailPart, name, contentType, encoding string, body io.Reader, depth int) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "application/octet-stream"
+	}
+	body = decodeTransfer(body, encoding)
+	if mediaType == "message/rfc822" && depth < maxEmailDepth {
+		part.attachments = append(part.attachments, fmt.Sprintf("%s (forwarded message)", name))
+		readForwarded(part, "Forwarded message: "+name, body, depth)
+		return
+	}
+
+	isText := strings.HasPrefix(mediaType, "text/") || isOneOf(strings.ToLower(path.Ext(name)), textAttachmentExtensions)
+	if !isText {
+		size, _ := io.Copy(io.Discard, body)
+		part.attachments = append(part.attachments, fmt.Sprintf("%s (%s, %s)", name, mediaType, FormatBytes(size)))
+		return
+	}
+	text := readText(body, params["charset"], maxAttachmentBytes)
+	rest, _ := io.Copy(io.Discard, body)
+	part.attachments = append(part.attachments, fmt.Sprintf("%s (%s, %s)", name, mediaType, FormatBytes(int64(len(text))+rest)))
+	if mediaType == "text/html" {
+		text = htmlToText(text)
+	}
+	if strings.TrimSpace(text) != "" && utf8.ValidString(text) {
+		part.texts = append(part.texts, fmt.Sprintf("## Attachment: %s\n\n%s", name, strings.TrimSpace(text)))
+	}
+}
+
+// readForwarded adds the text of a message attached to or forwarded in another
+func readForwarded(part *emailPart, title string, body io.Reader, depth int) {
+	message, err := mail.ReadMessage(body)
+	if err != nil {
+		return
+	}
+	var out strings.Builder
+	writeEmail(&out, message.Header, message.Body, depth+1)
+	part.texts = append(part.texts, fmt.Sprintf("## %s\n\n%s", title, strings.TrimSpace(out.String())))
+}
+
+// attachmentName returns the file name of an attachment, or "" for a part of the message body
+func attachmentName(disposition, contentType string) string {
+	kind, params, _ := mime.ParseMediaType(disposition)
+	name := params["filename"]
+	if name == "" {
+		if _, typeParams, err := mime.ParseMediaType(contentType); err == nil {
+			name = typeParams["name"]
+		}
+	}
+	if kind != "attachment" && name == "" {
+		return ""
+	}
+	if name = decodeHeader(name); name == "" {
+		name = "unnamed"
+	}
+	return name
+}
+
+// decodeTransfer undoes a part's content transfer encoding. The multipart reader already
+// decodes quoted-printable parts and removes the header.
+func decodeTransfer(body io.Reader, encoding string) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// readText reads a text part, converting single-byte Western charsets to UTF-8. A negative
+// limit reads the whole part.
+func readText(body io.Reader, charset string, limit int64) string {
+	if limit >= 0 {
+		body = io.LimitReader(body, limit)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil && len(data) == 0 {
+		return ""
+	}
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "cp1252":
+		runes := make([]rune, len(data))
+		for i, c := range data {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
+
+// decodeHeader decodes RFC 2047 encoded words such as =?UTF-8?Q?...?= and joins folded lines
+func decodeHeader(value string) string {
+	decoder := mime.WordDecoder{}
+	if decoded, err := decoder.DecodeHeader(value); err == nil {
+		value = decoded
+	}
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// emailSubject returns the decoded subject of a message, or "" when it has none
+func emailSubject(raw []byte) string {
+	message, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		return ""
+	}
+	return decodeHeader(message.Header.Get("Subject"))
+}
+
+// HTML to text patterns
+var (
+	htmlHiddenPattern = regexp.MustCompile(`(?is)<(script|style|head)\b.*?</(script|style|head)>`)
+	htmlBreakPattern  = regexp.MustCompile(`(?i)<(br|/p|/div|/tr|/h[1-6]|/pre|/blockquote)\b[^>]*>`)
+	htmlItemPattern   = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlTagPattern    = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText converts an HTML fragment, such as an email body or a Jira description, to plain
+// text that keeps its line breaks and list items
+func htmlToText(fragment string) string {
+	text := htmlHiddenPattern.ReplaceAllString(fragment, "")
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlItemPattern.ReplaceAllString(text, "\n- ")
+	text = html.UnescapeString(htmlTagPattern.ReplaceAllString(text, ""))
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
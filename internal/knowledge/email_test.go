@@ -0,0 +1,103 @@
+package knowledge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractEmail(t *testing.T) {
+	message := strings.ReplaceAll(`From: =?UTF-8?Q?Ren=C3=A9e_Lab?= <lab@example.com>
+To: support@example.com
+Subject: =?UTF-8?B?UmFjayAzIGZhdWx0?=
+Date: Tue, 2 Mar 2021 09:05:00 +0000
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Channel 12 tripped at 4.5=
+0 V.
+--inner
+Content-Type: text/html
+
+<p>Channel 12 tripped</p>
+--inner--
+--outer
+Content-Type: text/csv; name="readings.csv"
+Content-Disposition: attachment; filename="readings.csv"
+Content-Transfer-Encoding: base64
+
+Q2hhbm5lbCxWb2x0YWdlCjEyLDQuNQo=
+--outer
+Content-Type: image/png
+Content-Disposition: attachment; filename="scope.png"
+Content-Transfer-Encoding: base64
+
+iVBORw0KGgo=
+--outer
+Content-Type: message/rfc822
+Content-Disposition: attachment; filename="original.eml"
+
+From: operator@example.com
+Subject: First report
+
+The rack rebooted overnight.
+--outer--
+`, "\n", "\r\n")
+
+	got := extractEmail(message)
+	for _, want := range []string{
+		"Subject: Rack 3 fault\nFrom: Renée Lab <lab@example.com>\nTo: support@example.com\nDate: Tue, 2 Mar 2021 09:05:00 +0000\n",
+		"Channel 12 tripped at 4.50 V.",
+		"Attachments:\n- readings.csv (text/csv, 23 bytes)\n- scope.png (image/png, 8 bytes)\n- original.eml (forwarded message)\n",
+		"## Attachment: readings.csv\n\nChannel,Voltage\n12,4.5",
+		"## Forwarded message: original.eml\n\nSubject: First report\nFrom: operator@example.com",
+		"The rack rebooted overnight.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<p>") {
+		t.Errorf("the HTML body was used although there is a plain text body:\n%s", got)
+	}
+}
+
+func TestExtractEmailFallbacks(t *testing.T) {
+	html := "Subject: Status\r\nContent-Type: text/html; charset=iso-8859-1\r\n\r\n<p>Temp 40\xb0C</p>"
+	if got := extractEmail(html); !strings.Contains(got, "Temp 40°C") {
+		t.Errorf("the HTML body should be used when there is no plain text:\n%s", got)
+	}
+
+	saved := "Sam Lab\nSent: Tuesday\n\nNo headers here"
+	if got := extractEmail(saved); got != saved {
+		t.Errorf("a message that cannot be parsed should be kept as is, got %q", got)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"paragraphs", "<p>One</p><p>Two</p>", "One\nTwo"},
+		{"breaks", "Line<br>next<BR/>last", "Line\nnext\nlast"},
+		{"lists", "<ul><li>Power off</li><li>Reseat</li></ul>", "- Power off\n- Reseat"},
+		{"hidden", "<head><title>x</title></head><style>p{}</style><script>alert(1)</script>Body", "Body"},
+		{"entities", "Volts &gt; 4.2 &amp; amps&nbsp;&lt; 1", "Volts > 4.2 & amps < 1"},
+		{"whitespace", "  a \t  b  <div>\n\n\n\nc</div>", "a b\n\nc"},
+		{"table rows", "<table><tr><td>Channel</td><td>12</td></tr><tr><td>Volts</td></tr></table>", "Channel12\nVolts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToText(tt.html); got != tt.want {
+				t.Errorf("htmlToText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,440 @@
+package knowledge
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// maxIndexDepth stops runaway directory trees that symlink loop detection cannot see
+const maxIndexDepth = 32
+
+// sniffBytes is how much of a file is inspected to decide whether it is binary
+const sniffBytes = 8000
+
+// Reasons a file was not indexed, used to group the indexing report
+const (
+	SkipExcluded    = "excluded"
+	SkipNotIncluded = "not included"
+	SkipUnsupported = "unsupported type"
+	SkipTooLarge    = "too large"
+	SkipBinary      = "binary content"
+	SkipNoText      = "no text"
+	SkipLink        = "symlink"
+	SkipUnreadable  = "unreadable"
+)
+
+// SkippedFile is a file or directory that was not indexed
+type SkippedFile struct {
+	Path     string // Relative to the profile root
+	Category string // One of the Skip constants
+	Reason   string
+}
+
+// IndexReport describes the outcome of indexing a profile's documents
+type IndexReport struct {
+	Root     string
+	Indexed  int
+	Skipped  []SkippedFile
+	Duration time.Duration
+}
+
+// Counts returns the number of skipped files per category
+func (r IndexReport) Counts() map[string]int {
+	counts := make(map[string]int)
+	for _, skipped := range r.Skipped {
+		counts[skipped.Category]++
+	}
+	return counts
+}
+
+// Summary returns a one line description of the report
+func (r IndexReport) Summary() string {
+	summary := fmt.Sprintf("Indexed %d files from %s in %s", r.Indexed, r.Root, r.Duration.Round(time.Millisecond))
+	if len(r.Skipped) == 0 {
+		return summary
+	}
+
+	counts := r.Counts()
+	var categories []string
+	for category := range counts {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	var parts []string
+	for _, category := range categories {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[category], category))
+	}
+	return fmt.Sprintf("%s, skipped %d (%s)", summary, len(r.Skipped), strings.Join(parts, ", "))
+}
+
+// skip records a file that was not indexed
+func (r *IndexReport) skip(relPath, category, reason string) {
+	r.Skipped = append(r.Skipped, SkippedFile{Path: relPath, Category: category, Reason: reason})
+}
+
+// skipError explains why a file cannot be indexed or uploaded
+type skipError struct {
+	category string
+	reason   string
+}
+
+func (e *skipError) Error() string {
+	return e.reason
+}
+
+// Document types returned by classifyFile for files that have no extractor
+const (
+	docUnsupported DocumentType = ""        // A format that is known but not indexed
+	docUnknown     DocumentType = "unknown" // No extractor for the extension; may still be plain text
+)
+
+// knownImageFormats are image extensions recognised but possibly disabled in file_processing
+var knownImageFormats = []string{".png", ".jpg", ".jpeg", ".bmp", ".gif", ".tiff"}
+
+// classifyFile picks the document type for a file name from its extension and the policy's formats
+func classifyFile(name string, policy models.IndexPolicy) (DocumentType, *skipError) {
+	lowerName := strings.ToLower(name)
+	ext := filepath.Ext(lowerName)
+
+	switch {
+	case ext == ".log" || ext == ".logs" || (strings.Contains(lowerName, "log") && ext == ".txt"):
+		return DocLog, nil
+	case ext == ".txt":
+		return DocText, nil
+	case ext == ".html":
+		return DocHTML, nil
+	case ext == ".docx":
+		return DocWord, nil
+	case ext == ".doc":
+		return docUnsupported, &skipError{SkipUnsupported, "legacy .doc format is not supported - convert it to .docx"}
+	case ext == ".csv" || ext == ".tsv" || ext == ".xlsx":
+		return DocSheet, nil
+	case ext == ".xls":
+		return docUnsupported, &skipError{SkipUnsupported, "legacy .xls format is not supported - save it as .xlsx or .csv"}
+	case ext == ".eml" || ext == ".msg":
+		return DocEmail, nil
+	case isOneOf(ext, policy.DiagramFormats):
+		return DocDiagram, nil
+	case isOneOf(ext, dataExtensions):
+		return DocData, nil
+	case ext == ".md" || ext == ".markdown":
+		return DocMarkdown, nil
+	case codeLanguages[ext] != "":
+		return DocCode, nil
+	case isOneOf(ext, policy.PDFFormats):
+		return DocPDF, nil
+	case isOneOf(ext, policy.ImageFormats):
+		return DocImage, nil
+	case isOneOf(ext, knownImageFormats):
+		return docUnsupported, &skipError{SkipUnsupported, fmt.Sprintf("image format %s is not in supported_image_formats", ext)}
+	case ext == "":
+		return docUnknown, &skipError{SkipUnsupported, "file has no extension"}
+	}
+	return docUnknown, &skipError{SkipUnsupported, fmt.Sprintf("unsupported file type %s", ext)}
+}
+
+// sizeLimit returns the policy's size limit for a file; Office documents share the PDF limit
+func sizeLimit(docType DocumentType, name string, policy models.IndexPolicy) int64 {
+	switch docType {
+	case DocPDF, DocWord:
+		return policy.MaxPDFBytes
+	case DocSheet:
+		if isWorkbook(docType, name) {
+			return policy.MaxPDFBytes
+		}
+	case DocImage:
+		return policy.MaxImageBytes
+	}
+	return policy.MaxTextBytes
+}
+
+// looksBinary reports whether data appears to be binary rather than text: it contains a
+// NUL byte, or more than one in ten characters are invalid UTF-8 or control characters
+func looksBinary(data []byte) bool {
+	truncated := len(data) > sniffBytes
+	if truncated {
+		data = data[:sniffBytes]
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return true
+	}
+
+	suspicious, total := 0, 0
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 {
+			// A character cut off by the sniff window is not evidence of binary content
+			if truncated && !utf8.FullRune(data) {
+				break
+			}
+			suspicious++
+		} else if r < 0x20 && r != '\n' && r != '\r' && r != '\t' && r != '\f' {
+			suspicious++
+		}
+		total++
+		data = data[size:]
+	}
+	return total > 0 && suspicious*10 > total
+}
+
+// FormatBytes formats a file size for reports
+func FormatBytes(n int64) string {
+	if n < 1<<10 {
+		return fmt.Sprintf("%d bytes", n)
+	}
+	if n >= 1<<20 {
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+}
+
+// extractFile reads a file's text with the extractor for its type and fills in the document's
+// metadata other than its path. With allowText, files of unknown type are accepted when their
+// content is text.
+func (kb *KnowledgeDatabase) extractFile(filePath string, info os.FileInfo, allowText bool) (Document, error) {
+	name := filepath.Base(filePath)
+	docType, unsupported := classifyFile(name, kb.policy)
+	if unsupported != nil {
+		if !allowText || docType != docUnknown {
+			return Document{}, unsupported
+		}
+		docType = DocText
+	}
+
+	if limit := sizeLimit(docType, name, kb.policy); limit > 0 && info.Size() > limit {
+		return Document{}, &skipError{SkipTooLarge, fmt.Sprintf("%s exceeds the %s limit", FormatBytes(info.Size()), FormatBytes(limit))}
+	}
+
+	doc := Document{
+		FullPath: filePath,
+		Name:     name,
+		Type:     docType,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+	}
+	var raw []byte
+	switch {
+	case docType == DocPDF || docType == DocWord || docType == DocImage || isWorkbook(docType, name):
+		hash, err := hashFile(filePath)
+		if err != nil {
+			return Document{}, &skipError{SkipUnreadable, err.Error()}
+		}
+		doc.Hash = hash
+		switch docType {
+		case DocPDF:
+			content, pages, err := kb.extractPDFText(filePath)
+			if err != nil {
+				return Document{}, &skipError{SkipNoText, fmt.Sprintf("failed to extract text from PDF: %v", err)}
+			}
+			doc.Content, doc.Pages, doc.Extractor = content, pages, ExtractPDF
+		case DocWord:
+			doc.Content, doc.Extractor = kb.extractWordContent(filePath), ExtractDocx
+		case DocSheet:
+			tables, err := readWorkbook(filePath)
+			if err != nil {
+				return Document{}, &skipError{SkipNoText, fmt.Sprintf("failed to read the workbook: %v", err)}
+			}
+			doc.Content, doc.Extractor, doc.tables = renderTables(tables), ExtractXLSX, tables
+		default:
+			doc.Content, doc.Extractor = kb.extractImageContent(filePath), ExtractOCR
+		}
+	default:
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return Document{}, &skipError{SkipUnreadable, err.Error()}
+		}
+		if looksBinary(data) {
+			if unsupported != nil {
+				return Document{}, unsupported
+			}
+			if docType == DocEmail {
+				return Document{}, &skipError{SkipUnsupported, "Outlook .msg files cannot be read - save the message as .eml or text"}
+			}
+			return Document{}, &skipError{SkipBinary, "file looks binary rather than text"}
+		}
+		raw = data
+		sum := sha256.Sum256(data)
+		doc.Hash = hex.EncodeToString(sum[:])
+		if docType == DocSheet {
+			doc.tables = readDelimited(string(data), name)
+			doc.Content, doc.Extractor = renderTables(doc.tables), ExtractCSV
+		} else {
+			doc.Content, doc.Extractor = kb.extractTextContent(docType, string(data), name)
+		}
+		if doc.Extractor == ExtractJira {
+			docType, doc.Type = DocTicket, DocTicket
+		}
+	}
+
+	if strings.TrimSpace(doc.Content) == "" {
+		if docType == DocPDF {
+			return Document{}, &skipError{SkipNoText, "the PDF contains no text - it may be scanned images"}
+		}
+		return Document{}, &skipError{SkipNoText, "no text could be extracted"}
+	}
+	doc.Title = documentTitle(docType, name, raw, doc.Content)
+	doc.Tickets = ticketKeys(doc.Content)
+	return doc, nil
+}
+
+// extractTextContent converts the content of a text based file to indexable text and returns
+// the extractor used
+func (kb *KnowledgeDatabase) extractTextContent(docType DocumentType, content, name string) (string, string) {
+	switch docType {
+	case DocLog:
+		return kb.parseLogFile(content, name), ExtractLog
+	case DocHTML:
+		return kb.extractHTMLContent(content), ExtractHTML
+	case DocDiagram:
+		return kb.extractDrawIOContent(content), ExtractDrawIO
+	case DocEmail:
+		return extractEmail(content), ExtractEmail
+	case DocData:
+		// Jira issue exports are recognised by their content
+		if text, ok := extractJira(content, name); ok {
+			return text, ExtractJira
+		}
+		return extractData(content, name)
+	case DocMarkdown:
+		return extractMarkdown(content), ExtractMarkdown
+	case DocCode:
+		return extractCode(content, name), ExtractCode
+	}
+	// Text files that turn out to contain log output are summarised like log files
+	if kb.isLogFile(content) {
+		return kb.parseLogFile(content, name), ExtractLog
+	}
+	return content, ExtractPlain
+}
+
+// indexDocuments indexes the files under the profile root and records what was skipped
+func (kb *KnowledgeDatabase) indexDocuments() {
+	started := time.Now()
+	kb.report = IndexReport{Root: kb.root}
+	kb.exclude = parseRules(append(append([]string{}, kb.profile.Exclude...), readIgnoreFile(filepath.Join(kb.root, IgnoreFile))...))
+	kb.include = parseRules(kb.profile.Include)
+
+	kb.indexDir(kb.root, make(map[string]string), 0)
+	kb.documentsChanged()
+
+	kb.report.Duration = time.Since(started)
+	log.Printf("Knowledge profile %s: %s", kb.profile.Name, kb.report.Summary())
+}
+
+// indexDir indexes a directory and its subdirectories. Symlinks are followed, but a directory
+// reached a second time through a symlink is skipped so loops and duplicates are not indexed.
+func (kb *KnowledgeDatabase) indexDir(dirPath string, visited map[string]string, depth int) {
+	relDir := kb.RelativePath(dirPath)
+	realPath, err := filepath.EvalSymlinks(dirPath)
+	if err == nil {
+		realPath, err = filepath.Abs(realPath)
+	}
+	if err != nil {
+		kb.report.skip(relDir, SkipUnreadable, err.Error())
+		return
+	}
+	if first, seen := visited[realPath]; seen {
+		kb.report.skip(relDir, SkipLink, fmt.Sprintf("directory already indexed as %s", first))
+		return
+	}
+	visited[realPath] = relDir
+	if depth > maxIndexDepth {
+		kb.report.skip(relDir, SkipExcluded, fmt.Sprintf("more than %d folders deep", maxIndexDepth))
+		return
+	}
+
+	entries, err := os.ReadDir(dirPath)
+	if err != nil {
+		kb.report.skip(relDir, SkipUnreadable, err.Error())
+		return
+	}
+
+	for _, entry := range entries {
+		fullPath := filepath.Join(dirPath, entry.Name())
+		relPath := kb.RelativePath(fullPath)
+		if depth == 0 && entry.Name() == IgnoreFile {
+			continue
+		}
+
+		// Stat follows symlinks so linked files and directories are indexed like any other
+		info, err := os.Stat(fullPath)
+		if err != nil {
+			if entry.Type()&os.ModeSymlink != 0 {
+				kb.report.skip(relPath, SkipLink, "broken symlink")
+			} else {
+				kb.report.skip(relPath, SkipUnreadable, err.Error())
+			}
+			continue
+		}
+
+		if pattern, excluded := kb.exclude.excludes(relPath, info.IsDir()); excluded {
+			kb.report.skip(relPath, SkipExcluded, fmt.Sprintf("matches exclude pattern %q", pattern))
+			continue
+		}
+		if info.IsDir() {
+			kb.indexDir(fullPath, visited, depth+1)
+			continue
+		}
+		if !info.Mode().IsRegular() {
+			kb.report.skip(relPath, SkipUnsupported, "not a regular file")
+			continue
+		}
+		if !kb.include.includes(relPath, false) {
+			kb.report.skip(relPath, SkipNotIncluded, "does not match any include pattern")
+			continue
+		}
+		// The error codes are loaded from their files rather than searched as documents
+		if kb.isErrorCodesFile(fullPath) {
+			kb.report.skip(relPath, SkipExcluded, "loaded as the error codes file")
+			continue
+		}
+
+		doc, err := kb.extractFile(fullPath, info, false)
+		if err != nil {
+			category := SkipUnreadable
+			if skipped, ok := err.(*skipError); ok {
+				category = skipped.category
+			}
+			kb.report.skip(relPath, category, err.Error())
+			continue
+		}
+		doc.Path = relPath
+		kb.documents.Add(doc)
+		kb.report.Indexed++
+	}
+}
+
+// isErrorCodesFile reports whether a path is the profile's error codes file or its learned fixes
+func (kb *KnowledgeDatabase) isErrorCodesFile(fullPath string) bool {
+	fullPath, err := filepath.Abs(fullPath)
+	if err != nil {
+		return false
+	}
+	for _, dataPath := range []string{kb.errorCodesPath, kb.learnedPath} {
+		if absPath, err := filepath.Abs(dataPath); err == nil && dataPath != "" && absPath == fullPath {
+			return true
+		}
+	}
+	return false
+}
+
+// IndexReport returns the report from indexing the profile's documents
+func (kb *KnowledgeDatabase) IndexReport() IndexReport {
+	return kb.report
+}
+
+// Policy returns the size limits and formats the database was indexed with
+func (kb *KnowledgeDatabase) Policy() models.IndexPolicy {
+	return kb.policy
+}
@@ -0,0 +1,124 @@
+package knowledge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// indexedPaths returns the paths of the indexed documents in order
+func indexedPaths(kb *KnowledgeDatabase) []string {
+	var paths []string
+	for _, doc := range kb.Documents() {
+		paths = append(paths, doc.Path)
+	}
+	return paths
+}
+
+// skippedFiles maps the skipped paths of an index report to their category and reason
+func skippedFiles(report IndexReport) map[string]string {
+	skipped := make(map[string]string)
+	for _, file := range report.Skipped {
+		skipped[file.Path] = file.Category + ": " + file.Reason
+	}
+	return skipped
+}
+
+func TestIndexIgnoreFile(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		IgnoreFile:                    "# Generated output\nbuild/\n*.log\n!keep.log\n/notes/private-*\n",
+		"guide.txt":                   "Calibrate the rack.",
+		"build/out.txt":               "generated",
+		"logs/app.log":                "10:00:00 INFO start",
+		"logs/keep.log":               "10:00:00 INFO kept",
+		"notes/private-a.txt":         "private",
+		"notes/public.txt":            "public",
+		"archive/notes/private-b.txt": "not anchored here",
+	})
+	kb := newProfileDatabase(t, models.KnowledgeProfile{Root: root, Exclude: []string{"archive/"}})
+
+	want := []string{"guide.txt", "logs/keep.log", "notes/public.txt"}
+	if got := indexedPaths(kb); !reflect.DeepEqual(got, want) {
+		t.Errorf("indexed %v, want %v", got, want)
+	}
+	skipped := skippedFiles(kb.IndexReport())
+	for path, reason := range map[string]string{
+		"build":               `excluded: matches exclude pattern "build/"`,
+		"logs/app.log":        `excluded: matches exclude pattern "*.log"`,
+		"notes/private-a.txt": `excluded: matches exclude pattern "/notes/private-*"`,
+		"archive":             `excluded: matches exclude pattern "archive/"`,
+	} {
+		if skipped[path] != reason {
+			t.Errorf("%s skipped as %q, want %q", path, skipped[path], reason)
+		}
+	}
+	if _, ok := skipped[IgnoreFile]; ok {
+		t.Errorf("the ignore file itself should not be reported")
+	}
+}
+
+func TestIndexIncludePatterns(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"runbook.md": "# Runbook", "notes.txt": "notes", "docs/setup.md": "# Setup"})
+	kb := newProfileDatabase(t, models.KnowledgeProfile{Root: root, Include: []string{"*.md"}})
+
+	if got, want := indexedPaths(kb), []string{"docs/setup.md", "runbook.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("indexed %v, want %v", got, want)
+	}
+	if got := skippedFiles(kb.IndexReport())["notes.txt"]; !strings.HasPrefix(got, SkipNotIncluded) {
+		t.Errorf("notes.txt skipped as %q", got)
+	}
+}
+
+func TestIndexSymlinks(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"docs/guide.txt": "Calibrate the rack.", "docs/sub/deep.txt": "Deep notes."})
+	links := map[string]string{
+		"docs/sub/loop": "..",                                   // Points back at docs
+		"mirror":        "docs",                                 // Second path to docs
+		"broken.txt":    "missing.txt",                          // Target does not exist
+		"external.txt":  writeExternal(t, "Shared procedures."), // File outside the root
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(link))); err != nil {
+			t.Skipf("symlinks are not available: %v", err)
+		}
+	}
+
+	kb := newTestDatabase(t, root)
+	if got, want := indexedPaths(kb), []string{"docs/guide.txt", "docs/sub/deep.txt", "external.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("indexed %v, want %v", got, want)
+	}
+
+	skipped := skippedFiles(kb.IndexReport())
+	var reported []string
+	for path := range skipped {
+		reported = append(reported, path)
+	}
+	sort.Strings(reported)
+	if want := []string{"broken.txt", "docs/sub/loop", "mirror"}; !reflect.DeepEqual(reported, want) {
+		t.Fatalf("skipped %v, want %v", reported, want)
+	}
+	if got := skipped["docs/sub/loop"]; got != SkipLink+": directory already indexed as docs" {
+		t.Errorf("the loop was skipped as %q", got)
+	}
+	if got := skipped["broken.txt"]; got != SkipLink+": broken symlink" {
+		t.Errorf("the broken link was skipped as %q", got)
+	}
+}
+
+// writeExternal writes a text file outside the profile root and returns its path
+func writeExternal(t *testing.T, content string) string {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), "shared.txt")
+	if err := os.WriteFile(filePath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
@@ -0,0 +1,423 @@
+package knowledge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// ProposalStatus is the review state of a proposed knowledge base change
+type ProposalStatus string
+
+// Proposal review states
+const (
+	ProposalPending  ProposalStatus = "pending"
+	ProposalApproved ProposalStatus = "approved"
+	ProposalRejected ProposalStatus = "rejected"
+)
+
+// ProposalKind is the kind of knowledge base change a proposal makes
+type ProposalKind string
+
+// Proposal kinds
+const (
+	ProposalCommonIssue    ProposalKind = "common_issue"     // Add a new common issue
+	ProposalErrorCodeSteps ProposalKind = "error_code_steps" // Add troubleshooting steps to an existing error code
+)
+
+// maxProposalSteps limits how many steps are drafted from a single fix description
+const maxProposalSteps = 10
+
+var (
+	// stepMarkerPattern matches list markers and step labels at the start of a line
+	stepMarkerPattern = regexp.MustCompile(`^\s*(?:(?:[-*+]|\d+[.)])\s+)?(?:\*\*)?(?:Step \d+:)?(?:\*\*)?\s*`)
+	// citationMarkPattern matches citation markers such as [1] or [P2] left in answer text
+	citationMarkPattern = regexp.MustCompile(`\s*\[(?:P?\d+(?:,\s*P?\d+)*\??)\]`)
+)
+
+// Proposal is a knowledge base change drafted from a resolved conversation, waiting for review
+type Proposal struct {
+	ID         string         `json:"id"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Status     ProposalStatus `json:"status"`
+	Kind       ProposalKind   `json:"kind"`
+	Question   string         `json:"question"`
+	FeedbackID string         `json:"feedback_id,omitempty"`
+
+	CommonIssue models.CommonIssue `json:"common_issue"`         // For ProposalCommonIssue
+	ErrorCode   string             `json:"error_code,omitempty"` // For ProposalErrorCodeSteps
+	Steps       []string           `json:"steps,omitempty"`      // For ProposalErrorCodeSteps
+
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// ProposalQueue stores proposals in a JSON file
+type ProposalQueue struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewProposalQueue creates a proposal queue backed by the file at path
+func NewProposalQueue(path string) *ProposalQueue {
+	return &ProposalQueue{path: path}
+}
+
+// Load returns every proposal in the queue; a missing file means an empty queue
+func (q *ProposalQueue) Load() ([]Proposal, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.load()
+}
+
+// Pending returns the proposals waiting for review, oldest first
+func (q *ProposalQueue) Pending() ([]Proposal, error) {
+	proposals, err := q.Load()
+	if err != nil {
+		return nil, err
+	}
+	var pending []Proposal
+	for _, proposal := range proposals {
+		if proposal.Status == ProposalPending {
+			pending = append(pending, proposal)
+		}
+	}
+	return pending, nil
+}
+
+// Add appends a proposal to the queue, assigning its ID, creation time and pending status
+func (q *ProposalQueue) Add(proposal Proposal) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	proposals, err := q.load()
+	if err != nil {
+		return err
+	}
+	if proposal.CreatedAt.IsZero() {
+		proposal.CreatedAt = time.Now()
+	}
+	if proposal.ID == "" {
+		proposal.ID = fmt.Sprintf("kb-%d", proposal.CreatedAt.UnixNano())
+	}
+	proposal.Status = ProposalPending
+	return writeJSONFile(q.path, append(proposals, proposal))
+}
+
+// Update replaces the stored proposal with the same ID
+func (q *ProposalQueue) Update(proposal Proposal) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	proposals, err := q.load()
+	if err != nil {
+		return err
+	}
+	for i := range proposals {
+		if proposals[i].ID == proposal.ID {
+			proposals[i] = proposal
+			return writeJSONFile(q.path, proposals)
+		}
+	}
+	return fmt.Errorf("proposal %s not found", proposal.ID)
+}
+
+// load reads the queue file; the caller must hold q.mu
+func (q *ProposalQueue) load() ([]Proposal, error) {
+	data, err := os.ReadFile(q.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", q.path, err)
+	}
+	var proposals []Proposal
+	if err := json.Unmarshal(data, &proposals); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", q.path, err)
+	}
+	return proposals, nil
+}
+
+// DraftProposal turns a resolved question and the fix that worked into a proposal. If the
+// question mentions a known error code the fix is proposed as extra troubleshooting steps for
+// that code, otherwise as a new common issue.
+func DraftProposal(question, fix string, errorCodes []models.ErrorCode) Proposal {
+	steps := SplitSteps(fix)
+	lowerQuestion := strings.ToLower(question)
+
+	for _, errorCode := range errorCodes {
+		if errorCode.Code != "" && strings.Contains(lowerQuestion, strings.ToLower(errorCode.Code)) {
+			return Proposal{
+				Kind:      ProposalErrorCodeSteps,
+				Question:  question,
+				ErrorCode: errorCode.Code,
+				Steps:     steps,
+			}
+		}
+	}
+
+	return Proposal{
+		Kind:     ProposalCommonIssue,
+		Question: question,
+		CommonIssue: models.CommonIssue{
+			Issue:     summarizeIssue(question),
+			Symptoms:  []string{strings.TrimSpace(question)},
+			Solutions: steps,
+		},
+	}
+}
+
+// SplitSteps splits a free-text fix description into individual steps, one per line or sentence
+func SplitSteps(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 1 {
+		lines = splitSentences(lines[0])
+	}
+
+	var steps []string
+	for _, line := range lines {
+		step := citationMarkPattern.ReplaceAllString(line, "")
+		step = stepMarkerPattern.ReplaceAllString(step, "")
+		step = strings.TrimSpace(strings.NewReplacer("**", "", "⚠️", "").Replace(step))
+		step = strings.TrimSpace(strings.TrimRight(step, ";"))
+		if step != "" {
+			steps = append(steps, step)
+		}
+		if len(steps) == maxProposalSteps {
+			break
+		}
+	}
+	return steps
+}
+
+// ApplyProposal writes an approved proposal to the learned fixes file and merges it into the
+// loaded data so it is used for the next question
+func (kb *KnowledgeDatabase) ApplyProposal(proposal Proposal) error {
+	var change models.TroubleshootingData
+	switch proposal.Kind {
+	case ProposalCommonIssue:
+		if strings.TrimSpace(proposal.CommonIssue.Issue) == "" || len(proposal.CommonIssue.Solutions) == 0 {
+			return fmt.Errorf("a common issue needs a name and at least one solution")
+		}
+		change.CommonIssues = []models.CommonIssue{proposal.CommonIssue}
+	case ProposalErrorCodeSteps:
+		if len(proposal.Steps) == 0 {
+			return fmt.Errorf("no troubleshooting steps to add to %s", proposal.ErrorCode)
+		}
+		if !kb.hasErrorCode(proposal.ErrorCode) {
+			return fmt.Errorf("error code %s is not in the knowledge base", proposal.ErrorCode)
+		}
+		change.ErrorCodes = []models.ErrorCode{{Code: proposal.ErrorCode, TroubleshootingSteps: proposal.Steps}}
+	default:
+		return fmt.Errorf("unknown proposal kind %q", proposal.Kind)
+	}
+
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	learned, err := loadTroubleshootingFile(kb.learnedPath)
+	if err != nil {
+		return err
+	}
+	if err := writeJSONFile(kb.learnedPath, mergeTroubleshootingData(learned, &change)); err != nil {
+		return err
+	}
+	kb.data = mergeTroubleshootingData(kb.data, &change)
+	return nil
+}
+
+// hasErrorCode reports whether the loaded data contains the error code
+func (kb *KnowledgeDatabase) hasErrorCode(code string) bool {
+	for _, errorCode := range kb.GetData().ErrorCodes {
+		if strings.EqualFold(errorCode.Code, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// LearnedPath returns the learned fixes file that sits next to an error codes file,
+// e.g. testData/lsie_errors.learned.json for testData/lsie_errors.json
+func LearnedPath(errorCodesPath string) string {
+	return strings.TrimSuffix(errorCodesPath, filepath.Ext(errorCodesPath)) + ".learned.json"
+}
+
+// mergeLearned returns base with the learned fixes applied, and the error codes whose learned
+// steps were left out. Steps are added to the error code that has the learned code as its code or
+// an alias; steps for a code that was since renamed or deleted would otherwise become a record
+// without a description or severity.
+func mergeLearned(base, learned *models.TroubleshootingData) (*models.TroubleshootingData, []string) {
+	if learned == nil {
+		return mergeTroubleshootingData(base, nil), nil
+	}
+
+	amendments := &models.TroubleshootingData{CommonIssues: learned.CommonIssues}
+	var orphaned []string
+	for _, errorCode := range learned.ErrorCodes {
+		code, ok := resolveErrorCode(base, errorCode.Code)
+		if !ok {
+			orphaned = append(orphaned, errorCode.Code)
+			continue
+		}
+		errorCode.Code = code
+		amendments.ErrorCodes = append(amendments.ErrorCodes, errorCode)
+	}
+	return mergeTroubleshootingData(base, amendments), orphaned
+}
+
+// resolveErrorCode returns the code of the error code in data that is named code, by its code
+// or one of its aliases
+func resolveErrorCode(data *models.TroubleshootingData, code string) (string, bool) {
+	if data == nil {
+		return "", false
+	}
+	for _, errorCode := range data.ErrorCodes {
+		if strings.EqualFold(errorCode.Code, code) {
+			return errorCode.Code, true
+		}
+	}
+	for _, errorCode := range data.ErrorCodes {
+		for _, alias := range errorCode.Aliases {
+			if strings.EqualFold(alias, code) {
+				return errorCode.Code, true
+			}
+		}
+	}
+	return "", false
+}
+
+// mergeTroubleshootingData returns a copy of base with extra applied: steps and components for
+// existing error codes are appended, new codes are added, and common issues with the same name
+// gain the new symptoms and solutions
+func mergeTroubleshootingData(base, extra *models.TroubleshootingData) *models.TroubleshootingData {
+	merged := &models.TroubleshootingData{SchemaVersion: SchemaVersion}
+	if base != nil {
+		merged.ErrorCodes = append(merged.ErrorCodes, base.ErrorCodes...)
+		merged.CommonIssues = append(merged.CommonIssues, base.CommonIssues...)
+	}
+	if extra == nil {
+		return merged
+	}
+
+	for _, errorCode := range extra.ErrorCodes {
+		found := false
+		for i := range merged.ErrorCodes {
+			if strings.EqualFold(merged.ErrorCodes[i].Code, errorCode.Code) {
+				existing := merged.ErrorCodes[i]
+				existing.TroubleshootingSteps = appendMissing(existing.TroubleshootingSteps, errorCode.TroubleshootingSteps...)
+				existing.RelatedComponents = appendMissing(existing.RelatedComponents, errorCode.RelatedComponents...)
+				merged.ErrorCodes[i] = existing
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.ErrorCodes = append(merged.ErrorCodes, errorCode)
+		}
+	}
+
+	for _, issue := range extra.CommonIssues {
+		found := false
+		for i := range merged.CommonIssues {
+			if strings.EqualFold(merged.CommonIssues[i].Issue, issue.Issue) {
+				existing := merged.CommonIssues[i]
+				existing.Symptoms = appendMissing(existing.Symptoms, issue.Symptoms...)
+				existing.Solutions = appendMissing(existing.Solutions, issue.Solutions...)
+				merged.CommonIssues[i] = existing
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.CommonIssues = append(merged.CommonIssues, issue)
+		}
+	}
+	return merged
+}
+
+// writeJSONFile writes v as indented JSON to path, replacing the previous file atomically
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// summarizeIssue shortens a question to a common issue name
+func summarizeIssue(question string) string {
+	issue := strings.TrimSpace(splitSentences(strings.Join(strings.Fields(question), " "))[0])
+	issue = strings.TrimRight(issue, ".?!")
+	runes := []rune(issue)
+	if len(runes) > 80 {
+		issue = strings.TrimSpace(string(runes[:80])) + "..."
+	}
+	return issue
+}
+
+// splitSentences splits text after sentence punctuation followed by a space
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i := 0; i < len(text)-1; i++ {
+		if (text[i] == '.' || text[i] == '!' || text[i] == '?' || text[i] == ';') && text[i+1] == ' ' {
+			sentences = append(sentences, text[start:i+1])
+			start = i + 2
+		}
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	if len(sentences) == 0 {
+		sentences = []string{text}
+	}
+	return sentences
+}
+
+// appendMissing appends the values that are not already present, ignoring case
+func appendMissing(values []string, extra ...string) []string {
+	for _, value := range extra {
+		found := false
+		for _, existing := range values {
+			if strings.EqualFold(existing, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,192 @@
+package knowledge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// overVoltage is a complete error code record used by the learning tests
+var overVoltage = models.ErrorCode{
+	Code:                 "E-12",
+	Description:          "Channel over voltage",
+	Category:             "power",
+	Severity:             "high",
+	TroubleshootingSteps: []string{"Check the fuse"},
+}
+
+func TestSplitSteps(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"lines", "Check the fuse\n\nReplace the board\n", []string{"Check the fuse", "Replace the board"}},
+		{"sentences", "Check the fuse. Replace the board; call the vendor", []string{"Check the fuse.", "Replace the board", "call the vendor"}},
+		{"list markers", "1. Check the fuse\n2) Replace the board\n- Call the vendor\n* Log it", []string{"Check the fuse", "Replace the board", "Call the vendor", "Log it"}},
+		{"answer formatting", "- **Step 1:** Check the fuse [P2]\n- **Step 2:** ⚠️ Power down first [1, 3]", []string{"Check the fuse", "Power down first"}},
+		{"empty", "  \n ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitSteps(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitSteps(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	if got := SplitSteps(strings.Repeat("Step\n", 20)); len(got) != maxProposalSteps {
+		t.Errorf("drafted %d steps, want at most %d", len(got), maxProposalSteps)
+	}
+}
+
+func TestDraftProposal(t *testing.T) {
+	codes := []models.ErrorCode{overVoltage}
+
+	steps := DraftProposal("Rack 3 shows e-12 after power up", "Reseat the fuse.\nRestart the rack.", codes)
+	if steps.Kind != ProposalErrorCodeSteps || steps.ErrorCode != "E-12" ||
+		!reflect.DeepEqual(steps.Steps, []string{"Reseat the fuse.", "Restart the rack."}) {
+		t.Errorf("question with a known code drafted %+v", steps)
+	}
+
+	long := "The chiller on rack 3 keeps tripping whenever the compressors start up in the morning and nobody can say why. It happened twice."
+	issue := DraftProposal(long, "Stagger the compressor start", codes)
+	if issue.Kind != ProposalCommonIssue || issue.ErrorCode != "" {
+		t.Fatalf("question without a code drafted %+v", issue)
+	}
+	if name := issue.CommonIssue.Issue; !strings.HasSuffix(name, "...") || len([]rune(name)) != 83 || strings.Contains(name, "twice") {
+		t.Errorf("issue name %q, want the first sentence shortened to 80 characters", name)
+	}
+	if !reflect.DeepEqual(issue.CommonIssue.Symptoms, []string{long}) || !reflect.DeepEqual(issue.CommonIssue.Solutions, []string{"Stagger the compressor start"}) {
+		t.Errorf("drafted symptoms %q and solutions %q", issue.CommonIssue.Symptoms, issue.CommonIssue.Solutions)
+	}
+}
+
+func TestMergeTroubleshootingData(t *testing.T) {
+	base := &models.TroubleshootingData{
+		ErrorCodes:   []models.ErrorCode{overVoltage},
+		CommonIssues: []models.CommonIssue{{Issue: "Chiller trips", Symptoms: []string{"alarm"}, Solutions: []string{"Reset it"}}},
+	}
+	extra := &models.TroubleshootingData{
+		ErrorCodes: []models.ErrorCode{
+			{Code: "e-12", TroubleshootingSteps: []string{"check the FUSE", "Replace the board"}, RelatedComponents: []string{"PSU"}},
+			{Code: "E-40", Description: "Door open"},
+		},
+		CommonIssues: []models.CommonIssue{
+			{Issue: "chiller trips", Symptoms: []string{"Alarm", "noise"}, Solutions: []string{"Stagger the start"}},
+			{Issue: "Slow login", Solutions: []string{"Clear the cache"}},
+		},
+	}
+
+	merged := mergeTroubleshootingData(base, extra)
+	if merged.SchemaVersion != SchemaVersion || len(merged.ErrorCodes) != 2 || len(merged.CommonIssues) != 2 {
+		t.Fatalf("merged %+v", merged)
+	}
+	if got := merged.ErrorCodes[0]; got.Code != "E-12" || got.Severity != "high" ||
+		!reflect.DeepEqual(got.TroubleshootingSteps, []string{"Check the fuse", "Replace the board"}) ||
+		!reflect.DeepEqual(got.RelatedComponents, []string{"PSU"}) {
+		t.Errorf("amended error code %+v", got)
+	}
+	if got := merged.CommonIssues[0]; !reflect.DeepEqual(got.Symptoms, []string{"alarm", "noise"}) ||
+		!reflect.DeepEqual(got.Solutions, []string{"Reset it", "Stagger the start"}) {
+		t.Errorf("amended common issue %+v", got)
+	}
+	if len(base.ErrorCodes[0].TroubleshootingSteps) != 1 {
+		t.Errorf("merging modified the base data: %+v", base.ErrorCodes[0])
+	}
+}
+
+func TestMergeLearned(t *testing.T) {
+	learned := &models.TroubleshootingData{
+		ErrorCodes: []models.ErrorCode{
+			{Code: "E-12", TroubleshootingSteps: []string{"Replace the board"}},
+			{Code: "E-99", TroubleshootingSteps: []string{"Power cycle"}},
+		},
+		CommonIssues: []models.CommonIssue{{Issue: "Slow login", Solutions: []string{"Clear the cache"}}},
+	}
+
+	renamed := overVoltage
+	renamed.Code = "PWR-12"
+	renamed.Aliases = []string{"E-12"}
+	tests := []struct {
+		name     string
+		base     []models.ErrorCode
+		codes    []string
+		steps    []string // Steps of the first error code
+		orphaned []string
+	}{
+		{"by code", []models.ErrorCode{overVoltage}, []string{"E-12"}, []string{"Check the fuse", "Replace the board"}, []string{"E-99"}},
+		{"by alias", []models.ErrorCode{renamed}, []string{"PWR-12"}, []string{"Check the fuse", "Replace the board"}, []string{"E-99"}},
+		{"deleted", nil, nil, nil, []string{"E-12", "E-99"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, orphaned := mergeLearned(&models.TroubleshootingData{ErrorCodes: tt.base}, learned)
+			var codes []string
+			for _, errorCode := range merged.ErrorCodes {
+				codes = append(codes, errorCode.Code)
+			}
+			if !reflect.DeepEqual(codes, tt.codes) || !reflect.DeepEqual(orphaned, tt.orphaned) {
+				t.Errorf("merged codes %v with %v orphaned, want %v with %v orphaned", codes, orphaned, tt.codes, tt.orphaned)
+			}
+			if len(codes) > 0 && !reflect.DeepEqual(merged.ErrorCodes[0].TroubleshootingSteps, tt.steps) {
+				t.Errorf("steps %q, want %q", merged.ErrorCodes[0].TroubleshootingSteps, tt.steps)
+			}
+			if len(merged.CommonIssues) != 1 {
+				t.Errorf("learned common issues %+v, want them kept", merged.CommonIssues)
+			}
+		})
+	}
+}
+
+func TestApplyProposalAfterRenamingTheCode(t *testing.T) {
+	errorCodesFile := filepath.Join(t.TempDir(), "errors.json")
+	if err := writeJSONFile(errorCodesFile, &models.TroubleshootingData{SchemaVersion: SchemaVersion, ErrorCodes: []models.ErrorCode{overVoltage}}); err != nil {
+		t.Fatal(err)
+	}
+	kb := newProfileDatabase(t, models.KnowledgeProfile{Root: t.TempDir(), ErrorCodesFile: errorCodesFile})
+
+	if err := kb.ApplyProposal(Proposal{Kind: ProposalErrorCodeSteps, ErrorCode: "E-40", Steps: []string{"Close the door"}}); err == nil {
+		t.Error("steps for an unknown error code were applied")
+	}
+	if err := kb.ApplyProposal(DraftProposal("E-12 again", "Replace the board", kb.GetData().ErrorCodes)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(LearnedPath(errorCodesFile)); err != nil {
+		t.Fatalf("the learned fixes file was not written: %v", err)
+	}
+	if steps := kb.GetData().ErrorCodes[0].TroubleshootingSteps; !reflect.DeepEqual(steps, []string{"Check the fuse", "Replace the board"}) {
+		t.Errorf("steps after applying %q", steps)
+	}
+
+	// Renaming the code in the editor leaves the learned steps without a record to amend
+	renamed := overVoltage
+	renamed.Code = "PWR-12"
+	if err := kb.SaveBaseData(&models.TroubleshootingData{ErrorCodes: []models.ErrorCode{renamed}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(kb.GetData()); err != nil {
+		t.Errorf("merged data is invalid after the rename: %v", err)
+	}
+	if len(kb.GetData().ErrorCodes) != 1 || !reflect.DeepEqual(kb.OrphanedFixes(), []string{"E-12"}) {
+		t.Errorf("error codes %+v with orphaned fixes %v, want only PWR-12 with E-12 orphaned", kb.GetData().ErrorCodes, kb.OrphanedFixes())
+	}
+
+	reloaded := newProfileDatabase(t, models.KnowledgeProfile{Root: t.TempDir(), ErrorCodesFile: errorCodesFile})
+	if err := Validate(reloaded.GetData()); err != nil || !reflect.DeepEqual(reloaded.OrphanedFixes(), []string{"E-12"}) {
+		t.Errorf("reloading gave %v with orphaned fixes %v", err, reloaded.OrphanedFixes())
+	}
+
+	// Keeping the old code as an alias brings the learned steps back
+	renamed.Aliases = []string{"E-12"}
+	if err := kb.SaveBaseData(&models.TroubleshootingData{ErrorCodes: []models.ErrorCode{renamed}}); err != nil {
+		t.Fatal(err)
+	}
+	if steps := kb.GetData().ErrorCodes[0].TroubleshootingSteps; len(kb.OrphanedFixes()) != 0 || !reflect.DeepEqual(steps, []string{"Check the fuse", "Replace the board"}) {
+		t.Errorf("steps %q with orphaned fixes %v after adding the alias", steps, kb.OrphanedFixes())
+	}
+}
@@ -0,0 +1,140 @@
+package knowledge
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"strings"
+)
+
+// IgnoreFile is the name of the optional file in a profile root listing extra exclude patterns
+const IgnoreFile = ".beanbotignore"
+
+// globSegments splits a glob into path segments. "*" and "?" match within one path segment
+// and "**" matches any number of segments. A glob with a slash at the start or in the middle
+// is anchored to the profile root; any other glob matches at any depth, e.g. "*.tmp".
+func globSegments(pattern string) []string {
+	pattern = strings.ReplaceAll(pattern, "\\", "/")
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.Trim(pattern, "/")
+	if pattern == "" {
+		return nil
+	}
+	if !anchored {
+		pattern = "**/" + pattern
+	}
+	return strings.Split(pattern, "/")
+}
+
+// matchSegments matches pattern segments against path segments
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every number of segments for the double star, including none
+			for skip := 0; skip <= len(segments); skip++ {
+				if matchSegments(pattern[1:], segments[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// pathRule is one gitignore-style pattern
+type pathRule struct {
+	text     string // Pattern as written, for reports
+	segments []string
+	negate   bool // "!pattern" re-includes paths matched by an earlier pattern
+	dirOnly  bool // "pattern/" only matches directories and the files inside them
+}
+
+// matches reports whether the rule matches the path or one of its parent directories
+func (r pathRule) matches(relPath string, isDir bool) bool {
+	if (isDir || !r.dirOnly) && matchSegments(r.segments, strings.Split(relPath, "/")) {
+		return true
+	}
+	for dir := path.Dir(relPath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if matchSegments(r.segments, strings.Split(dir, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// pathRules is an ordered list of gitignore-style patterns where the last matching pattern wins
+type pathRules []pathRule
+
+// parseRules parses gitignore-style patterns. Blank lines and lines starting with "#" are
+// ignored, a leading "!" negates a pattern and a trailing "/" limits it to directories.
+func parseRules(lines []string) pathRules {
+	var rules pathRules
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := pathRule{text: line}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		rule.dirOnly = strings.HasSuffix(line, "/") || strings.HasSuffix(line, "\\")
+		if rule.segments = globSegments(line); rule.segments != nil {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// match returns the last rule matching the path, if any
+func (rules pathRules) match(relPath string, isDir bool) (pathRule, bool) {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].matches(relPath, isDir) {
+			return rules[i], true
+		}
+	}
+	return pathRule{}, false
+}
+
+// excludes reports whether the rules exclude the path, and the pattern responsible
+func (rules pathRules) excludes(relPath string, isDir bool) (string, bool) {
+	rule, ok := rules.match(relPath, isDir)
+	if !ok || rule.negate {
+		return "", false
+	}
+	return rule.text, true
+}
+
+// includes reports whether the rules include the path; an empty rule list includes everything
+func (rules pathRules) includes(relPath string, isDir bool) bool {
+	if len(rules) == 0 {
+		return true
+	}
+	rule, ok := rules.match(relPath, isDir)
+	return ok && !rule.negate
+}
+
+// readIgnoreFile returns the patterns in a profile root's ignore file, or none when it is missing
+func readIgnoreFile(filePath string) []string {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
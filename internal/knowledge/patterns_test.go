@@ -0,0 +1,57 @@
+package knowledge
+
+import "testing"
+
+func TestPathRulesExcludes(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []string
+		path    string
+		isDir   bool
+		want    bool
+		pattern string
+	}{
+		{"unanchored glob at any depth", []string{"*.tmp"}, "a/b/c.tmp", false, true, "*.tmp"},
+		{"glob stays within a segment", []string{"/*.tmp"}, "a/c.tmp", false, false, ""},
+		{"anchored path", []string{"Confluence/attachments"}, "Confluence/attachments/1.json", false, true, "Confluence/attachments"},
+		{"anchored path does not match deeper", []string{"attachments/old"}, "Confluence/attachments/old/1.txt", false, false, ""},
+		{"double star", []string{"docs/**/draft-*"}, "docs/a/b/draft-1.md", false, true, "docs/**/draft-*"},
+		{"double star matches no segments", []string{"docs/**/draft-*"}, "docs/draft-1.md", false, true, "docs/**/draft-*"},
+		{"backslashes", []string{"logs\\old\\"}, "logs/old", true, true, "logs\\old\\"},
+		{"comments and blank lines", []string{"# *.txt", "", "  "}, "notes.txt", false, false, ""},
+		{"directory rule matches the directory", []string{"build/"}, "build", true, true, "build/"},
+		{"directory rule matches files inside", []string{"build/"}, "build/out.log", false, true, "build/"},
+		{"directory rule ignores files of the same name", []string{"build/"}, "build", false, false, ""},
+		{"negation re-includes", []string{"*.log", "!keep.log"}, "logs/keep.log", false, false, ""},
+		{"last match wins", []string{"!keep.log", "*.log"}, "logs/keep.log", false, true, "*.log"},
+		{"later exclusion after negation", []string{"*.log", "!keep.log", "logs/"}, "logs/keep.log", false, true, "logs/"},
+		{"no rules", nil, "a.txt", false, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, excluded := parseRules(tt.rules).excludes(tt.path, tt.isDir)
+			if excluded != tt.want || pattern != tt.pattern {
+				t.Errorf("excludes(%q) = %q, %v, want %q, %v", tt.path, pattern, excluded, tt.pattern, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathRulesIncludes(t *testing.T) {
+	tests := []struct {
+		rules []string
+		path  string
+		want  bool
+	}{
+		{nil, "anything.bin", true},
+		{[]string{"*.md", "Runbooks/"}, "docs/setup.md", true},
+		{[]string{"*.md", "Runbooks/"}, "Runbooks/rack.txt", true},
+		{[]string{"*.md", "Runbooks/"}, "notes.txt", false},
+		{[]string{"*.md", "!drafts/"}, "drafts/wip.md", false},
+	}
+	for _, tt := range tests {
+		if got := parseRules(tt.rules).includes(tt.path, false); got != tt.want {
+			t.Errorf("includes(%v, %q) = %v, want %v", tt.rules, tt.path, got, tt.want)
+		}
+	}
+}
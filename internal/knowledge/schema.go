@@ -0,0 +1,166 @@
+package knowledge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// SchemaVersion is the troubleshooting data format written by this version; see
+// schemas/troubleshooting.schema.json. Files without a schema_version are version 1.
+const SchemaVersion = 2
+
+// Likelihoods accepted for probable causes
+var Likelihoods = []string{"low", "medium", "high"}
+
+// patternCache holds compiled error code patterns by expression
+var patternCache sync.Map
+
+// loadTroubleshootingFile reads and migrates a troubleshooting data file; a missing file is empty
+func loadTroubleshootingFile(path string) (*models.TroubleshootingData, error) {
+	data, _, err := readTroubleshootingFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &models.TroubleshootingData{}, nil
+	}
+	return data, err
+}
+
+// readTroubleshootingFile reads a troubleshooting data file and migrates it to the current
+// schema in memory, returning a note for every change the migration made
+func readTroubleshootingFile(path string) (*models.TroubleshootingData, []string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var data models.TroubleshootingData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if data.SchemaVersion > SchemaVersion {
+		return nil, nil, fmt.Errorf("%s uses schema version %d, but this version of BeanBot only supports up to %d", path, data.SchemaVersion, SchemaVersion)
+	}
+
+	return &data, migrate(&data), nil
+}
+
+// migrate upgrades data to the current schema version in place
+func migrate(data *models.TroubleshootingData) []string {
+	var notes []string
+	if data.SchemaVersion == 0 {
+		data.SchemaVersion = 1
+	}
+	if data.SchemaVersion < SchemaVersion {
+		notes = append(notes, fmt.Sprintf("upgraded from schema version %d to %d", data.SchemaVersion, SchemaVersion))
+	}
+
+	// Version 2 replaces the single documentation_reference with a list of document links.
+	// Files hand-edited or copied from old examples can have both, so the legacy field is
+	// folded in whatever the version.
+	for i := range data.ErrorCodes {
+		errorCode := &data.ErrorCodes[i]
+		reference := strings.TrimSpace(errorCode.DocumentationReference)
+		if errorCode.DocumentationReference == "" {
+			continue
+		}
+		errorCode.DocumentationReference = ""
+		if reference == "" || hasDocument(errorCode.Documentation, reference) {
+			notes = append(notes, fmt.Sprintf("%s: removed documentation_reference, which was empty or already in documentation", errorCode.Code))
+			continue
+		}
+		errorCode.Documentation = append([]models.DocumentLink{{Title: reference, Location: reference}}, errorCode.Documentation...)
+		notes = append(notes, fmt.Sprintf("%s: moved documentation_reference into documentation", errorCode.Code))
+	}
+	if data.SchemaVersion < 2 {
+		data.SchemaVersion = 2
+	}
+	return notes
+}
+
+// MigrateFile upgrades a troubleshooting data file to the current schema. The migrated data
+// must pass Validate; the previous file is kept as a .bak backup. A file that is already
+// current is left untouched.
+func MigrateFile(path string) ([]string, error) {
+	data, notes, err := readTroubleshootingFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) == 0 {
+		return nil, nil
+	}
+
+	cleaned := Normalize(data)
+	if err := Validate(cleaned); err != nil {
+		return notes, fmt.Errorf("%s is not valid after migration: %w", path, err)
+	}
+
+	if err := backupFile(path); err != nil {
+		return notes, err
+	}
+	if err := writeJSONFile(path, cleaned); err != nil {
+		return notes, err
+	}
+	return notes, nil
+}
+
+// MentionsErrorCode reports whether text names the error code by code or alias, or matches
+// one of its patterns. Codes and aliases are compared without regard to case.
+func MentionsErrorCode(errorCode models.ErrorCode, text string) bool {
+	lowerText := strings.ToLower(text)
+	for _, name := range append([]string{errorCode.Code}, errorCode.Aliases...) {
+		if name = strings.TrimSpace(name); name != "" && strings.Contains(lowerText, strings.ToLower(name)) {
+			return true
+		}
+	}
+	for _, pattern := range errorCode.Patterns {
+		if re := compilePattern(pattern); re != nil && re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePattern returns the case-insensitive compiled pattern, or nil if it is invalid
+func compilePattern(pattern string) *regexp.Regexp {
+	if cached, ok := patternCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil
+	}
+	patternCache.Store(pattern, re)
+	return re
+}
+
+// backupFile copies path to path.bak; a missing file needs no backup
+func backupFile(path string) error {
+	previous, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s for backup: %w", path, err)
+	}
+	if err := os.WriteFile(path+".bak", previous, 0644); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	return nil
+}
+
+// hasDocument reports whether the links include location
+func hasDocument(links []models.DocumentLink, location string) bool {
+	for _, link := range links {
+		if strings.EqualFold(link.Location, location) {
+			return true
+		}
+	}
+	return false
+}
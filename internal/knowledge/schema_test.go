@@ -0,0 +1,186 @@
+package knowledge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// manual is a document link used by the schema tests
+var manual = models.DocumentLink{Title: "Manual", Location: "docs/manual.pdf"}
+
+// writeTroubleshootingFile writes raw troubleshooting data and returns its path
+func writeTroubleshootingFile(t *testing.T, raw string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "errors.json")
+	if err := os.WriteFile(path, []byte(raw), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          models.TroubleshootingData
+		documentation []models.DocumentLink // Documentation of the first error code after migrating
+		notes         []string
+	}{
+		{
+			name:          "version 1 reference",
+			data:          models.TroubleshootingData{ErrorCodes: []models.ErrorCode{{Code: "E-12", DocumentationReference: " docs/e12.html "}}},
+			documentation: []models.DocumentLink{{Title: "docs/e12.html", Location: "docs/e12.html"}},
+			notes:         []string{"upgraded from schema version 1 to 2", "E-12: moved documentation_reference into documentation"},
+		},
+		{
+			name: "version 1 reference kept before the links",
+			data: models.TroubleshootingData{SchemaVersion: 1, ErrorCodes: []models.ErrorCode{
+				{Code: "E-12", DocumentationReference: "docs/e12.html", Documentation: []models.DocumentLink{manual}},
+			}},
+			documentation: []models.DocumentLink{{Title: "docs/e12.html", Location: "docs/e12.html"}, manual},
+			notes:         []string{"upgraded from schema version 1 to 2", "E-12: moved documentation_reference into documentation"},
+		},
+		{
+			name: "version 1 reference already linked",
+			data: models.TroubleshootingData{ErrorCodes: []models.ErrorCode{
+				{Code: "E-12", DocumentationReference: "DOCS/manual.pdf", Documentation: []models.DocumentLink{manual}},
+			}},
+			documentation: []models.DocumentLink{manual},
+			notes:         []string{"upgraded from schema version 1 to 2", "E-12: removed documentation_reference, which was empty or already in documentation"},
+		},
+		{
+			name:          "version 1 without a reference",
+			data:          models.TroubleshootingData{ErrorCodes: []models.ErrorCode{{Code: "E-12"}}},
+			documentation: nil,
+			notes:         []string{"upgraded from schema version 1 to 2"},
+		},
+		{
+			name:          "version 2",
+			data:          models.TroubleshootingData{SchemaVersion: 2, ErrorCodes: []models.ErrorCode{{Code: "E-12", Documentation: []models.DocumentLink{manual}}}},
+			documentation: []models.DocumentLink{manual},
+			notes:         nil,
+		},
+		{
+			name: "version 2 with a legacy reference",
+			data: models.TroubleshootingData{SchemaVersion: 2, ErrorCodes: []models.ErrorCode{
+				{Code: "E-12", DocumentationReference: "docs/e12.html", Documentation: []models.DocumentLink{manual}},
+			}},
+			documentation: []models.DocumentLink{{Title: "docs/e12.html", Location: "docs/e12.html"}, manual},
+			notes:         []string{"E-12: moved documentation_reference into documentation"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data
+			notes := migrate(&data)
+			if data.SchemaVersion != SchemaVersion {
+				t.Errorf("schema version %d after migrating, want %d", data.SchemaVersion, SchemaVersion)
+			}
+			errorCode := data.ErrorCodes[0]
+			if errorCode.DocumentationReference != "" || !reflect.DeepEqual(errorCode.Documentation, tt.documentation) {
+				t.Errorf("documentation_reference %q and documentation %+v, want %+v", errorCode.DocumentationReference, errorCode.Documentation, tt.documentation)
+			}
+			if !reflect.DeepEqual(notes, tt.notes) {
+				t.Errorf("notes %q, want %q", notes, tt.notes)
+			}
+		})
+	}
+}
+
+func TestReadTroubleshootingFile(t *testing.T) {
+	current := writeTroubleshootingFile(t, `{"schema_version": 2, "error_codes": [{"code": "E-12", "documentation": [{"title": "Manual", "location": "docs/manual.pdf"}]}]}`)
+	data, notes, err := readTroubleshootingFile(current)
+	if err != nil || notes != nil {
+		t.Fatalf("reading a current file gave notes %q and error %v", notes, err)
+	}
+	if !reflect.DeepEqual(data.ErrorCodes[0].Documentation, []models.DocumentLink{manual}) {
+		t.Errorf("documentation %+v", data.ErrorCodes[0].Documentation)
+	}
+
+	newer := writeTroubleshootingFile(t, `{"schema_version": 3, "error_codes": []}`)
+	if _, _, err := readTroubleshootingFile(newer); err == nil || !strings.Contains(err.Error(), "schema version 3") {
+		t.Errorf("reading a newer schema gave %v", err)
+	}
+
+	if data, err := loadTroubleshootingFile(filepath.Join(t.TempDir(), "missing.json")); err != nil || len(data.ErrorCodes) != 0 {
+		t.Errorf("loading a missing file gave %+v and %v, want empty data", data, err)
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	const version1 = `{"error_codes": [{"code": "E-12", "description": "Channel over voltage", "category": "power", ` +
+		`"severity": "high", "troubleshooting_steps": ["Check the fuse"], "documentation_reference": "docs/e12.html"}]}`
+	path := writeTroubleshootingFile(t, version1)
+
+	notes, err := MigrateFile(path)
+	if err != nil {
+		t.Fatalf("MigrateFile: %v", err)
+	}
+	if len(notes) != 2 {
+		t.Errorf("notes %q, want the upgrade and the moved reference", notes)
+	}
+	if backup, err := os.ReadFile(path + ".bak"); err != nil || string(backup) != version1 {
+		t.Errorf("backup %q (%v), want the version 1 file", backup, err)
+	}
+	data, notes, err := readTroubleshootingFile(path)
+	if err != nil || notes != nil {
+		t.Fatalf("reading the migrated file gave notes %q and error %v", notes, err)
+	}
+	if data.SchemaVersion != SchemaVersion || len(data.ErrorCodes[0].Documentation) != 1 || data.ErrorCodes[0].Documentation[0].Location != "docs/e12.html" {
+		t.Errorf("migrated %+v", data)
+	}
+
+	// A current file is left alone and gets no backup
+	if err := os.Remove(path + ".bak"); err != nil {
+		t.Fatal(err)
+	}
+	migrated, _ := os.ReadFile(path)
+	if notes, err := MigrateFile(path); notes != nil || err != nil {
+		t.Errorf("migrating again gave notes %q and error %v", notes, err)
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(migrated) {
+		t.Errorf("migrating again rewrote the file")
+	}
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("migrating again wrote a backup: %v", err)
+	}
+
+	// Data that is invalid after migrating is not written
+	invalid := writeTroubleshootingFile(t, `{"error_codes": [{"code": "E-12"}]}`)
+	if _, err := MigrateFile(invalid); err == nil || !strings.Contains(err.Error(), "not valid after migration") {
+		t.Errorf("migrating invalid data gave %v", err)
+	}
+	if _, err := os.Stat(invalid + ".bak"); !os.IsNotExist(err) {
+		t.Errorf("invalid data was backed up: %v", err)
+	}
+}
+
+func TestMentionsErrorCode(t *testing.T) {
+	errorCode := models.ErrorCode{
+		Code:     "E-12",
+		Aliases:  []string{"OVP-4", " "},
+		Patterns: []string{`ch\d+ over ?voltage`, `(unclosed`},
+	}
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"Rack 3 shows E-12", true},
+		{"rack 3 shows e-12", true},
+		{"the log says ovp-4 twice", true},
+		{"2025-03-01 CH4 OVERVOLTAGE", true},
+		{"ch4 over voltage", true},
+		{"E-1 and E-2", false},
+		{"(unclosed", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := MentionsErrorCode(errorCode, tt.text); got != tt.want {
+			t.Errorf("MentionsErrorCode(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package knowledge
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Search result limits
+const (
+	maxSnippets        = 3   // Snippets returned for each matching document
+	snippetContext     = 60  // Bytes of text shown either side of a match
+	maxSnippetLength   = 300 // Bytes of text after which close matches start a new snippet
+	maxTermOccurrences = 50  // Occurrences of each term considered for snippets
+)
+
+// Snippet is an excerpt of a document around search matches
+type Snippet struct {
+	Text       string   // Line breaks are replaced with spaces
+	Offset     int      // Where the snippet starts in the document's content
+	Highlights [][2]int // Byte ranges of the matched terms within Text
+}
+
+// SearchHit is a document that contains every search term
+type SearchHit struct {
+	Document Document
+	Matches  int // Occurrences of the terms in the document's text
+	Snippets []Snippet
+}
+
+// Search finds the indexed documents and uploads whose text or path contains every word of the
+// query, most matches first. Matching ignores ASCII case so offsets stay valid in the original text.
+func (kb *KnowledgeDatabase) Search(query string, limit int) []SearchHit {
+	terms := strings.Fields(asciiLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var hits []SearchHit
+	for _, doc := range append(kb.documents.All(), kb.Uploads()...) {
+		if doc.DuplicateOf != "" {
+			continue
+		}
+		lower := asciiLower(doc.Content)
+		lowerPath := asciiLower(doc.Path)
+		matches := 0
+		found := true
+		for _, term := range terms {
+			count := strings.Count(lower, term)
+			if count == 0 && !strings.Contains(lowerPath, term) {
+				found = false
+				break
+			}
+			matches += count
+		}
+		if found {
+			hits = append(hits, SearchHit{Document: doc, Matches: matches, Snippets: snippets(doc.Content, lower, terms)})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Matches != hits[j].Matches {
+			return hits[i].Matches > hits[j].Matches
+		}
+		return hits[i].Document.Path < hits[j].Document.Path
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// snippets returns the text around the first matches of the terms, merging matches that are close.
+// A snippet cut short by its length ends where the next one starts, so every match shown is highlighted.
+func snippets(content, lower string, terms []string) []Snippet {
+	type match struct{ start, end int }
+	var matches []match
+	for _, term := range terms {
+		for offset, n := 0, 0; n < maxTermOccurrences; n++ {
+			i := strings.Index(lower[offset:], term)
+			if i < 0 {
+				break
+			}
+			matches = append(matches, match{offset + i, offset + i + len(term)})
+			offset += i + len(term)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+
+	var result []Snippet
+	previous := 0 // End of the previous snippet
+	for i := 0; i < len(matches) && len(result) < maxSnippets; {
+		if matches[i].start < previous {
+			// Overlaps a match of another term that the previous snippet already shows
+			i++
+			continue
+		}
+		start := runeStart(content, max(matches[i].start-snippetContext, previous))
+		end := matches[i].end + snippetContext
+		covered := matches[i].end // End of the highlighted text
+		highlights := []match{matches[i]}
+		for i++; i < len(matches) && matches[i].start < end && matches[i].end-start <= maxSnippetLength; i++ {
+			end = max(end, matches[i].end+snippetContext)
+			covered = max(covered, matches[i].end)
+			highlights = append(highlights, matches[i])
+		}
+		if i < len(matches) && matches[i].start < end {
+			end = max(matches[i].start, covered)
+		}
+		end = runeStart(content, min(end, len(content)))
+		previous = end
+
+		snippet := Snippet{Text: strings.NewReplacer("\r", " ", "\n", " ", "\t", " ").Replace(content[start:end]), Offset: start}
+		for _, highlight := range highlights {
+			if highlight.start >= start && highlight.end <= end {
+				snippet.Highlights = append(snippet.Highlights, [2]int{highlight.start - start, highlight.end - start})
+			}
+		}
+		result = append(result, snippet)
+	}
+	return result
+}
+
+// runeStart moves an offset back to the start of the character it falls in
+func runeStart(text string, offset int) int {
+	for offset > 0 && offset < len(text) && !utf8.RuneStart(text[offset]) {
+		offset--
+	}
+	return offset
+}
+
+// asciiLower lower-cases ASCII letters only, keeping the text's length unchanged
+func asciiLower(text string) string {
+	lower := []byte(text)
+	for i, c := range lower {
+		if c >= 'A' && c <= 'Z' {
+			lower[i] = c + 'a' - 'A'
+		}
+	}
+	return string(lower)
+}
@@ -0,0 +1,179 @@
+package knowledge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSearch(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"fuse.txt":          "Replace the FUSE on the rack. Check the fuse holder and the rack fuse again.",
+		"rack.txt":          "The rack has one fuse.",
+		"copy/rack.txt":     "The rack has one fuse.",
+		"fan.txt":           "The rack fan is loud.",
+		"Calibration/a.txt": "Run the offset routine on the rack.",
+	})
+	kb := newTestDatabase(t, root)
+	upload := filepath.Join(t.TempDir(), "bench.log")
+	if err := os.WriteFile(upload, []byte("rack 3 fuse fuse fuse fuse fuse fuse blown"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kb.ProcessUserUpload(upload); err != nil {
+		t.Fatalf("ProcessUserUpload: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		limit   int
+		paths   []string // Hits in rank order
+		matches []int
+	}{
+		// rack.txt is a copy of copy/rack.txt, which is indexed first
+		{"every term must match", "rack fuse", 0, []string{"upload/bench.log", "fuse.txt", "copy/rack.txt"}, []int{7, 5, 2}},
+		{"ASCII case is ignored", "FUSE Rack", 0, []string{"upload/bench.log", "fuse.txt", "copy/rack.txt"}, []int{7, 5, 2}},
+		{"ties go in path order", "rack", 0, []string{"fuse.txt", "Calibration/a.txt", "copy/rack.txt", "fan.txt", "upload/bench.log"}, []int{2, 1, 1, 1, 1}},
+		{"limit", "rack fuse", 2, []string{"upload/bench.log", "fuse.txt"}, []int{7, 5}},
+		{"a term may match the path", "calibration offset", 0, []string{"Calibration/a.txt"}, []int{1}},
+		{"no document has every term", "fan fuse", 0, nil, nil},
+		{"empty query", "  ", 0, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			var matches []int
+			for _, hit := range kb.Search(tt.query, tt.limit) {
+				paths = append(paths, hit.Document.Path)
+				matches = append(matches, hit.Matches)
+			}
+			if !reflect.DeepEqual(paths, tt.paths) || !reflect.DeepEqual(matches, tt.matches) {
+				t.Errorf("Search(%q) = %v with %v matches, want %v with %v", tt.query, paths, matches, tt.paths, tt.matches)
+			}
+		})
+	}
+}
+
+func TestSnippets(t *testing.T) {
+	filler := strings.Repeat("x", 200)
+	tests := []struct {
+		name    string
+		content string
+		terms   []string
+		want    []string // Text of each snippet with its highlights in brackets
+		offsets []int
+	}{
+		{
+			name:    "whole short text",
+			content: "Replace the fuse.",
+			terms:   []string{"fuse"},
+			want:    []string{"Replace the [fuse]."},
+			offsets: []int{0},
+		},
+		{
+			name:    "case and line breaks",
+			content: "Step 1:\r\nReplace the FUSE\tnow",
+			terms:   []string{"fuse"},
+			want:    []string{"Step 1:  Replace the [FUSE] now"},
+			offsets: []int{0},
+		},
+		{
+			name:    "nearby matches are merged",
+			content: filler + " the rack fuse blew " + filler,
+			terms:   []string{"rack", "fuse"},
+			want:    []string{strings.Repeat("x", 55) + " the [rack] [fuse] blew " + strings.Repeat("x", 54)},
+			offsets: []int{145},
+		},
+		{
+			name:    "distant matches get their own snippets",
+			content: "fuse " + filler + " fuse",
+			terms:   []string{"fuse"},
+			want:    []string{"[fuse] " + strings.Repeat("x", 59), strings.Repeat("x", 59) + " [fuse]"},
+			offsets: []int{0, 146},
+		},
+		{
+			name:    "at most three snippets",
+			content: strings.Repeat("fuse "+filler, 5),
+			terms:   []string{"fuse"},
+			want: []string{
+				"[fuse] " + strings.Repeat("x", 59),
+				strings.Repeat("x", 60) + "[fuse] " + strings.Repeat("x", 59),
+				strings.Repeat("x", 60) + "[fuse] " + strings.Repeat("x", 59),
+			},
+			offsets: []int{0, 145, 350},
+		},
+		{
+			name:    "a long run of matches is split where the next snippet starts",
+			content: strings.Repeat("fuse xxxxxxxxxx ", 25),
+			terms:   []string{"fuse"},
+			want: []string{
+				strings.Repeat("[fuse] xxxxxxxxxx ", 19),
+				strings.Repeat("[fuse] xxxxxxxxxx ", 6),
+			},
+			offsets: []int{0, 304},
+		},
+		{
+			name:    "overlapping terms",
+			content: filler + " fuses " + filler,
+			terms:   []string{"fuse", "uses"},
+			want:    []string{strings.Repeat("x", 59) + " [fuse]s " + strings.Repeat("x", 59)},
+			offsets: []int{141},
+		},
+		{
+			name:    "bounds do not split characters",
+			content: strings.Repeat("é", 40) + " fuse " + strings.Repeat("ü", 40),
+			terms:   []string{"fuse"},
+			want:    []string{strings.Repeat("é", 30) + " [fuse] " + strings.Repeat("ü", 29)},
+			offsets: []int{20},
+		},
+		{
+			name:    "terms after multi-byte text",
+			content: "Größe prüfen: Sicherung fuse ✅ erneuern",
+			terms:   []string{"sicherung", "fuse", "erneuern"},
+			want:    []string{"Größe prüfen: [Sicherung] [fuse] ✅ [erneuern]"},
+			offsets: []int{0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := snippets(tt.content, asciiLower(tt.content), tt.terms)
+			var got []string
+			var offsets []int
+			for _, snippet := range result {
+				if !utf8.ValidString(snippet.Text) {
+					t.Errorf("snippet at %d is not valid UTF-8: %q", snippet.Offset, snippet.Text)
+				}
+				got = append(got, markHighlights(t, snippet))
+				offsets = append(offsets, snippet.Offset)
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(offsets, tt.offsets) {
+				t.Errorf("snippets at %v:\n%q\nwant at %v:\n%q", offsets, got, tt.offsets, tt.want)
+			}
+		})
+	}
+}
+
+// markHighlights writes a snippet's highlights in brackets, skipping overlapping ones as the
+// document browser does, and checks they are in order within the text
+func markHighlights(t *testing.T, snippet Snippet) string {
+	t.Helper()
+	var marked strings.Builder
+	last, previous := 0, 0
+	for _, highlight := range snippet.Highlights {
+		if highlight[0] < previous || highlight[1] > len(snippet.Text) || highlight[0] >= highlight[1] {
+			t.Fatalf("highlight %v is out of order or outside %q", highlight, snippet.Text)
+		}
+		previous = highlight[0]
+		if highlight[0] < last {
+			continue
+		}
+		marked.WriteString(snippet.Text[last:highlight[0]] + "[" + snippet.Text[highlight[0]:highlight[1]] + "]")
+		last = highlight[1]
+	}
+	marked.WriteString(snippet.Text[last:])
+	return marked.String()
+}
@@ -0,0 +1,780 @@
+package knowledge
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Spreadsheet extraction limits
+const (
+	maxTableRows         = 1000      // Rows of a table written as Markdown; summaries use every row
+	maxTableColumns      = 60        // Columns read from a table
+	maxListedFindings    = 20        // Out-of-range values listed for each column
+	maxDistinctValues    = 8         // Text columns with at most this many values list their counts
+	maxWorkbookPartBytes = 200 << 20 // Uncompressed size of a workbook part that is read
+)
+
+// Words in a column header that mark it as a limit rather than a measurement
+var (
+	upperLimitWords = map[string]bool{"max": true, "maximum": true, "upper": true, "high": true, "hi": true, "usl": true, "ceiling": true}
+	lowerLimitWords = map[string]bool{"min": true, "minimum": true, "lower": true, "low": true, "lo": true, "lsl": true, "floor": true}
+	limitWords      = map[string]bool{"limit": true, "limits": true, "lim": true, "spec": true, "threshold": true, "allowed": true}
+	// Headers made only of these words identify rows, like "Channel" or "Serial No"
+	identifierWords = map[string]bool{"channel": true, "ch": true, "id": true, "index": true, "no": true, "number": true, "name": true,
+		"cell": true, "serial": true, "sn": true, "slot": true, "device": true, "test": true, "step": true, "parameter": true, "signal": true}
+)
+
+// table is a sheet or delimited file with a header row
+type table struct {
+	name   string // Sheet name, or the file name for delimited files
+	header []string
+	rows   [][]string
+}
+
+// Kinds of limit a column can hold
+const (
+	notLimit = iota
+	lowerLimit
+	upperLimit
+)
+
+// tableColumn describes a column of a table for the summary
+type tableColumn struct {
+	index   int
+	name    string
+	tokens  []string // Lower-case header words without units
+	base    []string // Header words without the limit words, naming what a limit applies to
+	numeric bool     // Most non-empty cells are numbers
+	limit   int      // notLimit, lowerLimit or upperLimit
+	id      bool     // The column identifies rows
+}
+
+// limitRule is a row of a limits table, such as "Voltage | 2.5 | 4.2", applying to measurement
+// columns of other tables in the same document, and of uploaded tables in other documents
+type limitRule struct {
+	parameter    string
+	tokens       []string
+	lower, upper string // Cell text; empty when the limit is not set
+	source       string
+}
+
+// isWorkbook reports whether a spreadsheet is an .xlsx workbook rather than delimited text
+func isWorkbook(docType DocumentType, name string) bool {
+	return docType == DocSheet && strings.EqualFold(path.Ext(name), ".xlsx")
+}
+
+// readDelimited reads CSV or TSV content as a table
+func readDelimited(content, name string) []table {
+	content = strings.TrimPrefix(content, "\ufeff")
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.Comma = detectDelimiter(content, name)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Keep the rows read before the malformed line
+			log.Printf("Stopped reading %s at a malformed line: %v", name, err)
+			break
+		}
+		records = append(records, record)
+	}
+	t, ok := newTable(name, records)
+	if !ok {
+		return nil
+	}
+	return []table{t}
+}
+
+// detectDelimiter picks tabs for .tsv files, otherwise the most common of comma, semicolon and
+// tab on the first line
+func detectDelimiter(content, name string) rune {
+	if strings.EqualFold(path.Ext(name), ".tsv") {
+		return '\t'
+	}
+	first, _, _ := strings.Cut(content, "\n")
+	best, count := ',', strings.Count(first, ",")
+	for _, candidate := range []rune{';', '\t'} {
+		if n := strings.Count(first, string(candidate)); n > count {
+			best, count = candidate, n
+		}
+	}
+	return best
+}
+
+// newTable builds a table from records, using the first non-empty record as the header
+func newTable(name string, records [][]string) (table, bool) {
+	t := table{name: name}
+	width := 0
+	for _, record := range records {
+		for len(record) > 0 && strings.TrimSpace(record[len(record)-1]) == "" {
+			record = record[:len(record)-1]
+		}
+		if len(record) == 0 {
+			continue
+		}
+		if len(record) > maxTableColumns {
+			record = record[:maxTableColumns]
+		}
+		width = max(width, len(record))
+		if t.header == nil {
+			t.header = record
+		} else {
+			t.rows = append(t.rows, record)
+		}
+	}
+	if t.header == nil {
+		return t, false
+	}
+
+	for len(t.header) < width {
+		t.header = append(t.header, "")
+	}
+	for i, name := range t.header {
+		if name = strings.Join(strings.Fields(name), " "); name == "" {
+			name = fmt.Sprintf("Column %d", i+1)
+		}
+		t.header[i] = name
+	}
+	for i, row := range t.rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		t.rows[i] = row
+	}
+	return t, true
+}
+
+// documentLimitRules returns the rows of the limits tables in a spreadsheet document, with the
+// document named as their source
+func documentLimitRules(doc Document) []limitRule {
+	var rules []limitRule
+	for _, t := range doc.tables {
+		source := doc.Path
+		if t.name != doc.Name {
+			source += ", " + t.name
+		}
+		for _, rule := range limitRules(t) {
+			rule.source = source
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// crossLimitChecks checks the tables of a spreadsheet document against limits tables of other
+// documents. Columns that have limits within the document are left to its own checks.
+func crossLimitChecks(doc Document, rules []limitRule) string {
+	var own []limitRule
+	for _, t := range doc.tables {
+		own = append(own, limitRules(t)...)
+	}
+
+	var out strings.Builder
+	for _, t := range doc.tables {
+		columns := describeColumns(t)
+		checked := make(map[int]bool)
+		for _, check := range findLimitChecks(t, columns, own) {
+			checked[check.measured.index] = true
+		}
+		var results []string
+		for _, check := range findLimitChecks(t, columns, rules) {
+			if !checked[check.measured.index] {
+				results = append(results, evaluateLimits(t, columns, check))
+			}
+		}
+		if len(results) == 0 {
+			continue
+		}
+		fmt.Fprintf(&out, "### %s\n\n", t.name)
+		for _, result := range results {
+			out.WriteString("- " + result + "\n")
+		}
+		out.WriteString("\n")
+	}
+	if out.Len() == 0 {
+		return ""
+	}
+	return "## Limit checks against other files\n\n" + strings.TrimSpace(out.String())
+}
+
+// renderTables writes each table as a summary of its columns and limit checks followed by its
+// rows, so the findings come first in excerpts of the document
+func renderTables(tables []table) string {
+	var rules []limitRule
+	for _, t := range tables {
+		rules = append(rules, limitRules(t)...)
+	}
+
+	var out strings.Builder
+	for _, t := range tables {
+		columns := describeColumns(t)
+		fmt.Fprintf(&out, "## %s\n\n", t.name)
+		fmt.Fprintf(&out, "%d rows, columns: %s\n\n", len(t.rows), strings.Join(t.header, ", "))
+
+		if checks := checkLimits(t, columns, rules); len(checks) > 0 {
+			out.WriteString("Limit checks:\n")
+			for _, check := range checks {
+				out.WriteString("- " + check + "\n")
+			}
+			out.WriteString("\n")
+		}
+		if summaries := summarizeColumns(t, columns); len(summaries) > 0 {
+			out.WriteString("Column summary:\n")
+			for _, summary := range summaries {
+				out.WriteString("- " + summary + "\n")
+			}
+			out.WriteString("\n")
+		}
+
+		out.WriteString(markdownRow(t.header))
+		out.WriteString("|" + strings.Repeat(" --- |", len(t.header)) + "\n")
+		for i, row := range t.rows {
+			if i == maxTableRows {
+				fmt.Fprintf(&out, "\n%d more rows are not shown.\n", len(t.rows)-i)
+				break
+			}
+			out.WriteString(markdownRow(row))
+		}
+		out.WriteString("\n")
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// markdownRow formats cells as a Markdown table row
+func markdownRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = strings.ReplaceAll(strings.Join(strings.Fields(cell), " "), "|", `\|`)
+	}
+	return "| " + strings.Join(escaped, " | ") + " |\n"
+}
+
+// headerTokens splits a header into lower-case words, leaving out units in brackets
+func headerTokens(header string) []string {
+	var plain strings.Builder
+	depth := 0
+	for _, c := range strings.ToLower(header) {
+		switch {
+		case c == '(' || c == '[':
+			depth++
+		case (c == ')' || c == ']') && depth > 0:
+			depth--
+		case depth == 0:
+			plain.WriteRune(c)
+		}
+	}
+	return strings.FieldsFunc(plain.String(), func(c rune) bool { return !unicode.IsLetter(c) && !unicode.IsDigit(c) })
+}
+
+// describeColumns classifies the columns of a table
+func describeColumns(t table) []tableColumn {
+	columns := make([]tableColumn, len(t.header))
+	for i, name := range t.header {
+		column := tableColumn{index: i, name: name, tokens: headerTokens(name), id: true}
+		for _, token := range column.tokens {
+			switch {
+			case upperLimitWords[token]:
+				column.limit = upperLimit
+			case lowerLimitWords[token]:
+				column.limit = lowerLimit
+			case limitWords[token]:
+				if column.limit == notLimit {
+					column.limit = upperLimit
+				}
+			default:
+				column.base = append(column.base, token)
+			}
+			if !identifierWords[token] {
+				column.id = false
+			}
+		}
+		column.id = column.id && len(column.tokens) > 0
+
+		numbers, filled := 0, 0
+		for _, row := range t.rows {
+			if strings.TrimSpace(row[i]) == "" {
+				continue
+			}
+			filled++
+			if _, ok := parseNumber(row[i]); ok {
+				numbers++
+			}
+		}
+		column.numeric = filled > 0 && numbers*5 >= filled*4
+		columns[i] = column
+	}
+	return columns
+}
+
+// parseNumber reads a cell as a number, allowing a trailing percent sign
+func parseNumber(cell string) (float64, bool) {
+	cell = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(cell), "%"))
+	if cell == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(cell, 64)
+	return value, err == nil && !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+// limitRules reads a limits table: a table with a text column naming parameters and limit
+// columns such as Min and Max that name nothing else
+func limitRules(t table) []limitRule {
+	columns := describeColumns(t)
+	name, lower, upper := -1, -1, -1
+	for _, column := range columns {
+		switch {
+		case column.limit == lowerLimit && len(column.base) == 0 && column.numeric:
+			lower = column.index
+		case column.limit == upperLimit && len(column.base) == 0 && column.numeric:
+			upper = column.index
+		case name < 0 && !column.numeric && column.limit == notLimit:
+			name = column.index
+		}
+	}
+	if name < 0 || (lower < 0 && upper < 0) {
+		return nil
+	}
+
+	var rules []limitRule
+	for _, row := range t.rows {
+		rule := limitRule{parameter: strings.TrimSpace(row[name]), tokens: headerTokens(row[name]), source: t.name}
+		if lower >= 0 {
+			rule.lower = strings.TrimSpace(row[lower])
+		}
+		if upper >= 0 {
+			rule.upper = strings.TrimSpace(row[upper])
+		}
+		if len(rule.tokens) > 0 && (rule.lower != "" || rule.upper != "") {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// containsAll reports whether every word of want is in have
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if h == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// limitCheck is the limits that apply to a measurement column: limit columns of the same table
+// or a limits table row
+type limitCheck struct {
+	measured                 tableColumn
+	lowerColumn, upperColumn int // -1 when there is no limit column
+	rule                     *limitRule
+}
+
+// describe names where the limits of a check come from
+func (c limitCheck) describe(t table) string {
+	var parts []string
+	if c.lowerColumn >= 0 {
+		parts = append(parts, t.header[c.lowerColumn])
+	}
+	if c.upperColumn >= 0 {
+		parts = append(parts, t.header[c.upperColumn])
+	}
+	if c.rule != nil {
+		var bounds []string
+		if c.rule.lower != "" {
+			bounds = append(bounds, "min "+c.rule.lower)
+		}
+		if c.rule.upper != "" {
+			bounds = append(bounds, "max "+c.rule.upper)
+		}
+		parts = append(parts, fmt.Sprintf("%s in %s: %s", c.rule.parameter, c.rule.source, strings.Join(bounds, ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// checkLimits compares each measurement column with its limits and describes the rows outside them
+func checkLimits(t table, columns []tableColumn, rules []limitRule) []string {
+	var results []string
+	for _, check := range findLimitChecks(t, columns, rules) {
+		results = append(results, evaluateLimits(t, columns, check))
+	}
+	return results
+}
+
+// findLimitChecks returns the limits of each measurement column that has any: limit columns of
+// the table, or else the limits table row naming the column most specifically
+func findLimitChecks(t table, columns []tableColumn, rules []limitRule) []limitCheck {
+	var measurements []tableColumn
+	for _, column := range columns {
+		if column.numeric && column.limit == notLimit && !column.id {
+			measurements = append(measurements, column)
+		}
+	}
+
+	var checks []limitCheck
+	for _, measured := range measurements {
+		check := limitCheck{measured: measured, lowerColumn: -1, upperColumn: -1}
+		for _, column := range columns {
+			if column.limit == notLimit || !column.numeric {
+				continue
+			}
+			// A limit naming nothing applies when there is a single measurement column
+			applies := (len(column.base) == 0 && len(measurements) == 1) ||
+				(len(column.base) > 0 && containsAll(measured.tokens, column.base))
+			if applies && column.limit == lowerLimit && check.lowerColumn < 0 {
+				check.lowerColumn = column.index
+			}
+			if applies && column.limit == upperLimit && check.upperColumn < 0 {
+				check.upperColumn = column.index
+			}
+		}
+		if check.lowerColumn < 0 && check.upperColumn < 0 {
+			for i := range rules {
+				if rules[i].source != t.name && containsAll(measured.tokens, rules[i].tokens) &&
+					(check.rule == nil || len(rules[i].tokens) > len(check.rule.tokens)) {
+					check.rule = &rules[i]
+				}
+			}
+		}
+		if check.lowerColumn < 0 && check.upperColumn < 0 && check.rule == nil {
+			continue
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// evaluateLimits describes the values of a column that are outside its limits
+func evaluateLimits(t table, columns []tableColumn, check limitCheck) string {
+	measured := check.measured
+	bound := func(row []string, column int, ruleValue string) (float64, string, bool) {
+		text := ruleValue
+		if column >= 0 {
+			text = strings.TrimSpace(row[column])
+		}
+		value, ok := parseNumber(text)
+		return value, text, ok
+	}
+
+	var above, below []string
+	checked := 0
+	for i, row := range t.rows {
+		value, ok := parseNumber(row[measured.index])
+		if !ok {
+			continue
+		}
+		var ruleLower, ruleUpper string
+		if check.rule != nil {
+			ruleLower, ruleUpper = check.rule.lower, check.rule.upper
+		}
+		lower, lowerText, hasLower := bound(row, check.lowerColumn, ruleLower)
+		upper, upperText, hasUpper := bound(row, check.upperColumn, ruleUpper)
+		if !hasLower && !hasUpper {
+			continue
+		}
+		checked++
+		label := rowLabel(t, columns, i)
+		cell := strings.TrimSpace(row[measured.index])
+		if hasUpper && value > upper {
+			above = append(above, fmt.Sprintf("%s = %s (max %s)", label, cell, upperText))
+		} else if hasLower && value < lower {
+			below = append(below, fmt.Sprintf("%s = %s (min %s)", label, cell, lowerText))
+		}
+	}
+
+	source := check.describe(t)
+	if len(above) == 0 && len(below) == 0 {
+		return fmt.Sprintf("%s: all %d values within the limits (%s)", measured.name, checked, source)
+	}
+	var parts []string
+	if len(above) > 0 {
+		parts = append(parts, fmt.Sprintf("%d above the upper limit: %s", len(above), listFindings(above)))
+	}
+	if len(below) > 0 {
+		parts = append(parts, fmt.Sprintf("%d below the lower limit: %s", len(below), listFindings(below)))
+	}
+	return fmt.Sprintf("%s: %d of %d values out of range (%s) - %s", measured.name, len(above)+len(below), checked, source, strings.Join(parts, "; "))
+}
+
+// listFindings joins findings, shortening long lists
+func listFindings(findings []string) string {
+	if len(findings) > maxListedFindings {
+		return strings.Join(findings[:maxListedFindings], ", ") + fmt.Sprintf(" and %d more", len(findings)-maxListedFindings)
+	}
+	return strings.Join(findings, ", ")
+}
+
+// rowLabel names a row by its identifying column, such as "Channel 12", or its row number
+func rowLabel(t table, columns []tableColumn, row int) string {
+	for _, column := range columns {
+		if column.id {
+			if value := strings.TrimSpace(t.rows[row][column.index]); value != "" {
+				if _, numeric := parseNumber(value); numeric {
+					return column.name + " " + value
+				}
+				return value
+			}
+		}
+	}
+	return fmt.Sprintf("row %d", row+1)
+}
+
+// summarizeColumns gives the range of numeric columns and the counts of text columns with few values
+func summarizeColumns(t table, columns []tableColumn) []string {
+	var summaries []string
+	for _, column := range columns {
+		if column.id || column.limit != notLimit {
+			continue
+		}
+		if column.numeric {
+			minRow, maxRow, count, sum := -1, -1, 0, 0.0
+			var minValue, maxValue float64
+			for i, row := range t.rows {
+				value, ok := parseNumber(row[column.index])
+				if !ok {
+					continue
+				}
+				if minRow < 0 || value < minValue {
+					minRow, minValue = i, value
+				}
+				if maxRow < 0 || value > maxValue {
+					maxRow, maxValue = i, value
+				}
+				count++
+				sum += value
+			}
+			summaries = append(summaries, fmt.Sprintf("%s: min %s (%s), max %s (%s), mean %.4g",
+				column.name, strings.TrimSpace(t.rows[minRow][column.index]), rowLabel(t, columns, minRow),
+				strings.TrimSpace(t.rows[maxRow][column.index]), rowLabel(t, columns, maxRow), sum/float64(count)))
+			continue
+		}
+
+		counts := make(map[string]int)
+		for _, row := range t.rows {
+			if value := strings.TrimSpace(row[column.index]); value != "" {
+				counts[value]++
+			}
+		}
+		if len(counts) == 0 || len(counts) > maxDistinctValues || len(counts) == len(t.rows) {
+			continue
+		}
+		values := make([]string, 0, len(counts))
+		for value := range counts {
+			values = append(values, value)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if counts[values[i]] != counts[values[j]] {
+				return counts[values[i]] > counts[values[j]]
+			}
+			return values[i] < values[j]
+		})
+		for i, value := range values {
+			values[i] = fmt.Sprintf("%s %d", value, counts[value])
+		}
+		summaries = append(summaries, fmt.Sprintf("%s: %s", column.name, strings.Join(values, ", ")))
+	}
+	return summaries
+}
+
+// Parts of an .xlsx workbook
+type (
+	xlsxWorkbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	xlsxRelationships struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	xlsxText struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	}
+	xlsxSharedStrings struct {
+		Items []xlsxText `xml:"si"`
+	}
+	xlsxSheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string   `xml:"r,attr"`
+				Type   string   `xml:"t,attr"`
+				Value  string   `xml:"v"`
+				Inline xlsxText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+)
+
+// String joins the text of a shared or inline string, including its formatted runs
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var text strings.Builder
+	for _, run := range t.Runs {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+// readWorkbook reads every sheet of an .xlsx workbook that has a header row as a table
+func readWorkbook(filePath string) ([]table, error) {
+	archive, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	parts := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		parts[strings.TrimPrefix(file.Name, "/")] = file
+	}
+	var workbook xlsxWorkbook
+	if err := readWorkbookPart(parts, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	var relationships xlsxRelationships
+	if err := readWorkbookPart(parts, "xl/_rels/workbook.xml.rels", &relationships); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string)
+	for _, relationship := range relationships.Relationships {
+		target := strings.TrimPrefix(relationship.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[relationship.ID] = target
+	}
+	var shared xlsxSharedStrings
+	if _, ok := parts["xl/sharedStrings.xml"]; ok {
+		if err := readWorkbookPart(parts, "xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	var tables []table
+	for _, sheet := range workbook.Sheets {
+		var data xlsxSheet
+		if err := readWorkbookPart(parts, targets[sheet.RID], &data); err != nil {
+			return nil, fmt.Errorf("sheet %s: %w", sheet.Name, err)
+		}
+		var records [][]string
+		for _, row := range data.Rows {
+			var record []string
+			for _, cell := range row.Cells {
+				// Cells without a usable reference follow the previous cell
+				column := len(record)
+				if ref := cellColumn(cell.Ref); ref >= 0 {
+					column = ref
+				}
+				if column >= maxTableColumns {
+					continue
+				}
+				for len(record) <= column {
+					record = append(record, "")
+				}
+				record[column] = cellText(cell.Type, cell.Value, cell.Inline, shared)
+			}
+			records = append(records, record)
+		}
+		if t, ok := newTable("Sheet: "+sheet.Name, records); ok {
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
+
+// readWorkbookPart decodes an XML part of a workbook
+func readWorkbookPart(parts map[string]*zip.File, name string, v interface{}) error {
+	file, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("the workbook has no %s", name)
+	}
+	if file.UncompressedSize64 > maxWorkbookPartBytes {
+		return fmt.Errorf("%s is larger than %s", name, FormatBytes(maxWorkbookPartBytes))
+	}
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// cellColumn returns the zero-based column of a cell reference such as "AB12", or -1 when the
+// reference does not start with a column
+func cellColumn(ref string) int {
+	column := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		column = column*26 + int(c-'A'+1)
+	}
+	return column - 1
+}
+
+// cellText returns the displayed text of a cell
+func cellText(cellType, value string, inline xlsxText, shared xlsxSharedStrings) string {
+	switch cellType {
+	case "s":
+		if i, err := strconv.Atoi(value); err == nil && i >= 0 && i < len(shared.Items) {
+			return shared.Items[i].String()
+		}
+		return ""
+	case "inlineStr":
+		return inline.String()
+	case "b":
+		if value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "str", "e":
+		return value
+	}
+	// Numbers are stored with binary rounding, e.g. 4.35 as 4.3499999999999996
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	if number == math.Trunc(number) && math.Abs(number) < 1e15 {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(number, 'g', 15, 64)
+}
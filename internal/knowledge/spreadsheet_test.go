@@ -0,0 +1,324 @@
+package knowledge
+
+import (
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// extractDelimited renders CSV or TSV content as it is indexed
+func extractDelimited(content, name string) string {
+	return renderTables(readDelimited(content, name))
+}
+
+// extractWorkbook renders the sheets of an .xlsx file as they are indexed
+func extractWorkbook(filePath string) (string, error) {
+	tables, err := readWorkbook(filePath)
+	return renderTables(tables), err
+}
+
+// writeWorkbook writes a minimal .xlsx file with one sheet and optional shared strings
+func writeWorkbook(t *testing.T, sheet, sharedStrings string) string {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Results" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/worksheets/sheet1.xml":   `<worksheet><sheetData>` + sheet + `</sheetData></worksheet>`,
+	}
+	if sharedStrings != "" {
+		parts["xl/sharedStrings.xml"] = `<sst>` + sharedStrings + `</sst>`
+	}
+
+	filePath := filepath.Join(t.TempDir(), "book.xlsx")
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+	for name, content := range parts {
+		writer, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filePath
+}
+
+func TestExtractWorkbookBadCellRef(t *testing.T) {
+	filePath := writeWorkbook(t, `<row r="1"><c r="12" t="inlineStr"><is><t>Channel</t></is></c><c r="" t="inlineStr"><is><t>Voltage</t></is></c></row>`+
+		`<row r="2"><c r="A2"><v>1</v></c><c r="?"><v>4.1</v></c></row>`, "")
+
+	content, err := extractWorkbook(filePath)
+	if err != nil {
+		t.Fatalf("extractWorkbook: %v", err)
+	}
+	if !strings.Contains(content, "| Channel | Voltage |") || !strings.Contains(content, "| 1 | 4.1 |") {
+		t.Errorf("cells with bad references were not placed after the previous cell:\n%s", content)
+	}
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    rune
+	}{
+		{"comma", "data.csv", "Channel,Voltage\n1,4.1\n", ','},
+		{"semicolon", "data.csv", "Channel;Voltage;Current\n1;4,1;0,5\n", ';'},
+		{"tab in csv", "data.csv", "Channel\tVoltage\tName, first\n", '\t'},
+		{"tsv extension", "data.TSV", "Channel,Voltage\n", '\t'},
+		{"single column", "data.csv", "Channel\n1\n", ','},
+		{"only first line counts", "data.csv", "Channel;Voltage\n1,2,3,4,5\n", ';'},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectDelimiter(tt.content, tt.file); got != tt.want {
+				t.Errorf("detectDelimiter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractDelimited(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "semicolon table",
+			file:    "results.csv",
+			content: "\ufeffChannel;Voltage\n1;4.1\n2;4.2\n",
+			want:    []string{"## results.csv", "2 rows, columns: Channel, Voltage", "| Channel | Voltage |", "| 2 | 4.2 |"},
+		},
+		{
+			name:    "limit columns",
+			file:    "results.csv",
+			content: "Channel,Voltage,Voltage Max\n11,4.10,4.3\n12,4.50,4.3\n",
+			want:    []string{"Limit checks:", "Voltage: 1 of 2 values out of range (Voltage Max)", "Channel 12 = 4.50 (max 4.3)"},
+		},
+		{
+			name:    "values within limits",
+			file:    "results.csv",
+			content: "Channel,Current,Min,Max\n1,0.5,0.1,1.0\n2,0.7,0.1,1.0\n",
+			want:    []string{"Current: all 2 values within the limits (Min, Max)"},
+		},
+		{
+			name:    "empty header columns are named",
+			file:    "results.tsv",
+			content: "Channel\t\tVoltage\n1\tx\t4.1\n",
+			want:    []string{"| Channel | Column 2 | Voltage |"},
+		},
+		{
+			name:    "blank lines and trailing cells are dropped",
+			file:    "results.csv",
+			content: "\n,,\nChannel,Voltage,,\n1,4.1,,\n",
+			want:    []string{"1 rows, columns: Channel, Voltage\n"},
+		},
+		{
+			name:    "quoted cells",
+			file:    "results.csv",
+			content: "Channel,Name\n1,\"Rack 3, slot 2\"\n2,\"a \"\"b\"\"\"\n",
+			want:    []string{"| 1 | Rack 3, slot 2 |", "| 2 | a \"b\" |"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractDelimited(tt.content, tt.file)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("missing %q in:\n%s", want, got)
+				}
+			}
+		})
+	}
+
+	if got := extractDelimited("\n\n", "empty.csv"); got != "" {
+		t.Errorf("empty file gave %q, want no table", got)
+	}
+}
+
+func TestTableLimits(t *testing.T) {
+	var header []string
+	for i := 1; i <= maxTableColumns+5; i++ {
+		header = append(header, fmt.Sprintf("C%d", i))
+	}
+	wide := extractDelimited(strings.Join(header, ",")+"\n", "wide.csv")
+	if !strings.Contains(wide, fmt.Sprintf("C%d,", maxTableColumns-1)) || !strings.Contains(wide, fmt.Sprintf("C%d\n", maxTableColumns)) {
+		t.Errorf("the first %d columns should be kept:\n%s", maxTableColumns, wide)
+	}
+	if strings.Contains(wide, fmt.Sprintf("C%d", maxTableColumns+1)) {
+		t.Errorf("columns after %d should be dropped", maxTableColumns)
+	}
+
+	var rows strings.Builder
+	rows.WriteString("Channel,Voltage\n")
+	for i := 1; i <= maxTableRows+10; i++ {
+		fmt.Fprintf(&rows, "%d,%d\n", i, i)
+	}
+	long := extractDelimited(rows.String(), "long.csv")
+	if !strings.Contains(long, fmt.Sprintf("%d rows, columns", maxTableRows+10)) {
+		t.Errorf("the row count should include every row")
+	}
+	if !strings.Contains(long, fmt.Sprintf("| %d | %d |", maxTableRows, maxTableRows)) || strings.Contains(long, fmt.Sprintf("| %d | %d |", maxTableRows+1, maxTableRows+1)) {
+		t.Errorf("only the first %d rows should be written", maxTableRows)
+	}
+	if !strings.Contains(long, "10 more rows are not shown.") {
+		t.Errorf("the dropped rows should be counted")
+	}
+	if !strings.Contains(long, fmt.Sprintf("max %d (Channel %d)", maxTableRows+10, maxTableRows+10)) {
+		t.Errorf("the summary should use every row:\n%s", long[:400])
+	}
+}
+
+func TestCellColumn(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want int
+	}{
+		{"A1", 0},
+		{"Z9", 25},
+		{"AA1", 26},
+		{"AB12", 27},
+		{"XFD1048576", 16383},
+		{"12", -1},
+		{"", -1},
+		{"a1", -1},
+	}
+	for _, tt := range tests {
+		if got := cellColumn(tt.ref); got != tt.want {
+			t.Errorf("cellColumn(%q) = %d, want %d", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestCellText(t *testing.T) {
+	shared := xlsxSharedStrings{Items: []xlsxText{
+		{Text: "Voltage"},
+		{Runs: []struct {
+			Text string `xml:"t"`
+		}{{Text: "Pass"}, {Text: "/Fail"}}},
+	}}
+	tests := []struct {
+		name     string
+		cellType string
+		value    string
+		inline   xlsxText
+		want     string
+	}{
+		{"shared string", "s", "0", xlsxText{}, "Voltage"},
+		{"shared string with runs", "s", "1", xlsxText{}, "Pass/Fail"},
+		{"shared string out of range", "s", "5", xlsxText{}, ""},
+		{"inline string", "inlineStr", "", xlsxText{Text: "Channel"}, "Channel"},
+		{"boolean", "b", "1", xlsxText{}, "TRUE"},
+		{"formula string", "str", "OK", xlsxText{}, "OK"},
+		{"integer", "", "12", xlsxText{}, "12"},
+		{"binary rounding", "", "4.3499999999999996", xlsxText{}, "4.35"},
+		{"not a number", "", "n/a", xlsxText{}, "n/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cellText(tt.cellType, tt.value, tt.inline, shared); got != tt.want {
+				t.Errorf("cellText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractWorkbook(t *testing.T) {
+	filePath := writeWorkbook(t,
+		`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><r><t>Volt</t></r><r><t>age</t></r></is></c><c r="D1" t="s"><v>1</v></c></row>`+
+			`<row r="2"><c r="A2"><v>1</v></c><c r="B2"><v>4.0999999999999996</v></c><c r="D2" t="s"><v>2</v></c></row>`,
+		`<si><t>Channel</t></si><si><t>Result</t></si><si><t>Pass</t></si>`)
+
+	content, err := extractWorkbook(filePath)
+	if err != nil {
+		t.Fatalf("extractWorkbook: %v", err)
+	}
+	for _, want := range []string{"## Sheet: Results", "| Channel | Voltage | Column 3 | Result |", "| 1 | 4.1 |  | Pass |"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %q in:\n%s", want, content)
+		}
+	}
+}
+
+func TestRenderTablesLimitsTable(t *testing.T) {
+	limits, _ := newTable("Sheet: Limits", [][]string{{"Parameter", "Min", "Max"}, {"Voltage", "2.5", "4.3"}})
+	results, _ := newTable("Sheet: Results", [][]string{{"Channel", "Voltage"}, {"11", "4.1"}, {"12", "4.50"}, {"13", "2.0"}})
+
+	content := renderTables([]table{limits, results})
+	want := "Voltage: 2 of 3 values out of range (Voltage in Sheet: Limits: min 2.5, max 4.3) - " +
+		"1 above the upper limit: Channel 12 = 4.50 (max 4.3); 1 below the lower limit: Channel 13 = 2.0 (min 2.5)"
+	if !strings.Contains(content, want) {
+		t.Errorf("missing %q in:\n%s", want, content)
+	}
+}
+
+func TestUploadLimitChecksAcrossFiles(t *testing.T) {
+	const results = "Channel,Voltage\n11,4.1\n12,4.50\n13,2.0\n"
+	const limits = "Parameter,Min,Max\nVoltage,2.5,4.3\n"
+	const found = "1 above the upper limit: Channel 12 = 4.50 (max 4.3); 1 below the lower limit: Channel 13 = 2.0 (min 2.5)"
+
+	content := func(kb *KnowledgeDatabase, name string) string {
+		for _, doc := range kb.Uploads() {
+			if doc.Name == name {
+				return doc.Content
+			}
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name   string
+		files  map[string]string // Knowledge base files
+		order  []string          // Uploads, in order
+		source string            // Path the limits are reported from
+	}{
+		{"limits uploaded first", nil, []string{"limits.csv", "results.csv"}, "upload/limits.csv"},
+		{"limits uploaded last", nil, []string{"results.csv", "limits.csv"}, "upload/limits.csv"},
+		{"limits in the knowledge base", map[string]string{"limits.csv": limits}, []string{"results.csv"}, "limits.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+			kb := newTestDatabase(t, root)
+			uploads := make(map[string]Upload)
+			for _, name := range tt.order {
+				uploads[name] = uploadFile(t, kb, name, []byte(map[string]string{"results.csv": results, "limits.csv": limits}[name]))
+			}
+
+			want := "Voltage: 2 of 3 values out of range (Voltage in " + tt.source + ": min 2.5, max 4.3) - " + found
+			got := content(kb, "results.csv")
+			if !strings.Contains(got, "## Limit checks against other files") || !strings.Contains(got, want) {
+				t.Errorf("missing %q in:\n%s", want, got)
+			}
+			if doc, ok := kb.Document(uploads["results.csv"].ID); !ok || !strings.Contains(doc.Content, want) {
+				t.Errorf("Document() content is not checked against the limits:\n%s", doc.Content)
+			}
+			if strings.Contains(content(kb, "limits.csv"), "Limit checks against other files") {
+				t.Errorf("the limits table was checked against itself")
+			}
+
+			if limitsUpload, ok := uploads["limits.csv"]; ok {
+				kb.RemoveUpload(limitsUpload.ID)
+				if got := content(kb, "results.csv"); strings.Contains(got, "Limit checks") {
+					t.Errorf("checks are kept after removing the limits:\n%s", got)
+				}
+			}
+		})
+	}
+}
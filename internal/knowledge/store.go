@@ -0,0 +1,323 @@
+package knowledge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hashLength is the number of hex digits of the content hash used in document IDs
+const hashLength = 12
+
+// DocumentType is the kind of file a document was extracted from
+type DocumentType string
+
+// Document types
+const (
+	DocText     DocumentType = "text"
+	DocLog      DocumentType = "log"
+	DocHTML     DocumentType = "html"
+	DocDiagram  DocumentType = "diagram"
+	DocPDF      DocumentType = "pdf"
+	DocWord     DocumentType = "word"
+	DocImage    DocumentType = "image"
+	DocSheet    DocumentType = "spreadsheet"
+	DocEmail    DocumentType = "email"
+	DocTicket   DocumentType = "ticket" // A Jira issue export
+	DocMarkdown DocumentType = "markdown"
+	DocData     DocumentType = "data" // JSON, YAML and XML configuration and exports
+	DocCode     DocumentType = "code"
+	DocArchive  DocumentType = "archive" // An uploaded archive; its files are stored as separate documents
+)
+
+// Extractors that produce a document's text
+const (
+	ExtractPlain    = "plain"
+	ExtractLog      = "log"
+	ExtractHTML     = "html"
+	ExtractDrawIO   = "drawio"
+	ExtractPDF      = "pdf"
+	ExtractDocx     = "docx"
+	ExtractOCR      = "ocr"
+	ExtractCSV      = "csv"
+	ExtractXLSX     = "xlsx"
+	ExtractEmail    = "email"
+	ExtractJira     = "jira"
+	ExtractMarkdown = "markdown"
+	ExtractJSON     = "json"
+	ExtractYAML     = "yaml"
+	ExtractXML      = "xml"
+	ExtractCode     = "code"
+)
+
+// Document is an indexed file or upload with its extracted text. Documents are identified by
+// their path and content hash, so files with the same name in different folders stay separate
+// and an edited file gets a new ID.
+type Document struct {
+	ID          string // Path and content hash, e.g. "Confluence/Setup/index.html#3f2a9c1b04de"
+	Path        string // Relative to the profile root with forward slashes; "upload/<name>" for uploads
+	FullPath    string
+	Name        string
+	Type        DocumentType
+	Size        int64
+	ModTime     time.Time
+	Hash        string // SHA-256 of the file content
+	Extractor   string
+	Title       string
+	Content     string
+	Pages       []int     // Offset in Content where each page starts, for PDFs
+	DuplicateOf string    // ID of an earlier document with identical content, if any
+	UploadedAt  time.Time // Set for user uploads
+	Archive     string    // Path of the uploaded archive the document was extracted from, if any
+	Tickets     []string  // Sorted ticket keys in the text, e.g. "BTSI-1234", including strings like "UTF-8"
+
+	tables []table // Tables of a spreadsheet, kept to check uploads against limits tables in other documents
+}
+
+// minLocateLength is the shortest excerpt line used to find an excerpt in a document, so
+// headings and list markers do not match the wrong place
+const minLocateLength = 20
+
+// PageAt returns the 1-based page containing a content offset, or 0 when the document has no pages
+func (d Document) PageAt(offset int) int {
+	page := 0
+	for i, start := range d.Pages {
+		if start > offset {
+			break
+		}
+		page = i + 1
+	}
+	return page
+}
+
+// Locate finds an excerpt of the document's content, returning the offsets where it starts and
+// ends. Excerpts may have been shortened, rejoined or given a header line, so they are matched
+// by their first and last lines that appear in the content.
+func (d Document) Locate(excerpt string) (start, end int, ok bool) {
+	var lines []string
+	for _, line := range strings.Split(excerpt, "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), "..."))
+		if len(line) >= minLocateLength {
+			lines = append(lines, line)
+		}
+	}
+
+	start = -1
+	for i, line := range lines {
+		if offset := strings.Index(d.Content, line); offset >= 0 {
+			start, end = offset, offset+len(line)
+			lines = lines[i+1:]
+			break
+		}
+	}
+	if start < 0 {
+		return 0, 0, false
+	}
+
+	// The excerpt ends at its last line found after the first, within the excerpt's length
+	window := d.Content[end:min(len(d.Content), start+2*len(excerpt))]
+	for i := len(lines) - 1; i >= 0; i-- {
+		if offset := strings.Index(window, lines[i]); offset >= 0 {
+			end += offset + len(lines[i])
+			break
+		}
+	}
+	return start, end, true
+}
+
+// DocumentStore holds documents by ID in path order
+type DocumentStore struct {
+	mu     sync.RWMutex
+	docs   map[string]*Document
+	byPath map[string]string // Path to ID
+	byHash map[string]string // Content hash to the first document ID with that content
+	order  []string          // IDs sorted by path
+}
+
+// NewDocumentStore creates an empty document store
+func NewDocumentStore() *DocumentStore {
+	return &DocumentStore{
+		docs:   make(map[string]*Document),
+		byPath: make(map[string]string),
+		byHash: make(map[string]string),
+	}
+}
+
+// Add stores a document, replacing any document at the same path, and returns its ID
+func (s *DocumentStore) Add(doc Document) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc.ID = documentID(doc.Path, doc.Hash)
+	if previous, ok := s.byPath[doc.Path]; ok {
+		s.remove(previous)
+	}
+	if first, ok := s.byHash[doc.Hash]; ok && first != doc.ID {
+		doc.DuplicateOf = first
+	} else {
+		s.byHash[doc.Hash] = doc.ID
+	}
+
+	s.docs[doc.ID] = &doc
+	s.byPath[doc.Path] = doc.ID
+	i := sort.Search(len(s.order), func(i int) bool { return s.docs[s.order[i]].Path >= doc.Path })
+	s.order = append(s.order, "")
+	copy(s.order[i+1:], s.order[i:])
+	s.order[i] = doc.ID
+	return doc.ID
+}
+
+// Remove deletes a document, reporting whether it was stored
+func (s *DocumentStore) Remove(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.docs[id]
+	s.remove(id)
+	return ok
+}
+
+// remove deletes a document; the caller holds the lock. The first copy of a removed original
+// takes its place so identical content stays searchable.
+func (s *DocumentStore) remove(id string) {
+	doc, ok := s.docs[id]
+	if !ok {
+		return
+	}
+	delete(s.docs, id)
+	delete(s.byPath, doc.Path)
+	for i, existing := range s.order {
+		if existing == id {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+	if s.byHash[doc.Hash] != id {
+		return
+	}
+	delete(s.byHash, doc.Hash)
+	for _, other := range s.order {
+		if copyDoc := s.docs[other]; copyDoc.DuplicateOf == id {
+			if first, ok := s.byHash[doc.Hash]; ok {
+				copyDoc.DuplicateOf = first
+			} else {
+				copyDoc.DuplicateOf = ""
+				s.byHash[doc.Hash] = other
+			}
+		}
+	}
+}
+
+// Get returns the document with the given ID
+func (s *DocumentStore) Get(id string) (Document, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	doc, ok := s.docs[id]
+	if !ok {
+		return Document{}, false
+	}
+	return *doc, true
+}
+
+// ByPath returns the current document at a path relative to the profile root
+func (s *DocumentStore) ByPath(relPath string) (Document, bool) {
+	s.mu.RLock()
+	id, ok := s.byPath[relPath]
+	s.mu.RUnlock()
+	if !ok {
+		return Document{}, false
+	}
+	return s.Get(id)
+}
+
+// All returns every document in path order
+func (s *DocumentStore) All() []Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	docs := make([]Document, 0, len(s.order))
+	for _, id := range s.order {
+		docs = append(docs, *s.docs[id])
+	}
+	return docs
+}
+
+// Len returns the number of documents
+func (s *DocumentStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.docs)
+}
+
+// Clear removes every document
+func (s *DocumentStore) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs = make(map[string]*Document)
+	s.byPath = make(map[string]string)
+	s.byHash = make(map[string]string)
+	s.order = nil
+}
+
+// documentID builds a document ID from its path and content hash
+func documentID(relPath, hash string) string {
+	if len(hash) > hashLength {
+		hash = hash[:hashLength]
+	}
+	return relPath + "#" + hash
+}
+
+// hashFile returns the hex SHA-256 of a file's content
+func hashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", filePath, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// htmlTitlePattern matches the title element of an HTML page
+var htmlTitlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// documentTitle returns the title of an HTML page, the subject of an email, the key and summary
+// of a single issue Jira export, the first heading of a Markdown file, or the file name without
+// its extension
+func documentTitle(docType DocumentType, name string, raw []byte, content string) string {
+	switch docType {
+	case DocEmail:
+		if subject := emailSubject(raw); subject != "" {
+			return subject
+		}
+	case DocMarkdown:
+		for _, line := range strings.Split(string(raw), "\n") {
+			if match := markdownHeadingPattern.FindStringSubmatch(line); match != nil && len(match[1]) == 1 {
+				return match[2]
+			}
+		}
+	case DocTicket:
+		if headings := ticketHeadingPattern.FindAllStringIndex(content, 2); len(headings) == 1 {
+			title, _, _ := strings.Cut(content[2:], "\n")
+			return title
+		}
+	case DocHTML:
+		if match := htmlTitlePattern.FindSubmatch(raw); match != nil {
+			if title := strings.Join(strings.Fields(html.UnescapeString(string(match[1]))), " "); title != "" {
+				return title
+			}
+		}
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
@@ -0,0 +1,156 @@
+package knowledge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// hashOf returns the content hash the indexer records for content
+func hashOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// storedDoc is a document as the indexer would add it
+func storedDoc(path, content string) Document {
+	return Document{Path: path, Name: filepath.Base(path), Hash: hashOf(content), Content: content}
+}
+
+// storedIDs returns the IDs of the documents in path order
+func storedIDs(s *DocumentStore) []string {
+	var ids []string
+	for _, doc := range s.All() {
+		ids = append(ids, doc.ID)
+	}
+	return ids
+}
+
+func TestDocumentStoreSameNameInDifferentFolders(t *testing.T) {
+	store := NewDocumentStore()
+	setup := store.Add(storedDoc("Confluence/Setup/index.html", "<p>Power up the rack</p>"))
+	wiring := store.Add(storedDoc("Confluence/Wiring/index.html", "<p>Connect channel 4</p>"))
+
+	if setup == wiring || setup != "Confluence/Setup/index.html#"+hashOf("<p>Power up the rack</p>")[:hashLength] {
+		t.Fatalf("IDs %q and %q, want path and content hash", setup, wiring)
+	}
+	for path, id := range map[string]string{"Confluence/Setup/index.html": setup, "Confluence/Wiring/index.html": wiring} {
+		if doc, ok := store.ByPath(path); !ok || doc.ID != id || doc.DuplicateOf != "" {
+			t.Errorf("ByPath(%s) = %q, %v, duplicate of %q", path, doc.ID, ok, doc.DuplicateOf)
+		}
+	}
+	if store.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", store.Len())
+	}
+}
+
+func TestDocumentStoreDuplicates(t *testing.T) {
+	const content = "Replace the fuse on channel 4."
+	store := NewDocumentStore()
+	second := store.Add(storedDoc("b/fix.txt", content))
+	first := store.Add(storedDoc("a/fix.txt", content))
+	third := store.Add(storedDoc("c/fix.txt", content))
+	other := store.Add(storedDoc("d/other.txt", "Something else"))
+
+	if want := []string{first, second, third, other}; !reflect.DeepEqual(storedIDs(store), want) {
+		t.Errorf("All() = %v, want path order %v", storedIDs(store), want)
+	}
+	// The first document added with the content is the original, whatever its path
+	duplicates := func() map[string]string {
+		found := make(map[string]string)
+		for _, doc := range store.All() {
+			found[doc.ID] = doc.DuplicateOf
+		}
+		return found
+	}
+	if want := map[string]string{first: second, second: "", third: second, other: ""}; !reflect.DeepEqual(duplicates(), want) {
+		t.Errorf("duplicates %v, want %v", duplicates(), want)
+	}
+
+	// Removing the original makes the first copy in path order the original
+	if !store.Remove(second) || store.Remove(second) {
+		t.Fatal("Remove() should report the document only once")
+	}
+	if want := map[string]string{first: "", third: first, other: ""}; !reflect.DeepEqual(duplicates(), want) {
+		t.Errorf("after removing the original, duplicates %v, want %v", duplicates(), want)
+	}
+	if added := store.Add(storedDoc("e/fix.txt", content)); duplicates()[added] != first {
+		t.Errorf("a new copy is a duplicate of %q, want %q", duplicates()[added], first)
+	}
+
+	// Adding the original again keeps its ID but makes it the newer copy
+	if again := store.Add(storedDoc("a/fix.txt", content)); again != first || store.Len() != 4 {
+		t.Errorf("re-adding gave %q with %d documents", again, store.Len())
+	}
+	if found := duplicates(); found[first] != third || found[third] != "" {
+		t.Errorf("after re-adding the original, duplicates %v, want %s as the original", found, third)
+	}
+}
+
+func TestDocumentStoreChangedContent(t *testing.T) {
+	store := NewDocumentStore()
+	old := store.Add(storedDoc("notes.txt", "Rack 3 tripped."))
+	copyID := store.Add(storedDoc("archive/notes.txt", "Rack 3 tripped."))
+	edited := store.Add(storedDoc("notes.txt", "Rack 3 tripped twice."))
+
+	if edited == old || edited != "notes.txt#"+hashOf("Rack 3 tripped twice.")[:hashLength] {
+		t.Fatalf("edited ID %q, want a new ID with the new hash", edited)
+	}
+	if _, ok := store.Get(old); ok {
+		t.Errorf("the old ID %q is still stored", old)
+	}
+	if doc, ok := store.ByPath("notes.txt"); !ok || doc.ID != edited || doc.Content != "Rack 3 tripped twice." {
+		t.Errorf("ByPath() = %+v, %v", doc, ok)
+	}
+	if want := []string{copyID, edited}; !reflect.DeepEqual(storedIDs(store), want) {
+		t.Errorf("All() = %v, want %v", storedIDs(store), want)
+	}
+	// The copy of the old content is no longer a duplicate of anything stored
+	if doc, _ := store.Get(copyID); doc.DuplicateOf != "" {
+		t.Errorf("the copy is still a duplicate of %q", doc.DuplicateOf)
+	}
+
+	store.Clear()
+	if store.Len() != 0 || len(store.All()) != 0 {
+		t.Errorf("Clear() left %d documents", store.Len())
+	}
+	if id := store.Add(storedDoc("notes.txt", "Rack 3 tripped.")); id != old {
+		t.Errorf("adding after Clear() gave %q, want %q", id, old)
+	}
+}
+
+func TestIndexedDocumentIDs(t *testing.T) {
+	root := t.TempDir()
+	page := "<html>\n<title>Rack setup</title>\n<p>First step: power up the rack slowly.</p>\n</html>\n"
+	writeFiles(t, root, map[string]string{
+		"Confluence/Setup/index.html":  page,
+		"Confluence/Backup/index.html": page,
+		"Confluence/Wiring/index.html": "<html>\n<p>First step: connect channel 4 to the rack.</p>\n</html>\n",
+	})
+	kb := newTestDatabase(t, root)
+
+	backup, _ := kb.documents.ByPath("Confluence/Backup/index.html")
+	setup, _ := kb.documents.ByPath("Confluence/Setup/index.html")
+	wiring, _ := kb.documents.ByPath("Confluence/Wiring/index.html")
+	if backup.ID == setup.ID || setup.ID == wiring.ID || backup.Hash != hashOf(page) {
+		t.Fatalf("IDs %q, %q and %q with hash %q", backup.ID, setup.ID, wiring.ID, backup.Hash)
+	}
+	if setup.Title != "Rack setup" || setup.Name != "index.html" {
+		t.Errorf("setup is %q named %q", setup.Title, setup.Name)
+	}
+	// Files are indexed in path order, so the Backup folder holds the original
+	if setup.DuplicateOf != backup.ID || backup.DuplicateOf != "" || wiring.DuplicateOf != "" {
+		t.Errorf("duplicates: backup %q, setup %q, wiring %q", backup.DuplicateOf, setup.DuplicateOf, wiring.DuplicateOf)
+	}
+
+	hash, err := hashFile(filepath.Join(root, "Confluence", "Setup", "index.html"))
+	if err != nil || hash != hashOf(page) {
+		t.Errorf("hashFile() = %q, %v", hash, err)
+	}
+	if _, err := hashFile(filepath.Join(root, "missing.html")); !os.IsNotExist(err) {
+		t.Errorf("hashing a missing file gave %v", err)
+	}
+}
@@ -0,0 +1,514 @@
+package knowledge
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Outline limits
+const (
+	maxOutlineEntries = 2000 // Headings, keys, elements or symbols listed for a file
+	maxOutlineValue   = 120  // Characters of a value or comment shown in an entry
+)
+
+// outlineBodyHeading separates a structured document's outline from the file's own text
+const outlineBodyHeading = "\n## Content\n\n"
+
+// codeLanguages maps source file extensions to the language whose symbols are listed
+var codeLanguages = map[string]string{
+	".py": "python", ".go": "go", ".js": "javascript", ".ts": "javascript",
+	".cs": "c", ".java": "c", ".c": "c", ".h": "c", ".cpp": "c", ".hpp": "c",
+	".ps1": "powershell", ".sh": "shell", ".bat": "batch", ".cmd": "batch",
+}
+
+// dataExtensions are configuration and data files whose keys or elements are listed. TestStand
+// sequence files saved in XML format keep their .seq extension.
+var dataExtensions = []string{".json", ".yaml", ".yml", ".xml", ".seq"}
+
+// outline lists the structure of a file with the line each entry is on
+type outline struct {
+	title   string // e.g. "Keys"
+	entries []string
+	dropped int
+}
+
+// add lists an entry found on a 1-based line
+func (o *outline) add(line int, text string) {
+	if len(o.entries) == maxOutlineEntries {
+		o.dropped++
+		return
+	}
+	o.entries = append(o.entries, fmt.Sprintf("- %s (line %d)", text, line))
+}
+
+// render writes the outline followed by the file's text
+func (o *outline) render(content string) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "## %s\n\n", o.title)
+	out.WriteString(strings.Join(o.entries, "\n"))
+	if o.dropped > 0 {
+		fmt.Fprintf(&out, "\n- ...and %d more", o.dropped)
+	}
+	out.WriteString("\n" + outlineBodyHeading)
+	out.WriteString(content)
+	return out.String()
+}
+
+// SplitOutline separates the outline of a Markdown, data or source file from its text. Documents
+// without an outline return no entries and their whole content.
+func SplitOutline(content string) ([]string, string) {
+	if !strings.HasPrefix(content, "## ") {
+		return nil, content
+	}
+	end := strings.Index(content, outlineBodyHeading)
+	if end < 0 {
+		return nil, content
+	}
+	_, entries, _ := strings.Cut(content[:end], "\n\n")
+	return strings.Split(strings.TrimSpace(entries), "\n"), content[end+len(outlineBodyHeading):]
+}
+
+// lineIndex finds the line of an offset in a text
+type lineIndex []int
+
+// newLineIndex records where each line of a text starts
+func newLineIndex(content string) lineIndex {
+	starts := lineIndex{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// line returns the 1-based line containing an offset
+func (l lineIndex) line(offset int) int {
+	return sort.Search(len(l), func(i int) bool { return l[i] > offset })
+}
+
+// shortValue formats a value for an outline entry, shortening long values
+func shortValue(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if len(value) > maxOutlineValue {
+		value = value[:runeStart(value, maxOutlineValue)] + "..."
+	}
+	return value
+}
+
+// markdownHeadingPattern matches an ATX heading such as "## Network setup"
+var markdownHeadingPattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+
+// extractMarkdown lists the headings of a Markdown file as paths, e.g. "Setup > Network"
+func extractMarkdown(content string) string {
+	o := &outline{title: "Headings"}
+	var trail []string
+	fenced := false
+	for i, line := range strings.Split(content, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		match := markdownHeadingPattern.FindStringSubmatch(line)
+		if fenced || match == nil {
+			continue
+		}
+		level := len(match[1])
+		for len(trail) >= level {
+			trail = trail[:len(trail)-1]
+		}
+		for len(trail) < level-1 {
+			trail = append(trail, "")
+		}
+		trail = append(trail, match[2])
+		var parts []string
+		for _, heading := range trail {
+			if heading != "" {
+				parts = append(parts, heading)
+			}
+		}
+		o.add(i+1, strings.Join(parts, " > "))
+	}
+	if len(o.entries) == 0 {
+		return content
+	}
+	return o.render(content)
+}
+
+// extractData lists the keys of a JSON or YAML file, or the elements of an XML file, with their
+// values and lines. Files that cannot be parsed are kept as plain text.
+func extractData(content, name string) (string, string) {
+	var o *outline
+	var err error
+	var extractor string
+	switch ext := strings.ToLower(path.Ext(name)); ext {
+	case ".json":
+		o, err = jsonOutline(content)
+		extractor = ExtractJSON
+	case ".yaml", ".yml":
+		o, err = yamlOutline(content)
+		extractor = ExtractYAML
+	default:
+		o, err = xmlOutline(content)
+		extractor = ExtractXML
+	}
+	if err != nil {
+		log.Printf("Indexing %s as plain text: %v", name, err)
+	}
+	if err != nil || len(o.entries) == 0 {
+		return content, ExtractPlain
+	}
+	return o.render(content), extractor
+}
+
+// identifierPattern matches keys that can be written after a dot in a key path
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// keyPath appends a key to a key path, e.g. "stations[0]" and "name" give "stations[0].name"
+func keyPath(parent, key string) string {
+	if !identifierPattern.MatchString(key) {
+		return fmt.Sprintf("%s[%s]", parent, strconv.Quote(key))
+	}
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// jsonOutline lists every value of a JSON document by its key path
+func jsonOutline(content string) (*outline, error) {
+	o := &outline{title: "Keys"}
+	lines := newLineIndex(content)
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+
+	var walk func(keys string) error
+	walk = func(keys string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		line := lines.line(int(decoder.InputOffset()) - 1)
+		switch token := token.(type) {
+		case json.Delim:
+			empty := true
+			for i := 0; decoder.More(); i++ {
+				empty = false
+				child := fmt.Sprintf("%s[%d]", keys, i)
+				if token == '{' {
+					key, err := decoder.Token()
+					if err != nil {
+						return err
+					}
+					child = keyPath(keys, fmt.Sprint(key))
+				}
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			if _, err := decoder.Token(); err != nil {
+				return err
+			}
+			if empty && keys != "" {
+				value := "{}"
+				if token == '[' {
+					value = "[]"
+				}
+				o.add(line, keys+" = "+value)
+			}
+		case string:
+			o.add(line, fmt.Sprintf("%s = %s", keys, strconv.Quote(shortValue(token))))
+		case nil:
+			o.add(line, keys+" = null")
+		default:
+			o.add(line, fmt.Sprintf("%s = %v", keys, token))
+		}
+		return nil
+	}
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// yamlOutline lists every value of the documents in a YAML file by its key path
+func yamlOutline(content string) (*outline, error) {
+	o := &outline{title: "Keys"}
+	var walk func(node *yaml.Node, keys string)
+	walk = func(node *yaml.Node, keys string) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, child := range node.Content {
+				walk(child, keys)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walk(node.Content[i+1], keyPath(keys, node.Content[i].Value))
+			}
+			if len(node.Content) == 0 && keys != "" {
+				o.add(node.Line, keys+" = {}")
+			}
+		case yaml.SequenceNode:
+			for i, child := range node.Content {
+				walk(child, fmt.Sprintf("%s[%d]", keys, i))
+			}
+			if len(node.Content) == 0 && keys != "" {
+				o.add(node.Line, keys+" = []")
+			}
+		case yaml.AliasNode:
+			o.add(node.Line, fmt.Sprintf("%s = *%s", keys, node.Value))
+		case yaml.ScalarNode:
+			o.add(node.Line, fmt.Sprintf("%s = %s", keys, shortValue(node.Value)))
+		}
+	}
+
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if err == io.EOF {
+			return o, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		walk(&document, "")
+	}
+}
+
+// xmlNameAttributes are attributes that name an element; they are shown in its path, e.g.
+// "Step[Measure Voltage]", instead of being listed
+var xmlNameAttributes = map[string]bool{"name": true, "id": true, "key": true}
+
+// xmlOutline lists the text and attributes of the elements of an XML document by their path
+func xmlOutline(content string) (*outline, error) {
+	o := &outline{title: "Elements"}
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	type element struct {
+		path string
+		line int
+		text strings.Builder
+	}
+	var stack []*element
+	for {
+		line, _ := decoder.InputPos()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return o, nil
+		}
+		if err != nil {
+			if len(o.entries) > 0 {
+				// Keep the elements read before the malformed part
+				return o, nil
+			}
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			step := token.Name.Local
+			var attributes []xml.Attr
+			for _, attribute := range token.Attr {
+				if xmlNameAttributes[strings.ToLower(attribute.Name.Local)] && !strings.Contains(step, "[") {
+					step += "[" + shortValue(attribute.Value) + "]"
+				} else if attribute.Name.Space != "xmlns" && attribute.Name.Local != "xmlns" {
+					attributes = append(attributes, attribute)
+				}
+			}
+			parent := ""
+			if len(stack) > 0 {
+				parent = stack[len(stack)-1].path
+			}
+			current := &element{path: parent + "/" + step, line: line}
+			stack = append(stack, current)
+			for _, attribute := range attributes {
+				o.add(line, fmt.Sprintf("%s@%s = %s", current.path, attribute.Name.Local, shortValue(attribute.Value)))
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(token)
+			}
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if text := strings.TrimSpace(current.text.String()); text != "" {
+				o.add(current.line, fmt.Sprintf("%s = %s", current.path, shortValue(text)))
+			}
+		}
+	}
+}
+
+// codeSymbolPattern finds a kind of symbol in a language; the first group is the symbol's name
+// and the optional second its parameters
+type codeSymbolPattern struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+// codeSymbols are the symbol patterns of each language
+var codeSymbols = map[string][]codeSymbolPattern{
+	"python": {
+		{"class", regexp.MustCompile(`^\s*class\s+(\w+)`)},
+		{"function", regexp.MustCompile(`^\s*(?:async\s+)?def\s+(\w+)\s*\(([^)]*)`)},
+	},
+	"go": {
+		{"type", regexp.MustCompile(`^type\s+(\w+)`)},
+		{"function", regexp.MustCompile(`^func\s+(?:\([^)]*\)\s*)?(\w+)\s*\(([^)]*)`)},
+	},
+	"javascript": {
+		{"class", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?class\s+(\w+)`)},
+		{"function", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*(\w+)\s*\(([^)]*)`)},
+		{"function", regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+(\w+)\s*=\s*(?:async\s+)?(?:function\b|\(([^)]*)\)\s*=>)`)},
+	},
+	"c": {
+		{"type", regexp.MustCompile(`^\s*(?:(?:public|private|protected|internal|static|abstract|sealed|partial|final|typedef)\s+)*(?:class|struct|interface|enum)\s+(\w+)`)},
+		{"function", regexp.MustCompile(`^\s*(?:[\w:<>\[\],*&~]+\s+)+[*&]?(\w+)\s*\(([^;{)]*)\)?[^;]*$`)},
+	},
+	"powershell": {
+		{"function", regexp.MustCompile(`(?i)^\s*function\s+([\w-]+)\s*(?:\(([^)]*))?`)},
+	},
+	"shell": {
+		{"function", regexp.MustCompile(`^\s*(?:function\s+)?([\w-]+)\s*\(\)`)},
+		{"function", regexp.MustCompile(`^\s*function\s+([\w-]+)`)},
+	},
+	"batch": {
+		{"label", regexp.MustCompile(`^:(\w+)`)},
+	},
+}
+
+// codeStatementWords start lines that look like C-family declarations but are statements
+var codeStatementWords = map[string]bool{
+	"return": true, "new": true, "else": true, "throw": true, "await": true, "case": true, "delete": true, "goto": true,
+	"if": true, "for": true, "while": true, "switch": true, "catch": true, "using": true, "lock": true, "foreach": true, "sizeof": true,
+}
+
+// commentPrefixes start comment lines in each language
+var commentPrefixes = map[string][]string{
+	"python":     {"#"},
+	"powershell": {"#"},
+	"shell":      {"#"},
+	"go":         {"//", "/*", "*"},
+	"javascript": {"//", "/*", "*"},
+	"c":          {"//", "/*", "*"},
+	"batch":      {"::", "REM ", "rem "},
+}
+
+// extractCode lists the classes, functions and other symbols of a source file with their
+// parameters, the comment or docstring describing them and their lines
+func extractCode(content, name string) string {
+	language := codeLanguages[strings.ToLower(path.Ext(name))]
+	o := &outline{title: "Symbols"}
+	lines := strings.Split(content, "\n")
+
+	type scope struct {
+		name   string
+		indent int
+	}
+	var classes []scope // Python classes enclosing the current line, by indentation
+	for i, line := range lines {
+		for _, symbol := range codeSymbols[language] {
+			match := symbol.pattern.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			// Statements such as "return Foo(x)" or "} else if (x)" look like C-family declarations
+			if language == "c" && symbol.kind == "function" && (codeStatementWords[strings.Fields(line)[0]] || codeStatementWords[match[1]]) {
+				continue
+			}
+
+			symbolName := match[1]
+			indent := len(line) - len(strings.TrimLeft(line, " \t"))
+			if language == "python" {
+				for len(classes) > 0 && classes[len(classes)-1].indent >= indent {
+					classes = classes[:len(classes)-1]
+				}
+				if len(classes) > 0 {
+					symbolName = classes[len(classes)-1].name + "." + symbolName
+				}
+				if symbol.kind == "class" {
+					classes = append(classes, scope{symbolName, indent})
+				}
+			}
+
+			entry := symbol.kind + " " + symbolName
+			if symbol.kind == "function" {
+				parameters := ""
+				if len(match) > 2 {
+					parameters = shortValue(match[2])
+				}
+				entry += "(" + parameters + ")"
+			}
+			if comment := symbolComment(lines, i, language); comment != "" {
+				entry += ": " + comment
+			}
+			o.add(i+1, entry)
+			break
+		}
+	}
+	if len(o.entries) == 0 {
+		return content
+	}
+	return o.render(content)
+}
+
+// symbolComment returns the first line of the comment above a symbol, or of a Python docstring
+// below it
+func symbolComment(lines []string, at int, language string) string {
+	if language == "python" {
+		for _, line := range lines[at+1:] {
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" {
+				continue
+			}
+			for _, quote := range []string{`"""`, `'''`} {
+				if strings.HasPrefix(trimmed, quote) {
+					docstring := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(trimmed, quote), quote))
+					if docstring != "" {
+						return shortValue(docstring)
+					}
+				}
+			}
+			break
+		}
+	}
+
+	var comment []string
+	for i := at - 1; i >= 0; i-- {
+		trimmed := strings.TrimSpace(lines[i])
+		// Decorators and attributes sit between a symbol and its comment
+		if strings.HasPrefix(trimmed, "@") || (strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]")) {
+			continue
+		}
+		prefix := ""
+		for _, candidate := range commentPrefixes[language] {
+			if strings.HasPrefix(trimmed, candidate) {
+				prefix = candidate
+				break
+			}
+		}
+		if prefix == "" || strings.HasPrefix(trimmed, "#!") {
+			break
+		}
+		text := strings.TrimSuffix(strings.TrimLeft(strings.TrimPrefix(trimmed, prefix), "/*"), "*/")
+		if text = strings.TrimSpace(text); text != "" {
+			comment = append([]string{text}, comment...)
+		}
+	}
+	return shortValue(strings.Join(comment, " "))
+}
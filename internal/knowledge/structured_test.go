@@ -0,0 +1,174 @@
+package knowledge
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractCode(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		want    []string
+	}{
+		{
+			file: "test_cycler.py",
+			content: `#!/usr/bin/env python3
+# Connects to the fixture over serial
+class Fixture:
+    def connect(self, port="COM3"):
+        """Open the serial port."""
+        if port:
+            return True
+
+    def using(self):
+        pass
+
+@pytest.mark.slow
+def measure_voltage(channel, limit):
+    # not a doc
+    return 1
+`,
+			want: []string{
+				"- class Fixture: Connects to the fixture over serial (line 3)",
+				`- function Fixture.connect(self, port="COM3"): Open the serial port. (line 4)`,
+				"- function Fixture.using(self) (line 9)",
+				"- function measure_voltage(channel, limit) (line 13)",
+			},
+		},
+		{
+			file: "mutex.go",
+			content: `package sync
+
+// mutex guards the channel map
+type mutex struct{}
+
+// lock waits for the mutex
+func (m *mutex) lock() {
+	if true {
+		return
+	}
+}
+
+func New(size int) *mutex { return nil }
+`,
+			want: []string{
+				"- type mutex: mutex guards the channel map (line 4)",
+				"- function lock(): lock waits for the mutex (line 7)",
+				"- function New(size int) (line 13)",
+			},
+		},
+		{
+			file: "search.js",
+			content: `/** Highlights the matches */
+export default class Highlighter {}
+
+async function find(query, limit) {
+  if (query) { return run(query) }
+}
+
+const zoom = (level) => level * 2
+let handler = function () {}
+`,
+			want: []string{
+				"- class Highlighter: Highlights the matches (line 2)",
+				"- function find(query, limit) (line 4)",
+				"- function zoom(level) (line 8)",
+				"- function handler() (line 9)",
+			},
+		},
+		{
+			file: "tool.cs",
+			content: `/// <summary>Reads cycler data</summary>
+public class CyclerReader {
+    // Opens the channel
+    [Obsolete]
+    public async Task<int> OpenChannel(int channel, string name) {
+        if (x) { return Foo(1); }
+        else if (y) { Bar(); }
+        return Bar(channel);
+        throw new Exception(name);
+    }
+}
+`,
+			want: []string{
+				"- type CyclerReader: <summary>Reads cycler data</summary> (line 2)",
+				"- function OpenChannel(int channel, string name): Opens the channel (line 5)",
+			},
+		},
+		{
+			file: "channel.c",
+			content: `/* Resets a channel */
+static int reset_channel(struct channel *ch)
+{
+    return write_reg(ch, 0);
+}
+typedef struct channel_t channel;
+`,
+			want: []string{
+				"- function reset_channel(struct channel *ch): Resets a channel (line 2)",
+				"- type channel_t (line 6)",
+			},
+		},
+		{
+			file: "Deploy.ps1",
+			content: `# Copies the build to the rack
+function Copy-Build($Rack) {
+}
+FUNCTION Stop-Cycler {
+}
+`,
+			want: []string{
+				"- function Copy-Build($Rack): Copies the build to the rack (line 2)",
+				"- function Stop-Cycler() (line 4)",
+			},
+		},
+		{
+			file: "setup.sh",
+			content: `#!/bin/sh
+# Starts the rack
+start_rack() {
+  echo start
+}
+function stop-rack {
+}
+`,
+			want: []string{
+				"- function start_rack(): Starts the rack (line 3)",
+				"- function stop-rack() (line 6)",
+			},
+		},
+		{
+			file: "run.bat",
+			content: `@echo off
+:: Runs the self test
+:selftest
+goto :eof
+REM Cleans up
+:cleanup
+`,
+			want: []string{
+				"- label selftest: Runs the self test (line 3)",
+				"- label cleanup: Cleans up (line 6)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			got := extractCode(tt.content, tt.file)
+			entries, body := SplitOutline(got)
+			if !reflect.DeepEqual(entries, tt.want) {
+				t.Errorf("symbols:\n%s\nwant:\n%s", strings.Join(entries, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if body != tt.content {
+				t.Errorf("the source should follow the outline unchanged, got:\n%s", body)
+			}
+		})
+	}
+
+	plain := "echo hello\n"
+	if got := extractCode(plain, "hello.sh"); got != plain {
+		t.Errorf("a file without symbols should be kept as is, got %q", got)
+	}
+}
@@ -0,0 +1,518 @@
+package knowledge
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ticketKeyPattern matches Jira ticket keys such as BTSI-1234. Strings like UTF-8 match as well,
+// so keys are only linked when their project is known.
+var ticketKeyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9_]{1,9}-[1-9][0-9]{0,6}\b`)
+
+// ticketHeadingPattern matches the heading of each issue in an extracted Jira export
+var ticketHeadingPattern = regexp.MustCompile(`(?m)^# ([A-Z][A-Z0-9_]{1,9}-[1-9][0-9]{0,6}): `)
+
+// jiraIssue is an issue from a Jira XML or JSON export
+type jiraIssue struct {
+	Key, Summary, Type, Status, Resolution, Priority string
+	Reporter, Assignee, Created, Resolved            string
+	Components, Versions, Labels, Links              []string
+	Description, Environment                         string
+	Comments                                         []jiraComment
+}
+
+// jiraComment is a comment on a Jira issue
+type jiraComment struct {
+	Author, Created, Body string
+}
+
+// jiraXMLExport is the RSS document produced by Jira's "Export XML"
+type jiraXMLExport struct {
+	Items []struct {
+		Key         string   `xml:"key"`
+		Summary     string   `xml:"summary"`
+		Type        string   `xml:"type"`
+		Status      string   `xml:"status"`
+		Resolution  string   `xml:"resolution"`
+		Priority    string   `xml:"priority"`
+		Reporter    string   `xml:"reporter"`
+		Assignee    string   `xml:"assignee"`
+		Created     string   `xml:"created"`
+		Resolved    string   `xml:"resolved"`
+		Components  []string `xml:"component"`
+		Versions    []string `xml:"fixVersion"`
+		Labels      []string `xml:"labels>label"`
+		Description string   `xml:"description"`
+		Environment string   `xml:"environment"`
+		Parent      string   `xml:"parent"`
+		Subtasks    []string `xml:"subtasks>subtask"`
+		Outward     []string `xml:"issuelinks>issuelinktype>outwardlinks>issuelink>issuekey"`
+		Inward      []string `xml:"issuelinks>issuelinktype>inwardlinks>issuelink>issuekey"`
+		Comments    []struct {
+			Author  string `xml:"author,attr"`
+			Created string `xml:"created,attr"`
+			Body    string `xml:",chardata"`
+		} `xml:"comments>comment"`
+	} `xml:"channel>item"`
+}
+
+// jiraName is a named Jira field value such as a status or component
+type jiraName struct {
+	Name string `json:"name"`
+}
+
+// jiraUser is a Jira user; Server exports have a name, Cloud exports a display name
+type jiraUser struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+func (u jiraUser) String() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Name
+}
+
+// jiraKeyRef is a reference to another issue
+type jiraKeyRef struct {
+	Key string `json:"key"`
+}
+
+// jiraJSONIssue is an issue as returned by the Jira REST API and its JSON exports
+type jiraJSONIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary        string          `json:"summary"`
+		Description    json.RawMessage `json:"description"` // Wiki markup, or a document in Jira Cloud
+		Environment    json.RawMessage `json:"environment"`
+		IssueType      jiraName        `json:"issuetype"`
+		Status         jiraName        `json:"status"`
+		Resolution     jiraName        `json:"resolution"`
+		Priority       jiraName        `json:"priority"`
+		Components     []jiraName      `json:"components"`
+		FixVersions    []jiraName      `json:"fixVersions"`
+		Labels         []string        `json:"labels"`
+		Reporter       jiraUser        `json:"reporter"`
+		Assignee       jiraUser        `json:"assignee"`
+		Created        string          `json:"created"`
+		ResolutionDate string          `json:"resolutiondate"`
+		Parent         jiraKeyRef      `json:"parent"`
+		Subtasks       []jiraKeyRef    `json:"subtasks"`
+		IssueLinks     []struct {
+			InwardIssue  jiraKeyRef `json:"inwardIssue"`
+			OutwardIssue jiraKeyRef `json:"outwardIssue"`
+		} `json:"issuelinks"`
+		Comment struct {
+			Comments []struct {
+				Author  jiraUser        `json:"author"`
+				Created string          `json:"created"`
+				Body    json.RawMessage `json:"body"`
+			} `json:"comments"`
+		} `json:"comment"`
+	} `json:"fields"`
+}
+
+// adfNode is a node of an Atlassian document, the rich text format of Jira Cloud
+type adfNode struct {
+	Type  string `json:"type"`
+	Text  string `json:"text"`
+	Attrs struct {
+		Text string `json:"text"`
+	} `json:"attrs"`
+	Content []adfNode `json:"content"`
+}
+
+// extractJira converts a Jira XML or JSON export into one section per issue, reporting whether
+// the content was a Jira export
+func extractJira(content, name string) (string, bool) {
+	var issues []jiraIssue
+	if strings.EqualFold(path.Ext(name), ".xml") {
+		issues = parseJiraXML(content, name)
+	} else {
+		issues = parseJiraJSON(content)
+	}
+	if len(issues) == 0 {
+		return "", false
+	}
+
+	sections := make([]string, len(issues))
+	for i, issue := range issues {
+		sections[i] = issue.render()
+	}
+	return strings.Join(sections, "\n\n"), true
+}
+
+// parseJiraXML reads the issues of a Jira XML export
+func parseJiraXML(content, name string) []jiraIssue {
+	if !strings.Contains(content, "<rss") || !strings.Contains(content, "<key") {
+		return nil
+	}
+	var export jiraXMLExport
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+	if err := decoder.Decode(&export); err != nil {
+		log.Printf("Reading %s as a Jira export failed: %v", name, err)
+		return nil
+	}
+
+	var issues []jiraIssue
+	for _, item := range export.Items {
+		if strings.TrimSpace(item.Key) == "" {
+			continue
+		}
+		issue := jiraIssue{
+			Key:         strings.TrimSpace(item.Key),
+			Summary:     strings.TrimSpace(item.Summary),
+			Type:        item.Type,
+			Status:      item.Status,
+			Resolution:  item.Resolution,
+			Priority:    item.Priority,
+			Reporter:    item.Reporter,
+			Assignee:    item.Assignee,
+			Created:     jiraDate(item.Created),
+			Resolved:    jiraDate(item.Resolved),
+			Components:  item.Components,
+			Versions:    item.Versions,
+			Labels:      item.Labels,
+			Description: htmlToText(item.Description),
+			Environment: htmlToText(item.Environment),
+		}
+		if item.Parent != "" {
+			issue.Links = append(issue.Links, item.Parent)
+		}
+		issue.Links = append(append(append(issue.Links, item.Subtasks...), item.Outward...), item.Inward...)
+		for _, comment := range item.Comments {
+			issue.Comments = append(issue.Comments, jiraComment{Author: comment.Author, Created: jiraDate(comment.Created), Body: htmlToText(comment.Body)})
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// parseJiraJSON reads the issues of a Jira JSON export: a search result with an "issues" list,
+// a list of issues or a single issue
+func parseJiraJSON(content string) []jiraIssue {
+	var raw []jiraJSONIssue
+	var search struct {
+		Issues []jiraJSONIssue `json:"issues"`
+	}
+	var single jiraJSONIssue
+	switch {
+	case json.Unmarshal([]byte(content), &search) == nil && len(search.Issues) > 0:
+		raw = search.Issues
+	case json.Unmarshal([]byte(content), &single) == nil && single.Key != "":
+		raw = []jiraJSONIssue{single}
+	case json.Unmarshal([]byte(content), &raw) != nil:
+		return nil
+	}
+
+	var issues []jiraIssue
+	for _, item := range raw {
+		fields := item.Fields
+		if item.Key == "" || fields.Summary == "" {
+			continue
+		}
+		issue := jiraIssue{
+			Key:         item.Key,
+			Summary:     strings.TrimSpace(fields.Summary),
+			Type:        fields.IssueType.Name,
+			Status:      fields.Status.Name,
+			Resolution:  fields.Resolution.Name,
+			Priority:    fields.Priority.Name,
+			Reporter:    fields.Reporter.String(),
+			Assignee:    fields.Assignee.String(),
+			Created:     jiraDate(fields.Created),
+			Resolved:    jiraDate(fields.ResolutionDate),
+			Labels:      fields.Labels,
+			Description: jiraText(fields.Description),
+			Environment: jiraText(fields.Environment),
+		}
+		for _, component := range fields.Components {
+			issue.Components = append(issue.Components, component.Name)
+		}
+		for _, version := range fields.FixVersions {
+			issue.Versions = append(issue.Versions, version.Name)
+		}
+		if fields.Parent.Key != "" {
+			issue.Links = append(issue.Links, fields.Parent.Key)
+		}
+		for _, subtask := range fields.Subtasks {
+			issue.Links = append(issue.Links, subtask.Key)
+		}
+		for _, link := range fields.IssueLinks {
+			for _, key := range []string{link.OutwardIssue.Key, link.InwardIssue.Key} {
+				if key != "" {
+					issue.Links = append(issue.Links, key)
+				}
+			}
+		}
+		for _, comment := range fields.Comment.Comments {
+			issue.Comments = append(issue.Comments, jiraComment{Author: comment.Author.String(), Created: jiraDate(comment.Created), Body: jiraText(comment.Body)})
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// jiraText returns the text of a JSON field holding either a string or an Atlassian document
+func jiraText(raw json.RawMessage) string {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return strings.TrimSpace(text)
+	}
+	var doc adfNode
+	if json.Unmarshal(raw, &doc) != nil {
+		return ""
+	}
+	var out strings.Builder
+	doc.write(&out)
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(out.String(), "\n\n"))
+}
+
+// write appends the text of a document node and its children
+func (n adfNode) write(out *strings.Builder) {
+	switch n.Type {
+	case "text":
+		out.WriteString(n.Text)
+	case "hardBreak":
+		out.WriteString("\n")
+	case "mention", "emoji", "date", "status":
+		out.WriteString(n.Attrs.Text)
+	case "listItem":
+		out.WriteString("- ")
+	}
+	for _, child := range n.Content {
+		child.write(out)
+	}
+	switch n.Type {
+	case "paragraph", "heading", "codeBlock", "blockquote", "tableRow":
+		out.WriteString("\n\n")
+	case "tableCell", "tableHeader":
+		out.WriteString(" | ")
+	}
+}
+
+// jiraDate shortens the timestamps of Jira exports to minutes, leaving other formats unchanged
+func jiraDate(value string) string {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02T15:04:05.000-0700", "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Format("2006-01-02 15:04")
+		}
+	}
+	return value
+}
+
+// render writes an issue as a Markdown section headed by its key and summary
+func (issue jiraIssue) render() string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "# %s: %s\n\n", issue.Key, issue.Summary)
+
+	var status []string
+	for _, field := range [][2]string{{"Type", issue.Type}, {"Status", issue.Status}, {"Resolution", issue.Resolution}, {"Priority", issue.Priority}} {
+		if field[1] != "" {
+			status = append(status, field[0]+": "+field[1])
+		}
+	}
+	if len(status) > 0 {
+		out.WriteString(strings.Join(status, " · ") + "\n")
+	}
+	for _, field := range [][2]string{
+		{"Components", strings.Join(issue.Components, ", ")},
+		{"Fix versions", strings.Join(issue.Versions, ", ")},
+		{"Labels", strings.Join(issue.Labels, ", ")},
+		{"Reporter", issue.Reporter},
+		{"Assignee", issue.Assignee},
+		{"Created", issue.Created},
+		{"Resolved", issue.Resolved},
+		{"Linked tickets", strings.Join(issue.Links, ", ")},
+	} {
+		if field[1] != "" {
+			fmt.Fprintf(&out, "%s: %s\n", field[0], field[1])
+		}
+	}
+	if issue.Description != "" {
+		out.WriteString("\n## Description\n\n" + issue.Description + "\n")
+	}
+	if issue.Environment != "" {
+		out.WriteString("\n## Environment\n\n" + issue.Environment + "\n")
+	}
+	if len(issue.Comments) > 0 {
+		fmt.Fprintf(&out, "\n## Comments (%d)\n", len(issue.Comments))
+		for _, comment := range issue.Comments {
+			fmt.Fprintf(&out, "\n%s (%s):\n%s\n", comment.Author, comment.Created, comment.Body)
+		}
+	}
+	return strings.TrimSpace(out.String())
+}
+
+// ticketKeys returns the distinct ticket keys in a text in sorted order
+func ticketKeys(text string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, key := range ticketKeyPattern.FindAllString(text, -1) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ticketProject returns the project of a ticket key, e.g. "BTSI" for "BTSI-1234"
+func ticketProject(key string) string {
+	project, _, _ := strings.Cut(key, "-")
+	return project
+}
+
+// TicketLinks is a ticket and the documents that define or mention it
+type TicketLinks struct {
+	Key      string
+	Exports  []Document // Jira exports containing the issue
+	Mentions []Document // Other documents and uploads mentioning the key
+}
+
+// ticketProjects returns the projects whose keys are linked: those configured in the profile's
+// ticket_projects and those of the Jira exports in the knowledge base. The set is computed once
+// after the documents or uploads change and must not be modified.
+func (kb *KnowledgeDatabase) ticketProjects() map[string]bool {
+	kb.mu.RLock()
+	projects := kb.projects
+	kb.mu.RUnlock()
+	if projects != nil {
+		return projects
+	}
+
+	// Holding the lock while scanning makes a reset for a document added meanwhile wait, so
+	// the set stored here cannot outlive it
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if kb.projects != nil {
+		return kb.projects
+	}
+	projects = make(map[string]bool)
+	for _, project := range kb.profile.TicketProjects {
+		projects[strings.ToUpper(strings.TrimSpace(project))] = true
+	}
+	for _, doc := range append(kb.documents.All(), kb.uploads.All()...) {
+		if doc.Type == DocTicket {
+			for _, match := range ticketHeadingPattern.FindAllStringSubmatch(doc.Content, -1) {
+				projects[ticketProject(match[1])] = true
+			}
+		}
+	}
+	kb.projects = projects
+	return projects
+}
+
+// documentsChanged drops what is computed from the documents and uploads, the linked ticket
+// projects and the limit checks of uploads, after documents or uploads were added or removed
+func (kb *KnowledgeDatabase) documentsChanged() {
+	kb.mu.Lock()
+	kb.projects = nil
+	kb.limitChecks = nil
+	kb.mu.Unlock()
+}
+
+// FindTickets returns the ticket keys of known projects in a text
+func (kb *KnowledgeDatabase) FindTickets(text string) []string {
+	keys := ticketKeys(text)
+	if len(keys) == 0 {
+		return nil
+	}
+	projects := kb.ticketProjects()
+	var linked []string
+	for _, key := range keys {
+		if projects[ticketProject(key)] {
+			linked = append(linked, key)
+		}
+	}
+	return linked
+}
+
+// LinkedTickets returns the ticket keys of known projects that a document mentions or defines
+func (kb *KnowledgeDatabase) LinkedTickets(doc Document) []string {
+	if len(doc.Tickets) == 0 {
+		return nil
+	}
+	projects := kb.ticketProjects()
+	var linked []string
+	for _, key := range doc.Tickets {
+		if projects[ticketProject(key)] {
+			linked = append(linked, key)
+		}
+	}
+	return linked
+}
+
+// Ticket returns the Jira exports containing a ticket and the other documents and uploads that
+// mention it, leaving out copies of identical documents
+func (kb *KnowledgeDatabase) Ticket(key string) TicketLinks {
+	links := TicketLinks{Key: key}
+	for _, doc := range append(kb.documents.All(), kb.uploads.All()...) {
+		if doc.DuplicateOf != "" || !containsString(doc.Tickets, key) {
+			continue
+		}
+		if doc.Type == DocTicket && TicketSection(doc, key) != "" {
+			links.Exports = append(links.Exports, doc)
+		} else {
+			links.Mentions = append(links.Mentions, doc)
+		}
+	}
+	return links
+}
+
+// TicketSection returns the section of a Jira export describing one issue, or "" when the
+// document does not contain it
+func TicketSection(doc Document, key string) string {
+	heading := "# " + key + ": "
+	start := strings.Index(doc.Content, heading)
+	if start < 0 || (start > 0 && doc.Content[start-1] != '\n') {
+		return ""
+	}
+	section := doc.Content[start:]
+	if next := ticketHeadingPattern.FindStringIndex(section[len(heading):]); next != nil {
+		section = section[:len(heading)+next[0]]
+	}
+	return strings.TrimSpace(section)
+}
+
+// MentionExcerpt returns the paragraph of a document where a ticket key first appears, shortened
+// to about limit bytes around the key, or "" when the document does not mention it
+func MentionExcerpt(doc Document, key string, limit int) string {
+	at := strings.Index(doc.Content, key)
+	if at < 0 {
+		return ""
+	}
+	start := strings.LastIndex(doc.Content[:at], "\n\n") + 1
+	end := len(doc.Content)
+	if next := strings.Index(doc.Content[at:], "\n\n"); next >= 0 {
+		end = at + next
+	}
+	if end-start > limit {
+		start = runeStart(doc.Content, max(start, at-limit/2))
+		// Start at a line rather than part way through a word when one begins before the key
+		if newline := strings.IndexByte(doc.Content[start:at], '\n'); newline >= 0 {
+			start += newline + 1
+		}
+		end = runeStart(doc.Content, min(end, start+limit))
+	}
+	return strings.TrimSpace(doc.Content[start:end])
+}
+
+// containsString reports whether a sorted list contains a value
+func containsString(sorted []string, value string) bool {
+	i := sort.SearchStrings(sorted, value)
+	return i < len(sorted) && sorted[i] == value
+}
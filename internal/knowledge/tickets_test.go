@@ -0,0 +1,139 @@
+package knowledge
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTicketProjectsFollowUploads(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"notes.txt": "Waiting on CYC-12 and UTF-8 support.\n"})
+	kb := newTestDatabase(t, root)
+	notes, _ := kb.documents.ByPath("notes.txt")
+
+	if got := kb.LinkedTickets(notes); got != nil {
+		t.Fatalf("LinkedTickets() = %v before any CYC export", got)
+	}
+
+	export := filepath.Join(t.TempDir(), "cyc.json")
+	if err := os.WriteFile(export, []byte(`{"key": "CYC-7", "fields": {"summary": "Rack 3 reboots"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	upload, err := kb.ProcessUserUpload(export)
+	if err != nil {
+		t.Fatalf("ProcessUserUpload: %v", err)
+	}
+	if got, want := kb.LinkedTickets(notes), []string{"CYC-12"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LinkedTickets() = %v after uploading a CYC export, want %v", got, want)
+	}
+
+	kb.RemoveUpload(upload.ID)
+	if got := kb.LinkedTickets(notes); got != nil {
+		t.Errorf("LinkedTickets() = %v after removing the export", got)
+	}
+}
+
+func TestTicketKeys(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"See BTSI-1234 and BCIS-581.", []string{"BCIS-581", "BTSI-1234"}},
+		{"BTSI-1234, BTSI-1234 again", []string{"BTSI-1234"}},
+		{"(BTSIR-9)", []string{"BTSIR-9"}},
+		{"CH_2-15 uses UTF-8", []string{"CH_2-15", "UTF-8"}},
+		{"lower case btsi-1234 and Btsi-1234", nil},
+		{"single letter B-12 and numbered 2B-12", nil},
+		{"zero BTSI-0123 or leading BTSI-01", nil},
+		{"glued XBTSI-12X or BTSI-12a", nil},
+		{"too long ABCDEFGHIJK-1 or BTSI-12345678", nil},
+		{"path logs/BTSI-77.txt", []string{"BTSI-77"}},
+	}
+	for _, tt := range tests {
+		if got := ticketKeys(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ticketKeys(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestJiraText(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"wiki markup", `"  Reboot the *rack*  "`, "Reboot the *rack*"},
+		{"missing", `null`, ""},
+		{
+			name: "paragraphs and inline nodes",
+			raw: `{"type": "doc", "content": [
+				{"type": "paragraph", "content": [{"type": "text", "text": "Ask "}, {"type": "mention", "attrs": {"text": "@Sam"}},
+					{"type": "text", "text": " about"}, {"type": "hardBreak"}, {"type": "text", "text": "channel 12"}]},
+				{"type": "heading", "content": [{"type": "text", "text": "Steps"}]}]}`,
+			want: "Ask @Sam about\nchannel 12\n\nSteps",
+		},
+		{
+			name: "lists",
+			raw: `{"type": "doc", "content": [{"type": "bulletList", "content": [
+				{"type": "listItem", "content": [{"type": "paragraph", "content": [{"type": "text", "text": "Power off"}]}]},
+				{"type": "listItem", "content": [{"type": "paragraph", "content": [{"type": "text", "text": "Reseat"}]}]}]}]}`,
+			want: "- Power off\n\n- Reseat",
+		},
+		{
+			name: "tables",
+			raw: `{"type": "doc", "content": [{"type": "table", "content": [
+				{"type": "tableRow", "content": [{"type": "tableHeader", "content": [{"type": "text", "text": "Channel"}]}, {"type": "tableHeader", "content": [{"type": "text", "text": "Volts"}]}]},
+				{"type": "tableRow", "content": [{"type": "tableCell", "content": [{"type": "text", "text": "12"}]}, {"type": "tableCell", "content": [{"type": "text", "text": "4.5"}]}]}]}]}`,
+			want: "Channel | Volts | \n\n12 | 4.5 |",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jiraText(json.RawMessage(tt.raw)); got != tt.want {
+				t.Errorf("jiraText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractJira(t *testing.T) {
+	xmlExport := `<rss version="0.92"><channel><item>
+		<key id="1">BTSI-12</key><summary>Rack 3 reboots</summary><status>Open</status>
+		<created>Tue, 2 Mar 2021 09:05:00 +0000</created>
+		<description>&lt;p&gt;Seen on &lt;b&gt;channel 12&lt;/b&gt;&lt;/p&gt;</description>
+		<comments><comment author="sam" created="Wed, 3 Mar 2021 10:00:00 +0000">Replaced the fuse</comment></comments>
+	</item></channel></rss>`
+	jsonExport := `{"issues": [{"key": "BCIS-5", "fields": {"summary": "Cycler offline", "status": {"name": "Done"},
+		"created": "2021-03-02T09:05:00.000+0000", "issuelinks": [{"outwardIssue": {"key": "BTSI-12"}}]}}]}`
+
+	tests := []struct {
+		name, content, file string
+		want                []string
+	}{
+		{"xml", xmlExport, "export.xml", []string{"# BTSI-12: Rack 3 reboots", "Status: Open", "Created: 2021-03-02 09:05", "## Comments (1)", "sam (2021-03-03 10:00):\nReplaced the fuse"}},
+		{"json", jsonExport, "export.json", []string{"# BCIS-5: Cycler offline", "Status: Done", "Created: 2021-03-02 09:05", "Linked tickets: BTSI-12"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := extractJira(tt.content, tt.file)
+			if !ok {
+				t.Fatal("not recognised as a Jira export")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("missing %q in:\n%s", want, got)
+				}
+			}
+		})
+	}
+
+	for _, content := range []string{`{"stations": [{"key": "A"}]}`, `<config><key>a</key></config>`, `<rss><channel><item><key>broken`} {
+		if _, ok := extractJira(content, "other"); ok {
+			t.Errorf("%q was recognised as a Jira export", content)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package knowledge
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// uploadPrefix starts the document path of every user upload
+const uploadPrefix = "upload/"
+
+// Upload describes a file the user added to the conversation
+type Upload struct {
+	ID         string // ID of the upload's document
+	Name       string // Original file name
+	Path       string // Original location
+	Size       int64
+	Type       DocumentType
+	Extractor  string
+	Summary    string // How much text was extracted, e.g. "3 pages · 12.4 KB of text"
+	Pinned     bool   // Kept when a new conversation starts
+	UploadedAt time.Time
+	Members    []Upload      // Files extracted from an archive
+	Skipped    []SkippedFile // Files in an archive that were not extracted, by path within the archive
+}
+
+// ProcessUserUpload extracts the text of a user-uploaded file and adds it to the temporary
+// knowledge base. Uploading a file with the same name again replaces the earlier upload and
+// keeps its pin.
+func (kb *KnowledgeDatabase) ProcessUserUpload(filePath string) (Upload, error) {
+	filename := filepath.Base(filePath)
+	fmt.Printf("[DEBUG] ProcessUserUpload: Processing file %s\n", filePath)
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return Upload{}, fmt.Errorf("failed to process uploaded file %s: %w", filename, err)
+	}
+
+	// Archives are unpacked and each file in them is extracted separately
+	format, err := archiveFormat(filename)
+	if err != nil {
+		return Upload{}, fmt.Errorf("cannot use %s: %w", filename, err)
+	}
+	if format != "" {
+		return kb.processArchive(filePath, info, format)
+	}
+
+	// Extract text based on file type; files of other types are accepted when they contain text
+	doc, err := kb.extractFile(filePath, info, true)
+	if err != nil {
+		fmt.Printf("[DEBUG] ProcessUserUpload: Rejected %s: %v\n", filename, err)
+		return Upload{}, fmt.Errorf("cannot use %s: %w", filename, err)
+	}
+	doc.Path = uploadPrefix + filename
+	doc.UploadedAt = time.Now()
+	doc.ID = kb.uploads.Add(doc)
+	kb.documentsChanged()
+
+	fmt.Printf("[DEBUG] ProcessUserUpload: Stored %s with %s extractor, content length %d\n", doc.ID, doc.Extractor, len(doc.Content))
+	fmt.Printf("[DEBUG] ProcessUserUpload: Total uploaded files now: %d\n", kb.uploads.Len())
+
+	return kb.describeUpload(doc), nil
+}
+
+// Uploads returns the documents of the uploaded files in path order. Uploaded tables are also
+// checked against the limits tables of the other uploads and the knowledge base documents.
+func (kb *KnowledgeDatabase) Uploads() []Document {
+	uploads := kb.uploads.All()
+	checks := kb.uploadLimitChecks()
+	for i := range uploads {
+		uploads[i].Content += checks[uploads[i].ID]
+	}
+	return uploads
+}
+
+// uploadLimitChecks returns, by upload ID, the checks of uploaded tables against limits tables
+// in other files, computing them once after documents or uploads changed. Limits tables in
+// uploads are preferred to knowledge base ones naming the parameter equally well.
+func (kb *KnowledgeDatabase) uploadLimitChecks() map[string]string {
+	kb.mu.RLock()
+	checks := kb.limitChecks
+	kb.mu.RUnlock()
+	if checks != nil {
+		return checks
+	}
+
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if kb.limitChecks != nil {
+		return kb.limitChecks
+	}
+	checks = make(map[string]string)
+	uploads := kb.uploads.All()
+	var sheets []Document
+	for _, doc := range uploads {
+		if len(doc.tables) > 0 {
+			sheets = append(sheets, doc)
+		}
+	}
+	if len(sheets) > 0 {
+		documents := append(uploads, kb.documents.All()...)
+		for _, doc := range sheets {
+			var others []limitRule
+			for _, other := range documents {
+				if other.Path != doc.Path {
+					others = append(others, documentLimitRules(other)...)
+				}
+			}
+			if section := crossLimitChecks(doc, others); section != "" {
+				checks[doc.ID] = "\n\n" + section
+			}
+		}
+	}
+	kb.limitChecks = checks
+	return checks
+}
+
+// UserUploads describes the uploaded files in name order; an archive is one upload listing the
+// files extracted from it
+func (kb *KnowledgeDatabase) UserUploads() []Upload {
+	var uploads []Upload
+	for _, doc := range kb.uploads.All() {
+		if doc.Archive == "" {
+			uploads = append(uploads, kb.describeUpload(doc))
+		}
+	}
+	kb.mu.RLock()
+	for key, archive := range kb.archives {
+		archive.Pinned = kb.pinned[key]
+		uploads = append(uploads, archive)
+	}
+	kb.mu.RUnlock()
+	sort.SliceStable(uploads, func(i, j int) bool { return uploads[i].Name < uploads[j].Name })
+	return uploads
+}
+
+// RemoveUpload removes one uploaded file, or an archive and every file extracted from it,
+// reporting whether it was found
+func (kb *KnowledgeDatabase) RemoveUpload(id string) bool {
+	key, ok := kb.pinKey(id)
+	if !ok {
+		return false
+	}
+	if !kb.removeArchive(key) {
+		kb.uploads.Remove(id)
+		kb.documentsChanged()
+	}
+	kb.mu.Lock()
+	delete(kb.pinned, key)
+	kb.mu.Unlock()
+	return true
+}
+
+// PinUpload sets whether an uploaded file or archive is kept when a new conversation starts,
+// reporting whether it was found
+func (kb *KnowledgeDatabase) PinUpload(id string, pinned bool) bool {
+	key, ok := kb.pinKey(id)
+	if !ok {
+		return false
+	}
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+	if pinned {
+		kb.pinned[key] = true
+	} else {
+		delete(kb.pinned, key)
+	}
+	return true
+}
+
+// ClearUserUploads removes the uploaded files and archives that are not pinned
+func (kb *KnowledgeDatabase) ClearUserUploads() {
+	for _, doc := range kb.uploads.All() {
+		if doc.Archive == "" && !kb.isPinned(doc.Path) {
+			kb.uploads.Remove(doc.ID)
+		}
+	}
+	kb.documentsChanged()
+	kb.mu.RLock()
+	var archives []string
+	for key := range kb.archives {
+		if !kb.pinned[key] {
+			archives = append(archives, key)
+		}
+	}
+	kb.mu.RUnlock()
+	for _, key := range archives {
+		kb.removeArchive(key)
+	}
+}
+
+// removeArchive removes an uploaded archive and the files extracted from it, reporting whether
+// there was one
+func (kb *KnowledgeDatabase) removeArchive(key string) bool {
+	kb.mu.Lock()
+	_, ok := kb.archives[key]
+	delete(kb.archives, key)
+	kb.mu.Unlock()
+	for _, doc := range kb.uploads.All() {
+		if doc.Archive == key {
+			kb.uploads.Remove(doc.ID)
+		}
+	}
+	kb.documentsChanged()
+	return ok
+}
+
+// pinKey returns the path an upload's pin is recorded under: the archive's for an uploaded
+// archive, or the upload's document path
+func (kb *KnowledgeDatabase) pinKey(id string) (string, bool) {
+	kb.mu.RLock()
+	_, isArchive := kb.archives[id]
+	kb.mu.RUnlock()
+	if isArchive {
+		return id, true
+	}
+	doc, ok := kb.uploads.Get(id)
+	if !ok {
+		return "", false
+	}
+	if doc.Archive != "" {
+		return doc.Archive, true
+	}
+	return doc.Path, true
+}
+
+// isPinned reports whether the upload recorded under a pin key is pinned
+func (kb *KnowledgeDatabase) isPinned(key string) bool {
+	kb.mu.RLock()
+	defer kb.mu.RUnlock()
+	return kb.pinned[key]
+}
+
+// describeUpload summarises an upload's document
+func (kb *KnowledgeDatabase) describeUpload(doc Document) Upload {
+	return Upload{
+		ID:         doc.ID,
+		Name:       doc.Name,
+		Path:       doc.FullPath,
+		Size:       doc.Size,
+		Type:       doc.Type,
+		Extractor:  doc.Extractor,
+		Summary:    extractionSummary(doc),
+		Pinned:     kb.isPinned(doc.Path) || (doc.Archive != "" && kb.isPinned(doc.Archive)),
+		UploadedAt: doc.UploadedAt,
+	}
+}
+
+// extractionSummary describes how much text was extracted from a document
+func extractionSummary(doc Document) string {
+	text := FormatBytes(int64(len(doc.Content))) + " of text"
+	switch {
+	case len(doc.Pages) > 0:
+		return fmt.Sprintf("%d pages · %s", len(doc.Pages), text)
+	case doc.Extractor == ExtractOCR:
+		return "image details · " + text
+	case doc.Extractor == ExtractLog:
+		return "log summary · " + text
+	case doc.Extractor == ExtractXLSX:
+		return fmt.Sprintf("%d sheets · %s", strings.Count(doc.Content, "\n## Sheet: ")+1, text)
+	case doc.Extractor == ExtractCSV:
+		return "table · " + text
+	case doc.Extractor == ExtractJira:
+		return fmt.Sprintf("%d Jira issues · %s", len(ticketHeadingPattern.FindAllStringIndex(doc.Content, -1)), text)
+	case doc.Extractor == ExtractEmail:
+		return "email · " + text
+	case doc.Type == DocMarkdown || doc.Type == DocData || doc.Type == DocCode:
+		if entries, _ := SplitOutline(doc.Content); len(entries) > 0 {
+			return fmt.Sprintf("%d outline entries · %s", len(entries), text)
+		}
+	}
+	return fmt.Sprintf("%d lines · %s", strings.Count(strings.TrimSpace(doc.Content), "\n")+1, text)
+}
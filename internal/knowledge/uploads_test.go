@@ -0,0 +1,136 @@
+package knowledge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// uploadFile writes a file to upload and adds it to the knowledge base
+func uploadFile(t *testing.T, kb *KnowledgeDatabase, name string, data []byte) Upload {
+	t.Helper()
+	filePath := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	upload, err := kb.ProcessUserUpload(filePath)
+	if err != nil {
+		t.Fatalf("ProcessUserUpload(%s): %v", name, err)
+	}
+	return upload
+}
+
+// uploadNames lists the uploads by name with their pins, and the paths of the stored documents
+func uploadNames(kb *KnowledgeDatabase) (names []string, pinned []bool, paths []string) {
+	for _, upload := range kb.UserUploads() {
+		names = append(names, upload.Name)
+		pinned = append(pinned, upload.Pinned)
+	}
+	for _, doc := range kb.Uploads() {
+		paths = append(paths, doc.Path)
+	}
+	return names, pinned, paths
+}
+
+func TestClearKeepsPinnedUploads(t *testing.T) {
+	kb := newTestDatabase(t, t.TempDir())
+	notes := uploadFile(t, kb, "notes.txt", []byte("Rack 3 tripped twice."))
+	uploadFile(t, kb, "scratch.txt", []byte("Temporary notes."))
+	bundle := uploadFile(t, kb, "bundle.zip", zipArchive(t, textMember("logs/a.txt"), textMember("logs/b.txt")))
+	uploadFile(t, kb, "other.zip", zipArchive(t, textMember("c.txt")))
+
+	if !kb.PinUpload(notes.ID, true) || !kb.PinUpload(bundle.Members[1].ID, true) || kb.PinUpload("upload/missing.txt", true) {
+		t.Fatal("PinUpload() should find the uploads and archive members, and only those")
+	}
+	names, pinned, _ := uploadNames(kb)
+	if want := []bool{true, true, false, false}; !reflect.DeepEqual(pinned, want) {
+		t.Errorf("uploads %v pinned %v, want %v; pinning a member pins its archive", names, pinned, want)
+	}
+
+	kb.ClearUserUploads()
+	names, pinned, paths := uploadNames(kb)
+	if want := []string{"bundle.zip", "notes.txt"}; !reflect.DeepEqual(names, want) || !reflect.DeepEqual(pinned, []bool{true, true}) {
+		t.Errorf("after Clear the uploads are %v pinned %v, want %v pinned", names, pinned, want)
+	}
+	if want := []string{"upload/bundle.zip/logs/a.txt", "upload/bundle.zip/logs/b.txt", "upload/notes.txt"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("after Clear the documents are %v, want %v", paths, want)
+	}
+	if hits := kb.Search("rack tripped", 0); len(hits) != 1 || hits[0].Document.ID != notes.ID {
+		t.Errorf("the pinned upload is not searchable: %v", hits)
+	}
+
+	kb.PinUpload(notes.ID, false)
+	kb.PinUpload(bundle.ID, false)
+	kb.ClearUserUploads()
+	if names, _, paths := uploadNames(kb); names != nil || paths != nil {
+		t.Errorf("after unpinning and Clear the uploads are %v with documents %v", names, paths)
+	}
+}
+
+func TestReuploadKeepsPin(t *testing.T) {
+	kb := newTestDatabase(t, t.TempDir())
+	first := uploadFile(t, kb, "notes.txt", []byte("Rack 3 tripped."))
+	kb.PinUpload(first.ID, true)
+
+	second := uploadFile(t, kb, "notes.txt", []byte("Rack 3 tripped twice."))
+	if second.ID == first.ID || !second.Pinned {
+		t.Errorf("re-uploading gave %q pinned %v, want a new ID that is still pinned", second.ID, second.Pinned)
+	}
+	if _, ok := kb.Document(first.ID); ok {
+		t.Errorf("the earlier upload %q is still stored", first.ID)
+	}
+	if names, pinned, paths := uploadNames(kb); !reflect.DeepEqual(names, []string{"notes.txt"}) || !pinned[0] || len(paths) != 1 {
+		t.Errorf("uploads %v pinned %v with documents %v, want one pinned notes.txt", names, pinned, paths)
+	}
+
+	bundle := uploadFile(t, kb, "bundle.zip", zipArchive(t, textMember("a.txt"), textMember("b.txt")))
+	kb.PinUpload(bundle.ID, true)
+	bundle = uploadFile(t, kb, "bundle.zip", zipArchive(t, textMember("a.txt"), textMember("c.txt")))
+	if !bundle.Pinned || len(bundle.Members) != 2 || !bundle.Members[0].Pinned {
+		t.Errorf("re-uploading the archive gave %+v, want it and its members pinned", bundle)
+	}
+	if _, _, paths := uploadNames(kb); !reflect.DeepEqual(paths, []string{"upload/bundle.zip/a.txt", "upload/bundle.zip/c.txt", "upload/notes.txt"}) {
+		t.Errorf("documents %v, want the members of the new archive only", paths)
+	}
+
+	kb.ClearUserUploads()
+	if names, _, _ := uploadNames(kb); !reflect.DeepEqual(names, []string{"bundle.zip", "notes.txt"}) {
+		t.Errorf("after Clear the uploads are %v, want both re-uploads kept", names)
+	}
+}
+
+func TestRemoveArchiveMember(t *testing.T) {
+	kb := newTestDatabase(t, t.TempDir())
+	bundle := uploadFile(t, kb, "bundle.zip", zipArchive(t, textMember("logs/a.txt"), textMember("logs/b.txt")))
+	notes := uploadFile(t, kb, "notes.txt", []byte("Rack 3 tripped."))
+	kb.PinUpload(bundle.ID, true)
+
+	if !kb.RemoveUpload(bundle.Members[0].ID) {
+		t.Fatal("RemoveUpload() did not find the archive member")
+	}
+	names, _, paths := uploadNames(kb)
+	if !reflect.DeepEqual(names, []string{"notes.txt"}) || !reflect.DeepEqual(paths, []string{"upload/notes.txt"}) {
+		t.Errorf("after removing a member the uploads are %v with documents %v, want the whole archive removed", names, paths)
+	}
+	for _, member := range bundle.Members {
+		if _, ok := kb.Document(member.ID); ok {
+			t.Errorf("member %s is still stored", member.Name)
+		}
+	}
+	if kb.RemoveUpload(bundle.ID) || kb.RemoveUpload(bundle.Members[1].ID) {
+		t.Error("RemoveUpload() found the archive after it was removed")
+	}
+
+	// The pin goes with the archive
+	if bundle = uploadFile(t, kb, "bundle.zip", zipArchive(t, textMember("logs/a.txt"))); bundle.Pinned {
+		t.Error("the archive uploaded again after removing it is still pinned")
+	}
+
+	if !kb.RemoveUpload(notes.ID) || kb.RemoveUpload(notes.ID) {
+		t.Error("RemoveUpload() should report the file only once")
+	}
+	if names, _, _ := uploadNames(kb); !reflect.DeepEqual(names, []string{"bundle.zip"}) {
+		t.Errorf("uploads %v, want only the archive", names)
+	}
+}
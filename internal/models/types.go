@@ -1,20 +1,109 @@
 package models
 
+import (
+	"fmt"
+	"strings"
+)
+
 // TroubleshootingData represents the structure of our JSON data
 type TroubleshootingData struct {
-	ErrorCodes   []ErrorCode   `json:"error_codes"`
-	CommonIssues []CommonIssue `json:"common_issues"`
+	SchemaVersion int           `json:"schema_version,omitempty"` // Missing in files written before version 2
+	ErrorCodes    []ErrorCode   `json:"error_codes"`
+	CommonIssues  []CommonIssue `json:"common_issues"`
 }
 
 // ErrorCode represents an error code in the knowledge database
 type ErrorCode struct {
-	Code                   string   `json:"code"`
-	Description            string   `json:"description"`
-	Category               string   `json:"category"`
-	Severity               string   `json:"severity"`
-	TroubleshootingSteps   []string `json:"troubleshooting_steps"`
-	RelatedComponents      []string `json:"related_components"`
-	DocumentationReference string   `json:"documentation_reference"`
+	Code                 string   `json:"code"`
+	Description          string   `json:"description"`
+	Category             string   `json:"category"`
+	Severity             string   `json:"severity"`
+	TroubleshootingSteps []string `json:"troubleshooting_steps"`
+	RelatedComponents    []string `json:"related_components"`
+
+	// DocumentationReference is the single document of schema version 1; it is moved
+	// into Documentation when a file is migrated
+	DocumentationReference string `json:"documentation_reference,omitempty"`
+
+	Aliases          []string          `json:"aliases,omitempty"`  // Other names the error is reported as
+	Patterns         []string          `json:"patterns,omitempty"` // Regular expressions matching the error in logs and messages
+	Causes           []Cause           `json:"causes,omitempty"`
+	DiagnosticChecks []DiagnosticCheck `json:"diagnostic_checks,omitempty"`
+	SafetyWarnings   []string          `json:"safety_warnings,omitempty"`
+	Escalation       *Escalation       `json:"escalation,omitempty"`
+	Equipment        []string          `json:"applicable_equipment,omitempty"`
+	SoftwareVersions []string          `json:"software_versions,omitempty"`
+	Documentation    []DocumentLink    `json:"documentation,omitempty"`
+	RelatedCodes     []string          `json:"related_codes,omitempty"`
+	DecisionTree     *DecisionTree     `json:"decision_tree,omitempty"` // Guided troubleshooting, asked one question at a time
+}
+
+// DecisionTree is a guided troubleshooting procedure made of question and outcome nodes
+type DecisionTree struct {
+	Start string         `json:"start"` // ID of the first node
+	Nodes []DecisionNode `json:"nodes"`
+}
+
+// DecisionNode is a question with branches, or an outcome when it has no branches. An outcome
+// either resolves the problem or escalates it.
+type DecisionNode struct {
+	ID         string   `json:"id"`
+	Question   string   `json:"question,omitempty"`   // What to do or check, e.g. "Measure the supply output voltage"
+	Expected   string   `json:"expected,omitempty"`   // Observation expected on a healthy system, shown as a hint
+	Branches   []Branch `json:"branches,omitempty"`   // Possible answers, each leading to another node
+	Resolution string   `json:"resolution,omitempty"` // Outcome: the fix, or the reason for escalating
+	Escalate   bool     `json:"escalate,omitempty"`   // Outcome: hand over to the error code's escalation path
+}
+
+// Branch is one answer to a decision node's question
+type Branch struct {
+	Answer string `json:"answer"`
+	Next   string `json:"next"` // ID of the node the answer leads to
+}
+
+// Cause is a probable cause of an error code
+type Cause struct {
+	Description string `json:"description"`
+	Likelihood  string `json:"likelihood"` // low, medium or high
+}
+
+// DiagnosticCheck is a check a technician performs and the result expected when the system is healthy
+type DiagnosticCheck struct {
+	Check          string `json:"check"`
+	ExpectedResult string `json:"expected_result"`
+}
+
+// Escalation describes who to contact when the troubleshooting steps do not resolve an error
+type Escalation struct {
+	Team    string `json:"team"`
+	Contact string `json:"contact,omitempty"`
+	When    string `json:"when,omitempty"` // Condition for escalating, e.g. "after two failed restarts"
+}
+
+// String formats the escalation path as "Team (contact) - when"
+func (e Escalation) String() string {
+	text := e.Team
+	if e.Contact != "" {
+		text += fmt.Sprintf(" (%s)", e.Contact)
+	}
+	if e.When != "" {
+		text += " - " + e.When
+	}
+	return text
+}
+
+// DocumentLink references a document by title and file path or URL
+type DocumentLink struct {
+	Title    string `json:"title"`
+	Location string `json:"location"`
+}
+
+// String formats the link as "Title (location)", or just the location when there is no title
+func (d DocumentLink) String() string {
+	if d.Title == "" || d.Title == d.Location {
+		return d.Location
+	}
+	return fmt.Sprintf("%s (%s)", d.Title, d.Location)
 }
 
 // CommonIssue represents a common issue in the knowledge database
@@ -24,12 +113,145 @@ type CommonIssue struct {
 	Solutions []string `json:"solutions"`
 }
 
+// KnowledgeProfile is a named knowledge base: a document root, its error codes file and
+// the files under the root to index
+type KnowledgeProfile struct {
+	Name           string   `json:"name"`
+	Root           string   `json:"root"`
+	ErrorCodesFile string   `json:"error_codes_file"`
+	Include        []string `json:"include,omitempty"`         // Gitignore-style patterns; when set only matching files are indexed
+	Exclude        []string `json:"exclude,omitempty"`         // Gitignore-style patterns of files and directories to skip
+	LabName        string   `json:"lab_name,omitempty"`        // Overrides prompts.lab_name while the profile is active
+	TicketProjects []string `json:"ticket_projects,omitempty"` // Jira project keys, e.g. "BTSI", whose ticket keys are linked across documents
+}
+
+// IndexPolicy limits which files are indexed or accepted as uploads. A zero size limit means no limit.
+type IndexPolicy struct {
+	MaxTextBytes   int64    // Text, log, HTML and diagram files
+	MaxPDFBytes    int64    // PDF and Word documents
+	MaxImageBytes  int64    // Images
+	ImageFormats   []string // Extensions including the dot, e.g. ".png"
+	PDFFormats     []string
+	DiagramFormats []string
+}
+
 // OllamaRequest represents a request to the Ollama API
 type OllamaRequest struct {
-	Model   string                 `json:"model"`
-	Prompt  string                 `json:"prompt"`
-	Stream  bool                   `json:"stream"`
-	Options map[string]interface{} `json:"options,omitempty"`
+	Model     string                 `json:"model"`
+	Prompt    string                 `json:"prompt"`
+	Stream    bool                   `json:"stream"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+	KeepAlive string                 `json:"keep_alive,omitempty"`
+}
+
+// GenerationOptions holds the sampling and runtime parameters sent with a generation request.
+// Nil fields are left unset so a preset only overrides the values it specifies.
+type GenerationOptions struct {
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	TopK          *int     `json:"top_k,omitempty"`
+	NumCtx        *int     `json:"num_ctx,omitempty"`
+	NumPredict    *int     `json:"num_predict,omitempty"`
+	RepeatPenalty *float64 `json:"repeat_penalty,omitempty"`
+	Seed          *int     `json:"seed,omitempty"`
+	Stop          []string `json:"stop,omitempty"`
+	KeepAlive     string   `json:"keep_alive,omitempty"` // Duration the model stays loaded, e.g. "5m"
+}
+
+// Merge returns a copy of the options with every field set in override applied on top
+func (o GenerationOptions) Merge(override GenerationOptions) GenerationOptions {
+	merged := o
+	if override.Temperature != nil {
+		merged.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		merged.TopP = override.TopP
+	}
+	if override.TopK != nil {
+		merged.TopK = override.TopK
+	}
+	if override.NumCtx != nil {
+		merged.NumCtx = override.NumCtx
+	}
+	if override.NumPredict != nil {
+		merged.NumPredict = override.NumPredict
+	}
+	if override.RepeatPenalty != nil {
+		merged.RepeatPenalty = override.RepeatPenalty
+	}
+	if override.Seed != nil {
+		merged.Seed = override.Seed
+	}
+	if len(override.Stop) > 0 {
+		merged.Stop = override.Stop
+	}
+	if override.KeepAlive != "" {
+		merged.KeepAlive = override.KeepAlive
+	}
+	return merged
+}
+
+// RequestOptions converts the options to the Ollama "options" map (keep_alive is sent separately)
+func (o GenerationOptions) RequestOptions() map[string]interface{} {
+	options := make(map[string]interface{})
+	if o.Temperature != nil {
+		options["temperature"] = *o.Temperature
+	}
+	if o.TopP != nil {
+		options["top_p"] = *o.TopP
+	}
+	if o.TopK != nil {
+		options["top_k"] = *o.TopK
+	}
+	if o.NumCtx != nil {
+		options["num_ctx"] = *o.NumCtx
+	}
+	if o.NumPredict != nil {
+		options["num_predict"] = *o.NumPredict
+	}
+	if o.RepeatPenalty != nil {
+		options["repeat_penalty"] = *o.RepeatPenalty
+	}
+	if o.Seed != nil {
+		options["seed"] = *o.Seed
+	}
+	if len(o.Stop) > 0 {
+		options["stop"] = o.Stop
+	}
+	return options
+}
+
+// String returns a compact summary of the options that are set, for recording with answers
+func (o GenerationOptions) String() string {
+	var parts []string
+	if o.Temperature != nil {
+		parts = append(parts, fmt.Sprintf("temperature=%g", *o.Temperature))
+	}
+	if o.TopP != nil {
+		parts = append(parts, fmt.Sprintf("top_p=%g", *o.TopP))
+	}
+	if o.TopK != nil {
+		parts = append(parts, fmt.Sprintf("top_k=%d", *o.TopK))
+	}
+	if o.NumCtx != nil {
+		parts = append(parts, fmt.Sprintf("num_ctx=%d", *o.NumCtx))
+	}
+	if o.NumPredict != nil {
+		parts = append(parts, fmt.Sprintf("num_predict=%d", *o.NumPredict))
+	}
+	if o.RepeatPenalty != nil {
+		parts = append(parts, fmt.Sprintf("repeat_penalty=%g", *o.RepeatPenalty))
+	}
+	if o.Seed != nil {
+		parts = append(parts, fmt.Sprintf("seed=%d", *o.Seed))
+	}
+	if len(o.Stop) > 0 {
+		parts = append(parts, fmt.Sprintf("stop=%q", o.Stop))
+	}
+	if o.KeepAlive != "" {
+		parts = append(parts, "keep_alive="+o.KeepAlive)
+	}
+	return strings.Join(parts, ", ")
 }
 
 // OllamaResponse represents a response from the Ollama API
@@ -37,3 +259,56 @@ type OllamaResponse struct {
 	Response string `json:"response"`
 	Done     bool   `json:"done"`
 }
+
+// Passage represents an excerpt of a knowledge source included in the prompt context
+type Passage struct {
+	ID         string // Passage ID the model cites, e.g. "P1"
+	Source     string
+	DocumentID string // Knowledge base document the excerpt came from, if any
+	Text       string
+}
+
+// RetrievedContext holds the structured knowledge retrieved for a user question
+type RetrievedContext struct {
+	Question     string
+	ErrorCodes   []ErrorCode
+	CommonIssues []CommonIssue
+	Passages     []Passage // Documentation passages (not error codes or common issues)
+	Supplied     []Passage // Every item written to the prompt context, by passage ID
+
+	// Prompt budget report
+	ContextBudget int      // Tokens available for context in the model's window
+	ContextTokens int      // Estimated tokens of context actually used
+	Truncated     []string // Sources shortened to fit the budget
+	Dropped       []string // Sources left out because the budget was exhausted
+}
+
+// PullProgress represents a progress update streamed by the Ollama pull API
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Fraction returns the completed fraction of the current layer download, or 0 if unknown
+func (p PullProgress) Fraction() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Completed) / float64(p.Total)
+}
+
+// ModelInfo describes an installed model as reported by the Ollama show API
+type ModelInfo struct {
+	Name              string
+	Family            string
+	Families          []string
+	Format            string
+	ParameterSize     string
+	QuantizationLevel string
+	ContextLength     int               // Maximum context window in tokens, 0 if unknown
+	Capabilities      []string          // e.g. completion, vision, tools
+	Parameters        map[string]string // Default parameters from the Modelfile
+}
@@ -3,20 +3,59 @@ package ollama
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/beanspout/2025-beanbot/internal/models"
 )
 
+const (
+	// defaultHealthTTL is how long a health check result is reused before the server is contacted again
+	defaultHealthTTL = 30 * time.Second
+	// defaultContextWindow is Ollama's context size when num_ctx is not set and the model is unknown
+	defaultContextWindow = 2048
+	// maxAutoContextWindow caps the context window chosen from model metadata to limit memory use
+	maxAutoContextWindow = 8192
+)
+
 // Client handles communication with Ollama
 type Client struct {
-	baseURL string
-	model   string
-	client  *http.Client
+	baseURL        string
+	model          string
+	fallbackModels []string     // Models tried in order when the selected model fails
+	client         *http.Client // Generation client, replaced by SetTimeout
+	healthClient   *http.Client // Short timeout client used for health checks
+
+	mu              sync.Mutex
+	healthTTL       time.Duration
+	lastHealthCheck time.Time
+	lastHealthErr   error
+	installedModels []string // Model names reported by the last successful health check
+
+	generationDefaults models.GenerationOptions
+	modelPresets       map[string]models.GenerationOptions // Per-model overrides of generationDefaults
+	modelInfo          map[string]*models.ModelInfo        // Cached /api/show results
+	modelInfoFailed    map[string]time.Time                // When /api/show last failed for a model, retried after healthTTL
+}
+
+// Generation describes a successful response and which model produced it
+type Generation struct {
+	Text           string
+	Model          string                   // Model that produced the answer
+	RequestedModel string                   // Model that was selected when the request was made
+	Failures       []*ModelError            // Models that were tried before Model and why they failed
+	Options        models.GenerationOptions // Effective options sent with the successful request
+	Duration       time.Duration
+}
+
+// UsedFallback reports whether the answer came from a model other than the selected one
+func (g *Generation) UsedFallback() bool {
+	return g.Model != g.RequestedModel
 }
 
 // NewClient creates a new Ollama client
@@ -27,70 +66,206 @@ func NewClient(baseURL, model string) *Client {
 		client: &http.Client{
 			Timeout: 120 * time.Second, // 2 minute timeout for model response generation
 		},
+		healthClient:    &http.Client{Timeout: 5 * time.Second},
+		healthTTL:       defaultHealthTTL,
+		modelPresets:    make(map[string]models.GenerationOptions),
+		modelInfo:       make(map[string]*models.ModelInfo),
+		modelInfoFailed: make(map[string]time.Time),
 	}
 }
 
-// TestConnection tests the connection to Ollama
-func (oc *Client) TestConnection() bool {
-	resp, err := oc.client.Get(oc.baseURL)
-	if err != nil {
-		return false
+// SetGenerationOptions sets the default generation options and the per-model presets applied on top of them
+func (oc *Client) SetGenerationOptions(defaults models.GenerationOptions, presets map[string]models.GenerationOptions) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	oc.generationDefaults = defaults
+	oc.modelPresets = make(map[string]models.GenerationOptions, len(presets))
+	for model, preset := range presets {
+		oc.modelPresets[model] = preset
 	}
-	defer resp.Body.Close()
-	return resp.StatusCode == http.StatusOK
 }
 
-// FindAvailableModel tries to find an available model
-func (oc *Client) FindAvailableModel() (bool, string) {
-	// Check models in order, starting with llama3.2:1b as default
-	models := []string{
-		"llama3.2:1b", // Default model
-		"llama3.2:3b",
-		"llama3.2",
-		"llama3.2:latest",
-		// Other popular models
-		"gemma3:1b",
-		"gemma2:2b",
-		"gemma2:1b",
-		"phi3:mini",
-		"llama3.1:8b",
-		"llama3.1:7b",
-		"llama2:7b",
-		"mistral:7b",
-		"qwen2.5:1.5b",
-		"codellama:7b",
+// GenerationOptionsFor returns the effective generation options for a model. When num_ctx
+// is not configured it is set to the model's context window so prompt budgets are honoured.
+func (oc *Client) GenerationOptionsFor(model string) models.GenerationOptions {
+	oc.mu.Lock()
+	options := oc.generationDefaults.Merge(oc.modelPresets[model])
+	oc.mu.Unlock()
+
+	if options.NumCtx == nil {
+		window := oc.ContextWindow(model)
+		options.NumCtx = &window
+	}
+	return options
+}
+
+// ContextWindow returns the number of context tokens a model will use: the configured
+// num_ctx if set, otherwise the model's trained context length from /api/show (capped)
+func (oc *Client) ContextWindow(model string) int {
+	oc.mu.Lock()
+	options := oc.generationDefaults.Merge(oc.modelPresets[model])
+	info, cached := oc.modelInfo[model]
+	failedAt, failed := oc.modelInfoFailed[model]
+	oc.mu.Unlock()
+
+	if options.NumCtx != nil {
+		return *options.NumCtx
 	}
 
-	for _, model := range models {
-		if oc.testModel(model) {
-			return true, model
+	if !cached {
+		// A model that could not be read recently keeps the default until the failure expires
+		if failed && time.Since(failedAt) < oc.healthTTL {
+			return defaultContextWindow
+		}
+		var err error
+		info, err = oc.ShowModel(model)
+		oc.mu.Lock()
+		if err != nil {
+			oc.modelInfoFailed[model] = time.Now()
+		} else {
+			oc.modelInfo[model] = info
+			delete(oc.modelInfoFailed, model)
+		}
+		oc.mu.Unlock()
+		if err != nil {
+			log.Printf("[DEBUG] Could not read context length for %s: %v", model, err)
+			return defaultContextWindow
 		}
 	}
 
-	log.Printf("[DEBUG] No known models found. Consider installing: ollama pull llama3.2:1b")
-	return false, ""
+	if info.ContextLength <= 0 {
+		return defaultContextWindow
+	}
+	if info.ContextLength > maxAutoContextWindow {
+		return maxAutoContextWindow
+	}
+	return info.ContextLength
+}
+
+// forgetModelInfo drops the cached details of a model after it was pulled or deleted
+func (oc *Client) forgetModelInfo(model string) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	delete(oc.modelInfo, model)
+	delete(oc.modelInfoFailed, model)
+}
+
+// SetTimeout sets the timeout used for generation requests. Requests already in flight keep
+// the timeout they were sent with.
+func (oc *Client) SetTimeout(timeout time.Duration) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	oc.client = &http.Client{Timeout: timeout}
+}
+
+// SetFallbackModels sets the ordered list of models tried when the selected model fails
+func (oc *Client) SetFallbackModels(models []string) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	oc.fallbackModels = append([]string(nil), models...)
+}
+
+// GetFallbackModels returns the ordered fallback model list
+func (oc *Client) GetFallbackModels() []string {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	return append([]string(nil), oc.fallbackModels...)
+}
+
+// SetHealthCheckTTL sets how long a health check result is cached
+func (oc *Client) SetHealthCheckTTL(ttl time.Duration) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	oc.healthTTL = ttl
+}
+
+// CheckHealth verifies the server is reachable, reusing a recent result when available
+func (oc *Client) CheckHealth() error {
+	oc.mu.Lock()
+	if !oc.lastHealthCheck.IsZero() && time.Since(oc.lastHealthCheck) < oc.healthTTL {
+		err := oc.lastHealthErr
+		oc.mu.Unlock()
+		return err
+	}
+	oc.mu.Unlock()
+
+	installed, err := oc.fetchInstalledModels()
+
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	oc.lastHealthCheck = time.Now()
+	oc.lastHealthErr = err
+	if err == nil {
+		oc.installedModels = installed
+	}
+	return err
+}
+
+// InvalidateHealth forces the next health check to contact the server
+func (oc *Client) InvalidateHealth() {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	oc.lastHealthCheck = time.Time{}
+}
+
+// TestConnection tests the connection to Ollama
+func (oc *Client) TestConnection() bool {
+	return oc.CheckHealth() == nil
 }
 
 // SetModel sets the model to use
 func (oc *Client) SetModel(model string) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
 	oc.model = model
 }
 
 // GetCurrentModel returns the currently selected model
 func (oc *Client) GetCurrentModel() string {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
 	return oc.model
 }
 
 // GetAvailableModels gets all available models from Ollama
 func (oc *Client) GetAvailableModels() ([]string, error) {
-	resp, err := oc.client.Get(oc.baseURL + "/api/tags")
+	oc.InvalidateHealth()
+	if err := oc.CheckHealth(); err != nil {
+		return nil, err
+	}
+
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	return append([]string(nil), oc.installedModels...), nil
+}
+
+// ResolveModel returns the first installed model from the selected model followed by the
+// fallback list, along with the reasons any earlier candidates were skipped
+func (oc *Client) ResolveModel() (string, []*ModelError, error) {
+	if err := oc.CheckHealth(); err != nil {
+		return "", nil, err
+	}
+
+	var skipped []*ModelError
+	for _, model := range oc.candidateModels() {
+		if oc.isInstalled(model) {
+			return model, skipped, nil
+		}
+		skipped = append(skipped, &ModelError{Model: model, Err: ErrModelNotFound})
+	}
+	return "", skipped, &GenerationError{Attempts: skipped}
+}
+
+// fetchInstalledModels lists the models installed on the server
+func (oc *Client) fetchInstalledModels() ([]string, error) {
+	resp, err := oc.healthClient.Get(oc.baseURL + "/api/tags")
 	if err != nil {
-		return nil, fmt.Errorf("failed to get models: %w", err)
+		return nil, classifyRequestError(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ollama returned status %d", resp.StatusCode)
+		return nil, fmt.Errorf("%w: ollama returned status %d", ErrServerUnreachable, resp.StatusCode)
 	}
 
 	var response struct {
@@ -111,317 +286,127 @@ func (oc *Client) GetAvailableModels() ([]string, error) {
 	return models, nil
 }
 
-// testModel tests if a specific model is available
-func (oc *Client) testModel(model string) bool {
-	// Create a quick test client with shorter timeout
-	testClient := &http.Client{Timeout: 5 * time.Second}
-
-	reqBody := models.OllamaRequest{
-		Model:  model,
-		Prompt: "Hello",
-		Stream: false,
-		Options: map[string]interface{}{
-			"num_predict": 10, // Very short response for testing
-		},
+// candidateModels returns the selected model followed by the fallback models, without duplicates
+func (oc *Client) candidateModels() []string {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var candidates []string
+	for _, model := range append([]string{oc.model}, oc.fallbackModels...) {
+		if model != "" && !seen[model] {
+			seen[model] = true
+			candidates = append(candidates, model)
+		}
 	}
+	return candidates
+}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		log.Printf("Failed to marshal test request for model %s: %v", model, err)
-		return false
-	}
+// isInstalled reports whether the last health check listed the model
+func (oc *Client) isInstalled(model string) bool {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
 
-	resp, err := testClient.Post(oc.baseURL+"/api/generate", "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("Test request failed for model %s: %v", model, err)
-		return false
+	for _, installed := range oc.installedModels {
+		// Ollama reports untagged models with an implicit :latest tag
+		if installed == model || installed == model+":latest" {
+			return true
+		}
 	}
-	defer resp.Body.Close()
-
-	return resp.StatusCode == http.StatusOK
+	return false
 }
 
-// GenerateResponse generates a response using Ollama or fallback
-func (oc *Client) GenerateResponse(prompt string) (string, error) {
-	log.Printf("[DEBUG] GenerateResponse called with model: %s", oc.model)
+// GenerateResponse generates a response with the selected model, trying the configured
+// fallback models in order if it fails. The selected model is never changed.
+func (oc *Client) GenerateResponse(prompt string) (*Generation, error) {
+	requested := oc.GetCurrentModel()
+	log.Printf("[DEBUG] GenerateResponse called with model: %s", requested)
 
-	// First check if Ollama is available
-	if !oc.TestConnection() {
-		log.Printf("[DEBUG] Ollama not available, using fallback")
-		return oc.generateFallbackResponse(prompt), nil
+	if err := oc.CheckHealth(); err != nil {
+		log.Printf("[DEBUG] Ollama health check failed: %v", err)
+		return nil, err
 	}
 
-	log.Printf("[DEBUG] Testing model availability: %s", oc.model)
-	// Verify the current model is working, find alternative if not
-	if !oc.testModel(oc.model) {
-		log.Printf("[DEBUG] Model %s not working, searching for alternatives", oc.model)
-		available, newModel := oc.FindAvailableModel()
-		if !available {
-			log.Printf("[DEBUG] No models available, using fallback")
-			return oc.generateFallbackResponse(prompt), nil
+	start := time.Now()
+	var failures []*ModelError
+	for _, model := range oc.candidateModels() {
+		if !oc.isInstalled(model) {
+			log.Printf("[DEBUG] Model %s is not installed, skipping", model)
+			failures = append(failures, &ModelError{Model: model, Err: ErrModelNotFound})
+			continue
+		}
+
+		log.Printf("[DEBUG] Using model: %s for generation", model)
+		options := oc.GenerationOptionsFor(model)
+		text, err := oc.generate(model, prompt, options)
+		if err != nil {
+			log.Printf("[DEBUG] Model %s failed: %v", model, err)
+			failures = append(failures, &ModelError{Model: model, Err: err})
+			if errors.Is(err, ErrServerUnreachable) {
+				// No other model can answer if the server itself went away
+				oc.InvalidateHealth()
+				break
+			}
+			continue
 		}
-		log.Printf("[DEBUG] Switching to model: %s", newModel)
-		oc.model = newModel
+
+		log.Printf("[DEBUG] Successfully generated response using model: %s", model)
+		return &Generation{
+			Text:           text,
+			Model:          model,
+			RequestedModel: requested,
+			Failures:       failures,
+			Options:        options,
+			Duration:       time.Since(start),
+		}, nil
 	}
 
-	log.Printf("[DEBUG] Using model: %s for generation", oc.model)
+	return nil, &GenerationError{Attempts: failures}
+}
 
+// generate sends a single non-streaming generation request for the given model
+func (oc *Client) generate(model, prompt string, options models.GenerationOptions) (string, error) {
 	reqBody := models.OllamaRequest{
-		Model:  oc.model,
-		Prompt: prompt,
-		Stream: false,
-		Options: map[string]interface{}{
-			"num_predict": 1000, // Increased limit for more complete responses
-			"temperature": 0.7,  // Reduce randomness for more focused responses
-			"top_p":       0.9,  // Use nucleus sampling for better quality
-		},
+		Model:     model,
+		Prompt:    prompt,
+		Stream:    false,
+		Options:   options.RequestOptions(),
+		KeepAlive: options.KeepAlive,
 	}
 
-	log.Printf("[DEBUG] Request body created for model: %s", reqBody.Model)
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		log.Printf("[DEBUG] Failed to marshal request: %v", err)
-		return oc.generateFallbackResponse(prompt), nil
+		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	oc.mu.Lock()
+	client := oc.client
+	oc.mu.Unlock()
+
 	log.Printf("[DEBUG] Sending POST request to: %s", oc.baseURL+"/api/generate")
-	resp, err := oc.client.Post(oc.baseURL+"/api/generate", "application/json", bytes.NewBuffer(jsonData))
+	resp, err := client.Post(oc.baseURL+"/api/generate", "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
-		log.Printf("[DEBUG] Ollama request failed: %v, using fallback", err)
-		return oc.generateFallbackResponse(prompt), nil
+		return "", classifyRequestError(err)
 	}
 	defer resp.Body.Close()
 
 	log.Printf("[DEBUG] Received response with status: %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrModelNotFound
+	}
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("[DEBUG] Ollama returned status %d, using fallback", resp.StatusCode)
-		return oc.generateFallbackResponse(prompt), nil
+		return "", statusError(resp)
 	}
 
 	var ollamaResp models.OllamaResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		log.Printf("[DEBUG] Failed to decode Ollama response: %v, using fallback", err)
-		return oc.generateFallbackResponse(prompt), nil
-	}
-
-	log.Printf("[DEBUG] Response received, length: %d characters", len(ollamaResp.Response))
-	// Check if response is empty
-	if strings.TrimSpace(ollamaResp.Response) == "" {
-		log.Printf("[DEBUG] Empty response received, using fallback")
-		return oc.generateFallbackResponse(prompt), nil
+		return "", classifyRequestError(fmt.Errorf("failed to decode response: %w", err))
 	}
 
-	// Add model signature to response
 	response := strings.TrimSpace(ollamaResp.Response)
-	response += fmt.Sprintf("\n\n---\n*Response generated by %s*", oc.model)
-	log.Printf("[DEBUG] Successfully generated response using model: %s", oc.model)
-
-	return response, nil
-}
-
-// generateFallbackResponse generates a fallback response when Ollama is unavailable
-func (oc *Client) generateFallbackResponse(prompt string) string {
-	// Extract user input from prompt
-	lines := strings.Split(prompt, "\n")
-	userIssue := ""
-
-	for _, line := range lines {
-		if strings.HasPrefix(line, "User Issue:") {
-			userIssue = strings.TrimSpace(strings.TrimPrefix(line, "User Issue:"))
-			break
-		}
-	}
-
-	lowerIssue := strings.ToLower(userIssue)
-
-	// Generate basic troubleshooting based on keywords
-	if strings.Contains(lowerIssue, "e1001") || strings.Contains(lowerIssue, "communication") || strings.Contains(lowerIssue, "timeout") {
-		return `## ISSUE ANALYSIS:
-- **Problem**: Communication timeout with device (Error E1001)
-- **Likely causes**: Cable connection issues, power supply problems, or communication settings
-
-## STEP-BY-STEP TROUBLESHOOTING:
-
-1. **Check Physical Connections**
-   - Verify all cable connections are secure
-   - Inspect cables for damage or wear
-   - Ensure proper cable routing away from interference sources
-
-2. **Verify Power Supply**
-   - Check that all devices have proper power
-   - Verify voltage levels are within specification
-   - Test power supply connections
-
-3. **Test Communication Settings**
-   - Verify baud rate and protocol settings
-   - Check communication interface configuration
-   - Test with known good settings
-
-4. **Restart Communication Interface**
-   - Power cycle the communication interface
-   - Restart the LSIE application
-   - Re-establish communication link
-
-## ADDITIONAL RECOMMENDATIONS:
-- **Preventive measures**: Regular cable inspection and connection maintenance
-- **Escalate to support if**: Issue persists after all steps or multiple devices affected
-
-*Note: AI assistant offline - using built-in troubleshooting knowledge*
-
----
-*Response generated by Built-in Knowledge Base (Ollama offline)*`
-	}
-
-	if strings.Contains(lowerIssue, "e2005") || strings.Contains(lowerIssue, "temperature") || strings.Contains(lowerIssue, "sensor") {
-		return `## ISSUE ANALYSIS:
-- **Problem**: Temperature sensor fault (Error E2005)
-- **Likely causes**: Sensor wiring issues, calibration problems, or faulty sensor
-
-## STEP-BY-STEP TROUBLESHOOTING:
-
-1. **Check Sensor Wiring**
-   - Inspect all sensor connections
-   - Look for loose or corroded connections
-   - Verify proper wire routing and shielding
-
-2. **Verify Sensor Calibration**
-   - Check calibration settings in LSIE
-   - Compare readings with known reference
-   - Recalibrate if necessary
-
-3. **Test Sensor Functionality**
-   - Use multimeter to check sensor resistance
-   - Verify sensor specifications
-   - Test with known good sensor if available
-
-4. **Update Configuration**
-   - Check sensor configuration settings
-   - Verify temperature ranges and limits
-   - Update sensor parameters if needed
-
-## ADDITIONAL RECOMMENDATIONS:
-- **Preventive measures**: Regular sensor calibration and maintenance schedule
-- **Escalate to support if**: Sensor replacement needed or configuration issues persist
-
-*Note: AI assistant offline - using built-in troubleshooting knowledge*
-
----
-*Response generated by Built-in Knowledge Base (Ollama offline)*`
-	}
-
-	if strings.Contains(lowerIssue, "e3010") || strings.Contains(lowerIssue, "power") || strings.Contains(lowerIssue, "voltage") {
-		return `## ISSUE ANALYSIS:
-- **Problem**: Power supply voltage out of range (Error E3010)
-- **Likely causes**: Power supply failure, load issues, or configuration problems
-
-## STEP-BY-STEP TROUBLESHOOTING:
-
-1. **Check Input Voltage Levels**
-   - Measure input voltage with multimeter
-   - Verify voltage is within specification
-   - Check for voltage fluctuations
-
-2. **Inspect Power Supply Connections**
-   - Verify all power connections are secure
-   - Look for signs of overheating or damage
-   - Check fuse and circuit breaker status
-
-3. **Verify Load Requirements**
-   - Calculate total power consumption
-   - Ensure power supply capacity is adequate
-   - Check for short circuits or overloads
-
-4. **Replace Power Supply if Necessary**
-   - Test with known good power supply
-   - Check power supply specifications
-   - Replace if output is out of tolerance
-
-## ADDITIONAL RECOMMENDATIONS:
-- **Preventive measures**: Regular power supply monitoring and maintenance
-- **Escalate to support if**: Power supply replacement needed or electrical issues detected
-
-*Note: AI assistant offline - using built-in troubleshooting knowledge*
-
----
-*Response generated by Built-in Knowledge Base (Ollama offline)*`
-	}
-
-	if strings.Contains(lowerIssue, "cycler") || strings.Contains(lowerIssue, "limit") || strings.Contains(lowerIssue, "range") {
-		return `## ISSUE ANALYSIS:
-- **Problem**: Cycler limit out of range
-- **Likely causes**: Configuration limits exceeded, calibration drift, or equipment malfunction
-
-## STEP-BY-STEP TROUBLESHOOTING:
-
-1. **Check Current Settings**
-   - Review cycler voltage/current limits in software
-   - Verify configured ranges match equipment specifications
-   - Check for recent configuration changes
-
-2. **Verify Equipment Status**
-   - Inspect cycler for error indicators
-   - Check all connections to the cycler
-   - Ensure proper grounding and shielding
-
-3. **Review Test Parameters**
-   - Verify test voltage/current requirements
-   - Check if limits are appropriate for the test
-   - Confirm cell/battery specifications
-
-4. **Calibration Check**
-   - Verify last calibration date
-   - Check calibration certificates
-   - Perform basic calibration verification if needed
-
-5. **Adjust Limits if Necessary**
-   - Increase limits if within safe operating range
-   - Ensure limits don't exceed equipment capabilities
-   - Document any changes made
-
-## ADDITIONAL RECOMMENDATIONS:
-- **Preventive measures**: Regular calibration and limit verification
-- **Escalate to support if**: Equipment shows signs of malfunction or calibration issues persist
-
-*Note: AI assistant offline - using built-in troubleshooting knowledge*
-
----
-*Response generated by Built-in Knowledge Base (Ollama offline)*`
+	if response == "" {
+		return "", errors.New("model returned an empty response")
 	}
 
-	// Generic response for other issues
-	return fmt.Sprintf(`## ISSUE ANALYSIS:
-- **Problem**: %s
-- **Status**: AI assistant is currently offline, providing basic troubleshooting guidance
-
-## GENERAL TROUBLESHOOTING STEPS:
-
-1. **Document the Issue**
-   - Note exact error messages
-   - Record when the issue occurs
-   - Document any recent changes
-
-2. **Check Basic Connections**
-   - Verify all cables and connections
-   - Ensure proper power to all devices
-   - Check for loose connections
-
-3. **Review System Status**
-   - Check system logs for errors
-   - Verify all components are operational
-   - Look for warning indicators
-
-4. **Restart Components**
-   - Power cycle affected devices
-   - Restart software applications
-   - Re-establish connections
-
-## NEXT STEPS:
-- Install and configure Ollama with gemma3:1b model for full AI assistance
-- Consult technical documentation for specific error codes
-- Contact support if issue persists
-
-*Note: For full AI-powered troubleshooting, please install Ollama and run 'ollama pull llama3.2:1b' (recommended)*
-
----
-*Response generated by Built-in Knowledge Base (Ollama offline)*`, userIssue)
+	return response, nil
 }
@@ -0,0 +1,307 @@
+package ollama
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// showServer answers /api/show with the response and status of ready, counting the requests
+func showServer(t *testing.T, ready *atomic.Bool, shows *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			shows.Add(1)
+			if !ready.Load() {
+				http.Error(w, `{"error": "model not found"}`, http.StatusNotFound)
+				return
+			}
+			w.Write([]byte(`{"details": {"family": "llama"}, "model_info": {"llama.context_length": 4096}}`))
+		case "/api/delete":
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestContextWindowCachesFailures(t *testing.T) {
+	var ready atomic.Bool
+	var shows atomic.Int32
+	client := NewClient(showServer(t, &ready, &shows).URL, "llama3.2:1b")
+
+	for i := 0; i < 3; i++ {
+		if got := client.ContextWindow("llama3.2:1b"); got != defaultContextWindow {
+			t.Fatalf("ContextWindow() = %d for a missing model, want %d", got, defaultContextWindow)
+		}
+	}
+	if shows.Load() != 1 {
+		t.Errorf("a failed lookup was retried %d times within the TTL", shows.Load()-1)
+	}
+
+	// The failure expires after the TTL
+	ready.Store(true)
+	client.mu.Lock()
+	client.modelInfoFailed["llama3.2:1b"] = time.Now().Add(-2 * client.healthTTL)
+	client.mu.Unlock()
+	if got := client.ContextWindow("llama3.2:1b"); got != 4096 {
+		t.Errorf("ContextWindow() = %d after the failure expired, want 4096", got)
+	}
+	client.ContextWindow("llama3.2:1b")
+	if shows.Load() != 2 {
+		t.Errorf("made %d /api/show requests, want 2", shows.Load())
+	}
+}
+
+func TestDeleteModelForgetsModelInfo(t *testing.T) {
+	var ready atomic.Bool
+	var shows atomic.Int32
+	ready.Store(true)
+	client := NewClient(showServer(t, &ready, &shows).URL, "llama3.2:1b")
+
+	client.ContextWindow("llama3.2:1b")
+	if err := client.DeleteModel("llama3.2:1b"); err != nil {
+		t.Fatalf("DeleteModel: %v", err)
+	}
+	ready.Store(false)
+	if got := client.ContextWindow("llama3.2:1b"); got != defaultContextWindow {
+		t.Errorf("ContextWindow() = %d after deleting the model, want %d", got, defaultContextWindow)
+	}
+	if shows.Load() != 2 {
+		t.Errorf("made %d /api/show requests, want the details to be read again after the delete", shows.Load())
+	}
+}
+
+// fakeOllama is an Ollama server with a fixed set of installed models. Each model answers
+// generation requests with its reply, or fails with the model's status code, a dropped
+// connection or a delay.
+type fakeOllama struct {
+	installed []string
+	status    map[string]int           // Status code returned for a model instead of an answer
+	dropped   map[string]bool          // Models whose requests close the connection
+	delay     map[string]time.Duration // Time a model takes to answer
+
+	mu        sync.Mutex
+	tags      int      // /api/tags requests
+	generated []string // Models asked to generate, in order
+}
+
+// requests returns the number of health checks and the models asked to generate so far
+func (f *fakeOllama) requests() (int, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tags, strings.Join(f.generated, ",")
+}
+
+func (f *fakeOllama) start(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			f.mu.Lock()
+			f.tags++
+			f.mu.Unlock()
+			var tags struct {
+				Models []map[string]string `json:"models"`
+			}
+			for _, name := range f.installed {
+				tags.Models = append(tags.Models, map[string]string{"name": name})
+			}
+			json.NewEncoder(w).Encode(tags)
+		case "/api/generate":
+			var request models.OllamaRequest
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.mu.Lock()
+			f.generated = append(f.generated, request.Model)
+			f.mu.Unlock()
+
+			if f.dropped[request.Model] {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			select {
+			case <-time.After(f.delay[request.Model]):
+			case <-r.Context().Done():
+				return
+			}
+			if status := f.status[request.Model]; status != 0 {
+				w.WriteHeader(status)
+				w.Write([]byte(`{"error": "model failed to load"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(models.OllamaResponse{Response: "answer from " + request.Model, Done: true})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGenerateResponseFallbackOrder(t *testing.T) {
+	fake := &fakeOllama{
+		installed: []string{"broken:7b", "slow:1b", "good:latest", "spare:1b"},
+		status:    map[string]int{"broken:7b": http.StatusInternalServerError},
+		delay:     map[string]time.Duration{"slow:1b": time.Second},
+	}
+	client := NewClient(fake.start(t).URL, "missing:13b")
+	client.SetFallbackModels([]string{"broken:7b", "missing:13b", "slow:1b", "good", "spare:1b"})
+	client.SetTimeout(100 * time.Millisecond)
+
+	generation, err := client.GenerateResponse("Why is E-12 shown?")
+	if err != nil {
+		t.Fatalf("GenerateResponse: %v", err)
+	}
+	if generation.Model != "good" || generation.RequestedModel != "missing:13b" || !generation.UsedFallback() || generation.Text != "answer from good" {
+		t.Errorf("answered %q by %s for %s", generation.Text, generation.Model, generation.RequestedModel)
+	}
+	if _, got := fake.requests(); got != "broken:7b,slow:1b,good" {
+		t.Errorf("asked %s, want the installed candidates in order until one answers", got)
+	}
+
+	want := []struct {
+		model string
+		err   error
+	}{{"missing:13b", ErrModelNotFound}, {"broken:7b", nil}, {"slow:1b", ErrTimeout}}
+	if len(generation.Failures) != len(want) {
+		t.Fatalf("failures %v, want %d", generation.Failures, len(want))
+	}
+	for i, failure := range generation.Failures {
+		if failure.Model != want[i].model || (want[i].err != nil && !errors.Is(failure, want[i].err)) {
+			t.Errorf("failure %d is %v, want %s: %v", i, failure, want[i].model, want[i].err)
+		}
+	}
+	if !strings.Contains(generation.Failures[1].Error(), "model failed to load") {
+		t.Errorf("the status error %q does not include Ollama's message", generation.Failures[1])
+	}
+}
+
+func TestGenerateResponseErrors(t *testing.T) {
+	t.Run("no model installed", func(t *testing.T) {
+		fake := &fakeOllama{installed: []string{"other:1b"}}
+		client := NewClient(fake.start(t).URL, "llama3.2:1b")
+		client.SetFallbackModels([]string{"phi3"})
+
+		_, err := client.GenerateResponse("hello")
+		var generationErr *GenerationError
+		if !errors.As(err, &generationErr) || len(generationErr.Attempts) != 2 || !errors.Is(err, ErrModelNotFound) {
+			t.Fatalf("GenerateResponse() = %v, want ErrModelNotFound for both models", err)
+		}
+		if _, asked := fake.requests(); asked != "" {
+			t.Errorf("models that are not installed were asked: %s", asked)
+		}
+	})
+
+	t.Run("server not running", func(t *testing.T) {
+		server := (&fakeOllama{}).start(t)
+		server.Close()
+		_, err := NewClient(server.URL, "llama3.2:1b").GenerateResponse("hello")
+		if !errors.Is(err, ErrServerUnreachable) {
+			t.Errorf("GenerateResponse() = %v, want ErrServerUnreachable", err)
+		}
+	})
+
+	t.Run("server goes away", func(t *testing.T) {
+		fake := &fakeOllama{installed: []string{"llama3.2:1b", "phi3:latest"}, dropped: map[string]bool{"llama3.2:1b": true}}
+		client := NewClient(fake.start(t).URL, "llama3.2:1b")
+		client.SetFallbackModels([]string{"phi3"})
+
+		_, err := client.GenerateResponse("hello")
+		if !errors.Is(err, ErrServerUnreachable) {
+			t.Errorf("GenerateResponse() = %v, want ErrServerUnreachable", err)
+		}
+		if _, asked := fake.requests(); asked != "llama3.2:1b" {
+			t.Errorf("asked %s, want no fallback once the server went away", asked)
+		}
+
+		// The next request checks the server again instead of trusting the cached result
+		client.GenerateResponse("hello")
+		if tags, _ := fake.requests(); tags != 2 {
+			t.Errorf("made %d health checks, want the health result invalidated", tags)
+		}
+	})
+}
+
+func TestCheckHealthTTL(t *testing.T) {
+	fake := &fakeOllama{installed: []string{"llama3.2:1b"}}
+	server := fake.start(t)
+	client := NewClient(server.URL, "llama3.2:1b")
+
+	for i := 0; i < 3; i++ {
+		if err := client.CheckHealth(); err != nil {
+			t.Fatalf("CheckHealth: %v", err)
+		}
+	}
+	if tags, _ := fake.requests(); tags != 1 {
+		t.Errorf("made %d health checks within the TTL, want 1", tags)
+	}
+
+	client.InvalidateHealth()
+	client.CheckHealth()
+	if tags, _ := fake.requests(); tags != 2 {
+		t.Errorf("made %d health checks after invalidating, want 2", tags)
+	}
+
+	// A failed check is reused as well until it expires
+	server.Close()
+	client.SetHealthCheckTTL(time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+	if err := client.CheckHealth(); !errors.Is(err, ErrServerUnreachable) {
+		t.Fatalf("CheckHealth() = %v after the server stopped, want ErrServerUnreachable", err)
+	}
+	client.SetHealthCheckTTL(time.Hour)
+	if err := client.CheckHealth(); !errors.Is(err, ErrServerUnreachable) || client.TestConnection() {
+		t.Errorf("CheckHealth() = %v, want the cached failure", err)
+	}
+}
+
+func TestSetTimeoutDuringGeneration(t *testing.T) {
+	fake := &fakeOllama{installed: []string{"llama3.2:1b"}, delay: map[string]time.Duration{"llama3.2:1b": 20 * time.Millisecond}}
+	client := NewClient(fake.start(t).URL, "llama3.2:1b")
+
+	done := make(chan error)
+	go func() {
+		_, err := client.GenerateResponse("hello")
+		done <- err
+	}()
+	for i := 0; i < 10; i++ {
+		client.SetTimeout(time.Duration(10+i) * time.Second)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("GenerateResponse: %v", err)
+	}
+}
+
+func TestDescribeError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("%w: dial tcp: connection refused", ErrServerUnreachable), "not reachable"},
+		{&GenerationError{Attempts: []*ModelError{{Model: "phi3", Err: ErrModelNotFound}}}, "not installed"},
+		{&GenerationError{Attempts: []*ModelError{{Model: "phi3", Err: ErrModelNotFound}, {Model: "llama3", Err: fmt.Errorf("%w: deadline", ErrTimeout)}}}, "timed out"},
+		{errors.New("model returned an empty response"), "model returned an empty response"},
+	}
+	for _, tt := range tests {
+		if got := DescribeError(tt.err); !strings.Contains(got, tt.want) || (tt.want == "" && got != "") {
+			t.Errorf("DescribeError(%v) = %q, want it to mention %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package ollama
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+var (
+	// ErrServerUnreachable indicates the Ollama server could not be contacted
+	ErrServerUnreachable = errors.New("ollama server unreachable")
+	// ErrModelNotFound indicates the requested model is not installed on the server
+	ErrModelNotFound = errors.New("model not found")
+	// ErrTimeout indicates the request did not complete within the client timeout
+	ErrTimeout = errors.New("ollama request timed out")
+)
+
+// ModelError records why generation with a specific model failed
+type ModelError struct {
+	Model string
+	Err   error
+}
+
+// Error implements the error interface
+func (e *ModelError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Model, e.Err)
+}
+
+// Unwrap returns the underlying error so callers can use errors.Is
+func (e *ModelError) Unwrap() error {
+	return e.Err
+}
+
+// classifyRequestError maps a transport error to one of the typed errors
+func classifyRequestError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%w: %v", ErrServerUnreachable, err)
+	}
+
+	return err
+}
+
+// DescribeError returns a short, user-facing explanation of a generation error
+func DescribeError(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrServerUnreachable):
+		return "the Ollama server is not reachable (is `ollama serve` running?)"
+	case errors.Is(err, ErrTimeout):
+		return "the model did not answer before the request timed out"
+	case errors.Is(err, ErrModelNotFound):
+		return "the selected model is not installed on the Ollama server"
+	default:
+		return err.Error()
+	}
+}
+
+// GenerationError reports every model that was tried when none could answer
+type GenerationError struct {
+	Attempts []*ModelError
+}
+
+// Error implements the error interface
+func (e *GenerationError) Error() string {
+	if len(e.Attempts) == 1 {
+		return e.Attempts[0].Error()
+	}
+	msg := "all models failed"
+	for _, attempt := range e.Attempts {
+		msg += "; " + attempt.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the individual attempt errors so callers can use errors.Is
+func (e *GenerationError) Unwrap() []error {
+	errs := make([]error, len(e.Attempts))
+	for i, attempt := range e.Attempts {
+		errs[i] = attempt
+	}
+	return errs
+}
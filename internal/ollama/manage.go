@@ -0,0 +1,200 @@
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// PullModel downloads a model from the Ollama library, reporting streamed progress
+// updates to the progress callback until the pull completes
+func (oc *Client) PullModel(name string, progress func(models.PullProgress)) error {
+	jsonData, err := json.Marshal(map[string]interface{}{"model": name, "stream": true})
+	if err != nil {
+		return fmt.Errorf("failed to marshal pull request: %w", err)
+	}
+
+	// Pulls can take many minutes, so the generation timeout does not apply
+	pullClient := &http.Client{}
+	resp, err := pullClient.Post(oc.baseURL+"/api/pull", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return classifyRequestError(err)
+	}
+	defer resp.Body.Close()
+	defer oc.InvalidateHealth()
+	defer oc.forgetModelInfo(name)
+
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var update models.PullProgress
+		if err := json.Unmarshal(line, &update); err != nil {
+			return fmt.Errorf("failed to decode pull progress: %w", err)
+		}
+		if update.Error != "" {
+			return fmt.Errorf("pull %s failed: %s", name, update.Error)
+		}
+		if progress != nil {
+			progress(update)
+		}
+		if update.Status == "success" {
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return classifyRequestError(err)
+	}
+	return fmt.Errorf("pull %s ended before completion", name)
+}
+
+// DeleteModel removes an installed model from the Ollama server
+func (oc *Client) DeleteModel(name string) error {
+	jsonData, err := json.Marshal(map[string]string{"model": name})
+	if err != nil {
+		return fmt.Errorf("failed to marshal delete request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, oc.baseURL+"/api/delete", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create delete request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := oc.healthClient.Do(req)
+	if err != nil {
+		return classifyRequestError(err)
+	}
+	defer resp.Body.Close()
+	defer oc.InvalidateHealth()
+	defer oc.forgetModelInfo(name)
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &ModelError{Model: name, Err: ErrModelNotFound}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp)
+	}
+	return nil
+}
+
+// ShowModel returns the details of an installed model
+func (oc *Client) ShowModel(name string) (*models.ModelInfo, error) {
+	jsonData, err := json.Marshal(map[string]string{"model": name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal show request: %w", err)
+	}
+
+	resp, err := oc.healthClient.Post(oc.baseURL+"/api/show", "application/json", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, classifyRequestError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &ModelError{Model: name, Err: ErrModelNotFound}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(resp)
+	}
+
+	var response struct {
+		Parameters string `json:"parameters"`
+		Details    struct {
+			Format            string   `json:"format"`
+			Family            string   `json:"family"`
+			Families          []string `json:"families"`
+			ParameterSize     string   `json:"parameter_size"`
+			QuantizationLevel string   `json:"quantization_level"`
+		} `json:"details"`
+		ModelInfo    map[string]interface{} `json:"model_info"`
+		Capabilities []string               `json:"capabilities"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode show response: %w", err)
+	}
+
+	info := &models.ModelInfo{
+		Name:              name,
+		Family:            response.Details.Family,
+		Families:          response.Details.Families,
+		Format:            response.Details.Format,
+		ParameterSize:     response.Details.ParameterSize,
+		QuantizationLevel: response.Details.QuantizationLevel,
+		ContextLength:     contextLength(response.ModelInfo),
+		Capabilities:      response.Capabilities,
+		Parameters:        parseModelfileParameters(response.Parameters),
+	}
+	return info, nil
+}
+
+// contextLength finds the "<architecture>.context_length" entry in the model info map
+func contextLength(modelInfo map[string]interface{}) int {
+	keys := make([]string, 0, len(modelInfo))
+	for key := range modelInfo {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if value, ok := modelInfo[key].(float64); ok {
+			return int(value)
+		}
+	}
+	return 0
+}
+
+// parseModelfileParameters parses the "name value" lines returned by the show API.
+// Repeated parameters such as stop are joined with commas.
+func parseModelfileParameters(raw string) map[string]string {
+	params := make(map[string]string)
+	for _, line := range strings.Split(raw, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		value := strings.Join(fields[1:], " ")
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		if existing, ok := params[fields[0]]; ok {
+			value = existing + ", " + value
+		}
+		params[fields[0]] = value
+	}
+	return params
+}
+
+// statusError builds an error from a non-OK Ollama response, including its error message
+func statusError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+		return fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, apiErr.Error)
+	}
+	return errors.New(strings.TrimSpace(fmt.Sprintf("ollama returned status %d: %s", resp.StatusCode, string(body))))
+}
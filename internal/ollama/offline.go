@@ -0,0 +1,149 @@
+package ollama
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+const (
+	offlineMaxPassages     = 3   // Number of top documentation passages rendered offline
+	offlineMaxExcerptRunes = 400 // Maximum excerpt length per rendered passage
+)
+
+// GenerateOfflineResponse renders the retrieved knowledge into the standard
+// PROBLEM ANALYSIS / SOLUTION STEPS layout without using a model. The reason
+// is the generation error that caused the offline answer.
+func GenerateOfflineResponse(retrieved *models.RetrievedContext, reason error) string {
+	if retrieved == nil {
+		retrieved = &models.RetrievedContext{}
+	}
+
+	var response strings.Builder
+	response.WriteString("> **⚠️ OFFLINE MODE** - no AI model could answer. This answer was assembled directly from the knowledge base without AI analysis.\n")
+	if reason != nil {
+		response.WriteString(fmt.Sprintf(">\n> **Reason:** %s\n", DescribeError(reason)))
+		var genErr *GenerationError
+		if errors.As(reason, &genErr) && len(genErr.Attempts) > 1 {
+			for _, attempt := range genErr.Attempts {
+				response.WriteString(fmt.Sprintf("> - `%s`: %s\n", attempt.Model, DescribeError(attempt.Err)))
+			}
+		}
+	}
+	response.WriteString("\n")
+
+	// Problem analysis from matched error codes and common issues
+	response.WriteString("## **1. PROBLEM ANALYSIS**\n")
+	if question := strings.TrimSpace(retrieved.Question); question != "" {
+		response.WriteString(fmt.Sprintf("- **Reported issue:** %s\n", question))
+	}
+	for _, errorCode := range retrieved.ErrorCodes {
+		response.WriteString(fmt.Sprintf("- **Error %s** (%s severity, %s): %s\n",
+			errorCode.Code, errorCode.Severity, errorCode.Category, errorCode.Description))
+		for _, warning := range errorCode.SafetyWarnings {
+			response.WriteString(fmt.Sprintf("  - ⚠️ **Safety:** %s\n", warning))
+		}
+		if len(errorCode.RelatedComponents) > 0 {
+			response.WriteString(fmt.Sprintf("  - Related components: %s\n", strings.Join(errorCode.RelatedComponents, ", ")))
+		}
+		for _, cause := range errorCode.Causes {
+			response.WriteString(fmt.Sprintf("  - Probable cause (%s likelihood): %s\n", cause.Likelihood, cause.Description))
+		}
+	}
+	for _, issue := range retrieved.CommonIssues {
+		response.WriteString(fmt.Sprintf("- **Known issue:** %s\n", issue.Issue))
+		if len(issue.Symptoms) > 0 {
+			response.WriteString(fmt.Sprintf("  - Symptoms: %s\n", strings.Join(issue.Symptoms, "; ")))
+		}
+	}
+	if len(retrieved.ErrorCodes) == 0 && len(retrieved.CommonIssues) == 0 {
+		response.WriteString("- No matching error codes or known issues were found in the knowledge base.\n")
+	}
+	response.WriteString("\n")
+
+	// Solution steps taken verbatim from the knowledge base
+	response.WriteString("## **2. SOLUTION STEPS**\n")
+	step := 1
+	for _, errorCode := range retrieved.ErrorCodes {
+		for _, check := range errorCode.DiagnosticChecks {
+			if check.ExpectedResult != "" {
+				response.WriteString(fmt.Sprintf("- **Step %d:** %s - expected: %s *(%s)*\n", step, check.Check, check.ExpectedResult, errorCode.Code))
+			} else {
+				response.WriteString(fmt.Sprintf("- **Step %d:** %s *(%s)*\n", step, check.Check, errorCode.Code))
+			}
+			step++
+		}
+		for _, s := range errorCode.TroubleshootingSteps {
+			response.WriteString(fmt.Sprintf("- **Step %d:** %s *(%s)*\n", step, s, errorCode.Code))
+			step++
+		}
+	}
+	for _, issue := range retrieved.CommonIssues {
+		for _, solution := range issue.Solutions {
+			response.WriteString(fmt.Sprintf("- **Step %d:** %s *(%s)*\n", step, solution, issue.Issue))
+			step++
+		}
+	}
+	if step == 1 {
+		if len(retrieved.Passages) > 0 {
+			response.WriteString("- **Step 1:** Review the documentation excerpts below for procedures related to your issue\n")
+		} else {
+			response.WriteString("- **Step 1:** Note the exact error message, code and time of the failure\n")
+			response.WriteString("- **Step 2:** Rephrase the question with the error code or affected component so it can be matched against the knowledge base\n")
+		}
+	}
+	response.WriteString("\n")
+
+	// Escalation and further reading
+	response.WriteString("## **3. IF PROBLEM PERSISTS**\n")
+	escalated := false
+	for _, errorCode := range retrieved.ErrorCodes {
+		for _, link := range errorCode.Documentation {
+			response.WriteString(fmt.Sprintf("- Consult **%s** for error %s\n", link, errorCode.Code))
+		}
+		if len(errorCode.RelatedCodes) > 0 {
+			response.WriteString(fmt.Sprintf("- Related error codes: %s\n", strings.Join(errorCode.RelatedCodes, ", ")))
+		}
+		if errorCode.Escalation != nil {
+			response.WriteString(fmt.Sprintf("- Escalate error %s to **%s** with the error details and the steps already attempted\n", errorCode.Code, errorCode.Escalation))
+			escalated = true
+		}
+	}
+	if !escalated {
+		response.WriteString("- Escalate to the lab support team with the error details and the steps already attempted\n")
+	}
+	response.WriteString("- Start Ollama (`ollama serve`) and ask again for an AI-assisted analysis\n\n")
+
+	// Top passages that were retrieved for the question
+	if len(retrieved.Passages) > 0 {
+		response.WriteString("## **📄 RELEVANT DOCUMENTATION**\n")
+		for i, passage := range retrieved.Passages {
+			if i >= offlineMaxPassages {
+				break
+			}
+			response.WriteString(fmt.Sprintf("**%s**\n\n", passage.Source))
+			response.WriteString(fmt.Sprintf("> %s\n\n", strings.ReplaceAll(truncateRunes(passage.Text, offlineMaxExcerptRunes), "\n", "\n> ")))
+		}
+	}
+
+	response.WriteString("---\n*Response generated by BeanBot offline mode (knowledge base only)*")
+	return response.String()
+}
+
+// truncateRunes shortens text to at most limit runes, preferring a line boundary
+func truncateRunes(text string, limit int) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := string(runes[:limit])
+	if idx := strings.LastIndex(cut, "\n"); idx > len(cut)/2 {
+		cut = cut[:idx]
+	}
+	return strings.TrimSpace(cut) + "..."
+}
@@ -0,0 +1,198 @@
+package prompt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// EstimateTokens approximates the number of tokens a model will use for text.
+// Most tokenizers average about four characters per token for English prose, but
+// technical text with many short words and symbols tokenizes denser, so the larger
+// of the character and word based estimates is used.
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	byChars := (utf8.RuneCountInString(text) + 3) / 4
+
+	words := 0
+	symbols := 0
+	for _, field := range strings.Fields(text) {
+		words++
+		for _, r := range field {
+			if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+				symbols++
+			}
+		}
+	}
+	byWords := (words*4)/3 + symbols/2
+
+	if byWords > byChars {
+		return byWords
+	}
+	return byChars
+}
+
+// ContextItem is a candidate block of knowledge for the prompt context
+type ContextItem struct {
+	ID         string      // Passage ID written before the item, e.g. "P1"; assigned by Build
+	Priority   int         // Lower values are included first
+	Score      int         // Relevance within a priority, higher values are included first
+	Source     string      // Reference shown to the user
+	DocumentID string      // ID of the knowledge base document the text came from, if any
+	Header     string      // Line written before the text, e.g. "From Confluence/Setup.html:"
+	Text       string      // Content, split on blank lines or line breaks when truncated
+	Payload    interface{} // Optional structured data the item was built from
+}
+
+// ContextReport describes how the context window budget was spent
+type ContextReport struct {
+	BudgetTokens int
+	UsedTokens   int
+	Included     []ContextItem // Items in the order they were written, with truncated text
+	Truncated    []string      // Sources that were shortened to fit
+	Dropped      []string      // Sources that did not fit at all
+}
+
+// ContextBuilder collects context items and fills a token budget by priority
+type ContextBuilder struct {
+	items []ContextItem
+}
+
+// NewContextBuilder creates an empty context builder
+func NewContextBuilder() *ContextBuilder {
+	return &ContextBuilder{}
+}
+
+// Add adds a candidate item to the builder
+func (cb *ContextBuilder) Add(item ContextItem) {
+	cb.items = append(cb.items, item)
+}
+
+// Len returns the number of candidate items
+func (cb *ContextBuilder) Len() int {
+	return len(cb.items)
+}
+
+// Build writes the highest priority items that fit within budget tokens. Items that do
+// not fit whole are truncated on passage boundaries, or within their first passage when
+// not even that fits; items with no room left are dropped.
+// Each included item is labelled with a passage ID such as [P1] so answers can cite it.
+func (cb *ContextBuilder) Build(budget int) (string, *ContextReport) {
+	items := append([]ContextItem(nil), cb.items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Priority != items[j].Priority {
+			return items[i].Priority < items[j].Priority
+		}
+		return items[i].Score > items[j].Score
+	})
+
+	report := &ContextReport{BudgetTokens: budget}
+	var context strings.Builder
+
+	for _, item := range items {
+		remaining := budget - report.UsedTokens
+		id := fmt.Sprintf("P%d", len(report.Included)+1)
+		label := "[" + id + "] "
+		item.ID = id
+		block := label + item.Header + item.Text + "\n\n"
+		cost := EstimateTokens(block)
+
+		if cost <= remaining {
+			context.WriteString(block)
+			report.UsedTokens += cost
+			report.Included = append(report.Included, item)
+			continue
+		}
+
+		// Try to keep the leading passages of the item
+		text := fitPassages(item.Text, remaining-EstimateTokens(label+item.Header)-4)
+		if text == "" {
+			report.Dropped = append(report.Dropped, item.Source)
+			continue
+		}
+
+		block = label + item.Header + text + "\n[...truncated]\n\n"
+		context.WriteString(block)
+		report.UsedTokens += EstimateTokens(block)
+		item.Text = text
+		report.Included = append(report.Included, item)
+		report.Truncated = append(report.Truncated, item.Source)
+	}
+
+	return context.String(), report
+}
+
+// fitPassages returns the leading passages of text that fit within budget tokens. When the
+// first passage alone is too long, as in a log or upload without paragraphs, its beginning is kept.
+func fitPassages(text string, budget int) string {
+	if budget <= 0 {
+		return ""
+	}
+
+	passages := SplitPassages(text)
+	separator := passageSeparator(text)
+	var kept []string
+	used := 0
+	for _, passage := range passages {
+		cost := EstimateTokens(passage) + 1
+		if used+cost > budget {
+			break
+		}
+		kept = append(kept, passage)
+		used += cost
+	}
+	if len(kept) == 0 && len(passages) > 0 {
+		return cutPassage(passages[0], budget-1)
+	}
+	return strings.Join(kept, separator)
+}
+
+// cutPassage returns the longest beginning of passage that fits within budget tokens, ending at
+// a word boundary when there is one and never inside a UTF-8 sequence
+func cutPassage(passage string, budget int) string {
+	if budget <= 0 {
+		return ""
+	}
+
+	runes := []rune(passage)
+	// Token estimates only grow as the text gets longer, so the longest fitting prefix can be searched for
+	length := sort.Search(len(runes)+1, func(n int) bool {
+		return EstimateTokens(string(runes[:n])) > budget
+	}) - 1
+	if length <= 0 {
+		return ""
+	}
+
+	cut := string(runes[:length])
+	if length < len(runes) && !unicode.IsSpace(runes[length]) {
+		if space := strings.LastIndexFunc(cut, unicode.IsSpace); space > 0 {
+			cut = cut[:space]
+		}
+	}
+	return strings.TrimRightFunc(cut, unicode.IsSpace)
+}
+
+// SplitPassages splits text into passages on blank lines, or on line breaks when
+// the text has no paragraph structure
+func SplitPassages(text string) []string {
+	var passages []string
+	for _, passage := range strings.Split(text, passageSeparator(text)) {
+		if strings.TrimSpace(passage) != "" {
+			passages = append(passages, passage)
+		}
+	}
+	return passages
+}
+
+// passageSeparator returns the separator used to split text into passages
+func passageSeparator(text string) string {
+	if strings.Contains(text, "\n\n") {
+		return "\n\n"
+	}
+	return "\n"
+}
@@ -0,0 +1,214 @@
+package prompt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"characters", "abcdefgh", 2},
+		{"rounds up", "abcde", 2},
+		{"words", "a b c", 4},
+		{"symbols", "E-1: {x}", 4},
+		{"runes not bytes", "ééééé", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.text); got != tt.want {
+				t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitPassages(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"one\n\ntwo\nlines\n\n\n", []string{"one", "two\nlines"}},
+		{"one\ntwo\n\nthree", []string{"one\ntwo", "three"}},
+		{"line one\nline two", []string{"line one", "line two"}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		if got := SplitPassages(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitPassages(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+// blockCost is the tokens an item costs when it is included whole as passage id
+func blockCost(id string, item ContextItem) int {
+	return EstimateTokens("[" + id + "] " + item.Header + item.Text + "\n\n")
+}
+
+func TestContextBuilderBuild(t *testing.T) {
+	errorCode := ContextItem{Priority: 1, Source: "Error Code E-12", Header: "Error E-12:\n", Text: "Channel over voltage."}
+	manual := ContextItem{Priority: 2, Score: 5, Source: "PDF: manual.pdf", Header: "From manual.pdf:\n",
+		Text: "Check the fuse on the rack.\n\nReplace the channel board.\n\nCall the vendor if it trips again."}
+	notes := ContextItem{Priority: 2, Score: 1, Source: "notes.txt", Header: "From notes.txt:\n", Text: "Rack 3 tripped twice last week."}
+
+	exact := blockCost("P1", errorCode) + blockCost("P2", manual) + blockCost("P3", notes)
+
+	tests := []struct {
+		name      string
+		budget    int
+		items     []ContextItem
+		included  []string // Sources in the order they were written
+		ids       []string
+		truncated []string
+		dropped   []string
+	}{
+		{
+			name:     "exact fit",
+			budget:   exact,
+			items:    []ContextItem{notes, manual, errorCode},
+			included: []string{"Error Code E-12", "PDF: manual.pdf", "notes.txt"},
+			ids:      []string{"P1", "P2", "P3"},
+		},
+		{
+			name:      "one token short cuts the last item",
+			budget:    exact - 1,
+			items:     []ContextItem{notes, manual, errorCode},
+			included:  []string{"Error Code E-12", "PDF: manual.pdf", "notes.txt"},
+			ids:       []string{"P1", "P2", "P3"},
+			truncated: []string{"notes.txt"},
+		},
+		{
+			name:     "no room for the header drops the item",
+			budget:   blockCost("P1", errorCode) + blockCost("P2", manual) + 3,
+			items:    []ContextItem{notes, manual, errorCode},
+			included: []string{"Error Code E-12", "PDF: manual.pdf"},
+			ids:      []string{"P1", "P2"},
+			dropped:  []string{"notes.txt"},
+		},
+		{
+			name:      "truncate keeps the leading passages",
+			budget:    blockCost("P1", errorCode) + blockCost("P2", manual) - 8,
+			items:     []ContextItem{manual, errorCode},
+			included:  []string{"Error Code E-12", "PDF: manual.pdf"},
+			ids:       []string{"P1", "P2"},
+			truncated: []string{"PDF: manual.pdf"},
+		},
+		{
+			name:     "dropped items do not use a passage ID",
+			budget:   blockCost("P1", errorCode) + blockCost("P2", notes),
+			items:    []ContextItem{errorCode, {Priority: 1, Source: "huge", Header: strings.Repeat("From huge.txt ", 50), Text: "word"}, notes},
+			included: []string{"Error Code E-12", "notes.txt"},
+			ids:      []string{"P1", "P2"},
+			dropped:  []string{"huge"},
+		},
+		{
+			name:    "no budget",
+			budget:  0,
+			items:   []ContextItem{errorCode},
+			dropped: []string{"Error Code E-12"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewContextBuilder()
+			for _, item := range tt.items {
+				builder.Add(item)
+			}
+			context, report := builder.Build(tt.budget)
+
+			var included, ids []string
+			for _, item := range report.Included {
+				included = append(included, item.Source)
+				ids = append(ids, item.ID)
+				if !strings.Contains(context, "["+item.ID+"] "+item.Header+item.Text) {
+					t.Errorf("%s is not written as [%s] in:\n%s", item.Source, item.ID, context)
+				}
+			}
+			if !reflect.DeepEqual(included, tt.included) || !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("included %v as %v, want %v as %v", included, ids, tt.included, tt.ids)
+			}
+			if !reflect.DeepEqual(report.Truncated, tt.truncated) || !reflect.DeepEqual(report.Dropped, tt.dropped) {
+				t.Errorf("truncated %v and dropped %v, want %v and %v", report.Truncated, report.Dropped, tt.truncated, tt.dropped)
+			}
+			if report.UsedTokens > tt.budget {
+				t.Errorf("used %d tokens of %d", report.UsedTokens, tt.budget)
+			}
+		})
+	}
+}
+
+func TestContextBuilderTruncatedText(t *testing.T) {
+	kept := ContextItem{Text: "Check the fuse on the rack.\n\nReplace the channel board."}
+	manual := ContextItem{Source: "manual.pdf", Text: kept.Text + "\n\n" + strings.Repeat("Call the vendor. ", 50)}
+	builder := NewContextBuilder()
+	builder.Add(manual)
+
+	context, report := builder.Build(blockCost("P1", kept) + 10)
+	if len(report.Included) != 1 || report.Included[0].Text != "Check the fuse on the rack.\n\nReplace the channel board." {
+		t.Fatalf("included %+v, want the first two passages", report.Included)
+	}
+	if !strings.HasSuffix(context, "Replace the channel board.\n[...truncated]\n\n") {
+		t.Errorf("the truncated item is not marked:\n%s", context)
+	}
+}
+
+func TestContextBuilderCutsLongPassage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string // Beginning of the text that is kept
+	}{
+		{"log line", strings.Repeat("2025-03-01 ch4 over voltage ", 200), "2025-03-01 ch4 over voltage 2025-03-01"},
+		{"one word", strings.Repeat("x", 4000), strings.Repeat("x", 40)},
+		{"multi-byte runes", strings.Repeat("é", 4000), strings.Repeat("é", 40)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upload := ContextItem{Source: "upload.log", Header: "From upload.log:\n", Text: tt.text}
+			builder := NewContextBuilder()
+			builder.Add(upload)
+
+			context, report := builder.Build(200)
+			if len(report.Included) != 1 || len(report.Dropped) != 0 {
+				t.Fatalf("included %d and dropped %v, want the upload cut to fit", len(report.Included), report.Dropped)
+			}
+			text := report.Included[0].Text
+			if !utf8.ValidString(text) || !strings.HasPrefix(text, tt.want) || len(text) >= len(tt.text) {
+				t.Errorf("kept %d of %d bytes: %q", len(text), len(tt.text), text)
+			}
+			if strings.HasSuffix(text, " ") || !strings.HasSuffix(context, text+"\n[...truncated]\n\n") {
+				t.Errorf("the cut text is not written as truncated:\n%s", context)
+			}
+			if !reflect.DeepEqual(report.Truncated, []string{"upload.log"}) || report.UsedTokens > 200 || report.UsedTokens < 150 {
+				t.Errorf("truncated %v using %d of 200 tokens", report.Truncated, report.UsedTokens)
+			}
+		})
+	}
+}
+
+func TestCutPassage(t *testing.T) {
+	tests := []struct {
+		passage string
+		budget  int
+		want    string
+	}{
+		{"Check the fuse on the rack", 5, "Check the fuse on"},
+		{"Check the fuse on the rack", 0, ""},
+		{"Check the fuse on the rack", 100, "Check the fuse on the rack"},
+		{"überprüfen Sie die Sicherung", 4, "überprüfen Sie"},
+		{"ch4:overvoltage", 2, "ch4:over"},
+		{"ch4:overvoltage", 1, "ch4:"},
+	}
+	for _, tt := range tests {
+		got := cutPassage(tt.passage, tt.budget)
+		if got != tt.want || EstimateTokens(got) > tt.budget {
+			t.Errorf("cutPassage(%q, %d) = %q (%d tokens), want %q", tt.passage, tt.budget, got, EstimateTokens(got), tt.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package prompt
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Template names, one per kind of question
+const (
+	TemplateTroubleshoot = "troubleshoot"
+	TemplateExplain      = "explain"
+	TemplateHowTo        = "howto"
+	TemplateSummarizeLog = "summarize_log"
+	TemplateErrorLookup  = "error_lookup"
+	TemplateMeetingNotes = "meeting_notes"
+	TemplateChitChat     = "chitchat"
+)
+
+// templateExt is the file extension of prompt templates
+const templateExt = ".tmpl"
+
+// versionPattern matches the version comment at the top of a template, e.g. {{/* version: 3 */}}
+var versionPattern = regexp.MustCompile(`\{\{-?\s*/\*\s*version:\s*([^\s*]+)\s*\*/\s*-?\}\}`)
+
+// templateFuncs are the helper functions available to templates
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+// Data holds the variables available to prompt templates
+type Data struct {
+	Question   string   // The user's question
+	Context    string   // Knowledge base context selected for the question
+	ErrorCodes []string // Error codes detected in the question or retrieved from the knowledge base
+	History    []string // Recent questions in this conversation, oldest first
+	LabName    string   // Name of the lab or site BeanBot is deployed for
+}
+
+// Template is a parsed prompt template
+type Template struct {
+	Name    string
+	Version string
+	Path    string // Empty for the built-in template
+	tmpl    *template.Template
+}
+
+// Rendered is a prompt produced from a template
+type Rendered struct {
+	Text     string
+	Template string
+	Version  string
+}
+
+// Label returns the template name and version for display, e.g. "troubleshoot v2"
+func (r *Rendered) Label() string {
+	return fmt.Sprintf("%s v%s", r.Template, r.Version)
+}
+
+// Library holds the prompt templates loaded from a directory
+type Library struct {
+	dir       string
+	mu        sync.RWMutex
+	templates map[string]*Template
+	builtin   *Template
+}
+
+// NewLibrary creates a template library for dir. Call Load to read the templates; until
+// then, and for any template that is missing, the built-in troubleshooting prompt is used.
+func NewLibrary(dir string) *Library {
+	return &Library{
+		dir:       dir,
+		templates: make(map[string]*Template),
+		builtin: &Template{
+			Name:    TemplateTroubleshoot,
+			Version: "builtin",
+			tmpl:    template.Must(template.New(TemplateTroubleshoot).Funcs(templateFuncs).Parse(builtinTemplate)),
+		},
+	}
+}
+
+// Dir returns the directory templates are loaded from
+func (l *Library) Dir() string {
+	return l.dir
+}
+
+// Load reads every template in the directory, replacing the loaded set only if all of
+// them parse. It can be called again at any time to pick up edited templates.
+func (l *Library) Load() error {
+	paths, err := filepath.Glob(filepath.Join(l.dir, "*"+templateExt))
+	if err != nil {
+		return fmt.Errorf("failed to list templates in %s: %w", l.dir, err)
+	}
+
+	loaded := make(map[string]*Template, len(paths))
+	for _, path := range paths {
+		tmpl, err := parseTemplateFile(path)
+		if err != nil {
+			return err
+		}
+		loaded[tmpl.Name] = tmpl
+	}
+
+	l.mu.Lock()
+	l.templates = loaded
+	l.mu.Unlock()
+
+	log.Printf("[DEBUG] Loaded %d prompt templates from %s", len(loaded), l.dir)
+	return nil
+}
+
+// Templates returns the loaded templates sorted by name
+func (l *Library) Templates() []*Template {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	list := make([]*Template, 0, len(l.templates))
+	for _, tmpl := range l.templates {
+		list = append(list, tmpl)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Render executes the named template, falling back to the troubleshooting template
+// and then the built-in prompt when it is not available
+func (l *Library) Render(name string, data Data) (*Rendered, error) {
+	tmpl := l.lookup(name)
+
+	var text strings.Builder
+	if err := tmpl.tmpl.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render prompt template %s: %w", tmpl.Name, err)
+	}
+
+	return &Rendered{
+		Text:     strings.TrimSpace(text.String()),
+		Template: tmpl.Name,
+		Version:  tmpl.Version,
+	}, nil
+}
+
+// lookup returns the template to use for name
+func (l *Library) lookup(name string) *Template {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if tmpl, ok := l.templates[name]; ok {
+		return tmpl
+	}
+	if tmpl, ok := l.templates[TemplateTroubleshoot]; ok {
+		return tmpl
+	}
+	return l.builtin
+}
+
+// parseTemplateFile parses a template file; the file name without extension is the template name
+func parseTemplateFile(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), templateExt)
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+
+	version := "0"
+	if match := versionPattern.FindStringSubmatch(string(data)); match != nil {
+		version = match[1]
+	}
+
+	return &Template{Name: name, Version: version, Path: path, tmpl: tmpl}, nil
+}
+
+// builtinTemplate is used when no template files are available
+const builtinTemplate = `You are BeanBot, an engineering support assistant. Analyze the user's issue and provide structured engineering guidance based on the provided knowledge base.
+
+User Issue: {{.Question}}
+
+Knowledge Base:
+{{.Context}}
+
+Provide structured engineering response in markdown format:
+
+## **1. PROBLEM ANALYSIS**
+[Identify the core issue: What is failing? What symptoms are described? What system/component is affected?]
+
+## **2. SOLUTION STEPS**
+- **Step 1:** [First diagnostic/corrective action]
+- **Step 2:** [Next action based on knowledge base]
+- **Step 3:** [Additional verification/fix step]
+
+## **3. IF PROBLEM PERSISTS**
+[Advanced troubleshooting or escalation steps]
+
+Each knowledge base entry starts with a passage ID such as [P1]. After every statement that uses the knowledge base, cite the passage IDs it came from in square brackets, e.g. "Check the cable connections [P2]." Only cite passage IDs that appear above, and leave general advice uncited.
+
+Important: Base your response on the knowledge base provided. If the knowledge base contains relevant information, reference it in your solution. Analyze the user's description carefully and provide specific, actionable engineering guidance. Use proper markdown formatting with **bold** text for emphasis.`
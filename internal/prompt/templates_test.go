@@ -0,0 +1,166 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTemplate writes a template file into dir
+func writeTemplate(t *testing.T, dir, name, text string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name+templateExt), []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// render renders the named template and fails the test on errors
+func render(t *testing.T, library *Library, name string, data Data) *Rendered {
+	t.Helper()
+	rendered, err := library.Render(name, data)
+	if err != nil {
+		t.Fatalf("Render(%s): %v", name, err)
+	}
+	return rendered
+}
+
+func TestParseTemplateVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "{{/* version: 3 */}}\nQ: {{.Question}}", "3"},
+		{"trim markers", "{{- /* version: 2.1 */ -}}\nQ: {{.Question}}", "2.1"},
+		{"no spaces", "{{/*version:7*/}}Q: {{.Question}}", "7"},
+		{"later in the file", "Q: {{.Question}}\n{{/* version: 4 */}}", "4"},
+		{"missing", "Q: {{.Question}}", "0"},
+		{"ordinary comment", "{{/* written by the lab */}}Q: {{.Question}}", "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTemplate(t, dir, TemplateHowTo, tt.text)
+			tmpl, err := parseTemplateFile(filepath.Join(dir, TemplateHowTo+templateExt))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tmpl.Name != TemplateHowTo || tmpl.Version != tt.want {
+				t.Errorf("parsed %s v%s, want %s v%s", tmpl.Name, tmpl.Version, TemplateHowTo, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderFallsBack(t *testing.T) {
+	data := Data{Question: "Why is E-101 shown?", LabName: "Bench 4", ErrorCodes: []string{"E-101", "E-102"}}
+
+	t.Run("builtin before loading", func(t *testing.T) {
+		rendered := render(t, NewLibrary(t.TempDir()), TemplateHowTo, data)
+		if rendered.Template != TemplateTroubleshoot || rendered.Version != "builtin" {
+			t.Errorf("rendered with %s, want the builtin troubleshooting template", rendered.Label())
+		}
+		if !strings.Contains(rendered.Text, "User Issue: Why is E-101 shown?") {
+			t.Errorf("builtin prompt does not contain the question:\n%s", rendered.Text)
+		}
+	})
+
+	t.Run("missing template uses troubleshoot", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTemplate(t, dir, TemplateTroubleshoot, "{{/* version: 5 */}}{{.LabName}}: {{join .ErrorCodes \", \"}}")
+		library := NewLibrary(dir)
+		if err := library.Load(); err != nil {
+			t.Fatal(err)
+		}
+		rendered := render(t, library, TemplateMeetingNotes, data)
+		if rendered.Label() != "troubleshoot v5" || rendered.Text != "Bench 4: E-101, E-102" {
+			t.Errorf("rendered %s %q", rendered.Label(), rendered.Text)
+		}
+	})
+
+	t.Run("empty directory uses builtin", func(t *testing.T) {
+		library := NewLibrary(t.TempDir())
+		if err := library.Load(); err != nil {
+			t.Fatal(err)
+		}
+		if rendered := render(t, library, TemplateExplain, data); rendered.Version != "builtin" {
+			t.Errorf("rendered with %s, want the builtin template", rendered.Label())
+		}
+	})
+
+	t.Run("broken file keeps the loaded set", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTemplate(t, dir, TemplateHowTo, "{{/* version: 1 */}}Steps for {{.Question}}")
+		library := NewLibrary(dir)
+		if err := library.Load(); err != nil {
+			t.Fatal(err)
+		}
+
+		writeTemplate(t, dir, TemplateExplain, "{{/* version: 1 */}}{{if .Question}}unclosed")
+		err := library.Load()
+		if err == nil || !strings.Contains(err.Error(), TemplateExplain+templateExt) {
+			t.Fatalf("Load() = %v, want a parse error naming the broken file", err)
+		}
+		if rendered := render(t, library, TemplateHowTo, data); rendered.Label() != "howto v1" {
+			t.Errorf("after a failed reload rendered with %s, want howto v1", rendered.Label())
+		}
+		if rendered := render(t, library, TemplateExplain, data); rendered.Version != "builtin" {
+			t.Errorf("broken template rendered with %s, want the builtin template", rendered.Label())
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		library := NewLibrary(filepath.Join(t.TempDir(), "missing"))
+		if err := library.Load(); err != nil {
+			t.Fatalf("Load() = %v, want the builtin template to be used", err)
+		}
+		if rendered := render(t, library, TemplateHowTo, data); rendered.Version != "builtin" {
+			t.Errorf("rendered with %s, want the builtin template", rendered.Label())
+		}
+	})
+}
+
+func TestReloadPicksUpEdits(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplate(t, dir, TemplateHowTo, "{{/* version: 1 */}}Old steps for {{.Question}}")
+	library := NewLibrary(dir)
+	if err := library.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTemplate(t, dir, TemplateHowTo, "{{/* version: 2 */}}\nNew steps for {{.Question}}\n")
+	writeTemplate(t, dir, TemplateChitChat, "{{/* version: 1 */}}Hello")
+	if rendered := render(t, library, TemplateHowTo, Data{Question: "calibration"}); rendered.Label() != "howto v1" {
+		t.Errorf("before reloading rendered with %s, want howto v1", rendered.Label())
+	}
+
+	if err := library.Load(); err != nil {
+		t.Fatal(err)
+	}
+	rendered := render(t, library, TemplateHowTo, Data{Question: "calibration"})
+	if rendered.Label() != "howto v2" || rendered.Text != "New steps for calibration" {
+		t.Errorf("after reloading rendered %s %q", rendered.Label(), rendered.Text)
+	}
+
+	var names []string
+	for _, tmpl := range library.Templates() {
+		names = append(names, tmpl.Name)
+	}
+	if strings.Join(names, ",") != "chitchat,howto" {
+		t.Errorf("Templates() = %v, want chitchat and howto", names)
+	}
+}
+
+func TestShippedTemplatesParse(t *testing.T) {
+	library := NewLibrary(filepath.Join("..", "..", "prompts"))
+	if err := library.Load(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{TemplateTroubleshoot, TemplateExplain, TemplateHowTo, TemplateSummarizeLog, TemplateErrorLookup, TemplateMeetingNotes, TemplateChitChat} {
+		rendered := render(t, library, name, Data{Question: "E-101 on bench 4", Context: "[P1] Reseat the cable.", History: []string{"earlier"}})
+		if rendered.Template != name || rendered.Version == "0" {
+			t.Errorf("%s rendered with %s, want its own versioned template", name, rendered.Label())
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// maxTitleLength is the length of a title made from a session's first question
+const maxTitleLength = 60
+
+// Attachment is a file uploaded during a session
+type Attachment struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`             // Original location, used to upload the file again when the session is reopened
+	Stored     string    `json:"stored,omitempty"` // Copy kept with the saved session, if uploads are kept
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// Source returns the file to upload again when the session is reopened, preferring the copy
+// kept with the session
+func (a Attachment) Source() string {
+	if a.Stored != "" {
+		if _, err := os.Stat(a.Stored); err == nil {
+			return a.Stored
+		}
+	}
+	return a.Path
+}
+
+// Reference is a knowledge source supplied to the model for an answer
+type Reference struct {
+	Passage    string `json:"passage,omitempty"` // Passage ID the model cited it by, e.g. "P1"
+	Source     string `json:"source"`
+	DocumentID string `json:"document_id,omitempty"`
+	Path       string `json:"path,omitempty"`     // Location of the document the excerpt came from, if any
+	Footnote   int    `json:"footnote,omitempty"` // Citation number in the answer; 0 when it was not cited
+	Excerpt    string `json:"excerpt,omitempty"`  // Text sent to the model
+}
+
+// Cited reports whether the answer cited the reference
+func (r Reference) Cited() bool {
+	return r.Footnote > 0
+}
+
+// Turn is one question and the answer shown for it
+type Turn struct {
+	Question    string                    `json:"question"`
+	Answer      string                    `json:"answer"`         // Markdown as displayed, including the sources section
+	Body        string                    `json:"body,omitempty"` // The answer alone, without the sources and context sections
+	Sources     []string                  `json:"sources,omitempty"`
+	References  []Reference               `json:"references,omitempty"`
+	ErrorCodes  []string                  `json:"error_codes,omitempty"` // Error codes detected in the question and retrieved for the answer
+	Attachments []string                  `json:"attachments,omitempty"` // Names of the uploads available when the question was asked
+	Intent      string                    `json:"intent,omitempty"`
+	Model       string                    `json:"model,omitempty"`
+	Options     *models.GenerationOptions `json:"generation_options,omitempty"` // Effective options the answer was generated with; nil for offline answers
+	AskedAt     time.Time                 `json:"asked_at"`
+	AnsweredAt  time.Time                 `json:"answered_at"`
+}
+
+// Reply returns the answer without its sources section; sessions saved before the answer was
+// kept separately only have the displayed markdown
+func (t Turn) Reply() string {
+	if t.Body != "" {
+		return t.Body
+	}
+	return t.Answer
+}
+
+// Session is a saved conversation
+type Session struct {
+	ID          string       `json:"id"`
+	Title       string       `json:"title"`
+	Profile     string       `json:"profile,omitempty"` // Knowledge profile the session was started with
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	Turns       []Turn       `json:"turns"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+// New creates an empty session for a knowledge profile
+func New(profile string) *Session {
+	now := time.Now()
+	return &Session{
+		ID:        fmt.Sprintf("s-%d", now.UnixNano()),
+		Profile:   profile,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// AddTurn appends a turn, titling the session after its first question
+func (s *Session) AddTurn(turn Turn) {
+	s.Turns = append(s.Turns, turn)
+	s.UpdatedAt = time.Now()
+	if s.Title == "" {
+		s.Title = TitleFor(turn.Question)
+	}
+}
+
+// AddAttachment records an uploaded file, replacing an earlier upload with the same name
+func (s *Session) AddAttachment(name, path string) {
+	for i, attachment := range s.Attachments {
+		if attachment.Name == name {
+			s.Attachments = append(s.Attachments[:i], s.Attachments[i+1:]...)
+			break
+		}
+	}
+	s.Attachments = append(s.Attachments, Attachment{Name: name, Path: path, UploadedAt: time.Now()})
+	s.UpdatedAt = time.Now()
+}
+
+// RemoveAttachment forgets an uploaded file, returning it so a kept copy can be deleted
+func (s *Session) RemoveAttachment(name string) (Attachment, bool) {
+	for i, attachment := range s.Attachments {
+		if attachment.Name == name {
+			s.Attachments = append(s.Attachments[:i], s.Attachments[i+1:]...)
+			s.UpdatedAt = time.Now()
+			return attachment, true
+		}
+	}
+	return Attachment{}, false
+}
+
+// Matches reports whether the title, a question or an answer contains the query, ignoring case
+func (s *Session) Matches(query string) bool {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" || strings.Contains(strings.ToLower(s.Title), query) {
+		return true
+	}
+	for _, turn := range s.Turns {
+		if strings.Contains(strings.ToLower(turn.Question), query) || strings.Contains(strings.ToLower(turn.Answer), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// TitleFor makes a session title from a question
+func TitleFor(question string) string {
+	title := strings.Join(strings.Fields(question), " ")
+	if utf8.RuneCountInString(title) <= maxTitleLength {
+		return title
+	}
+	runes := []rune(title)
+	cut := string(runes[:maxTitleLength])
+	if idx := strings.LastIndex(cut, " "); idx > maxTitleLength/2 {
+		cut = cut[:idx]
+	}
+	return cut + "..."
+}
+
+// Store keeps each session in its own JSON file in a directory
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewStore creates a session store backed by the directory at dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the location of the session files
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// List returns every saved session, most recently updated first. Files that cannot be read
+// are skipped and reported in the returned error alongside the sessions that could be.
+func (s *Store) List() ([]Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", s.dir, err)
+	}
+
+	var sessions []Session
+	var failed []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		session, err := s.read(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		sessions = append(sessions, *session)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt) })
+
+	if len(failed) > 0 {
+		return sessions, fmt.Errorf("skipped unreadable sessions: %s", strings.Join(failed, "; "))
+	}
+	return sessions, nil
+}
+
+// Load reads the session with the given ID
+func (s *Store) Load(id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(s.path(id))
+}
+
+// Save writes a session, replacing the previous file atomically
+func (s *Store) Save(session *Session) error {
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal session %s: %w", session.ID, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+	path := s.path(session.ID)
+	tmp, err := os.CreateTemp(s.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
+
+// Rename changes the title of a saved session
+func (s *Store) Rename(id, title string) error {
+	title = strings.TrimSpace(title)
+	if title == "" {
+		return fmt.Errorf("session title cannot be empty")
+	}
+	session, err := s.Load(id)
+	if err != nil {
+		return err
+	}
+	session.Title = title
+	return s.Save(session)
+}
+
+// Delete removes a saved session and the uploads kept with it; deleting a session that was
+// never saved is not an error
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete session %s: %w", id, err)
+	}
+	if err := os.RemoveAll(s.uploadDir(id)); err != nil {
+		return fmt.Errorf("failed to delete the uploads of session %s: %w", id, err)
+	}
+	return nil
+}
+
+// KeepUploads copies a session's uploaded files that have no copy yet next to the session file,
+// so the session can be reopened after the originals are moved or deleted. Files that cannot be
+// copied keep only their original location and are reported in the returned error.
+func (s *Store) KeepUploads(session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var failed []string
+	for i, attachment := range session.Attachments {
+		if attachment.Stored != "" {
+			continue
+		}
+		stored := filepath.Join(s.uploadDir(session.ID), filepath.Base(attachment.Name))
+		if err := copyFile(attachment.Path, stored); err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		session.Attachments[i].Stored = stored
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not keep uploads: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// DeleteUpload removes the copy of an upload kept with a session, if there is one. Only files
+// in a session's uploads directory are removed.
+func (s *Store) DeleteUpload(attachment Attachment) error {
+	uploads := filepath.Dir(attachment.Stored)
+	if attachment.Stored == "" || filepath.Dir(uploads) != filepath.Clean(s.dir) || filepath.Ext(uploads) != ".uploads" {
+		return nil
+	}
+	if err := os.Remove(attachment.Stored); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete %s: %w", attachment.Stored, err)
+	}
+	return nil
+}
+
+// path returns the file of a session, keeping IDs from escaping the directory
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, filepath.Base(id)+".json")
+}
+
+// uploadDir returns the directory holding the uploads kept with a session
+func (s *Store) uploadDir(id string) string {
+	return filepath.Join(s.dir, filepath.Base(id)+".uploads")
+}
+
+// copyFile copies a file, creating the destination directory
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dst), err)
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return nil
+}
+
+// read parses a session file
+func (s *Store) read(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &session, nil
+}
@@ -0,0 +1,206 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// writeUpload writes a file to upload and returns its path
+func writeUpload(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStoreSaveLoad(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), "sessions"))
+	session := New("default")
+	temperature, numCtx := 0.2, 4096
+	session.AddTurn(Turn{
+		Question:   "What does error E-12 mean?",
+		Answer:     "Over voltage [1].",
+		References: []Reference{{Passage: "P1", Source: "Error Code E-12", Footnote: 1}},
+		Model:      "llama3.2:1b",
+		Options:    &models.GenerationOptions{Temperature: &temperature, NumCtx: &numCtx},
+		AskedAt:    time.Date(2021, 3, 2, 9, 5, 0, 0, time.UTC),
+	})
+
+	if err := store.Save(session); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := store.Load(session.ID)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if loaded.Title != "What does error E-12 mean?" || !reflect.DeepEqual(loaded.Turns, session.Turns) || !loaded.CreatedAt.Equal(session.CreatedAt) {
+		t.Errorf("Load() = %+v, want %+v", loaded, session)
+	}
+
+	// Saving again replaces the file without leaving temporary files behind
+	session.AddTurn(Turn{Question: "And E-13?"})
+	if err := store.Save(session); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	entries, _ := os.ReadDir(store.Dir())
+	if len(entries) != 1 || entries[0].Name() != session.ID+".json" {
+		t.Errorf("the session directory holds %v, want only %s.json", entries, session.ID)
+	}
+	if loaded, _ := store.Load(session.ID); loaded == nil || len(loaded.Turns) != 2 {
+		t.Errorf("the second save was not written")
+	}
+}
+
+func TestStoreList(t *testing.T) {
+	store := NewStore(t.TempDir())
+	if sessions, err := NewStore(filepath.Join(store.Dir(), "missing")).List(); sessions != nil || err != nil {
+		t.Errorf("a missing directory listed %v, %v", sessions, err)
+	}
+
+	older, newer := New("default"), New("default")
+	older.ID, newer.ID = "s-1", "s-2"
+	older.UpdatedAt = newer.UpdatedAt.Add(-time.Hour)
+	for _, session := range []*Session{older, newer} {
+		if err := store.Save(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(store.Dir(), "broken.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := store.List()
+	if err == nil || !strings.Contains(err.Error(), "broken.json") {
+		t.Errorf("List() error = %v, want the unreadable file reported", err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != "s-2" || sessions[1].ID != "s-1" {
+		t.Errorf("List() = %v, want s-2 then s-1", sessions)
+	}
+}
+
+func TestStoreRename(t *testing.T) {
+	store := NewStore(t.TempDir())
+	session := New("default")
+	if err := store.Save(session); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Rename(session.ID, "  Rack 3 fuse  "); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if loaded, _ := store.Load(session.ID); loaded.Title != "Rack 3 fuse" {
+		t.Errorf("title = %q after renaming", loaded.Title)
+	}
+	if err := store.Rename(session.ID, " "); err == nil {
+		t.Error("an empty title was accepted")
+	}
+	if err := store.Rename("s-missing", "Title"); err == nil {
+		t.Error("renaming a session that was never saved succeeded")
+	}
+}
+
+func TestStoreKeepAndDeleteUploads(t *testing.T) {
+	store := NewStore(t.TempDir())
+	session := New("default")
+	session.AddAttachment("app.log", writeUpload(t, "app.log", "10:00:00 ERROR E-12"))
+	session.AddAttachment("gone.txt", filepath.Join(t.TempDir(), "gone.txt"))
+
+	err := store.KeepUploads(session)
+	if err == nil || !strings.Contains(err.Error(), "gone.txt") {
+		t.Errorf("KeepUploads() error = %v, want the missing file reported", err)
+	}
+	kept := session.Attachments[0]
+	if want := filepath.Join(store.Dir(), session.ID+".uploads", "app.log"); kept.Stored != want || kept.Source() != want {
+		t.Fatalf("app.log stored at %q, want %q", kept.Stored, want)
+	}
+	if data, _ := os.ReadFile(kept.Stored); string(data) != "10:00:00 ERROR E-12" {
+		t.Errorf("the kept copy holds %q", data)
+	}
+	if session.Attachments[1].Stored != "" || session.Attachments[1].Source() != session.Attachments[1].Path {
+		t.Errorf("a file that could not be copied has a stored copy: %+v", session.Attachments[1])
+	}
+
+	// Files outside a session's uploads directory are never deleted
+	outside := writeUpload(t, "notes.txt", "keep")
+	other := filepath.Join(store.Dir(), "other", "notes.txt")
+	if err := os.MkdirAll(filepath.Dir(other), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, stored := range []string{outside, other} {
+		if err := store.DeleteUpload(Attachment{Name: "notes.txt", Stored: stored}); err != nil {
+			t.Errorf("DeleteUpload(%s): %v", stored, err)
+		}
+		if _, err := os.Stat(stored); err != nil {
+			t.Errorf("DeleteUpload removed %s", stored)
+		}
+	}
+
+	if err := store.DeleteUpload(kept); err != nil {
+		t.Fatalf("DeleteUpload: %v", err)
+	}
+	if _, err := os.Stat(kept.Stored); !os.IsNotExist(err) {
+		t.Errorf("the kept copy still exists: %v", err)
+	}
+	if err := store.DeleteUpload(kept); err != nil {
+		t.Errorf("deleting a copy twice failed: %v", err)
+	}
+}
+
+func TestStoreDelete(t *testing.T) {
+	store := NewStore(t.TempDir())
+	session := New("default")
+	session.AddAttachment("app.log", writeUpload(t, "app.log", "log"))
+	if err := store.KeepUploads(session); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(session); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Delete(session.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if entries, _ := os.ReadDir(store.Dir()); len(entries) != 0 {
+		t.Errorf("Delete left %v", entries)
+	}
+	if err := store.Delete("s-never-saved"); err != nil {
+		t.Errorf("deleting an unsaved session failed: %v", err)
+	}
+
+	// IDs cannot reach outside the store
+	outside := writeUpload(t, "victim.json", "{}")
+	if err := store.Delete("../" + strings.TrimSuffix(filepath.Base(outside), ".json")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("Delete removed a file outside the store: %v", err)
+	}
+}
+
+func TestTitleFor(t *testing.T) {
+	long := strings.Repeat("channel ", 10)
+	tests := []struct {
+		question string
+		want     string
+	}{
+		{"  What does\nE-12 mean? ", "What does E-12 mean?"},
+		{long, strings.TrimSpace(strings.Repeat("channel ", 7)) + "..."},
+		{strings.Repeat("é", 70), strings.Repeat("é", maxTitleLength) + "..."},
+	}
+	for _, tt := range tests {
+		if got := TitleFor(tt.question); got != tt.want {
+			t.Errorf("TitleFor(%q) = %q, want %q", tt.question, got, tt.want)
+		}
+	}
+}
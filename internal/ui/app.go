@@ -1,18 +1,28 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"log"
-	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 
+	"github.com/beanspout/2025-beanbot/internal/config"
+	"github.com/beanspout/2025-beanbot/internal/feedback"
+	"github.com/beanspout/2025-beanbot/internal/grounding"
+	"github.com/beanspout/2025-beanbot/internal/intent"
 	"github.com/beanspout/2025-beanbot/internal/knowledge"
+	"github.com/beanspout/2025-beanbot/internal/models"
 	"github.com/beanspout/2025-beanbot/internal/ollama"
+	"github.com/beanspout/2025-beanbot/internal/prompt"
+	"github.com/beanspout/2025-beanbot/internal/session"
 )
 
 // BeanBot represents the main application UI structure
@@ -20,33 +30,79 @@ type BeanBot struct {
 	app             fyne.App
 	window          fyne.Window
 	knowledgeDB     *knowledge.KnowledgeDatabase
+	knowledgeMu     sync.RWMutex // Guards knowledgeDB, which a profile switch replaces
 	ollamaClient    *ollama.Client
+	config          *config.Config
+	templates       *prompt.Library
+	classifier      *intent.Classifier
 	submitBtn       *widget.Button
 	statusLabel     *widget.Label     // Add reference to status label for updates
 	modelSelect     *widget.Select    // Add reference to model dropdown
+	profileSelect   *widget.Select    // Knowledge profile dropdown
 	debugMode       bool              // Debug mode flag
 	scrollContainer *container.Scroll // Add reference to scroll container
+	history         []string          // Recent questions passed to prompt templates
+
+	sessions      *session.Store    // Saved conversations
+	session       *session.Session  // Conversation shown in the transcript
+	sessionItems  []session.Session // Saved conversations listed in the sidebar
+	sessionList   *widget.List
+	sessionSearch *widget.Entry
+	transcript    *fyne.Container // Questions and answers of the current conversation
+	uploadBar     *fyne.Container // Uploaded files above the question box
+	uploadChips   *fyne.Container
+
+	feedbackStore   *feedback.Store
+	proposals       *knowledge.ProposalQueue
+	reviewBtn       *widget.Button
+	feedbackBar     *fyne.Container
+	feedbackLabel   *widget.Label
+	feedbackUpBtn   *widget.Button
+	feedbackDownBtn *widget.Button
+	guideBtn        *widget.Button  // Starts guided troubleshooting for the answer's error code
+	lastAnswer      *feedback.Entry // Answer the feedback bar refers to
 }
 
+// maxHistory is the number of recent questions included in prompts
+const maxHistory = 3
+
+// chitChatReply is shown for conversational messages when no model is available
+const chitChatReply = "I'm BeanBot, specifically designed for engineering support. I can help with engineering errors, system issues, device problems, how-to procedures, log analysis and meeting notes from the knowledge base."
+
 // NewBeanBot creates a new BeanBot UI instance with all required dependencies
-func NewBeanBot(app fyne.App, window fyne.Window, kb *knowledge.KnowledgeDatabase, client *ollama.Client) *BeanBot {
-	return &BeanBot{
-		app:          app,
-		window:       window,
-		knowledgeDB:  kb,
-		ollamaClient: client,
+func NewBeanBot(app fyne.App, window fyne.Window, kb *knowledge.KnowledgeDatabase, client *ollama.Client, templates *prompt.Library, cfg *config.Config) *BeanBot {
+	bot := &BeanBot{
+		app:           app,
+		window:        window,
+		knowledgeDB:   kb,
+		ollamaClient:  client,
+		templates:     templates,
+		classifier:    intent.NewClassifier(),
+		feedbackStore: feedback.NewStore(cfg.Feedback.File),
+		proposals:     knowledge.NewProposalQueue(cfg.Feedback.ProposalsFile),
+		sessions:      session.NewStore(cfg.Sessions.Directory),
+		session:       session.New(kb.Profile().Name),
+		config:        cfg,
+	}
+	if cfg.Intent.UseModel {
+		bot.classifier.SetModel(bot.askModel, cfg.Intent.ModelThreshold)
 	}
+	return bot
 }
 
 // SetupUI sets up the main UI
 func (b *BeanBot) SetupUI() {
+	// Saved conversations on the left, the current conversation on the right
+	split := container.NewHSplit(b.createSessionSidebar(), b.createMainContent())
+	split.Offset = 0.25
+
 	// Create main layout without header since it's redundant
 	content := container.NewBorder(
-		nil,                   // No header - window title is sufficient
-		b.createFooter(),      // Footer with cute status
-		nil,                   // No left sidebar
-		nil,                   // No right sidebar
-		b.createMainContent(), // Main content area
+		nil,              // No header - window title is sufficient
+		b.createFooter(), // Footer with cute status
+		nil,              // Sessions sidebar is part of the split
+		nil,              // No right sidebar
+		split,            // Main content area
 	)
 
 	b.window.SetContent(content)
@@ -87,18 +143,8 @@ func (b *BeanBot) createFooter() *fyne.Container {
 				// Update dropdown options to show new current model
 				go func() {
 					models, err := b.ollamaClient.GetAvailableModels()
-					if err == nil && b.modelSelect != nil {
-						var options []string
-						for _, model := range models {
-							if model == modelName {
-								options = append(options, fmt.Sprintf("%s (current)", model))
-							} else {
-								options = append(options, model)
-							}
-						}
-						b.modelSelect.Options = options
-						b.modelSelect.SetSelected(fmt.Sprintf("%s (current)", modelName))
-						b.modelSelect.Refresh()
+					if err == nil {
+						b.updateModelOptions(models, modelName)
 					}
 				}()
 			}
@@ -110,70 +156,80 @@ func (b *BeanBot) createFooter() *fyne.Container {
 	// Store reference to model dropdown
 	b.modelSelect = modelSelect
 
+	// Button to open the model manager for downloading, deleting and inspecting models
+	modelsBtn := widget.NewButton("Models", b.showModelManager)
+
+	// Button to open the generation settings panel
+	settingsBtn := widget.NewButton("Settings", b.showSettings)
+
+	// Button to open the error code and common issue editor
+	knowledgeBtn := widget.NewButton("Knowledge", b.showKnowledgeEditor)
+
+	// Button to browse and search the documents of the active profile
+	documentsBtn := widget.NewButton("Documents", b.showDocumentBrowser)
+
+	// Button to review knowledge base fixes proposed from resolved answers
+	b.reviewBtn = widget.NewButton("Review", b.showReviewQueue)
+	b.refreshReviewButton()
+
 	// Create a horizontal container with status and dropdown
 	statusContainer := container.NewHBox(
 		status,
 		widget.NewLabel(" | "), // Separator
 		modelSelect,
+		b.createProfileSelect(),
+		modelsBtn,
+		settingsBtn,
+		knowledgeBtn,
+		documentsBtn,
+		b.reviewBtn,
 	)
 
 	// Test Ollama connection and populate model dropdown
 	go func() {
 		b.debugLog("Testing Ollama connection...")
-		if b.ollamaClient.TestConnection() {
-			b.debugLog("Ollama connection successful, searching for available models")
-
-			// Get all available models
-			models, err := b.ollamaClient.GetAvailableModels()
-			if err != nil {
-				b.debugLog("Failed to get available models: %v", err)
+		models, err := b.ollamaClient.GetAvailableModels()
+		if err != nil {
+			b.debugLog("Ollama health check failed: %v", err)
+			if errors.Is(err, ollama.ErrServerUnreachable) || errors.Is(err, ollama.ErrTimeout) {
+				modelSelect.Options = []string{"Ollama server offline - start with: ollama serve"}
+				modelSelect.Refresh()
+				status.SetText("🤖 BeanBot AI ❌ offline")
+			} else {
 				modelSelect.Options = []string{"Error loading models"}
 				modelSelect.Refresh()
 				status.SetText("🤖 BeanBot AI ❌ error loading models")
-				return
 			}
+			return
+		}
 
-			if len(models) > 0 {
-				// Try to find the best available model
-				available, preferredModel := b.ollamaClient.FindAvailableModel()
-				if available {
-					b.debugLog("Found preferred model: %s", preferredModel)
-					// Set the preferred model as current
-					b.ollamaClient.SetModel(preferredModel)
-					b.debugLog("Set active model to: %s", preferredModel)
-
-					// Populate dropdown with all available models - cleaner format
-					var options []string
-					for _, model := range models {
-						if model == preferredModel {
-							options = append(options, fmt.Sprintf("%s (current)", model))
-						} else {
-							options = append(options, model)
-						}
-					}
-					modelSelect.Options = options
-					modelSelect.SetSelected(fmt.Sprintf("%s (current)", preferredModel))
-					modelSelect.Refresh()
-
-					status.SetText(fmt.Sprintf("🤖 BeanBot AI - %s ✅ ready to help!", preferredModel))
-				} else {
-					b.debugLog("No working models found")
-					modelSelect.Options = []string{"No working models available"}
-					modelSelect.Refresh()
-					status.SetText("🤖 BeanBot AI ❌ no working models")
-				}
-			} else {
-				b.debugLog("No models found")
-				modelSelect.Options = []string{"No models installed - run: ollama pull llama3.2:1b"}
-				modelSelect.Refresh()
-				status.SetText("🤖 BeanBot AI ❌ no models found - install with: ollama pull llama3.2:1b")
-			}
-		} else {
-			b.debugLog("Ollama connection failed - server offline")
-			modelSelect.Options = []string{"Ollama server offline - start with: ollama serve"}
+		if len(models) == 0 {
+			b.debugLog("No models found")
+			modelSelect.Options = []string{"No models installed - download one from Models"}
 			modelSelect.Refresh()
-			status.SetText("🤖 BeanBot AI ❌ offline")
+			status.SetText("🤖 BeanBot AI ❌ no models found - download one with the Models button")
+			return
+		}
+
+		// Resolve the configured model against the fallback policy and report any substitution
+		configuredModel := b.ollamaClient.GetCurrentModel()
+		resolvedModel, skipped, err := b.ollamaClient.ResolveModel()
+		if err != nil {
+			b.debugLog("No configured model is installed: %v", err)
+			b.updateModelOptions(models, "")
+			status.SetText(fmt.Sprintf("🤖 BeanBot AI ⚠️ %s not installed - pick a model", configuredModel))
+			return
+		}
+
+		if resolvedModel != configuredModel {
+			b.debugLog("Configured model %s unavailable (%d skipped), selecting fallback %s", configuredModel, len(skipped), resolvedModel)
+			b.ollamaClient.SetModel(resolvedModel)
+			status.SetText(fmt.Sprintf("🤖 BeanBot AI - %s ⚠️ (%s not installed)", resolvedModel, configuredModel))
+		} else {
+			status.SetText(fmt.Sprintf("🤖 BeanBot AI - %s ✅ ready to help!", resolvedModel))
 		}
+		b.debugLog("Set active model to: %s", resolvedModel)
+		b.updateModelOptions(models, resolvedModel)
 	}()
 
 	return container.NewVBox(
@@ -190,34 +246,27 @@ func (b *BeanBot) createMainContent() fyne.CanvasObject {
 	inputEntry.Wrapping = fyne.TextWrapWord   // Enable word wrapping for input too
 	inputEntry.Resize(fyne.NewSize(800, 100)) // Set a reasonable height for input
 
-	// Create response area using RichText with Border Layout Pattern (no white space)
-	responseText := widget.NewRichTextFromMarkdown("\n\n\n\n## 🤖 Hi there! \n\n### What engineering challenge can I help you with today? 💭")
-	responseText.Wrapping = fyne.TextWrapWord
+	// The transcript holds every question and answer of the conversation
+	b.transcript = container.NewVBox()
+	b.appendMessage(welcomeMessage)
 
-	// Create submit button with handler for RichText
+	// Create submit button; the question moves into the transcript
 	submitBtn := widget.NewButton("Ask", func() {
-		b.handleEngineeringRequest(inputEntry.Text, responseText)
+		question := inputEntry.Text
+		if b.handleEngineeringRequest(question) {
+			inputEntry.SetText("")
+		}
 	})
 	submitBtn.Importance = widget.HighImportance
 
-	// Create clear button to reset everything
-	clearBtn := widget.NewButton("Clear", func() {
-		// Clear the input field
+	// Create new chat button to start a fresh conversation; the current one stays saved
+	clearBtn := widget.NewButton("New Chat", func() {
 		inputEntry.SetText("")
-		// Clear user uploads
-		b.knowledgeDB.ClearUserUploads()
-		// Reset response area to welcome message
-		responseText.ParseMarkdown("\n\n\n\n## 🤖 Hi there! \n\n### What engineering challenge can I help you with today? 💭")
-		// Scroll to top when clearing
-		if b.scrollContainer != nil {
-			b.scrollContainer.ScrollToTop()
-		}
+		b.newSession()
 	})
 
 	// Create upload button to add user files
-	uploadBtn := widget.NewButton("Upload Files", func() {
-		b.handleFileUpload(responseText)
-	})
+	uploadBtn := widget.NewButton("Upload Files", b.handleFileUpload)
 	uploadBtn.Importance = widget.MediumImportance
 
 	// Store reference to button for progress handling
@@ -226,16 +275,15 @@ func (b *BeanBot) createMainContent() fyne.CanvasObject {
 	// Fixed content for bottom section (input area) - clean chat-style layout with three buttons
 	buttonContainer := container.NewGridWithColumns(3, submitBtn, uploadBtn, clearBtn)
 	bottomSection := container.NewVBox(
+		b.createUploadBar(),
 		inputEntry,
 		buttonContainer,
 	)
 
 	// Apply Border Layout Pattern to eliminate "big box" scroll container issue
 	// Following the proven pattern: fixed content in bottom, scrollable content in center
-	// Use border layout with spacers to center content horizontally
-	leftSpacer := container.NewWithoutLayout()
-	rightSpacer := container.NewWithoutLayout()
-	centeredContent := container.NewBorder(nil, nil, leftSpacer, rightSpacer, responseText)
+	// The feedback bar follows the latest answer at the end of the transcript
+	centeredContent := container.NewBorder(nil, b.createFeedbackBar(), nil, nil, b.transcript)
 
 	// Create scroll container and store reference for programmatic scrolling
 	scrollContainer := container.NewScroll(centeredContent)
@@ -246,36 +294,40 @@ func (b *BeanBot) createMainContent() fyne.CanvasObject {
 		bottomSection,   // Bottom - fixed size input area (chat-style)
 		nil,             // Left - not needed
 		nil,             // Right - not needed
-		scrollContainer, // Center - scrollable centered RichText (takes remaining space)
+		scrollContainer, // Center - scrollable transcript (takes remaining space)
 	)
 
 	return mainContainer
 }
 
-// handleEngineeringRequest handles the engineering support request
-func (b *BeanBot) handleEngineeringRequest(userInput string, responseEntry *widget.RichText) {
+// handleEngineeringRequest adds the question to the transcript and answers it in the background.
+// It reports whether the question was accepted.
+func (b *BeanBot) handleEngineeringRequest(userInput string) bool {
 	if strings.TrimSpace(userInput) == "" {
-		emptyResponse := "Please describe your engineering issue to get started."
-		emptyResponse += "\n\n---\n\n## **📚 Sources Referenced:**\n\n"
-		emptyResponse += "- *No documents from testData were referenced because no query was provided.*\n"
-		responseEntry.ParseMarkdown(emptyResponse)
-		return
+		b.appendMessage("*Please describe your engineering issue to get started.*")
+		return false
 	}
 
 	b.debugLog("Handling engineering request: %s", userInput)
 	b.debugLog("Current model: %s", b.ollamaClient.GetCurrentModel())
 
-	// Scroll to top when Ask is pressed
-	if b.scrollContainer != nil {
-		b.scrollContainer.ScrollToTop()
-		b.debugLog("Scrolled to top of response area")
+	// The answer is recorded in the conversation that was open when the question was asked
+	// The whole answer uses the knowledge base that was active when the question was asked,
+	// even if the profile is switched while it is being answered
+	kb := b.activeKnowledge()
+	current := b.session
+	turn := session.Turn{Question: strings.TrimSpace(userInput), AskedAt: time.Now()}
+	for _, upload := range kb.UserUploads() {
+		turn.Attachments = append(turn.Attachments, upload.Name)
 	}
 
 	// Show progress by changing button text
 	originalText := b.submitBtn.Text
 	b.submitBtn.SetText("Processing...")
 	b.submitBtn.Disable()
-	responseEntry.ParseMarkdown("\n\n\n\n## 🔍 Looking into this for you... \n\n### ✨ Just a moment! ✨")
+	b.hideFeedbackBar()
+	b.appendQuestion(userInput)
+	responseEntry := b.appendMessage("## 🔍 Looking into this for you... \n\n### ✨ Just a moment! ✨")
 
 	go func() {
 		defer func() {
@@ -284,101 +336,223 @@ func (b *BeanBot) handleEngineeringRequest(userInput string, responseEntry *widg
 			b.submitBtn.Enable()
 		}()
 
+		// Route the question to the retrieval strategy and prompt template for its intent
+		classification := b.classifyQuestion(kb, userInput)
+		templateName := templateFor(classification.Intent, userInput)
+		b.debugLog("Intent: %s, signals: %v, template: %s", classification, classification.Reasons, templateName)
+
 		b.debugLog("Building engineering context...")
 		// Build context from knowledge database
-		context, sources := b.buildEngineeringContext(userInput)
+		context, sources, retrieved := b.buildEngineeringContext(kb, userInput, classification.Intent, b.contextBudget(kb, userInput, templateName))
 		b.debugLog("Context length: %d characters", len(context))
 		b.debugLog("Referenced %d source documents", len(sources))
 
-		// Create prompt for Ollama
-		prompt := b.createEngineeringPrompt(userInput, context)
-		b.debugLog("Prompt length: %d characters", len(prompt))
-
-		// Check if this is a direct response (not a prompt for Ollama)
-		if strings.Contains(context, "outside my technical troubleshooting expertise") {
-			b.debugLog("Using direct response (outside expertise)")
-			// Always add source information even for direct responses
-			context += "\n\n---\n\n## **📚 Sources Referenced:**\n\n"
-			if len(sources) > 0 {
-				for _, source := range sources {
-					context += fmt.Sprintf("- %s\n", source)
-				}
-			} else {
-				context += "- *No relevant documents from testData were found for this query. This response indicates the question is outside the scope of available technical documentation.*\n"
-			}
-			responseEntry.ParseMarkdown(context)
+		// Create prompt for Ollama from the template for this kind of question
+		rendered, err := b.renderPrompt(kb, templateName, userInput, context, retrieved)
+		if err != nil {
+			b.debugLog("Failed to render prompt: %v", err)
+			responseEntry.ParseMarkdown(fmt.Sprintf("## **❌ Prompt template error**\n\n%v\n\nFix the template in %s and reload it from Settings.", err, b.templates.Dir()))
 			return
 		}
-
-		originalModel := b.ollamaClient.GetCurrentModel()
-		b.debugLog("Sending request to Ollama with model: %s", originalModel)
-		// Get response from Ollama
-		response, err := b.ollamaClient.GenerateResponse(prompt)
+		b.debugLog("Prompt template: %s, length: %d characters", rendered.Label(), len(rendered.Text))
+
+		b.debugLog("Sending request to Ollama with model: %s", b.ollamaClient.GetCurrentModel())
+		// Get response from Ollama, falling back to an offline answer when no model can respond
+		var response, body string
+		var citations *grounding.Report
+		answer := &feedback.Entry{
+			Question:        userInput,
+			Intent:          string(classification.Intent),
+			Sources:         sources,
+			Template:        rendered.Template,
+			TemplateVersion: rendered.Version,
+			RequestedModel:  b.ollamaClient.GetCurrentModel(),
+		}
+		generation, err := b.ollamaClient.GenerateResponse(rendered.Text)
 		if err != nil {
 			b.debugLog("Error getting AI response: %v", err)
 			log.Printf("Error getting AI response: %v", err)
-			errorResponse := fmt.Sprintf("Error getting AI response: %v", err)
-			// Always add source information even for error responses
-			errorResponse += "\n\n---\n\n## **📚 Sources Referenced:**\n\n"
-			if len(sources) > 0 {
-				for _, source := range sources {
-					errorResponse += fmt.Sprintf("- %s\n", source)
-				}
+			if classification.Intent == intent.ChitChat {
+				response = chitChatReply
 			} else {
-				errorResponse += "- *No documents from testData were referenced due to the error. Please try rephrasing your question.*\n"
+				response = ollama.GenerateOfflineResponse(retrieved, err)
 			}
-			responseEntry.ParseMarkdown(errorResponse)
-			return
-		}
-
-		// Check if the model was automatically changed during generation
-		currentModel := b.ollamaClient.GetCurrentModel()
-		if currentModel != originalModel {
-			b.debugLog("Model was automatically changed from %s to %s during generation", originalModel, currentModel)
-			// Update the status label to reflect the actual model used
-			b.statusLabel.SetText(fmt.Sprintf("🤖 BeanBot AI - %s ✅ ready to help!", currentModel))
-
-			// Update the dropdown to show the new current model
-			if b.modelSelect != nil {
-				// Refresh dropdown options to reflect the current model
-				go func() {
-					models, err := b.ollamaClient.GetAvailableModels()
-					if err == nil {
-						var updatedOptions []string
-						for _, model := range models {
-							if model == currentModel {
-								updatedOptions = append(updatedOptions, fmt.Sprintf("%s (current)", model))
-							} else {
-								updatedOptions = append(updatedOptions, model)
-							}
-						}
-						b.modelSelect.Options = updatedOptions
-						b.modelSelect.SetSelected(fmt.Sprintf("%s (current)", currentModel))
-						b.modelSelect.Refresh()
+			answer.Model = "offline"
+			answer.Offline = true
+			answer.Answer = response
+			body = response
+			b.statusLabel.SetText(fmt.Sprintf("🤖 BeanBot AI ⚠️ offline answer - %s", ollama.DescribeError(err)))
+		} else {
+			b.debugLog("Received response from %s in %s, length: %d characters", generation.Model, generation.Duration, len(generation.Text))
+			answer.Model = generation.Model
+			answer.Options = &generation.Options
+			answer.Answer = generation.Text
+			if len(retrieved.Supplied) > 0 {
+				// Verify the passage citations and flag statements the knowledge base does not support
+				generation.Text, citations = grounding.Annotate(generation.Text, retrieved.Supplied)
+				generation.Text += citations.Render()
+				for _, passage := range retrieved.Supplied {
+					if citations.IsCited(passage.ID) {
+						answer.CitedSources = append(answer.CitedSources, passage.Source)
 					}
-				}()
+				}
+				b.debugLog("Grounding: %d claims, %d unsupported, %d invalid citations",
+					len(citations.Claims), len(citations.Unsupported()), len(citations.Invalid))
+			}
+			body = generation.Text
+			response = b.formatGeneration(generation, rendered)
+			response += fmt.Sprintf("\n\n*Question type: %s*", classification)
+			if generation.UsedFallback() {
+				b.statusLabel.SetText(fmt.Sprintf("🤖 BeanBot AI - %s ⚠️ answered by fallback %s", generation.RequestedModel, generation.Model))
+			} else {
+				b.statusLabel.SetText(fmt.Sprintf("🤖 BeanBot AI - %s ✅ ready to help!", generation.Model))
 			}
 		}
 
-		b.debugLog("Received response from Ollama, length: %d characters", len(response))
-
 		// Always add source references to the response - this is mandatory
 		response += "\n\n---\n\n## **📚 Sources Referenced:**\n\n"
 		if len(sources) > 0 {
-			for _, source := range sources {
-				response += fmt.Sprintf("- %s\n", source)
-			}
+			response += formatSources(retrieved, citations)
+		} else if classification.Intent == intent.ChitChat {
+			response += "- *No documents were needed for this conversational reply.*\n"
 		} else {
-			response += "- *No documents from testData were referenced for this response. This answer is based on general AI knowledge and may not reflect your specific documentation or procedures.*\n"
+			response += fmt.Sprintf("- *No documents from the %s knowledge base were referenced for this response. This answer is based on general AI knowledge and may not reflect your specific documentation or procedures.*\n", kb.Profile().Name)
 		}
+		response += formatContextReport(retrieved)
+		b.rememberQuestion(userInput)
 
-		// Display response in the same window
+		// Display the answer in the transcript and save it with the conversation
+		references := b.references(kb, retrieved, citations)
 		responseEntry.ParseMarkdown(response)
+		b.linkSources(responseEntry, references)
+		b.scrollContainer.ScrollToBottom()
+		turn.Answer = response
+		turn.Body = body
+		turn.Sources = sources
+		turn.References = references
+		for _, errorCode := range retrieved.ErrorCodes {
+			turn.ErrorCodes = append(turn.ErrorCodes, errorCode.Code)
+		}
+		turn.Intent = string(classification.Intent)
+		turn.Model = answer.Model
+		turn.Options = answer.Options
+		turn.AnsweredAt = time.Now()
+		current.AddTurn(turn)
+		turnIndex := len(current.Turns) - 1
+		b.saveSession(current)
+
+		if b.session != current {
+			return
+		}
+		b.appendExcerpts(references)
+		b.showFeedbackBar(answer)
+		b.offerGuide(retrieved.ErrorCodes, func(summary string) {
+			response = summary + "\n\n---\n\n" + response
+			responseEntry.ParseMarkdown(response)
+			b.linkSources(responseEntry, references)
+			current.Turns[turnIndex].Answer = response
+			body = summary + "\n\n---\n\n" + body
+			current.Turns[turnIndex].Body = body
+			b.saveSession(current)
+		})
 	}()
+	return true
+}
+
+// formatSources lists the sources supplied to the model, marking which ones the answer cited.
+// Each source links to the document viewer.
+func formatSources(retrieved *models.RetrievedContext, citations *grounding.Report) string {
+	var list strings.Builder
+	for _, passage := range retrieved.Supplied {
+		link := sourceLink(passage.ID, passage.Source)
+		switch {
+		case citations == nil:
+			list.WriteString(fmt.Sprintf("- %s\n", link))
+		case citations.IsCited(passage.ID):
+			list.WriteString(fmt.Sprintf("- ✅ [%d] %s\n", citations.Footnote(passage.ID), link))
+		default:
+			list.WriteString(fmt.Sprintf("- %s *(provided, not cited)*\n", link))
+		}
+	}
+	return list.String()
+}
+
+// references records the sources supplied for an answer with the location of their documents
+func (b *BeanBot) references(kb *knowledge.KnowledgeDatabase, retrieved *models.RetrievedContext, citations *grounding.Report) []session.Reference {
+	var references []session.Reference
+	for _, passage := range retrieved.Supplied {
+		reference := session.Reference{Passage: passage.ID, Source: passage.Source, DocumentID: passage.DocumentID, Excerpt: passage.Text}
+		if doc, ok := kb.Document(passage.DocumentID); ok {
+			reference.Path = doc.FullPath
+		}
+		if citations != nil {
+			reference.Footnote = citations.Footnote(passage.ID)
+		}
+		references = append(references, reference)
+	}
+	return references
+}
+
+// formatContextReport describes which sources were shortened or left out to fit the model's context window
+func formatContextReport(retrieved *models.RetrievedContext) string {
+	if len(retrieved.Truncated) == 0 && len(retrieved.Dropped) == 0 {
+		return ""
+	}
+
+	var report strings.Builder
+	report.WriteString("\n## **✂️ Context Budget:**\n\n")
+	report.WriteString(fmt.Sprintf("- Used ~%d of %d context tokens available for this model\n", retrieved.ContextTokens, retrieved.ContextBudget))
+	for _, source := range retrieved.Truncated {
+		report.WriteString(fmt.Sprintf("- **Truncated:** %s\n", source))
+	}
+	for _, source := range retrieved.Dropped {
+		report.WriteString(fmt.Sprintf("- **Dropped (did not fit):** %s\n", source))
+	}
+	return report.String()
+}
+
+// formatGeneration appends the model signature and prompt template version to a response and
+// reports any fallback that occurred
+func (b *BeanBot) formatGeneration(generation *ollama.Generation, rendered *prompt.Rendered) string {
+	var response strings.Builder
+	if generation.UsedFallback() {
+		response.WriteString(fmt.Sprintf("> **⚠️ Model fallback:** answered by **%s** because the selected model could not respond:\n", generation.Model))
+		for _, failure := range generation.Failures {
+			response.WriteString(fmt.Sprintf("> - `%s`: %s\n", failure.Model, ollama.DescribeError(failure.Err)))
+		}
+		response.WriteString("\n")
+	}
+	response.WriteString(generation.Text)
+	response.WriteString(fmt.Sprintf("\n\n---\n*Response generated by %s using prompt template %s*", generation.Model, rendered.Label()))
+	if options := generation.Options.String(); options != "" {
+		response.WriteString(fmt.Sprintf("\n\n*Generation options: %s*", options))
+	}
+	return response.String()
+}
+
+// updateModelOptions populates the model dropdown, marking the current model
+func (b *BeanBot) updateModelOptions(available []string, currentModel string) {
+	if b.modelSelect == nil {
+		return
+	}
+
+	var options []string
+	for _, model := range available {
+		if model == currentModel {
+			options = append(options, fmt.Sprintf("%s (current)", model))
+		} else {
+			options = append(options, model)
+		}
+	}
+	b.modelSelect.Options = options
+	if currentModel != "" {
+		b.modelSelect.SetSelected(fmt.Sprintf("%s (current)", currentModel))
+	}
+	b.modelSelect.Refresh()
 }
 
 // handleFileUpload handles user file uploads using Windows system dialog
-func (b *BeanBot) handleFileUpload(responseEntry *widget.RichText) {
+func (b *BeanBot) handleFileUpload() {
 	b.debugLog("Opening file upload dialog")
 
 	// Show the system file dialog
@@ -396,8 +570,10 @@ func (b *BeanBot) handleFileUpload(responseEntry *widget.RichText) {
 
 	b.debugLog("Processing %d uploaded files", len(files))
 
-	// Show processing message
-	responseEntry.ParseMarkdown("\n\n\n\n## 📁 Processing uploaded files... \n\n### ✨ Please wait while I analyze your files ✨")
+	// Show processing message in the transcript; uploads are attached to the current conversation
+	kb := b.activeKnowledge()
+	current := b.session
+	responseEntry := b.appendMessage("## 📁 Processing uploaded files... \n\n### ✨ Please wait while I analyze your files ✨")
 
 	// Process files in background
 	go func() {
@@ -405,13 +581,14 @@ func (b *BeanBot) handleFileUpload(responseEntry *widget.RichText) {
 		var errors []string
 
 		for _, filePath := range files {
-			err := b.knowledgeDB.ProcessUserUpload(filePath)
+			upload, err := kb.ProcessUserUpload(filePath)
 			if err != nil {
 				b.debugLog("Error processing file %s: %v", filePath, err)
 				errors = append(errors, fmt.Sprintf("• %s: %v", filePath, err))
 			} else {
 				b.debugLog("Successfully processed file: %s", filePath)
-				processedFiles = append(processedFiles, filePath)
+				processedFiles = append(processedFiles, uploadMarkdown(upload))
+				current.AddAttachment(upload.Name, filePath)
 			}
 		}
 
@@ -422,8 +599,7 @@ func (b *BeanBot) handleFileUpload(responseEntry *widget.RichText) {
 		if len(processedFiles) > 0 {
 			message.WriteString("### **✅ Successfully uploaded and processed:**\n\n")
 			for _, file := range processedFiles {
-				fileName := filepath.Base(file)
-				message.WriteString(fmt.Sprintf("- **%s**\n", fileName))
+				message.WriteString(file)
 			}
 			message.WriteString("\n*These files are now available for your questions and will be included in AI responses.*\n\n")
 		}
@@ -436,361 +612,67 @@ func (b *BeanBot) handleFileUpload(responseEntry *widget.RichText) {
 			message.WriteString("\n")
 		}
 
-		// Show currently uploaded files
-		uploadedList := b.knowledgeDB.GetUploadedFilesList()
-		if len(uploadedList) > 0 {
-			message.WriteString("### **📋 All uploaded files in this session:**\n\n")
-			for _, file := range uploadedList {
-				message.WriteString(fmt.Sprintf("- %s\n", file))
-			}
-			message.WriteString("\n*Use 'Clear' button to remove uploaded files and start fresh.*\n")
+		// The uploaded files are listed above the question box
+		if len(kb.Uploads()) > 0 {
+			message.WriteString("*Your files are listed above the question box: ✕ removes a file and 📌 keeps it when you start a New Chat.*\n")
 		}
 
 		responseEntry.ParseMarkdown(message.String())
+		b.refreshUploads()
+		b.saveSession(current)
 	}()
 }
 
-// buildEngineeringContext builds context from the knowledge database and returns sources
-func (b *BeanBot) buildEngineeringContext(userInput string) (string, []string) {
-	var context strings.Builder
-	var sources []string
-	lowerInput := strings.ToLower(userInput)
-
-	// PRIORITY 0: Include user-uploaded files first (highest priority)
-	// User uploads get preferential treatment - include them more liberally since user specifically uploaded them
-	userUploads := b.knowledgeDB.GetUserUploads()
-	b.debugLog("Processing user uploads: found %d uploaded files", len(userUploads))
-
-	for filename, content := range userUploads {
-		b.debugLog("Checking uploaded file: %s, content length: %d", filename, len(content))
-
-		// For user uploads, use much more liberal inclusion criteria
-		// Include if ANY of these conditions are met:
-		// 1. Contains any word from user input (even short words)
-		// 2. User input is very short (general query - include all uploads)
-		// 3. Contains common troubleshooting keywords
-		// 4. File has substantial content (user uploaded it for a reason)
-		shouldInclude := false
-
-		if len(strings.TrimSpace(lowerInput)) <= 10 {
-			// Very short queries - include all uploaded files
-			shouldInclude = true
-			b.debugLog("Including %s: short user query", filename)
-		} else if len(content) > 50 {
-			// Check for any word matches (much more liberal than IsRelevantContent)
-			inputWords := strings.Fields(lowerInput)
-			lowerContent := strings.ToLower(content)
-
-			for _, word := range inputWords {
-				if len(word) > 2 && strings.Contains(lowerContent, word) {
-					shouldInclude = true
-					b.debugLog("Including %s: found word match '%s'", filename, word)
-					break
-				}
-			}
-
-			// Also include if content has technical keywords
-			technicalKeywords := []string{"error", "problem", "issue", "step", "solution", "configure", "install", "troubleshoot"}
-			for _, keyword := range technicalKeywords {
-				if strings.Contains(lowerContent, keyword) {
-					shouldInclude = true
-					b.debugLog("Including %s: contains technical keyword '%s'", filename, keyword)
-					break
-				}
-			}
-		}
-
-		if shouldInclude {
-			b.debugLog("File %s is included for user input", filename)
-			// Remove timestamp prefix for display
-			displayName := filename
-			if strings.Contains(filename, "_") {
-				parts := strings.SplitN(filename, "_", 3)
-				if len(parts) >= 3 {
-					displayName = parts[2] // Get the original filename part
-				}
-			}
-
-			context.WriteString(fmt.Sprintf("From User Upload (%s):\n", displayName))
-			sources = append(sources, "User Upload: "+displayName)
-			// Give more content space to user uploads since they're specifically relevant
-			if len(content) > 800 {
-				context.WriteString(content[:800] + "...\n\n")
-			} else {
-				context.WriteString(content + "\n\n")
-			}
-		} else {
-			b.debugLog("File %s is NOT included for user input '%s'", filename, lowerInput)
-		}
-	}
-
-	// Check if this is a technical engineering question vs general question
-	isTechnicalQuestion := strings.Contains(lowerInput, "error") ||
-		strings.Contains(lowerInput, "problem") ||
-		strings.Contains(lowerInput, "troubleshoot") ||
-		strings.Contains(lowerInput, "timeout") ||
-		strings.Contains(lowerInput, "connection") ||
-		strings.Contains(lowerInput, "device") ||
-		strings.Contains(lowerInput, "communication") ||
-		strings.Contains(lowerInput, "system") ||
-		strings.Contains(lowerInput, "software") ||
-		strings.Contains(lowerInput, "hardware") ||
-		strings.Contains(lowerInput, "issue") ||
-		strings.Contains(lowerInput, "failure") ||
-		strings.Contains(lowerInput, "malfunction")
-
-	// For non-technical questions, use more selective context
-	if !isTechnicalQuestion {
-		// Search through text files for relevant content (more selective)
-		for filename, content := range b.knowledgeDB.GetTextFiles() {
-			// Only include if highly relevant to user input
-			relevantKeywords := 0
-			inputWords := strings.Fields(lowerInput)
-
-			for _, word := range inputWords {
-				if len(word) > 3 && strings.Contains(strings.ToLower(content), word) {
-					relevantKeywords++
-				}
-			}
-
-			// Only include if at least 2 keywords match
-			if relevantKeywords >= 2 {
-				hierarchicalPath := b.knowledgeDB.GetFilePaths()[filename]
-				formattedPath := b.formatHierarchicalReference(hierarchicalPath, filename)
-				context.WriteString(fmt.Sprintf("From %s:\n", formattedPath))
-				sources = append(sources, formattedPath)
-				if len(content) > 400 {
-					context.WriteString(content[:400] + "...\n\n")
-				} else {
-					context.WriteString(content + "\n\n")
-				}
-			}
-		}
-
-		// If still no relevant context, provide a general response
-		if context.Len() == 0 {
-			return fmt.Sprintf("I'm BeanBot, specifically designed for engineering support. Your question '%s' seems to be outside my technical expertise. I can help with engineering errors, system issues, device problems, and technical troubleshooting.", userInput), []string{}
-		}
-
-		return context.String(), sources
+// renderPrompt renders the named prompt template with the question, context and conversation state
+func (b *BeanBot) renderPrompt(kb *knowledge.KnowledgeDatabase, name, userInput, context string, retrieved *models.RetrievedContext) (*prompt.Rendered, error) {
+	data := prompt.Data{
+		Question: userInput,
+		Context:  context,
+		History:  b.history,
+		LabName:  b.labName(kb),
 	}
-
-	// For technical questions, use comprehensive search with priority on documentation
-	// PRIORITY 1: Search HTML documentation files first (most comprehensive documentation)
-	supportDocsFound := false
-	for filename, content := range b.knowledgeDB.GetTextFiles() {
-		// Prioritize HTML files from documentation
-		if strings.Contains(strings.ToLower(filename), ".html") {
-			if b.knowledgeDB.IsRelevantContent(lowerInput, content) {
-				context.WriteString(fmt.Sprintf("From Engineering Documentation (%s):\n", filename))
-				sources = append(sources, "Engineering Documentation: "+filename)
-				if len(content) > 500 {
-					context.WriteString(content[:500] + "...\n\n")
-				} else {
-					context.WriteString(content + "\n\n")
-				}
-				supportDocsFound = true
-			}
-		}
-	}
-
-	// PRIORITY 2: Search for relevant error codes
-	for _, errorCode := range b.knowledgeDB.GetData().ErrorCodes {
-		if strings.Contains(lowerInput, strings.ToLower(errorCode.Code)) ||
-			strings.Contains(lowerInput, strings.ToLower(errorCode.Description)) ||
-			b.knowledgeDB.ContainsAnyKeyword(lowerInput, errorCode.RelatedComponents) {
-
-			context.WriteString(fmt.Sprintf("Error Code %s: %s\n", errorCode.Code, errorCode.Description))
-			sources = append(sources, "Error Code: "+errorCode.Code)
-			context.WriteString("Troubleshooting Steps:\n")
-			for i, step := range errorCode.TroubleshootingSteps {
-				context.WriteString(fmt.Sprintf("%d. %s\n", i+1, step))
-			}
-			context.WriteString("\n")
-		}
-	}
-
-	// PRIORITY 3: Search for relevant common issues
-	for _, issue := range b.knowledgeDB.GetData().CommonIssues {
-		if strings.Contains(lowerInput, strings.ToLower(issue.Issue)) ||
-			b.knowledgeDB.ContainsAnyKeyword(lowerInput, issue.Symptoms) {
-
-			context.WriteString(fmt.Sprintf("Common Issue: %s\n", issue.Issue))
-			sources = append(sources, "Common Issue: "+issue.Issue)
-			context.WriteString("Solutions:\n")
-			for i, solution := range issue.Solutions {
-				context.WriteString(fmt.Sprintf("%d. %s\n", i+1, solution))
-			}
-			context.WriteString("\n")
+	if retrieved != nil {
+		for _, errorCode := range retrieved.ErrorCodes {
+			data.ErrorCodes = append(data.ErrorCodes, errorCode.Code)
 		}
 	}
+	return b.templates.Render(name, data)
+}
 
-	// PRIORITY 4: Search through other text files (non-HTML) for relevant content
-	for filename, content := range b.knowledgeDB.GetTextFiles() {
-		// Skip HTML files as they were already processed in priority 1
-		if !strings.Contains(strings.ToLower(filename), ".html") {
-			if b.knowledgeDB.IsRelevantContent(lowerInput, content) {
-				hierarchicalPath := b.knowledgeDB.GetFilePaths()[filename]
-				formattedPath := b.formatHierarchicalReference(hierarchicalPath, filename)
-				context.WriteString(fmt.Sprintf("From %s:\n", formattedPath))
-				sources = append(sources, formattedPath)
-				if len(content) > 400 {
-					context.WriteString(content[:400] + "...\n\n")
-				} else {
-					context.WriteString(content + "\n\n")
-				}
-			}
-		}
-	}
-
-	// Search through PDF content for relevant information (skip if no readable text)
-	for filename, content := range b.knowledgeDB.GetPDFContents() {
-		// Skip if content looks like PDF metadata rather than text
-		if strings.Contains(content, "<<") && strings.Contains(content, ">>") {
-			continue // Skip PDF metadata
-		}
-
-		if b.knowledgeDB.IsRelevantContent(lowerInput, content) {
-			hierarchicalPath := b.knowledgeDB.GetFilePaths()[filename]
-			formattedPath := b.formatHierarchicalReference(hierarchicalPath, filename)
-			context.WriteString(fmt.Sprintf("From %s:\n", formattedPath))
-			sources = append(sources, "PDF: "+formattedPath)
-			// For large PDFs like TLM, try to find the most relevant section
-			if len(content) > 1000 {
-				relevantSection := b.findMostRelevantSection(content, lowerInput, 800)
-				context.WriteString(relevantSection + "...\n\n")
-			} else if len(content) > 600 {
-				context.WriteString(content[:600] + "...\n\n")
-			} else {
-				context.WriteString(content + "\n\n")
-			}
-		}
+// rememberQuestion adds a question to the conversation history used by prompt templates
+func (b *BeanBot) rememberQuestion(userInput string) {
+	b.history = append(b.history, strings.TrimSpace(userInput))
+	if len(b.history) > maxHistory {
+		b.history = b.history[len(b.history)-maxHistory:]
 	}
+}
 
-	// Search through Word document content for relevant information
-	for filename, content := range b.knowledgeDB.GetWordContents() {
-		// Skip if content indicates an error or no content
-		if strings.Contains(content, "Failed to extract") || strings.Contains(content, "not supported") {
-			continue
-		}
-
-		if b.knowledgeDB.IsRelevantContent(lowerInput, content) {
-			hierarchicalPath := b.knowledgeDB.GetFilePaths()[filename]
-			formattedPath := b.formatHierarchicalReference(hierarchicalPath, filename)
-			context.WriteString(fmt.Sprintf("From Word Document (%s):\n", formattedPath))
-			sources = append(sources, "Word Document: "+formattedPath)
-			// For large Word documents, try to find the most relevant section
-			if len(content) > 1000 {
-				relevantSection := b.findMostRelevantSection(content, lowerInput, 800)
-				context.WriteString(relevantSection + "...\n\n")
-			} else if len(content) > 600 {
-				context.WriteString(content[:600] + "...\n\n")
-			} else {
-				context.WriteString(content + "\n\n")
-			}
-		}
+// excerpt returns content truncated to at most limit bytes with an ellipsis marker, cutting on a
+// line boundary where possible and never inside a UTF-8 sequence
+func excerpt(content string, limit int) string {
+	if len(content) <= limit {
+		return content
 	}
 
-	// Search through image content for relevant information
-	for filename, content := range b.knowledgeDB.GetImageContents() {
-		// Skip if content indicates an error
-		if strings.Contains(content, "Failed to process") {
-			continue
-		}
-
-		if b.knowledgeDB.IsRelevantContent(lowerInput, content) {
-			hierarchicalPath := b.knowledgeDB.GetFilePaths()[filename]
-			formattedPath := b.formatHierarchicalReference(hierarchicalPath, filename)
-			context.WriteString(fmt.Sprintf("From Image (%s):\n", formattedPath))
-			sources = append(sources, "Image: "+formattedPath)
-			context.WriteString(content + "\n\n")
-		}
+	cut := content[:limit]
+	if idx := strings.LastIndex(cut, "\n"); idx > limit/2 {
+		cut = cut[:idx]
 	}
-
-	// If no specific context found, include some general troubleshooting content
-	if context.Len() == 0 {
-		context.WriteString("General Engineering Knowledge:\n\n")
-
-		// If no supportDocs were found, try to include relevant HTML documentation
-		if !supportDocsFound {
-			// Include relevant HTML files even if not perfectly matched
-			htmlCount := 0
-			for filename, content := range b.knowledgeDB.GetTextFiles() {
-				if strings.Contains(strings.ToLower(filename), ".html") && htmlCount < 2 {
-					hierarchicalPath := b.knowledgeDB.GetFilePaths()[filename]
-					formattedPath := b.formatHierarchicalReference(hierarchicalPath, filename)
-					context.WriteString(fmt.Sprintf("From Engineering Documentation (%s):\n", formattedPath))
-					sources = append(sources, "Engineering Documentation (General): "+formattedPath)
-					if len(content) > 300 {
-						context.WriteString(content[:300] + "...\n\n")
-					} else {
-						context.WriteString(content + "\n\n")
-					}
-					htmlCount++
-				}
-			}
-		}
-
-		// Include all error codes as general reference
-		for _, errorCode := range b.knowledgeDB.GetData().ErrorCodes {
-			context.WriteString(fmt.Sprintf("Error Code %s: %s\n", errorCode.Code, errorCode.Description))
-			sources = append(sources, "Error Code Reference: "+errorCode.Code)
-		}
-		context.WriteString("\n")
-
-		// Include first non-HTML text file as general reference
-		for filename, content := range b.knowledgeDB.GetTextFiles() {
-			if !strings.Contains(strings.ToLower(filename), ".html") {
-				hierarchicalPath := b.knowledgeDB.GetFilePaths()[filename]
-				formattedPath := b.formatHierarchicalReference(hierarchicalPath, filename)
-				context.WriteString(fmt.Sprintf("From %s:\n", formattedPath))
-				sources = append(sources, "General Reference: "+formattedPath)
-				if len(content) > 300 {
-					context.WriteString(content[:300] + "...\n\n")
-				} else {
-					context.WriteString(content + "\n\n")
-				}
-				break // Just include first non-HTML file for general context
-			}
-		}
+	for len(cut) > 0 && !utf8.ValidString(cut) {
+		cut = cut[:len(cut)-1]
 	}
+	return cut + "..."
+}
 
-	// Limit total context size - increased limit since we have more comprehensive docs and longer responses
-	result := context.String()
-	if len(result) > 1500 {
-		result = result[:1500] + "\n[Context truncated to prevent timeout...]"
+// relevantExcerpt returns the most relevant section of a large document, or a plain excerpt of a smaller one
+func (b *BeanBot) relevantExcerpt(content, lowerInput string) string {
+	if len(content) > 1000 {
+		return b.findMostRelevantSection(content, lowerInput, 800) + "..."
 	}
-
-	return result, sources
+	return excerpt(content, 600)
 }
 
-// createEngineeringPrompt creates the prompt for Ollama
-func (b *BeanBot) createEngineeringPrompt(userInput, context string) string {
-	// For technical questions, use the standard engineering support format
-	prompt := fmt.Sprintf(`You are BeanBot, an engineering support assistant. Analyze the user's issue and provide structured engineering guidance based on the provided knowledge base.
-
-User Issue: %s
-
-Knowledge Base:
-%s
-
-Provide structured engineering response in markdown format:
-
-## **1. PROBLEM ANALYSIS**
-[Identify the core issue: What is failing? What symptoms are described? What system/component is affected?]
-
-## **2. SOLUTION STEPS**
-- **Step 1:** [First diagnostic/corrective action]
-- **Step 2:** [Next action based on knowledge base]
-- **Step 3:** [Additional verification/fix step]
-
-## **3. IF PROBLEM PERSISTS**
-[Advanced troubleshooting or escalation steps]
-
-Important: Base your response on the knowledge base provided. If the knowledge base contains relevant information, reference it in your solution. Analyze the user's description carefully and provide specific, actionable engineering guidance. Use proper markdown formatting with **bold** text for emphasis.`, userInput, context)
-
-	return prompt
-} // findMostRelevantSection finds the most relevant section of a large text for the given input
+// findMostRelevantSection finds the most relevant section of a large text for the given input
 func (b *BeanBot) findMostRelevantSection(content, userInput string, maxLength int) string {
 	lowerInput := strings.ToLower(userInput)
 
@@ -847,7 +729,7 @@ func (b *BeanBot) findMostRelevantSection(content, userInput string, maxLength i
 
 	// If no good section found, return the beginning
 	if bestSection == "" && len(content) > maxLength {
-		return content[:maxLength]
+		return strings.TrimSuffix(excerpt(content, maxLength), "...")
 	}
 
 	return bestSection
@@ -865,53 +747,3 @@ func (b *BeanBot) debugLog(format string, args ...interface{}) {
 		log.Printf("[DEBUG] "+format, args...)
 	}
 }
-
-// formatHierarchicalReference formats document references with hierarchical folder paths
-func (b *BeanBot) formatHierarchicalReference(filepath, filename string) string {
-	if filepath == "" {
-		return filename
-	}
-
-	// Keep the full filename with extension
-	baseName := filename
-
-	// Get the directory parts
-	dir := filepath
-	if strings.HasSuffix(dir, filename) {
-		dir = strings.TrimSuffix(dir, filename)
-		dir = strings.TrimSuffix(dir, "/")
-		dir = strings.TrimSuffix(dir, "\\")
-	}
-
-	// Remove testData prefix
-	dir = strings.TrimPrefix(dir, "testData/")
-	dir = strings.TrimPrefix(dir, "testData\\")
-	dir = strings.TrimPrefix(dir, "testData")
-
-	if dir == "" || dir == "." {
-		return baseName
-	}
-
-	// Split directory path and format hierarchically
-	parts := strings.Split(strings.ReplaceAll(dir, "\\", "/"), "/")
-	var pathParts []string
-	for _, part := range parts {
-		if part != "" && part != "." && part != "testData" {
-			pathParts = append(pathParts, part)
-		}
-	}
-
-	if len(pathParts) == 0 {
-		return baseName
-	}
-
-	// Format as parent/reference (no brackets, skip testData grandparent)
-	if len(pathParts) == 1 {
-		return fmt.Sprintf("%s/%s", pathParts[0], baseName)
-	} else if len(pathParts) >= 2 {
-		// Show last folder and file
-		return fmt.Sprintf("%s/%s", pathParts[len(pathParts)-1], baseName)
-	}
-
-	return baseName
-}
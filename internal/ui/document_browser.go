@@ -0,0 +1,364 @@
+package ui
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/knowledge"
+)
+
+// Document browser choices
+const (
+	groupByFolder  = "Folder"
+	groupByType    = "Type"
+	showAllFiles   = "All files"
+	showIndexed    = "Indexed"
+	showNotIndexed = "Not indexed"
+)
+
+// maxSearchResults is the number of documents listed for a full-text search
+const maxSearchResults = 200
+
+// browserEntry is a file in the document browser: an indexed document or upload, or a file that
+// was not indexed
+type browserEntry struct {
+	path     string // Relative to the profile root
+	kind     string // Document type, or the extension of a file that was not indexed
+	document *knowledge.Document
+	skipped  *knowledge.SkippedFile
+}
+
+// icon shows at a glance whether the entry's text was extracted
+func (e browserEntry) icon() string {
+	if e.document != nil {
+		return "✅"
+	}
+	switch e.skipped.Category {
+	case knowledge.SkipNoText, knowledge.SkipUnreadable, knowledge.SkipBinary:
+		return "❌"
+	case knowledge.SkipUnsupported:
+		return "⚠️"
+	}
+	return "⏭️"
+}
+
+// status describes how the entry's text was extracted, or why it was not
+func (e browserEntry) status() string {
+	if e.document == nil {
+		return fmt.Sprintf("%s Not indexed (%s): %s", e.icon(), e.skipped.Category, e.skipped.Reason)
+	}
+	status := fmt.Sprintf("%s Indexed with the %s extractor", e.icon(), e.document.Extractor)
+	if e.document.DuplicateOf != "" {
+		original, _, _ := strings.Cut(e.document.DuplicateOf, "#")
+		status += fmt.Sprintf(" · same content as %s, so only that copy is searched", original)
+	}
+	return status
+}
+
+// browserEntries lists the documents, uploads and skipped files of a knowledge base by path
+func (b *BeanBot) browserEntries(kb *knowledge.KnowledgeDatabase) []browserEntry {
+	var entries []browserEntry
+	for _, doc := range append(kb.Documents(), kb.Uploads()...) {
+		doc := doc
+		entries = append(entries, browserEntry{path: doc.Path, kind: string(doc.Type), document: &doc})
+	}
+	for _, skipped := range kb.IndexReport().Skipped {
+		skipped := skipped
+		kind := strings.TrimPrefix(strings.ToLower(path.Ext(skipped.Path)), ".")
+		if kind == "" {
+			kind = "no extension"
+		}
+		entries = append(entries, browserEntry{path: skipped.Path, kind: kind, skipped: &skipped})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+	return entries
+}
+
+// browserTree groups entries into tree nodes. Branch IDs are "d:" and a folder path or "t:" and a
+// type; leaf IDs are "f:" and a file path. The root is the empty ID.
+type browserTree struct {
+	children map[string][]string
+	counts   map[string]int // Files under each branch
+	entries  map[string]browserEntry
+}
+
+// newBrowserTree groups entries by folder or by type, folders before files
+func newBrowserTree(entries []browserEntry, groupBy string) *browserTree {
+	tree := &browserTree{children: make(map[string][]string), counts: make(map[string]int), entries: make(map[string]browserEntry)}
+	for _, entry := range entries {
+		var branches []string
+		if groupBy == groupByType {
+			branches = []string{"t:" + entry.kind}
+		} else {
+			parts := strings.Split(entry.path, "/")
+			for i := 1; i < len(parts); i++ {
+				branches = append(branches, "d:"+strings.Join(parts[:i], "/"))
+			}
+		}
+
+		parent := ""
+		for _, branch := range branches {
+			if _, ok := tree.counts[branch]; !ok {
+				tree.children[parent] = append(tree.children[parent], branch)
+			}
+			tree.counts[branch]++
+			parent = branch
+		}
+		leaf := "f:" + entry.path
+		if _, ok := tree.entries[leaf]; !ok {
+			tree.children[parent] = append(tree.children[parent], leaf)
+		}
+		tree.entries[leaf] = entry
+	}
+
+	for _, ids := range tree.children {
+		sort.SliceStable(ids, func(i, j int) bool {
+			if iFile, jFile := strings.HasPrefix(ids[i], "f:"), strings.HasPrefix(ids[j], "f:"); iFile != jFile {
+				return jFile
+			}
+			return strings.ToLower(ids[i]) < strings.ToLower(ids[j])
+		})
+	}
+	return tree
+}
+
+// label names a tree node; files grouped by type show their full path
+func (t *browserTree) label(id string, groupBy string) string {
+	if entry, ok := t.entries[id]; ok {
+		if groupBy == groupByType {
+			return entry.icon() + " " + entry.path
+		}
+		return entry.icon() + " " + path.Base(entry.path)
+	}
+	if strings.HasPrefix(id, "t:") {
+		return fmt.Sprintf("📄 %s (%d)", strings.TrimPrefix(id, "t:"), t.counts[id])
+	}
+	return fmt.Sprintf("📁 %s (%d)", path.Base(strings.TrimPrefix(id, "d:")), t.counts[id])
+}
+
+// showDocumentBrowser lists every file of the active profile with its extraction status, searches
+// the extracted text of all documents and previews the text the model would be given
+func (b *BeanBot) showDocumentBrowser() {
+	kb := b.activeKnowledge()
+	entries := b.browserEntries(kb)
+	byPath := make(map[string]browserEntry, len(entries))
+	for _, entry := range entries {
+		byPath[entry.path] = entry
+	}
+
+	summary := widget.NewLabel(kb.IndexReport().Summary())
+	summary.Wrapping = fyne.TextWrapWord
+
+	// Preview of the selected document
+	preview := container.NewStack(widget.NewLabel("Select a file to see its extracted text."))
+	showPreview := func(entry browserEntry, terms []string) {
+		content, scrollTo := b.documentPreview(entry, terms)
+		preview.Objects = []fyne.CanvasObject{content}
+		preview.Refresh()
+		if scrollTo != nil {
+			scrollTo()
+		}
+	}
+
+	// Files grouped by folder or type
+	groupBy, show := groupByFolder, showAllFiles
+	current := newBrowserTree(entries, groupBy)
+	tree := widget.NewTree(
+		func(id widget.TreeNodeID) []widget.TreeNodeID { return current.children[id] },
+		func(id widget.TreeNodeID) bool { return !strings.HasPrefix(id, "f:") },
+		func(bool) fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TreeNodeID, _ bool, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(current.label(id, groupBy))
+		},
+	)
+	tree.OnSelected = func(id widget.TreeNodeID) {
+		if entry, ok := current.entries[id]; ok {
+			showPreview(entry, nil)
+		}
+	}
+	rebuild := func() {
+		var visible []browserEntry
+		for _, entry := range entries {
+			if show == showAllFiles || (show == showIndexed) == (entry.document != nil) {
+				visible = append(visible, entry)
+			}
+		}
+		current = newBrowserTree(visible, groupBy)
+		tree.UnselectAll()
+		tree.CloseAllBranches()
+		tree.Refresh()
+	}
+	groupSelect := widget.NewSelect([]string{groupByFolder, groupByType}, func(selected string) {
+		groupBy = selected
+		rebuild()
+	})
+	groupSelect.SetSelected(groupBy)
+	showSelect := widget.NewSelect([]string{showAllFiles, showIndexed, showNotIndexed}, func(selected string) {
+		show = selected
+		rebuild()
+	})
+	showSelect.SetSelected(show)
+	treePane := container.NewBorder(
+		container.NewGridWithColumns(2,
+			container.NewBorder(nil, nil, widget.NewLabel("Group by:"), nil, groupSelect),
+			container.NewBorder(nil, nil, widget.NewLabel("Show:"), nil, showSelect)),
+		nil, nil, nil, tree)
+
+	// Full-text search results
+	var hits []knowledge.SearchHit
+	var terms []string
+	resultsLabel := widget.NewLabel("")
+	results := widget.NewList(
+		func() int { return len(hits) },
+		func() fyne.CanvasObject {
+			title := widget.NewLabel("")
+			title.TextStyle = fyne.TextStyle{Bold: true}
+			title.Truncation = fyne.TextTruncateEllipsis
+			snippet := widget.NewRichText()
+			snippet.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(title, snippet)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			hit := hits[id]
+			objects := item.(*fyne.Container).Objects
+			objects[0].(*widget.Label).SetText(fmt.Sprintf("%s (%d matches)", hit.Document.Path, hit.Matches))
+			snippet := objects[1].(*widget.RichText)
+			snippet.Segments = nil
+			if len(hit.Snippets) > 0 {
+				snippet.Segments = snippetSegments(hit.Snippets[0])
+			}
+			snippet.Refresh()
+		},
+	)
+	results.OnSelected = func(id widget.ListItemID) {
+		if entry, ok := byPath[hits[id].Document.Path]; ok {
+			showPreview(entry, terms)
+		}
+	}
+	resultsPane := container.NewBorder(resultsLabel, nil, nil, nil, results)
+	resultsPane.Hide()
+
+	search := widget.NewEntry()
+	search.SetPlaceHolder("Search the text of all documents...")
+	runSearch := func(query string) {
+		if strings.TrimSpace(query) == "" {
+			resultsPane.Hide()
+			treePane.Show()
+			return
+		}
+		hits = kb.Search(query, maxSearchResults)
+		terms = strings.Fields(strings.ToLower(query))
+		b.debugLog("Document search for %q found %d documents", query, len(hits))
+		switch {
+		case len(hits) == 0:
+			resultsLabel.SetText("No documents contain every word of the search.")
+		case len(hits) == maxSearchResults:
+			resultsLabel.SetText(fmt.Sprintf("Showing the first %d matching documents", len(hits)))
+		default:
+			resultsLabel.SetText(fmt.Sprintf("%d matching documents", len(hits)))
+		}
+		results.UnselectAll()
+		results.Refresh()
+		results.ScrollToTop()
+		treePane.Hide()
+		resultsPane.Show()
+	}
+	search.OnSubmitted = runSearch
+	search.OnChanged = func(text string) {
+		if text == "" {
+			runSearch("")
+		}
+	}
+	searchBtn := widget.NewButtonWithIcon("", theme.SearchIcon(), func() { runSearch(search.Text) })
+
+	left := container.NewBorder(container.NewBorder(nil, nil, nil, searchBtn, search), nil, nil, nil,
+		container.NewStack(treePane, resultsPane))
+	split := container.NewHSplit(left, preview)
+	split.Offset = 0.4
+
+	browser := dialog.NewCustom(fmt.Sprintf("Documents - %s", kb.Profile().Name), "Close",
+		container.NewBorder(summary, nil, nil, nil, split), b.window)
+	browser.Resize(fyne.NewSize(980, 660))
+	browser.Show()
+}
+
+// documentPreview shows a file's details and extracted text, highlighting lines that contain any
+// of the terms. The returned function scrolls to the first highlighted line once the preview is shown.
+func (b *BeanBot) documentPreview(entry browserEntry, terms []string) (fyne.CanvasObject, func()) {
+	title := widget.NewLabel(entry.path)
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	title.Wrapping = fyne.TextWrapWord
+	status := widget.NewLabel(entry.status())
+	status.Wrapping = fyne.TextWrapWord
+	if entry.document == nil {
+		return container.NewVBox(title, status), nil
+	}
+
+	doc := *entry.document
+	details := fmt.Sprintf("%s · %s · modified %s · %d characters of text",
+		doc.Type, knowledge.FormatBytes(doc.Size), doc.ModTime.Format("2006-01-02 15:04"), len(doc.Content))
+	if len(doc.Pages) > 0 {
+		details += fmt.Sprintf(" · %d pages", len(doc.Pages))
+	}
+	detailsLabel := widget.NewLabel(details)
+	detailsLabel.Wrapping = fyne.TextWrapWord
+	header := container.NewVBox(title, detailsLabel, status)
+	if tickets := b.ticketLinks(doc); tickets != nil {
+		header.Add(tickets)
+	}
+	if doc.FullPath != "" {
+		header.Add(widget.NewButton("Open in default application", func() { b.openWithSystem(doc.FullPath) }))
+	}
+
+	if strings.TrimSpace(doc.Content) == "" {
+		return container.NewBorder(header, nil, nil, nil, widget.NewLabel("No text was extracted from this file.")), nil
+	}
+	lines := documentLines(doc)
+	highlighted := func(line viewerLine) bool {
+		lower := strings.ToLower(line.text)
+		for _, term := range terms {
+			if !line.page && strings.Contains(lower, term) {
+				return true
+			}
+		}
+		return false
+	}
+	list := documentTextList(lines, highlighted)
+
+	var scrollTo func()
+	for i, line := range lines {
+		if highlighted(line) {
+			scrollTo = func() { list.ScrollTo(max(i-2, 0)) }
+			break
+		}
+	}
+	return container.NewBorder(header, nil, nil, nil, list), scrollTo
+}
+
+// snippetSegments formats a search snippet with its matches emphasised
+func snippetSegments(snippet knowledge.Snippet) []widget.RichTextSegment {
+	highlight := widget.RichTextStyleInline
+	highlight.ColorName = theme.ColorNamePrimary
+	highlight.TextStyle = fyne.TextStyle{Bold: true}
+
+	segments := []widget.RichTextSegment{&widget.TextSegment{Text: "…", Style: widget.RichTextStyleInline}}
+	last := 0
+	for _, match := range snippet.Highlights {
+		if match[0] < last {
+			continue
+		}
+		segments = append(segments,
+			&widget.TextSegment{Text: snippet.Text[last:match[0]], Style: widget.RichTextStyleInline},
+			&widget.TextSegment{Text: snippet.Text[match[0]:match[1]], Style: highlight})
+		last = match[1]
+	}
+	return append(segments, &widget.TextSegment{Text: snippet.Text[last:] + "…", Style: widget.RichTextStyleInline})
+}
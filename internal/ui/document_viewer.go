@@ -0,0 +1,277 @@
+package ui
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/knowledge"
+	"github.com/beanspout/2025-beanbot/internal/session"
+)
+
+// sourceLinkPrefix starts the links in an answer's sources list; the passage ID follows it
+const sourceLinkPrefix = "beanbot://source/"
+
+// viewerLineWidth is the number of characters after which the document viewer wraps lines
+const viewerLineWidth = 110
+
+// sourceLink formats a source as a Markdown link to the document viewer. The rich text widget
+// only shows a link's text up to the first Markdown delimiter, so delimiters are replaced here
+// and linkSources puts the exact source name back.
+func sourceLink(passageID, source string) string {
+	text := strings.Map(func(c rune) rune {
+		if strings.ContainsRune("\\[]*_`<&", c) {
+			return ' '
+		}
+		return c
+	}, source)
+	return fmt.Sprintf("[%s](%s%s)", text, sourceLinkPrefix, passageID)
+}
+
+// linkSources makes the source links of a displayed answer open the document viewer instead of
+// being passed to the system
+func (b *BeanBot) linkSources(text *widget.RichText, references []session.Reference) {
+	if len(references) == 0 {
+		return
+	}
+	byPassage := make(map[string]session.Reference, len(references))
+	for _, reference := range references {
+		byPassage[reference.Passage] = reference
+	}
+
+	var link func(segments []widget.RichTextSegment)
+	link = func(segments []widget.RichTextSegment) {
+		for _, segment := range segments {
+			switch segment := segment.(type) {
+			case *widget.HyperlinkSegment:
+				if segment.URL == nil || !strings.HasPrefix(segment.URL.String(), sourceLinkPrefix) {
+					continue
+				}
+				if reference, ok := byPassage[strings.TrimPrefix(segment.URL.String(), sourceLinkPrefix)]; ok {
+					segment.Text = reference.Source
+					segment.OnTapped = func() { b.showReference(reference) }
+				}
+			case *widget.ParagraphSegment:
+				link(segment.Texts)
+			case *widget.ListSegment:
+				link(segment.Items)
+			}
+		}
+	}
+	link(text.Segments)
+	text.Refresh()
+}
+
+// appendExcerpts adds an expandable list of the excerpts an answer was given to the transcript
+func (b *BeanBot) appendExcerpts(references []session.Reference) {
+	var excerpts []fyne.CanvasObject
+	for _, reference := range references {
+		if reference.Excerpt == "" {
+			continue
+		}
+		reference := reference
+		title := reference.Source
+		if reference.Cited() {
+			title = fmt.Sprintf("[%d] %s", reference.Footnote, title)
+		}
+		open := widget.NewHyperlink(title, nil)
+		open.OnTapped = func() { b.showReference(reference) }
+		text := widget.NewLabel(strings.TrimSpace(reference.Excerpt))
+		text.Wrapping = fyne.TextWrapWord
+		excerpts = append(excerpts, open, text)
+	}
+	if len(excerpts) == 0 {
+		return
+	}
+
+	accordion := widget.NewAccordion(widget.NewAccordionItem(
+		fmt.Sprintf("🔎 Excerpts sent to the model (%d)", len(excerpts)/2), container.NewVBox(excerpts...)))
+	b.transcript.Add(accordion)
+}
+
+// findDocument returns the knowledge base document a reference came from. A document that was
+// re-indexed since the answer has a new ID, so it is looked up by its location as well.
+func (b *BeanBot) findDocument(reference session.Reference) (knowledge.Document, bool) {
+	kb := b.activeKnowledge()
+	if reference.DocumentID != "" {
+		if doc, ok := kb.Document(reference.DocumentID); ok {
+			return doc, true
+		}
+	}
+	if reference.Path != "" {
+		for _, doc := range append(kb.Documents(), kb.Uploads()...) {
+			if doc.FullPath == reference.Path {
+				return doc, true
+			}
+		}
+	}
+	return knowledge.Document{}, false
+}
+
+// viewerLine is a line of a document as shown in the viewer
+type viewerLine struct {
+	text   string
+	offset int  // Where the line starts in the document's content
+	page   bool // A page heading rather than document text
+}
+
+// showReference opens the document viewer at the excerpt a source supplied to the model, with the
+// excerpt highlighted. Sources that are not documents, such as error codes, show the excerpt alone.
+func (b *BeanBot) showReference(reference session.Reference) {
+	title := widget.NewLabel(reference.Source)
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	title.Wrapping = fyne.TextWrapWord
+	header := container.NewVBox(title)
+
+	excerpt := widget.NewLabel(strings.TrimSpace(reference.Excerpt))
+	excerpt.Wrapping = fyne.TextWrapWord
+	excerptScroll := container.NewVScroll(excerpt)
+	excerptScroll.SetMinSize(fyne.NewSize(0, 140))
+	excerptItem := widget.NewAccordionItem("🔎 Excerpt sent to the model", excerptScroll)
+
+	var body fyne.CanvasObject
+	var scrollTo func()
+	doc, found := b.findDocument(reference)
+	switch {
+	case found:
+		lines := documentLines(doc)
+		start, end, located := doc.Locate(reference.Excerpt)
+		first := -1
+		highlighted := func(line viewerLine) bool {
+			return located && !line.page && line.offset < end && line.offset+len(line.text) > start
+		}
+		for i, line := range lines {
+			if highlighted(line) {
+				first = i
+				break
+			}
+		}
+
+		list := documentTextList(lines, highlighted)
+		body = list
+
+		location := fmt.Sprintf("%s · %s", doc.Path, doc.Type)
+		if located {
+			if page := doc.PageAt(start); page > 0 {
+				location += fmt.Sprintf(" · page %d of %d", page, len(doc.Pages))
+			}
+			location += " · excerpt highlighted"
+			scrollTo = func() { list.ScrollTo(max(first-2, 0)) }
+		} else {
+			location += " · the excerpt was not found in the current version of the document"
+			excerptItem.Open = true
+		}
+		locationLabel := widget.NewLabel(location)
+		locationLabel.Wrapping = fyne.TextWrapWord
+		header.Add(locationLabel)
+		if tickets := b.ticketLinks(doc); tickets != nil {
+			header.Add(tickets)
+		}
+	case reference.Path != "":
+		body = widget.NewLabel("This document is not in the current knowledge base.")
+		excerptItem.Open = true
+	default:
+		body = container.NewVScroll(excerpt)
+		excerptItem = nil
+	}
+
+	if excerptItem != nil && reference.Excerpt != "" {
+		header.Add(widget.NewAccordion(excerptItem))
+	}
+	path := reference.Path
+	if found {
+		path = doc.FullPath
+	}
+	if _, err := os.Stat(path); path != "" && err == nil {
+		header.Add(widget.NewButton("Open in default application", func() { b.openWithSystem(path) }))
+	}
+
+	viewer := dialog.NewCustom("Document Viewer", "Close", container.NewBorder(header, nil, nil, nil, body), b.window)
+	viewer.Resize(fyne.NewSize(820, 620))
+	viewer.Show()
+	if scrollTo != nil {
+		scrollTo()
+	}
+}
+
+// openWithSystem opens a file with the application the system associates with it
+func (b *BeanBot) openWithSystem(path string) {
+	link, err := url.Parse(storage.NewFileURI(path).String())
+	if err == nil {
+		err = b.app.OpenURL(link)
+	}
+	if err != nil {
+		b.debugLog("Failed to open %s: %v", path, err)
+		dialog.ShowError(fmt.Errorf("could not open %s: %w", path, err), b.window)
+	}
+}
+
+// documentTextList shows a document's lines, emphasising the highlighted ones
+func documentTextList(lines []viewerLine, highlighted func(viewerLine) bool) *widget.List {
+	return widget.NewList(
+		func() int { return len(lines) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			label := item.(*widget.Label)
+			line := lines[id]
+			switch {
+			case line.page:
+				label.TextStyle = fyne.TextStyle{Italic: true}
+				label.Importance = widget.LowImportance
+			case highlighted(line):
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.Importance = widget.HighImportance
+			default:
+				label.TextStyle = fyne.TextStyle{}
+				label.Importance = widget.MediumImportance
+			}
+			label.SetText(line.text)
+		},
+	)
+}
+
+// documentLines splits a document's content into wrapped lines, with a heading at the start of
+// each page of a PDF
+func documentLines(doc knowledge.Document) []viewerLine {
+	var lines []viewerLine
+	offset, page := 0, 0
+	for _, raw := range strings.SplitAfter(doc.Content, "\n") {
+		for page < len(doc.Pages) && doc.Pages[page] <= offset {
+			// Pages without text start where the next page does and get no heading
+			if page+1 == len(doc.Pages) || doc.Pages[page+1] > doc.Pages[page] {
+				lines = append(lines, viewerLine{text: fmt.Sprintf("── Page %d ──", page+1), offset: offset, page: true})
+			}
+			page++
+		}
+		text := strings.TrimRight(raw, "\r\n")
+		for {
+			cut := len(text)
+			if utf8.RuneCountInString(text) > viewerLineWidth {
+				cut = 0
+				for i := 0; i < viewerLineWidth; i++ {
+					_, size := utf8.DecodeRuneInString(text[cut:])
+					cut += size
+				}
+				if space := strings.LastIndex(text[:cut], " "); space > cut/2 {
+					cut = space + 1
+				}
+			}
+			lines = append(lines, viewerLine{text: text[:cut], offset: offset})
+			offset += cut
+			text = text[cut:]
+			if text == "" {
+				break
+			}
+		}
+		offset += len(raw) - len(strings.TrimRight(raw, "\r\n"))
+	}
+	return lines
+}
@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/export"
+	"github.com/beanspout/2025-beanbot/internal/session"
+)
+
+// wholeConversation is the export choice that includes every answer of the conversation
+const wholeConversation = "Whole conversation"
+
+// showExportDialog exports the current conversation or one of its answers as a report file or
+// Jira markup. answer is the index of the answer selected first; -1 selects the whole conversation.
+func (b *BeanBot) showExportDialog(answer int) {
+	current := b.session
+	if len(current.Turns) == 0 {
+		dialog.ShowInformation("Export", "Ask a question first - there is nothing to export yet.", b.window)
+		return
+	}
+
+	choices := []string{wholeConversation}
+	for i, turn := range current.Turns {
+		choices = append(choices, fmt.Sprintf("%d. %s", i+1, session.TitleFor(turn.Question)))
+	}
+	scope := widget.NewSelect(choices, nil)
+	scope.SetSelectedIndex(0)
+	if answer >= 0 && answer < len(current.Turns) {
+		scope.SetSelectedIndex(answer + 1)
+	}
+	report := func() export.Report {
+		if i := scope.SelectedIndex(); i > 0 {
+			return export.AnswerReport(current, i-1)
+		}
+		return export.SessionReport(current)
+	}
+
+	var formats []string
+	for _, format := range export.Formats {
+		formats = append(formats, string(format))
+	}
+	format := widget.NewRadioGroup(formats, nil)
+	format.Horizontal = true
+	format.Required = true
+	format.SetSelected(string(export.PDF))
+
+	var exportDialog dialog.Dialog
+	saveBtn := widget.NewButton("💾 Save...", func() {
+		exportDialog.Hide()
+		b.saveExport(report(), export.Format(format.Selected))
+	})
+	saveBtn.Importance = widget.HighImportance
+	jiraBtn := widget.NewButton("📋 Copy as Jira markup", func() {
+		selected := report()
+		b.window.Clipboard().SetContent(selected.Jira())
+		b.debugLog("Copied %d answers as Jira markup", len(selected.Turns))
+		exportDialog.Hide()
+		dialog.ShowInformation("Export", "Jira markup copied to the clipboard - paste it into a ticket or comment.", b.window)
+	})
+
+	form := widget.NewForm(widget.NewFormItem("Export", scope), widget.NewFormItem("Format", format))
+	exportDialog = dialog.NewCustom("Export", "Close", container.NewVBox(form, container.NewGridWithColumns(2, saveBtn, jiraBtn)), b.window)
+	exportDialog.Resize(fyne.NewSize(520, 240))
+	exportDialog.Show()
+}
+
+// saveExport asks where to save a report and writes it in the chosen format
+func (b *BeanBot) saveExport(report export.Report, format export.Format) {
+	data, err := report.Render(format)
+	if err != nil {
+		b.debugLog("Failed to render %s export: %v", format, err)
+		dialog.ShowError(fmt.Errorf("could not create the export: %w", err), b.window)
+		return
+	}
+
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		if writer == nil {
+			return // Cancelled
+		}
+		_, err = writer.Write(data)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			b.debugLog("Failed to save export to %s: %v", writer.URI().Path(), err)
+			dialog.ShowError(fmt.Errorf("could not save the export: %w", err), b.window)
+			return
+		}
+		b.debugLog("Exported %d answers as %s to %s", len(report.Turns), format, writer.URI().Path())
+		dialog.ShowInformation("Export", fmt.Sprintf("Saved %s", writer.URI().Path()), b.window)
+	}, b.window)
+	save.SetFileName(report.FileName(format))
+	save.SetFilter(storage.NewExtensionFileFilter([]string{format.Extension()}))
+	save.Resize(fyne.NewSize(720, 520))
+	save.Show()
+}
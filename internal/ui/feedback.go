@@ -0,0 +1,144 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/feedback"
+	"github.com/beanspout/2025-beanbot/internal/knowledge"
+)
+
+// createFeedbackBar creates the bar shown under each answer for rating it
+func (b *BeanBot) createFeedbackBar() *fyne.Container {
+	b.feedbackLabel = widget.NewLabel("Was this answer helpful?")
+	b.feedbackUpBtn = widget.NewButton("👍 Helpful", func() {
+		b.showFeedbackDialog(feedback.RatingUp)
+	})
+	b.feedbackDownBtn = widget.NewButton("👎 Not helpful", func() {
+		b.showFeedbackDialog(feedback.RatingDown)
+	})
+
+	b.guideBtn = widget.NewButton("", nil)
+	b.guideBtn.Hide()
+
+	// The bar follows the latest answer, so export starts with that answer selected
+	exportBtn := widget.NewButton("📤 Export", func() {
+		b.showExportDialog(len(b.session.Turns) - 1)
+	})
+
+	b.feedbackBar = container.NewHBox(b.feedbackLabel, b.feedbackUpBtn, b.feedbackDownBtn, exportBtn, b.guideBtn)
+	b.feedbackBar.Hide()
+	return b.feedbackBar
+}
+
+// showFeedbackBar offers feedback on the answer that was just displayed
+func (b *BeanBot) showFeedbackBar(answer *feedback.Entry) {
+	b.lastAnswer = answer
+	b.feedbackLabel.SetText("Was this answer helpful?")
+	b.feedbackUpBtn.Enable()
+	b.feedbackDownBtn.Enable()
+	b.feedbackBar.Show()
+}
+
+// hideFeedbackBar hides the feedback bar while there is no answer to rate
+func (b *BeanBot) hideFeedbackBar() {
+	b.lastAnswer = nil
+	if b.feedbackBar != nil {
+		b.feedbackBar.Hide()
+	}
+}
+
+// showFeedbackDialog asks for an optional comment and correct fix, then stores the feedback
+func (b *BeanBot) showFeedbackDialog(rating feedback.Rating) {
+	if b.lastAnswer == nil {
+		return
+	}
+	answer := *b.lastAnswer
+
+	comment := widget.NewMultiLineEntry()
+	comment.SetPlaceHolder("Optional comment")
+	comment.Wrapping = fyne.TextWrapWord
+	correctFix := widget.NewMultiLineEntry()
+	correctFix.SetPlaceHolder("Optional: what actually fixed the problem")
+	correctFix.Wrapping = fyne.TextWrapWord
+
+	resolved := widget.NewCheck("This solved my problem - suggest these steps for the knowledge base", nil)
+
+	items := []*widget.FormItem{widget.NewFormItem("Comment", comment)}
+	title := "👍 Helpful answer"
+	if rating == feedback.RatingDown {
+		title = "👎 Answer was not helpful"
+		resolved.Text = "The correct fix solved it - suggest it for the knowledge base"
+		items = append(items, widget.NewFormItem("The correct fix was...", correctFix))
+	}
+	if !answer.Offline || rating == feedback.RatingDown {
+		items = append(items, widget.NewFormItem("", resolved))
+	}
+
+	form := dialog.NewForm(title, "Send", "Cancel", items, func(send bool) {
+		if !send {
+			return
+		}
+		answer.Rating = rating
+		answer.Comment = comment.Text
+		answer.CorrectFix = correctFix.Text
+		answer.Resolved = resolved.Checked
+		id, err := b.feedbackStore.Add(answer)
+		if err != nil {
+			b.debugLog("Failed to save feedback: %v", err)
+			dialog.ShowError(fmt.Errorf("could not save feedback: %w", err), b.window)
+			return
+		}
+		b.debugLog("Saved %s feedback for question: %s", rating, answer.Question)
+
+		if answer.Resolved {
+			// The fix that worked becomes a knowledge base proposal waiting for review
+			fix := answer.CorrectFix
+			if rating == feedback.RatingUp {
+				fix = solutionLines(answer.Answer)
+			}
+			if err := b.proposeFix(id, answer.Question, fix); err != nil {
+				dialog.ShowError(err, b.window)
+			}
+		}
+		b.feedbackLabel.SetText("Thanks for the feedback!")
+		b.feedbackUpBtn.Disable()
+		b.feedbackDownBtn.Disable()
+	}, b.window)
+	form.Resize(fyne.NewSize(420, 320))
+	form.Show()
+}
+
+// proposeFix drafts a knowledge base proposal from a resolved question and queues it for review
+func (b *BeanBot) proposeFix(feedbackID, question, fix string) error {
+	if strings.TrimSpace(fix) == "" {
+		return nil
+	}
+	proposal := knowledge.DraftProposal(question, fix, b.activeKnowledge().GetData().ErrorCodes)
+	proposal.FeedbackID = feedbackID
+	if err := b.proposals.Add(proposal); err != nil {
+		b.debugLog("Failed to queue knowledge base proposal: %v", err)
+		return fmt.Errorf("feedback saved, but the fix could not be queued for review: %w", err)
+	}
+	b.debugLog("Queued %s proposal for review: %s", proposal.Kind, question)
+	b.refreshReviewButton()
+	return nil
+}
+
+// solutionLines returns the list items of an answer, which hold its solution steps
+func solutionLines(answer string) string {
+	var lines []string
+	for _, line := range strings.Split(answer, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") ||
+			(len(trimmed) > 2 && trimmed[0] >= '0' && trimmed[0] <= '9' && strings.Contains(trimmed[:3], ".")) {
+			lines = append(lines, trimmed)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
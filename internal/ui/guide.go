@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/guide"
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// offerGuide shows the guided troubleshooting button for the first retrieved error code that
+// has a decision tree; onSummary receives the summary when a guided session finishes
+func (b *BeanBot) offerGuide(errorCodes []models.ErrorCode, onSummary func(string)) {
+	for _, errorCode := range errorCodes {
+		if errorCode.DecisionTree == nil {
+			continue
+		}
+		errorCode := errorCode
+		b.guideBtn.SetText(fmt.Sprintf("🧭 Guide me through %s", errorCode.Code))
+		b.guideBtn.OnTapped = func() { b.showGuidedTroubleshooting(errorCode, onSummary) }
+		b.guideBtn.Show()
+		return
+	}
+	b.guideBtn.Hide()
+}
+
+// showGuidedTroubleshooting walks through an error code's decision tree one question at a time
+func (b *BeanBot) showGuidedTroubleshooting(errorCode models.ErrorCode, onSummary func(string)) {
+	session, err := guide.NewSession(errorCode)
+	if err != nil {
+		dialog.ShowError(err, b.window)
+		return
+	}
+	b.debugLog("Started guided troubleshooting for %s", errorCode.Code)
+
+	header := container.NewVBox(widget.NewLabelWithStyle(
+		fmt.Sprintf("%s - %s", errorCode.Code, errorCode.Description), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+	for _, warning := range errorCode.SafetyWarnings {
+		label := widget.NewLabel("⚠️ " + warning)
+		label.Wrapping = fyne.TextWrapWord
+		header.Add(label)
+	}
+
+	body := container.NewVBox()
+	var guideDialog dialog.Dialog
+
+	// render shows the current question with one button per answer, or the summary once finished
+	var render func()
+	render = func() {
+		body.RemoveAll()
+		node := session.Current()
+
+		backBtn := widget.NewButton("Back", func() {
+			session.Back()
+			render()
+		})
+		if session.Step() == 1 {
+			backBtn.Disable()
+		}
+
+		if session.Finished() {
+			summary := session.Summary()
+			b.debugLog("Guided troubleshooting for %s finished after %d answers, escalated: %v", errorCode.Code, len(session.Answers), session.Escalated())
+			text := widget.NewRichTextFromMarkdown(summary)
+			text.Wrapping = fyne.TextWrapWord
+			body.Add(text)
+
+			actions := container.NewHBox(backBtn, widget.NewButton("Copy Summary", func() {
+				b.window.Clipboard().SetContent(summary)
+			}))
+			if onSummary != nil {
+				actions.Add(widget.NewButton("Add to Answer", func() {
+					onSummary(summary)
+					guideDialog.Hide()
+				}))
+			}
+			body.Add(actions)
+			return
+		}
+
+		question := widget.NewLabel(fmt.Sprintf("Step %d: %s", session.Step(), node.Question))
+		question.Wrapping = fyne.TextWrapWord
+		question.TextStyle = fyne.TextStyle{Bold: true}
+		body.Add(question)
+		if node.Expected != "" {
+			expected := widget.NewLabel("Expected: " + node.Expected)
+			expected.Wrapping = fyne.TextWrapWord
+			expected.TextStyle = fyne.TextStyle{Italic: true}
+			body.Add(expected)
+		}
+		for i, branch := range node.Branches {
+			i := i
+			body.Add(widget.NewButton(branch.Answer, func() {
+				if err := session.Choose(i); err != nil {
+					dialog.ShowError(err, b.window)
+					return
+				}
+				render()
+			}))
+		}
+		body.Add(backBtn)
+	}
+	render()
+
+	content := container.NewBorder(header, nil, nil, nil, container.NewVScroll(body))
+	guideDialog = dialog.NewCustom("Guided Troubleshooting", "Close", content, b.window)
+	guideDialog.Resize(fyne.NewSize(560, 520))
+	guideDialog.Show()
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/knowledge"
+)
+
+// allSkipReasons is the report filter that shows every skipped file
+const allSkipReasons = "All reasons"
+
+// showIndexReport lists the files that were not indexed for the active profile and why
+func (b *BeanBot) showIndexReport() {
+	kb := b.activeKnowledge()
+	report := kb.IndexReport()
+
+	summary := widget.NewLabel(report.Summary())
+	summary.Wrapping = fyne.TextWrapWord
+	policy := kb.Policy()
+	limits := widget.NewLabel(fmt.Sprintf("Limits: text %s, PDF and Word %s, images %s. Add patterns to %s in the profile root to skip more files.",
+		formatLimit(policy.MaxTextBytes), formatLimit(policy.MaxPDFBytes), formatLimit(policy.MaxImageBytes), knowledge.IgnoreFile))
+	limits.Wrapping = fyne.TextWrapWord
+
+	counts := report.Counts()
+	categories := []string{allSkipReasons}
+	for category := range counts {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories[1:])
+
+	visible := report.Skipped
+	list := widget.NewList(
+		func() int { return len(visible) },
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Wrapping = fyne.TextWrapWord
+			return label
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			skipped := visible[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s\n%s: %s", skipped.Path, skipped.Category, skipped.Reason))
+		},
+	)
+
+	filter := widget.NewSelect(categories, func(selected string) {
+		visible = nil
+		for _, skipped := range report.Skipped {
+			if selected == allSkipReasons || skipped.Category == selected {
+				visible = append(visible, skipped)
+			}
+		}
+		list.Refresh()
+	})
+	filter.SetSelected(allSkipReasons)
+
+	header := container.NewVBox(summary, limits, container.NewBorder(nil, nil, widget.NewLabel("Show:"), nil, filter))
+	var body fyne.CanvasObject = list
+	if len(report.Skipped) == 0 {
+		body = widget.NewLabel("No files were skipped.")
+	}
+
+	reportDialog := dialog.NewCustom(fmt.Sprintf("Indexing Report - %s", kb.Profile().Name), "Close",
+		container.NewBorder(header, nil, nil, nil, body), b.window)
+	reportDialog.Resize(fyne.NewSize(600, 560))
+	reportDialog.Show()
+}
+
+// formatLimit formats a size limit in bytes for display
+func formatLimit(limit int64) string {
+	if limit <= 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%d MB", limit>>20)
+}
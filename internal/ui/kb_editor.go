@@ -0,0 +1,593 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/knowledge"
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// listEditor edits an ordered list of strings with one entry per row
+type listEditor struct {
+	placeholder string
+	entries     []*widget.Entry
+	rows        *fyne.Container
+	content     fyne.CanvasObject
+}
+
+// newListEditor creates an empty list editor
+func newListEditor(placeholder string) *listEditor {
+	l := &listEditor{placeholder: placeholder, rows: container.NewVBox()}
+	addBtn := widget.NewButtonWithIcon("Add", theme.ContentAddIcon(), func() {
+		l.SetItems(append(l.Items(), ""))
+	})
+	l.content = container.NewVBox(l.rows, container.NewHBox(addBtn))
+	return l
+}
+
+// SetItems replaces the rows of the editor
+func (l *listEditor) SetItems(items []string) {
+	l.entries = nil
+	l.rows.RemoveAll()
+	for i, item := range items {
+		i := i
+		entry := widget.NewEntry()
+		entry.SetPlaceHolder(l.placeholder)
+		entry.SetText(item)
+		l.entries = append(l.entries, entry)
+
+		upBtn := widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() { l.move(i, i-1) })
+		downBtn := widget.NewButtonWithIcon("", theme.MoveDownIcon(), func() { l.move(i, i+1) })
+		removeBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() { l.remove(i) })
+		if i == 0 {
+			upBtn.Disable()
+		}
+		if i == len(items)-1 {
+			downBtn.Disable()
+		}
+		l.rows.Add(container.NewBorder(nil, nil, nil, container.NewHBox(upBtn, downBtn, removeBtn), entry))
+	}
+	l.rows.Refresh()
+}
+
+// Items returns the current values, including empty rows
+func (l *listEditor) Items() []string {
+	items := make([]string, len(l.entries))
+	for i, entry := range l.entries {
+		items[i] = entry.Text
+	}
+	return items
+}
+
+// move swaps the row at from with the row at to
+func (l *listEditor) move(from, to int) {
+	items := l.Items()
+	if to < 0 || to >= len(items) {
+		return
+	}
+	items[from], items[to] = items[to], items[from]
+	l.SetItems(items)
+}
+
+// remove deletes the row at index
+func (l *listEditor) remove(index int) {
+	items := l.Items()
+	l.SetItems(append(items[:index], items[index+1:]...))
+}
+
+// recordList is a searchable list of the records in one section of the knowledge base
+type recordList struct {
+	list     *widget.List
+	search   *widget.Entry
+	visible  []int // Indexes of the records matching the search
+	selected int   // Index of the selected record, or -1
+
+	count   func() int
+	title   func(index int) string
+	matches func(index int, query string) bool
+}
+
+// newRecordList creates a record list; onSelect is called with the record index, or -1 when the selection is cleared
+func newRecordList(count func() int, title func(int) string, matches func(int, string) bool, onSelect func(int)) *recordList {
+	r := &recordList{selected: -1, count: count, title: title, matches: matches}
+	r.list = widget.NewList(
+		func() int { return len(r.visible) },
+		func() fyne.CanvasObject { return widget.NewLabel("record title placeholder") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(r.title(r.visible[id]))
+		},
+	)
+	r.list.OnSelected = func(id widget.ListItemID) {
+		onSelect(r.visible[id])
+		r.selected = r.visible[id]
+	}
+	r.list.OnUnselected = func(id widget.ListItemID) {
+		onSelect(-1)
+		r.selected = -1
+	}
+
+	r.search = widget.NewEntry()
+	r.search.SetPlaceHolder("Search...")
+	r.search.OnChanged = func(string) { r.filter() }
+	r.filter()
+	return r
+}
+
+// filter recomputes the visible records from the search text
+func (r *recordList) filter() {
+	query := strings.ToLower(strings.TrimSpace(r.search.Text))
+	r.visible = nil
+	for i := 0; i < r.count(); i++ {
+		if query == "" || r.matches(i, query) {
+			r.visible = append(r.visible, i)
+		}
+	}
+	r.list.UnselectAll()
+	r.list.Refresh()
+}
+
+// selectRecord clears the search and selects the record at index
+func (r *recordList) selectRecord(index int) {
+	r.search.SetText("")
+	r.filter()
+	for id, visible := range r.visible {
+		if visible == index {
+			r.list.Select(id)
+			r.list.ScrollTo(id)
+			return
+		}
+	}
+}
+
+// pairSeparator separates the two parts of causes, checks and document links in list editors
+const pairSeparator = " | "
+
+// errorCodeFields holds the widgets of the error code form
+type errorCodeFields struct {
+	code             *widget.Entry
+	description      *widget.Entry
+	category         *widget.Entry
+	severity         *widget.Select
+	aliases          *listEditor
+	patterns         *listEditor
+	safetyWarnings   *listEditor
+	steps            *listEditor
+	causes           *listEditor
+	checks           *listEditor
+	components       *listEditor
+	equipment        *listEditor
+	softwareVersions *listEditor
+	documentation    *listEditor
+	relatedCodes     *listEditor
+	escalationTeam   *widget.Entry
+	escalationWho    *widget.Entry
+	escalationWhen   *widget.Entry
+	decisionTree     *widget.Label
+}
+
+// newErrorCodeFields creates the widgets of the error code form
+func newErrorCodeFields() *errorCodeFields {
+	f := &errorCodeFields{
+		code:             widget.NewEntry(),
+		description:      widget.NewMultiLineEntry(),
+		category:         widget.NewEntry(),
+		severity:         widget.NewSelect(knowledge.Severities, nil),
+		aliases:          newListEditor("Other name, e.g. ERR-1001"),
+		patterns:         newListEditor(`Regular expression, e.g. timeout.*VICM`),
+		safetyWarnings:   newListEditor("Safety warning"),
+		steps:            newListEditor("Troubleshooting step"),
+		causes:           newListEditor("likelihood | cause, e.g. high | Loose USB cable"),
+		checks:           newListEditor("check | expected result"),
+		components:       newListEditor("Component"),
+		equipment:        newListEditor("Equipment, e.g. Cycler 3"),
+		softwareVersions: newListEditor("Version, e.g. iTest 4.2"),
+		documentation:    newListEditor("title | path or URL"),
+		relatedCodes:     newListEditor("Error code"),
+		escalationTeam:   widget.NewEntry(),
+		escalationWho:    widget.NewEntry(),
+		escalationWhen:   widget.NewEntry(),
+		decisionTree:     widget.NewLabel(""),
+	}
+	f.decisionTree.Wrapping = fyne.TextWrapWord
+	f.code.SetPlaceHolder("e.g. E1001")
+	f.escalationTeam.SetPlaceHolder("Team")
+	f.escalationWho.SetPlaceHolder("Contact")
+	f.escalationWhen.SetPlaceHolder("When to escalate")
+	return f
+}
+
+// form lays out the error code fields
+func (f *errorCodeFields) form() *widget.Form {
+	return widget.NewForm(
+		widget.NewFormItem("Code", f.code),
+		widget.NewFormItem("Aliases", f.aliases.content),
+		widget.NewFormItem("Description", f.description),
+		widget.NewFormItem("Category", f.category),
+		widget.NewFormItem("Severity", f.severity),
+		widget.NewFormItem("Safety warnings", f.safetyWarnings.content),
+		widget.NewFormItem("Steps", f.steps.content),
+		widget.NewFormItem("Probable causes", f.causes.content),
+		widget.NewFormItem("Diagnostic checks", f.checks.content),
+		widget.NewFormItem("Log patterns", f.patterns.content),
+		widget.NewFormItem("Components", f.components.content),
+		widget.NewFormItem("Equipment", f.equipment.content),
+		widget.NewFormItem("Software versions", f.softwareVersions.content),
+		widget.NewFormItem("Documentation", f.documentation.content),
+		widget.NewFormItem("Related codes", f.relatedCodes.content),
+		widget.NewFormItem("Escalation", container.NewVBox(f.escalationTeam, f.escalationWho, f.escalationWhen)),
+		widget.NewFormItem("Decision tree", f.decisionTree),
+	)
+}
+
+// load fills the form from an error code
+func (f *errorCodeFields) load(errorCode models.ErrorCode) {
+	f.code.SetText(errorCode.Code)
+	f.description.SetText(errorCode.Description)
+	f.category.SetText(errorCode.Category)
+	f.severity.SetSelected(strings.ToLower(errorCode.Severity))
+	f.aliases.SetItems(errorCode.Aliases)
+	f.patterns.SetItems(errorCode.Patterns)
+	f.safetyWarnings.SetItems(errorCode.SafetyWarnings)
+	f.steps.SetItems(errorCode.TroubleshootingSteps)
+	f.components.SetItems(errorCode.RelatedComponents)
+	f.equipment.SetItems(errorCode.Equipment)
+	f.softwareVersions.SetItems(errorCode.SoftwareVersions)
+	f.relatedCodes.SetItems(errorCode.RelatedCodes)
+
+	var causes, checks, documents []string
+	for _, cause := range errorCode.Causes {
+		causes = append(causes, joinPair(cause.Likelihood, cause.Description))
+	}
+	for _, check := range errorCode.DiagnosticChecks {
+		checks = append(checks, joinPair(check.Check, check.ExpectedResult))
+	}
+	for _, link := range errorCode.Documentation {
+		if link.Title == link.Location {
+			documents = append(documents, link.Location)
+		} else {
+			documents = append(documents, joinPair(link.Title, link.Location))
+		}
+	}
+	f.causes.SetItems(causes)
+	f.checks.SetItems(checks)
+	f.documentation.SetItems(documents)
+
+	var escalation models.Escalation
+	if errorCode.Escalation != nil {
+		escalation = *errorCode.Escalation
+	}
+	f.escalationTeam.SetText(escalation.Team)
+	f.escalationWho.SetText(escalation.Contact)
+	f.escalationWhen.SetText(escalation.When)
+
+	if errorCode.DecisionTree != nil {
+		f.decisionTree.SetText(fmt.Sprintf("%d nodes, starting at %q. Decision trees are edited in the JSON file.",
+			len(errorCode.DecisionTree.Nodes), errorCode.DecisionTree.Start))
+	} else {
+		f.decisionTree.SetText("None. Decision trees are edited in the JSON file.")
+	}
+}
+
+// value returns existing updated with the values of the form; fields the form does not edit are kept
+func (f *errorCodeFields) value(existing models.ErrorCode) models.ErrorCode {
+	errorCode := existing
+	errorCode.Code = f.code.Text
+	errorCode.Description = f.description.Text
+	errorCode.Category = f.category.Text
+	errorCode.Severity = f.severity.Selected
+	errorCode.Aliases = f.aliases.Items()
+	errorCode.Patterns = f.patterns.Items()
+	errorCode.SafetyWarnings = f.safetyWarnings.Items()
+	errorCode.TroubleshootingSteps = f.steps.Items()
+	errorCode.RelatedComponents = f.components.Items()
+	errorCode.Equipment = f.equipment.Items()
+	errorCode.SoftwareVersions = f.softwareVersions.Items()
+	errorCode.RelatedCodes = f.relatedCodes.Items()
+	errorCode.Escalation = &models.Escalation{
+		Team:    f.escalationTeam.Text,
+		Contact: f.escalationWho.Text,
+		When:    f.escalationWhen.Text,
+	}
+	errorCode.Causes = nil
+	errorCode.DiagnosticChecks = nil
+	errorCode.Documentation = nil
+
+	for _, item := range f.causes.Items() {
+		likelihood, description := splitPair(item)
+		if description == "" {
+			// A cause without a likelihood is kept so validation can report it
+			likelihood, description = "", likelihood
+		}
+		errorCode.Causes = append(errorCode.Causes, models.Cause{Description: description, Likelihood: likelihood})
+	}
+	for _, item := range f.checks.Items() {
+		check, expected := splitPair(item)
+		errorCode.DiagnosticChecks = append(errorCode.DiagnosticChecks, models.DiagnosticCheck{Check: check, ExpectedResult: expected})
+	}
+	for _, item := range f.documentation.Items() {
+		title, location := splitPair(item)
+		if location == "" {
+			title, location = item, item
+		}
+		errorCode.Documentation = append(errorCode.Documentation, models.DocumentLink{Title: title, Location: location})
+	}
+	return errorCode
+}
+
+// joinPair formats two values for a list editor row, omitting the separator when the second is empty
+func joinPair(first, second string) string {
+	if second == "" {
+		return first
+	}
+	return first + pairSeparator + second
+}
+
+// splitPair splits a list editor row at the first separator
+func splitPair(text string) (string, string) {
+	first, second, _ := strings.Cut(text, strings.TrimSpace(pairSeparator))
+	return strings.TrimSpace(first), strings.TrimSpace(second)
+}
+
+// commonIssueFields holds the widgets of the common issue form
+type commonIssueFields struct {
+	issue     *widget.Entry
+	symptoms  *listEditor
+	solutions *listEditor
+}
+
+// load fills the form from a common issue
+func (f *commonIssueFields) load(issue models.CommonIssue) {
+	f.issue.SetText(issue.Issue)
+	f.symptoms.SetItems(issue.Symptoms)
+	f.solutions.SetItems(issue.Solutions)
+}
+
+// value returns the common issue described by the form
+func (f *commonIssueFields) value() models.CommonIssue {
+	return models.CommonIssue{
+		Issue:     f.issue.Text,
+		Symptoms:  f.symptoms.Items(),
+		Solutions: f.solutions.Items(),
+	}
+}
+
+// showKnowledgeEditor opens the editor for the error codes and common issues in the error codes file
+func (b *BeanBot) showKnowledgeEditor() {
+	kb := b.activeKnowledge()
+	data, err := kb.LoadBaseData()
+	if err != nil {
+		dialog.ShowError(err, b.window)
+		return
+	}
+	// saved is compared with data to detect unsaved changes
+	saved, err := kb.LoadBaseData()
+	if err != nil {
+		dialog.ShowError(err, b.window)
+		return
+	}
+
+	status := widget.NewLabel(fmt.Sprintf("Editing %s. Fixes learned from feedback are kept separately and merged on top.", kb.ErrorCodesPath()) +
+		orphanedFixesNote(kb.OrphanedFixes()))
+	status.Wrapping = fyne.TextWrapWord
+
+	// Error codes tab
+	codeFields := newErrorCodeFields()
+	codeForm := container.NewVScroll(codeFields.form())
+	codeForm.Hide()
+
+	var codes *recordList
+	// commitCode writes the form back to the selected error code
+	commitCode := func() {
+		if codes != nil && codes.selected >= 0 {
+			data.ErrorCodes[codes.selected] = codeFields.value(data.ErrorCodes[codes.selected])
+			codes.list.Refresh()
+		}
+	}
+	codes = newRecordList(
+		func() int { return len(data.ErrorCodes) },
+		func(i int) string {
+			errorCode := data.ErrorCodes[i]
+			if strings.TrimSpace(errorCode.Code) == "" {
+				return "(new error code)"
+			}
+			return fmt.Sprintf("%s - %s", errorCode.Code, errorCode.Description)
+		},
+		func(i int, query string) bool {
+			errorCode := data.ErrorCodes[i]
+			return containsFold(query, errorCode.Code, errorCode.Description, errorCode.Category, errorCode.Severity) ||
+				containsFold(query, errorCode.Aliases...) ||
+				containsFold(query, errorCode.TroubleshootingSteps...) ||
+				containsFold(query, errorCode.RelatedComponents...)
+		},
+		func(i int) {
+			commitCode()
+			if i < 0 {
+				codeForm.Hide()
+				return
+			}
+			codeFields.load(data.ErrorCodes[i])
+			codeForm.Show()
+		},
+	)
+
+	newCodeBtn := widget.NewButtonWithIcon("New", theme.ContentAddIcon(), func() {
+		commitCode()
+		data.ErrorCodes = append(data.ErrorCodes, models.ErrorCode{Severity: "medium"})
+		codes.selectRecord(len(data.ErrorCodes) - 1)
+	})
+	deleteCodeBtn := widget.NewButtonWithIcon("Delete", theme.DeleteIcon(), func() {
+		index := codes.selected
+		if index < 0 {
+			return
+		}
+		dialog.ShowConfirm("Delete Error Code", fmt.Sprintf("Delete %s?", codes.title(index)), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			codes.selected = -1
+			data.ErrorCodes = append(data.ErrorCodes[:index], data.ErrorCodes[index+1:]...)
+			codeForm.Hide()
+			codes.filter()
+		}, b.window)
+	})
+	deleteCodeBtn.Importance = widget.DangerImportance
+
+	// Common issues tab
+	issueFields := &commonIssueFields{
+		issue:     widget.NewEntry(),
+		symptoms:  newListEditor("Symptom"),
+		solutions: newListEditor("Solution"),
+	}
+	issueForm := container.NewVScroll(widget.NewForm(
+		widget.NewFormItem("Issue", issueFields.issue),
+		widget.NewFormItem("Symptoms", issueFields.symptoms.content),
+		widget.NewFormItem("Solutions", issueFields.solutions.content),
+	))
+	issueForm.Hide()
+
+	var issues *recordList
+	// commitIssue writes the form back to the selected common issue
+	commitIssue := func() {
+		if issues != nil && issues.selected >= 0 {
+			data.CommonIssues[issues.selected] = issueFields.value()
+			issues.list.Refresh()
+		}
+	}
+	issues = newRecordList(
+		func() int { return len(data.CommonIssues) },
+		func(i int) string {
+			if strings.TrimSpace(data.CommonIssues[i].Issue) == "" {
+				return "(new common issue)"
+			}
+			return data.CommonIssues[i].Issue
+		},
+		func(i int, query string) bool {
+			issue := data.CommonIssues[i]
+			return containsFold(query, issue.Issue) ||
+				containsFold(query, issue.Symptoms...) ||
+				containsFold(query, issue.Solutions...)
+		},
+		func(i int) {
+			commitIssue()
+			if i < 0 {
+				issueForm.Hide()
+				return
+			}
+			issueFields.load(data.CommonIssues[i])
+			issueForm.Show()
+		},
+	)
+
+	newIssueBtn := widget.NewButtonWithIcon("New", theme.ContentAddIcon(), func() {
+		commitIssue()
+		data.CommonIssues = append(data.CommonIssues, models.CommonIssue{})
+		issues.selectRecord(len(data.CommonIssues) - 1)
+	})
+	deleteIssueBtn := widget.NewButtonWithIcon("Delete", theme.DeleteIcon(), func() {
+		index := issues.selected
+		if index < 0 {
+			return
+		}
+		dialog.ShowConfirm("Delete Common Issue", fmt.Sprintf("Delete %q?", issues.title(index)), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			issues.selected = -1
+			data.CommonIssues = append(data.CommonIssues[:index], data.CommonIssues[index+1:]...)
+			issueForm.Hide()
+			issues.filter()
+		}, b.window)
+	})
+	deleteIssueBtn.Importance = widget.DangerImportance
+
+	// save validates the working copy and writes it with a backup of the previous file
+	save := func() bool {
+		commitCode()
+		commitIssue()
+		if err := kb.SaveBaseData(data); err != nil {
+			var invalid *knowledge.ValidationError
+			if errors.As(err, &invalid) {
+				b.showValidationProblems(invalid)
+			} else {
+				dialog.ShowError(err, b.window)
+			}
+			return false
+		}
+		b.debugLog("Saved %d error codes and %d common issues to %s", len(data.ErrorCodes), len(data.CommonIssues), kb.ErrorCodesPath())
+		status.SetText(fmt.Sprintf("✅ Saved %d error codes and %d common issues. The previous file was kept as %s.bak.",
+			len(data.ErrorCodes), len(data.CommonIssues), kb.ErrorCodesPath()) + orphanedFixesNote(kb.OrphanedFixes()))
+		if reloaded, err := kb.LoadBaseData(); err == nil {
+			saved = reloaded
+		}
+		return true
+	}
+	saveBtn := widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() { save() })
+	saveBtn.Importance = widget.HighImportance
+
+	recordPane := func(records *recordList, form fyne.CanvasObject, newBtn, deleteBtn *widget.Button) fyne.CanvasObject {
+		left := container.NewBorder(records.search, container.NewGridWithColumns(2, newBtn, deleteBtn), nil, nil, records.list)
+		split := container.NewHSplit(left, form)
+		split.Offset = 0.35
+		return split
+	}
+	tabs := container.NewAppTabs(
+		container.NewTabItem("Error Codes", recordPane(codes, codeForm, newCodeBtn, deleteCodeBtn)),
+		container.NewTabItem("Common Issues", recordPane(issues, issueForm, newIssueBtn, deleteIssueBtn)),
+	)
+
+	content := container.NewBorder(status, container.NewHBox(saveBtn), nil, nil, tabs)
+	editorDialog := dialog.NewCustom("Knowledge Base Editor", "Close", content, b.window)
+	editorDialog.SetOnClosed(func() {
+		commitCode()
+		commitIssue()
+		if reflect.DeepEqual(knowledge.Normalize(data), knowledge.Normalize(saved)) {
+			return
+		}
+		dialog.ShowConfirm("Unsaved Changes", "Save your changes to the knowledge base?", func(confirmed bool) {
+			if confirmed && !save() {
+				// Keep the edits open so the problems can be fixed
+				editorDialog.Show()
+			}
+		}, b.window)
+	})
+	editorDialog.Resize(fyne.NewSize(860, 600))
+	editorDialog.Show()
+}
+
+// orphanedFixesNote warns that fixes learned for error codes that were renamed or deleted are not used
+func orphanedFixesNote(codes []string) string {
+	if len(codes) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n⚠️ Fixes learned from feedback for %s are not used because those error codes were renamed or deleted. Add the old code as an alias to keep them.",
+		strings.Join(codes, ", "))
+}
+
+// showValidationProblems lists the problems that prevented the knowledge base from being saved
+func (b *BeanBot) showValidationProblems(invalid *knowledge.ValidationError) {
+	problems := widget.NewLabel("- " + strings.Join(invalid.Problems, "\n- "))
+	problems.Wrapping = fyne.TextWrapWord
+	scroll := container.NewVScroll(problems)
+	scroll.SetMinSize(fyne.NewSize(460, 240))
+	content := container.NewBorder(widget.NewLabel("Fix these problems before saving:"), nil, nil, nil, scroll)
+	dialog.ShowCustom("Knowledge Base Not Saved", "OK", content, b.window)
+}
+
+// containsFold reports whether any value contains the lower case query, ignoring case
+func containsFold(query string, values ...string) bool {
+	for _, value := range values {
+		if strings.Contains(strings.ToLower(value), query) {
+			return true
+		}
+	}
+	return false
+}
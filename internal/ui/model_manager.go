@@ -0,0 +1,226 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// showModelManager opens the dialog for downloading, deleting and inspecting Ollama models
+func (b *BeanBot) showModelManager() {
+	var installed []string
+	selected := ""
+
+	details := widget.NewRichTextFromMarkdown("*Select a model to see its details*")
+	details.Wrapping = fyne.TextWrapWord
+
+	modelList := widget.NewList(
+		func() int { return len(installed) },
+		func() fyne.CanvasObject { return widget.NewLabel("model name placeholder") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			name := installed[id]
+			if name == b.ollamaClient.GetCurrentModel() {
+				name += " (current)"
+			}
+			item.(*widget.Label).SetText(name)
+		},
+	)
+
+	useBtn := widget.NewButton("Use Model", nil)
+	deleteBtn := widget.NewButton("Delete", nil)
+	deleteBtn.Importance = widget.DangerImportance
+	useBtn.Disable()
+	deleteBtn.Disable()
+
+	// refresh reloads the installed models and keeps the footer dropdown in sync
+	refresh := func() {
+		go func() {
+			available, err := b.ollamaClient.GetAvailableModels()
+			if err != nil {
+				b.debugLog("Model manager failed to list models: %v", err)
+				details.ParseMarkdown(fmt.Sprintf("**❌ Could not list models:** %v", err))
+				return
+			}
+			sort.Strings(available)
+			installed = available
+			modelList.UnselectAll()
+			modelList.Refresh()
+			b.updateModelOptions(available, b.ollamaClient.GetCurrentModel())
+		}()
+	}
+
+	modelList.OnSelected = func(id widget.ListItemID) {
+		selected = installed[id]
+		useBtn.Enable()
+		deleteBtn.Enable()
+		details.ParseMarkdown(fmt.Sprintf("## %s\n\n*Loading details...*", selected))
+
+		name := selected
+		go func() {
+			info, err := b.ollamaClient.ShowModel(name)
+			if name != selected {
+				return // Selection changed while loading
+			}
+			if err != nil {
+				details.ParseMarkdown(fmt.Sprintf("## %s\n\n**❌ Could not load details:** %v", name, err))
+				return
+			}
+			details.ParseMarkdown(formatModelInfo(info))
+		}()
+	}
+	modelList.OnUnselected = func(id widget.ListItemID) {
+		selected = ""
+		useBtn.Disable()
+		deleteBtn.Disable()
+	}
+
+	useBtn.OnTapped = func() {
+		if selected == "" {
+			return
+		}
+		b.debugLog("Model selected from model manager: %s", selected)
+		b.ollamaClient.SetModel(selected)
+		b.statusLabel.SetText(fmt.Sprintf("🤖 BeanBot AI - %s ✅ ready to help!", selected))
+		refresh()
+	}
+
+	deleteBtn.OnTapped = func() {
+		if selected == "" {
+			return
+		}
+		name := selected
+		dialog.ShowConfirm("Delete Model", fmt.Sprintf("Delete %s from the Ollama server?", name), func(confirmed bool) {
+			if !confirmed {
+				return
+			}
+			go func() {
+				if err := b.ollamaClient.DeleteModel(name); err != nil {
+					dialog.ShowError(fmt.Errorf("failed to delete %s: %w", name, err), b.window)
+					return
+				}
+				b.debugLog("Deleted model: %s", name)
+				details.ParseMarkdown(fmt.Sprintf("*%s was deleted*", name))
+				refresh()
+			}()
+		}, b.window)
+	}
+
+	// Download section with one progress bar per pull
+	pullEntry := widget.NewEntry()
+	pullEntry.SetPlaceHolder("Model to download, e.g. llama3.2:3b")
+	downloads := container.NewVBox()
+
+	pullBtn := widget.NewButton("Download", func() {
+		name := strings.TrimSpace(pullEntry.Text)
+		if name == "" {
+			return
+		}
+		pullEntry.SetText("")
+
+		progressLabel := widget.NewLabel(fmt.Sprintf("%s: starting download...", name))
+		progressBar := widget.NewProgressBar()
+		downloads.Add(container.NewVBox(progressLabel, progressBar))
+
+		go func() {
+			b.debugLog("Pulling model: %s", name)
+			err := b.ollamaClient.PullModel(name, func(update models.PullProgress) {
+				if update.Total > 0 {
+					progressLabel.SetText(fmt.Sprintf("%s: %s (%s / %s)", name, update.Status,
+						formatBytes(update.Completed), formatBytes(update.Total)))
+					progressBar.SetValue(update.Fraction())
+				} else {
+					progressLabel.SetText(fmt.Sprintf("%s: %s", name, update.Status))
+				}
+			})
+			if err != nil {
+				b.debugLog("Pull of %s failed: %v", name, err)
+				progressLabel.SetText(fmt.Sprintf("❌ %s: %v", name, err))
+				return
+			}
+			progressBar.SetValue(1)
+			progressLabel.SetText(fmt.Sprintf("✅ %s downloaded", name))
+			refresh()
+		}()
+	})
+	pullBtn.Importance = widget.HighImportance
+
+	top := container.NewVBox(
+		container.NewBorder(nil, nil, nil, pullBtn, pullEntry),
+		downloads,
+	)
+	split := container.NewHSplit(modelList, container.NewScroll(details))
+	split.Offset = 0.35
+
+	content := container.NewBorder(
+		top,
+		container.NewGridWithColumns(2, useBtn, deleteBtn),
+		nil,
+		nil,
+		split,
+	)
+
+	modelDialog := dialog.NewCustom("Model Manager", "Close", content, b.window)
+	modelDialog.Resize(fyne.NewSize(700, 500))
+	modelDialog.Show()
+	refresh()
+}
+
+// formatModelInfo renders model details as markdown
+func formatModelInfo(info *models.ModelInfo) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("## %s\n\n", info.Name))
+	text.WriteString(fmt.Sprintf("- **Family:** %s\n", valueOrUnknown(info.Family)))
+	text.WriteString(fmt.Sprintf("- **Parameters:** %s\n", valueOrUnknown(info.ParameterSize)))
+	text.WriteString(fmt.Sprintf("- **Quantization:** %s\n", valueOrUnknown(info.QuantizationLevel)))
+	text.WriteString(fmt.Sprintf("- **Format:** %s\n", valueOrUnknown(info.Format)))
+	if info.ContextLength > 0 {
+		text.WriteString(fmt.Sprintf("- **Context length:** %d tokens\n", info.ContextLength))
+	} else {
+		text.WriteString("- **Context length:** unknown\n")
+	}
+	if len(info.Capabilities) > 0 {
+		text.WriteString(fmt.Sprintf("- **Capabilities:** %s\n", strings.Join(info.Capabilities, ", ")))
+	}
+
+	if len(info.Parameters) > 0 {
+		text.WriteString("\n### Default Parameters\n\n")
+		names := make([]string, 0, len(info.Parameters))
+		for name := range info.Parameters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			text.WriteString(fmt.Sprintf("- **%s:** %s\n", name, info.Parameters[name]))
+		}
+	}
+	return text.String()
+}
+
+// valueOrUnknown returns the value or "unknown" when it is empty
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
+
+// formatBytes formats a byte count using binary units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
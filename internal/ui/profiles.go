@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/config"
+	"github.com/beanspout/2025-beanbot/internal/knowledge"
+)
+
+// createProfileSelect creates the footer dropdown for switching knowledge profiles
+func (b *BeanBot) createProfileSelect() *widget.Select {
+	var names []string
+	for _, profile := range b.config.KnowledgeBase.ProfileList() {
+		names = append(names, profile.Name)
+	}
+	b.profileSelect = widget.NewSelect(names, nil)
+	b.profileSelect.SetSelected(b.activeKnowledge().Profile().Name)
+	b.profileSelect.OnChanged = b.switchProfile
+	return b.profileSelect
+}
+
+// switchProfile loads the named knowledge profile and makes it the active knowledge base.
+// Uploads belong to the previous knowledge base and are not carried over.
+func (b *BeanBot) switchProfile(name string) {
+	current := b.activeKnowledge().Profile().Name
+	profile, ok := b.config.KnowledgeBase.Profile(name)
+	if !ok || name == current {
+		return
+	}
+
+	b.debugLog("Switching knowledge profile from %s to %s", current, name)
+	b.statusLabel.SetText(fmt.Sprintf("📚 Loading the %s knowledge base...", name))
+	b.profileSelect.Disable()
+
+	go func() {
+		defer b.profileSelect.Enable()
+
+		kb, err := knowledge.NewKnowledgeDatabase(profile, b.config.IndexPolicy())
+		if err != nil {
+			b.debugLog("Failed to load knowledge profile %s: %v", name, err)
+			dialog.ShowError(fmt.Errorf("could not load the %s knowledge base: %w", name, err), b.window)
+			b.statusLabel.SetText(fmt.Sprintf("📚 Still using the %s knowledge base", current))
+			b.profileSelect.SetSelected(current)
+			return
+		}
+
+		b.knowledgeMu.Lock()
+		b.knowledgeDB = kb
+		b.knowledgeMu.Unlock()
+		b.refreshUploads()
+		b.hideFeedbackBar()
+		b.statusLabel.SetText(fmt.Sprintf("📚 Using the %s knowledge base: %d error codes, %d documents, %d files skipped",
+			name, len(kb.GetData().ErrorCodes), kb.DocumentCount(), len(kb.IndexReport().Skipped)))
+
+		b.config.KnowledgeBase.ActiveProfile = name
+		if err := b.config.Save(config.DefaultPath); err != nil {
+			b.debugLog("Failed to save the active knowledge profile: %v", err)
+		}
+	}()
+}
+
+// activeKnowledge returns the knowledge base of the selected profile. Work that spans several
+// lookups, such as answering a question, reads it once and keeps using that knowledge base.
+func (b *BeanBot) activeKnowledge() *knowledge.KnowledgeDatabase {
+	b.knowledgeMu.RLock()
+	defer b.knowledgeMu.RUnlock()
+	return b.knowledgeDB
+}
+
+// labName returns the lab name passed to prompt templates, preferring the knowledge base profile's
+func (b *BeanBot) labName(kb *knowledge.KnowledgeDatabase) string {
+	if name := kb.Profile().LabName; name != "" {
+		return name
+	}
+	return b.config.Prompts.LabName
+}
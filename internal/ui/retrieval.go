@@ -0,0 +1,652 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/beanspout/2025-beanbot/internal/intent"
+	"github.com/beanspout/2025-beanbot/internal/knowledge"
+	"github.com/beanspout/2025-beanbot/internal/models"
+	"github.com/beanspout/2025-beanbot/internal/prompt"
+)
+
+// Context priorities used when filling the prompt budget (lower values are included first)
+const (
+	priorityUpload = iota
+	priorityErrorCode
+	priorityCommonIssue
+	priorityDocument
+	priorityGeneral
+)
+
+const (
+	// minContextBudget is the smallest context budget used even when the window is nearly full
+	minContextBudget = 256
+	// defaultAnswerReserve is the number of tokens reserved for the answer when num_predict is unset
+	defaultAnswerReserve = 512
+	// maxRecentMeetings is the number of recent meeting notes used when none match the question
+	maxRecentMeetings = 3
+	// maxTicketMentions is the number of documents mentioning a ticket that are added for it
+	maxTicketMentions = 3
+	// maxOutlineMatches is the number of headings, keys or symbols matching a question that are
+	// added ahead of a Markdown, data or source file's text
+	maxOutlineMatches = 12
+)
+
+// datePattern matches ISO dates such as 2021-02-03 in questions and meeting note file names
+var datePattern = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+
+// stopWords are ignored when scoring how well a document matches a question
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "was": true, "were": true, "what": true,
+	"how": true, "can": true, "does": true, "with": true, "this": true, "that": true, "from": true,
+	"have": true, "has": true, "you": true, "when": true, "where": true, "why": true, "who": true,
+	"our": true, "about": true, "there": true, "into": true, "not": true, "get": true,
+}
+
+// classifyQuestion determines the intent of a question using the knowledge base's error codes and their aliases
+func (b *BeanBot) classifyQuestion(kb *knowledge.KnowledgeDatabase, userInput string) intent.Result {
+	var codes []string
+	for _, errorCode := range kb.GetData().ErrorCodes {
+		codes = append(codes, errorCode.Code)
+		codes = append(codes, errorCode.Aliases...)
+	}
+	return b.classifier.Classify(userInput, intent.Signals{
+		ErrorCodes: codes,
+		HasUploads: len(kb.Uploads()) > 0,
+	})
+}
+
+// askModel sends a short prompt to the current model, used by the classifier's model pass
+func (b *BeanBot) askModel(text string) (string, error) {
+	generation, err := b.ollamaClient.GenerateResponse(text)
+	if err != nil {
+		return "", err
+	}
+	return generation.Text, nil
+}
+
+// templateFor returns the prompt template used to answer a question of the given intent
+func templateFor(kind intent.Intent, userInput string) string {
+	switch kind {
+	case intent.ErrorLookup:
+		return prompt.TemplateErrorLookup
+	case intent.HowTo:
+		lowerInput := strings.ToLower(strings.TrimSpace(userInput))
+		for _, prefix := range []string{"what is", "what are", "what does", "explain", "describe", "why "} {
+			if strings.HasPrefix(lowerInput, prefix) {
+				return prompt.TemplateExplain
+			}
+		}
+		return prompt.TemplateHowTo
+	case intent.LogAnalysis:
+		return prompt.TemplateSummarizeLog
+	case intent.MeetingNotes:
+		return prompt.TemplateMeetingNotes
+	case intent.ChitChat:
+		return prompt.TemplateChitChat
+	default:
+		return prompt.TemplateTroubleshoot
+	}
+}
+
+// contextBudget returns the number of tokens available for knowledge base context once the
+// prompt template and the answer have been accounted for in the current model's window
+func (b *BeanBot) contextBudget(kb *knowledge.KnowledgeDatabase, userInput, templateName string) int {
+	model := b.ollamaClient.GetCurrentModel()
+	window := b.ollamaClient.ContextWindow(model)
+
+	reserve := defaultAnswerReserve
+	if options := b.ollamaClient.GenerationOptionsFor(model); options.NumPredict != nil && *options.NumPredict > 0 {
+		reserve = *options.NumPredict
+	}
+
+	overhead := 0
+	if rendered, err := b.renderPrompt(kb, templateName, userInput, "", nil); err == nil {
+		overhead = prompt.EstimateTokens(rendered.Text)
+	}
+
+	budget := window - reserve - overhead
+	b.debugLog("Context window for %s: %d tokens, answer reserve %d, context budget %d", model, window, reserve, budget)
+	if budget < minContextBudget {
+		return minContextBudget
+	}
+	return budget
+}
+
+// buildEngineeringContext builds context from the given knowledge database using the retrieval strategy
+// for the question's intent, and returns the sources that were included along with the structured
+// knowledge that was retrieved
+func (b *BeanBot) buildEngineeringContext(kb *knowledge.KnowledgeDatabase, userInput string, kind intent.Intent, budget int) (string, []string, *models.RetrievedContext) {
+	candidates := prompt.NewContextBuilder()
+	lowerInput := strings.ToLower(userInput)
+
+	switch kind {
+	case intent.ChitChat:
+		// Small talk is answered without knowledge base context
+		return "", []string{}, &models.RetrievedContext{Question: userInput}
+
+	case intent.ErrorLookup:
+		// The error code entries themselves, then documents that mention the code
+		b.addErrorCodes(kb, candidates, lowerInput)
+		b.addCommonIssues(kb, candidates, lowerInput)
+		b.addDocuments(kb, candidates, lowerInput, func(doc knowledge.Document) bool {
+			return mentionsErrorCode(strings.ToLower(doc.Content), kb.GetData().ErrorCodes, lowerInput)
+		})
+
+	case intent.HowTo:
+		// Documentation only, ranked by how well it matches the question
+		b.addUploads(kb, candidates, lowerInput, 800)
+		b.addDocuments(kb, candidates, lowerInput, func(doc knowledge.Document) bool {
+			return keywordScore(lowerInput, doc.Path+" "+doc.Content) > 0
+		})
+
+	case intent.LogAnalysis:
+		// Uploaded and known logs get most of the budget, then the error codes they contain
+		b.addUploads(kb, candidates, lowerInput, 2000)
+		logText := lowerInput
+		for _, doc := range b.searchableDocuments(kb) {
+			if doc.Extractor == knowledge.ExtractLog && keywordScore(lowerInput, doc.Path+" "+doc.Content) > 0 {
+				b.addPassage(candidates, lowerInput, priorityUpload, fmt.Sprintf("From Log (%s):\n", doc.Path), "Log: "+doc.Path, doc.ID, excerpt(doc.Content, 1500))
+				logText += " " + strings.ToLower(doc.Content)
+			}
+		}
+		for _, upload := range kb.Uploads() {
+			logText += " " + strings.ToLower(upload.Content)
+		}
+		b.addErrorCodes(kb, candidates, logText)
+		b.addCommonIssues(kb, candidates, lowerInput)
+
+	case intent.MeetingNotes:
+		b.addMeetingNotes(kb, candidates, lowerInput)
+
+	default:
+		b.addTroubleshootingContext(kb, candidates, lowerInput)
+	}
+
+	// Tickets named in the question are added whatever the intent
+	b.addTickets(kb, candidates, userInput, lowerInput)
+
+	// If no specific context found, include some general troubleshooting content
+	if candidates.Len() == 0 {
+		b.addGeneralContext(kb, candidates)
+	}
+
+	return b.fillContext(userInput, candidates, budget)
+}
+
+// addTroubleshootingContext adds uploads, error codes, common issues and every relevant document
+func (b *BeanBot) addTroubleshootingContext(kb *knowledge.KnowledgeDatabase, candidates *prompt.ContextBuilder, lowerInput string) {
+	// PRIORITY 0: Include user-uploaded files first (highest priority)
+	b.addUploads(kb, candidates, lowerInput, 800)
+
+	// Search HTML documentation files (most comprehensive documentation)
+	for _, doc := range b.searchableDocuments(kb) {
+		if doc.Type == knowledge.DocHTML && kb.IsRelevantContent(lowerInput, doc.Content) {
+			b.addPassage(candidates, lowerInput, priorityDocument, fmt.Sprintf("From Engineering Documentation (%s):\n", doc.Path), "Engineering Documentation: "+doc.Path, doc.ID, excerpt(doc.Content, 500))
+		}
+	}
+
+	// PRIORITY 1 and 2: Search for relevant error codes and common issues
+	b.addErrorCodes(kb, candidates, lowerInput)
+	b.addCommonIssues(kb, candidates, lowerInput)
+
+	// PRIORITY 3: Search through other text files (non-HTML) and documents for relevant content
+	b.addDocuments(kb, candidates, lowerInput, func(doc knowledge.Document) bool {
+		return doc.Type != knowledge.DocHTML && kb.IsRelevantContent(lowerInput, doc.Content)
+	})
+}
+
+// addTickets adds the Jira exports of the tickets named in the question and the documents that
+// mention them, such as meeting notes and email threads
+func (b *BeanBot) addTickets(kb *knowledge.KnowledgeDatabase, candidates *prompt.ContextBuilder, userInput, lowerInput string) {
+	for _, key := range kb.FindTickets(strings.ToUpper(userInput)) {
+		links := kb.Ticket(key)
+		b.debugLog("Ticket %s: %d exports, %d mentions", key, len(links.Exports), len(links.Mentions))
+		for _, doc := range links.Exports {
+			candidates.Add(prompt.ContextItem{
+				Priority:   priorityErrorCode,
+				Score:      10, // The ticket the user asked about outranks keyword matches
+				Source:     fmt.Sprintf("Jira Ticket %s: %s", key, doc.Path),
+				DocumentID: doc.ID,
+				Header:     fmt.Sprintf("From Jira Ticket %s (%s):\n", key, doc.Path),
+				Text:       knowledge.TicketSection(doc, key),
+			})
+		}
+		for i, doc := range links.Mentions {
+			if i == maxTicketMentions {
+				break
+			}
+			b.addPassage(candidates, lowerInput, priorityDocument, fmt.Sprintf("Mentions %s (%s):\n", key, doc.Path), fmt.Sprintf("Mentions %s: %s", key, doc.Path), doc.ID, knowledge.MentionExcerpt(doc, key, 800))
+		}
+	}
+}
+
+// addPassage adds a document excerpt as a context candidate scored against the question
+func (b *BeanBot) addPassage(candidates *prompt.ContextBuilder, lowerInput string, priority int, header, source, documentID, text string) {
+	candidates.Add(prompt.ContextItem{
+		Priority:   priority,
+		Score:      keywordScore(lowerInput, source+" "+text),
+		Source:     source,
+		DocumentID: documentID,
+		Header:     header,
+		Text:       text,
+	})
+}
+
+// searchableDocuments returns the indexed documents, leaving out copies of documents with identical content
+func (b *BeanBot) searchableDocuments(kb *knowledge.KnowledgeDatabase) []knowledge.Document {
+	var docs []knowledge.Document
+	for _, doc := range kb.Documents() {
+		if doc.DuplicateOf == "" {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// addUploads adds the user's uploaded files, using liberal inclusion since the user chose them
+func (b *BeanBot) addUploads(kb *knowledge.KnowledgeDatabase, candidates *prompt.ContextBuilder, lowerInput string, limit int) {
+	userUploads := kb.Uploads()
+	b.debugLog("Processing user uploads: found %d uploaded files", len(userUploads))
+
+	for _, upload := range userUploads {
+		filename, content := upload.Name, upload.Content
+		b.debugLog("Checking uploaded file: %s, content length: %d", filename, len(content))
+
+		// For user uploads, use much more liberal inclusion criteria
+		// Include if ANY of these conditions are met:
+		// 1. Contains any word from user input (even short words)
+		// 2. User input is very short (general query - include all uploads)
+		// 3. Contains common troubleshooting keywords
+		// 4. File has substantial content (user uploaded it for a reason)
+		shouldInclude := false
+
+		if len(strings.TrimSpace(lowerInput)) <= 10 {
+			// Very short queries - include all uploaded files
+			shouldInclude = true
+			b.debugLog("Including %s: short user query", filename)
+		} else if len(content) > 50 {
+			// Check for any word matches (much more liberal than IsRelevantContent)
+			inputWords := strings.Fields(lowerInput)
+			lowerContent := strings.ToLower(content)
+
+			for _, word := range inputWords {
+				if len(word) > 2 && strings.Contains(lowerContent, word) {
+					shouldInclude = true
+					b.debugLog("Including %s: found word match '%s'", filename, word)
+					break
+				}
+			}
+
+			// Also include if content has technical keywords
+			technicalKeywords := []string{"error", "problem", "issue", "step", "solution", "configure", "install", "troubleshoot"}
+			for _, keyword := range technicalKeywords {
+				if strings.Contains(lowerContent, keyword) {
+					shouldInclude = true
+					b.debugLog("Including %s: contains technical keyword '%s'", filename, keyword)
+					break
+				}
+			}
+		}
+
+		if !shouldInclude {
+			b.debugLog("File %s is NOT included for user input '%s'", filename, lowerInput)
+			continue
+		}
+
+		b.debugLog("File %s is included for user input", filename)
+		b.addPassage(candidates, lowerInput, priorityUpload, fmt.Sprintf("From User Upload (%s):\n", filename), "User Upload: "+filename, upload.ID, excerpt(content, limit))
+	}
+}
+
+// addErrorCodes adds the error codes that match the text by code, alias, pattern, description or component
+func (b *BeanBot) addErrorCodes(kb *knowledge.KnowledgeDatabase, candidates *prompt.ContextBuilder, lowerText string) {
+	for _, errorCode := range kb.GetData().ErrorCodes {
+		mentioned := knowledge.MentionsErrorCode(errorCode, lowerText)
+		if !mentioned &&
+			!strings.Contains(lowerText, strings.ToLower(errorCode.Description)) &&
+			!kb.ContainsAnyKeyword(lowerText, errorCode.RelatedComponents) {
+			continue
+		}
+
+		score := keywordScore(lowerText, errorCode.Code+" "+errorCode.Description)
+		if mentioned {
+			score += 10 // An exact code match outranks keyword matches
+		}
+		candidates.Add(prompt.ContextItem{
+			Priority: priorityErrorCode,
+			Score:    score,
+			Source:   "Error Code: " + errorCode.Code,
+			Header:   fmt.Sprintf("Error Code %s: %s\n", errorCode.Code, errorCode.Description),
+			Text:     errorCodeContext(errorCode),
+			Payload:  errorCode,
+		})
+	}
+}
+
+// errorCodeContext formats an error code for the prompt as blank line separated sections, so
+// truncation keeps whole sections. Safety warnings come first so they survive truncation.
+func errorCodeContext(errorCode models.ErrorCode) string {
+	var sections []string
+	list := func(title string, items []string, numbered bool) {
+		if len(items) == 0 {
+			return
+		}
+		var section strings.Builder
+		section.WriteString(title + ":")
+		for i, item := range items {
+			if numbered {
+				section.WriteString(fmt.Sprintf("\n%d. %s", i+1, item))
+			} else {
+				section.WriteString("\n- " + item)
+			}
+		}
+		sections = append(sections, section.String())
+	}
+
+	list("Safety Warnings", errorCode.SafetyWarnings, false)
+	list("Troubleshooting Steps", errorCode.TroubleshootingSteps, true)
+
+	var causes []string
+	for _, cause := range errorCode.Causes {
+		causes = append(causes, fmt.Sprintf("%s (%s likelihood)", cause.Description, cause.Likelihood))
+	}
+	list("Probable Causes", causes, false)
+
+	var checks []string
+	for _, check := range errorCode.DiagnosticChecks {
+		if check.ExpectedResult != "" {
+			checks = append(checks, fmt.Sprintf("%s - expected: %s", check.Check, check.ExpectedResult))
+		} else {
+			checks = append(checks, check.Check)
+		}
+	}
+	list("Diagnostic Checks", checks, true)
+
+	var details []string
+	if errorCode.Escalation != nil {
+		details = append(details, "Escalation: "+errorCode.Escalation.String())
+	}
+	if len(errorCode.Equipment) > 0 {
+		details = append(details, "Applies to: "+strings.Join(errorCode.Equipment, ", "))
+	}
+	if len(errorCode.SoftwareVersions) > 0 {
+		details = append(details, "Software versions: "+strings.Join(errorCode.SoftwareVersions, ", "))
+	}
+	if len(errorCode.RelatedCodes) > 0 {
+		details = append(details, "Related codes: "+strings.Join(errorCode.RelatedCodes, ", "))
+	}
+	if len(details) > 0 {
+		sections = append(sections, strings.Join(details, "\n"))
+	}
+
+	return strings.Join(sections, "\n\n")
+}
+
+// addCommonIssues adds the common issues that match the question by name or symptom
+func (b *BeanBot) addCommonIssues(kb *knowledge.KnowledgeDatabase, candidates *prompt.ContextBuilder, lowerInput string) {
+	for _, issue := range kb.GetData().CommonIssues {
+		if !strings.Contains(lowerInput, strings.ToLower(issue.Issue)) &&
+			!kb.ContainsAnyKeyword(lowerInput, issue.Symptoms) {
+			continue
+		}
+
+		var solutions strings.Builder
+		solutions.WriteString("Solutions:\n")
+		for i, solution := range issue.Solutions {
+			solutions.WriteString(fmt.Sprintf("%d. %s\n", i+1, solution))
+		}
+		candidates.Add(prompt.ContextItem{
+			Priority: priorityCommonIssue,
+			Score:    keywordScore(lowerInput, issue.Issue+" "+strings.Join(issue.Symptoms, " ")),
+			Source:   "Common Issue: " + issue.Issue,
+			Header:   fmt.Sprintf("Common Issue: %s\n", issue.Issue),
+			Text:     strings.TrimSuffix(solutions.String(), "\n"),
+			Payload:  issue,
+		})
+	}
+}
+
+// addDocuments adds text files, PDFs, Word documents, spreadsheets, images, Markdown, data and
+// source files accepted by the filter
+func (b *BeanBot) addDocuments(kb *knowledge.KnowledgeDatabase, candidates *prompt.ContextBuilder, lowerInput string, accept func(doc knowledge.Document) bool) {
+	for _, doc := range b.searchableDocuments(kb) {
+		// Skip PDFs whose content looks like PDF metadata rather than text
+		if doc.Type == knowledge.DocPDF && strings.Contains(doc.Content, "<<") && strings.Contains(doc.Content, ">>") {
+			continue
+		}
+		if !accept(doc) {
+			continue
+		}
+
+		switch doc.Type {
+		case knowledge.DocPDF:
+			// For large PDFs like TLM, try to find the most relevant section
+			b.addPassage(candidates, lowerInput, priorityDocument, fmt.Sprintf("From %s:\n", doc.Path), "PDF: "+doc.Path, doc.ID, b.relevantExcerpt(doc.Content, lowerInput))
+		case knowledge.DocWord:
+			// For large Word documents, try to find the most relevant section
+			b.addPassage(candidates, lowerInput, priorityDocument, fmt.Sprintf("From Word Document (%s):\n", doc.Path), "Word Document: "+doc.Path, doc.ID, b.relevantExcerpt(doc.Content, lowerInput))
+		case knowledge.DocImage:
+			b.addPassage(candidates, lowerInput, priorityDocument, fmt.Sprintf("From Image (%s):\n", doc.Path), "Image: "+doc.Path, doc.ID, doc.Content)
+		case knowledge.DocSheet:
+			// Spreadsheets start with their limit checks and column summaries, ahead of the rows
+			b.addPassage(candidates, lowerInput, priorityDocument, fmt.Sprintf("From Spreadsheet (%s):\n", doc.Path), "Spreadsheet: "+doc.Path, doc.ID, excerpt(doc.Content, 1200))
+		case knowledge.DocMarkdown:
+			b.addPassage(candidates, lowerInput, priorityDocument, fmt.Sprintf("From Markdown (%s):\n", doc.Path), "Markdown: "+doc.Path, doc.ID, b.outlineExcerpt(doc, lowerInput))
+		case knowledge.DocData:
+			b.addPassage(candidates, lowerInput, priorityDocument, fmt.Sprintf("From Config (%s):\n", doc.Path), "Config: "+doc.Path, doc.ID, b.outlineExcerpt(doc, lowerInput))
+		case knowledge.DocCode:
+			b.addPassage(candidates, lowerInput, priorityDocument, fmt.Sprintf("From Source (%s):\n", doc.Path), "Source: "+doc.Path, doc.ID, b.outlineExcerpt(doc, lowerInput))
+		default:
+			b.addPassage(candidates, lowerInput, priorityDocument, fmt.Sprintf("From %s:\n", doc.Path), doc.Path, doc.ID, excerpt(doc.Content, 400))
+		}
+	}
+}
+
+// outlineExcerpt lists the headings, keys or symbols of a document that match the question, in
+// file order, followed by the most relevant section of its text
+func (b *BeanBot) outlineExcerpt(doc knowledge.Document, lowerInput string) string {
+	entries, body := knowledge.SplitOutline(doc.Content)
+
+	type match struct {
+		index int
+		score int
+	}
+	var matches []match
+	for i, entry := range entries {
+		if score := keywordScore(lowerInput, entry); score > 0 {
+			matches = append(matches, match{i, score})
+		}
+	}
+	if len(matches) == 0 {
+		return b.relevantExcerpt(body, lowerInput)
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	if len(matches) > maxOutlineMatches {
+		matches = matches[:maxOutlineMatches]
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].index < matches[j].index })
+
+	var out strings.Builder
+	out.WriteString("Matching entries:\n")
+	for _, m := range matches {
+		out.WriteString(entries[m.index] + "\n")
+	}
+	out.WriteString("\n" + b.relevantExcerpt(body, lowerInput))
+	return out.String()
+}
+
+// addMeetingNotes adds the meeting notes that match the question by date or keywords, or the
+// most recent notes when none match
+func (b *BeanBot) addMeetingNotes(kb *knowledge.KnowledgeDatabase, candidates *prompt.ContextBuilder, lowerInput string) {
+	dates := datePattern.FindAllString(lowerInput, -1)
+
+	var meetings []knowledge.Document
+	for _, doc := range b.searchableDocuments(kb) {
+		if isTextDocument(doc) && isMeetingNotes(doc.Name, doc.Content) {
+			meetings = append(meetings, doc)
+		}
+	}
+	// Meeting note file names start with their date, so this sorts newest first
+	sort.SliceStable(meetings, func(i, j int) bool { return meetings[i].Name > meetings[j].Name })
+
+	added := 0
+	for _, doc := range meetings {
+		score := keywordScore(lowerInput, doc.Name+" "+doc.Content)
+		for _, date := range dates {
+			if strings.Contains(doc.Name, date) {
+				score += 10 // The meeting the user asked for by date
+			}
+		}
+		if score == 0 || (len(dates) > 0 && score < 10) {
+			continue
+		}
+
+		candidates.Add(prompt.ContextItem{
+			Priority:   priorityDocument,
+			Score:      score,
+			Source:     "Meeting Notes: " + doc.Path,
+			DocumentID: doc.ID,
+			Header:     fmt.Sprintf("From Meeting Notes (%s):\n", doc.Path),
+			Text:       excerpt(doc.Content, 1200),
+		})
+		added++
+	}
+
+	if added > 0 {
+		return
+	}
+	for i, doc := range meetings {
+		if i >= maxRecentMeetings {
+			break
+		}
+		candidates.Add(prompt.ContextItem{
+			Priority:   priorityGeneral,
+			Source:     "Recent Meeting Notes: " + doc.Path,
+			DocumentID: doc.ID,
+			Header:     fmt.Sprintf("From Meeting Notes (%s):\n", doc.Path),
+			Text:       excerpt(doc.Content, 800),
+		})
+	}
+}
+
+// addGeneralContext adds general reference material when nothing specific matched
+func (b *BeanBot) addGeneralContext(kb *knowledge.KnowledgeDatabase, candidates *prompt.ContextBuilder) {
+	docs := b.searchableDocuments(kb)
+
+	// Include relevant HTML files even if not perfectly matched
+	htmlCount := 0
+	for _, doc := range docs {
+		if doc.Type == knowledge.DocHTML && htmlCount < 2 {
+			candidates.Add(prompt.ContextItem{
+				Priority:   priorityGeneral,
+				Source:     "Engineering Documentation (General): " + doc.Path,
+				DocumentID: doc.ID,
+				Header:     fmt.Sprintf("From Engineering Documentation (%s):\n", doc.Path),
+				Text:       excerpt(doc.Content, 300),
+			})
+			htmlCount++
+		}
+	}
+
+	// Include all error codes as general reference
+	for _, errorCode := range kb.GetData().ErrorCodes {
+		candidates.Add(prompt.ContextItem{
+			Priority: priorityGeneral,
+			Source:   "Error Code Reference: " + errorCode.Code,
+			Text:     fmt.Sprintf("Error Code %s: %s", errorCode.Code, errorCode.Description),
+		})
+	}
+
+	// Include first non-HTML text file as general reference
+	for _, doc := range docs {
+		if isTextDocument(doc) && doc.Type != knowledge.DocHTML {
+			candidates.Add(prompt.ContextItem{
+				Priority:   priorityGeneral,
+				Source:     "General Reference: " + doc.Path,
+				DocumentID: doc.ID,
+				Header:     fmt.Sprintf("From %s:\n", doc.Path),
+				Text:       excerpt(doc.Content, 300),
+			})
+			break // Just include first non-HTML file for general context
+		}
+	}
+}
+
+// fillContext fills the token budget from the candidates and records what was included, truncated and dropped
+func (b *BeanBot) fillContext(userInput string, candidates *prompt.ContextBuilder, budget int) (string, []string, *models.RetrievedContext) {
+	context, report := candidates.Build(budget)
+	b.debugLog("Context budget: used ~%d of %d tokens, %d included, %d truncated, %d dropped",
+		report.UsedTokens, report.BudgetTokens, len(report.Included), len(report.Truncated), len(report.Dropped))
+
+	retrieved := &models.RetrievedContext{
+		Question:      userInput,
+		ContextBudget: report.BudgetTokens,
+		ContextTokens: report.UsedTokens,
+		Truncated:     report.Truncated,
+		Dropped:       report.Dropped,
+	}
+
+	var sources []string
+	for _, item := range report.Included {
+		sources = append(sources, item.Source)
+		retrieved.Supplied = append(retrieved.Supplied, models.Passage{ID: item.ID, Source: item.Source, DocumentID: item.DocumentID, Text: item.Header + item.Text})
+		switch payload := item.Payload.(type) {
+		case models.ErrorCode:
+			retrieved.ErrorCodes = append(retrieved.ErrorCodes, payload)
+		case models.CommonIssue:
+			retrieved.CommonIssues = append(retrieved.CommonIssues, payload)
+		default:
+			retrieved.Passages = append(retrieved.Passages, models.Passage{ID: item.ID, Source: item.Source, DocumentID: item.DocumentID, Text: item.Text})
+		}
+	}
+
+	return context, sources, retrieved
+}
+
+// isTextDocument reports whether a document was indexed from a text, log, HTML or diagram file
+func isTextDocument(doc knowledge.Document) bool {
+	switch doc.Type {
+	case knowledge.DocPDF, knowledge.DocWord, knowledge.DocImage, knowledge.DocSheet:
+		return false
+	}
+	return true
+}
+
+// isMeetingNotes reports whether a document is meeting notes, judged by its name or content
+func isMeetingNotes(filename, content string) bool {
+	lowerName := strings.ToLower(filename)
+	if strings.Contains(lowerName, "meeting") || strings.Contains(lowerName, "retrospective") {
+		return true
+	}
+	lowerContent := strings.ToLower(content)
+	return strings.Contains(lowerContent, "attendees") && strings.Contains(lowerContent, "action items")
+}
+
+// mentionsErrorCode reports whether the content mentions an error code that appears in the question
+func mentionsErrorCode(lowerContent string, errorCodes []models.ErrorCode, lowerInput string) bool {
+	for _, errorCode := range errorCodes {
+		if knowledge.MentionsErrorCode(errorCode, lowerInput) && knowledge.MentionsErrorCode(errorCode, lowerContent) {
+			return true
+		}
+	}
+	return false
+}
+
+// keywordScore counts how many distinct words of the input appear in the text, ignoring stop words
+func keywordScore(lowerInput, text string) int {
+	lowerText := strings.ToLower(text)
+	score := 0
+	seen := make(map[string]bool)
+	for _, word := range strings.Fields(lowerInput) {
+		word = strings.Trim(word, ".,;:!?\"'()[]")
+		if len(word) > 2 && !stopWords[word] && !seen[word] && strings.Contains(lowerText, word) {
+			seen[word] = true
+			score++
+		}
+	}
+	return score
+}
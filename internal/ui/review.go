@@ -0,0 +1,214 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/knowledge"
+)
+
+// Proposal kind options shown in the review form
+const (
+	reviewKindCommonIssue = "New common issue"
+	reviewKindErrorSteps  = "Add steps to error code"
+)
+
+// refreshReviewButton shows the number of proposals waiting for review on the footer button
+func (b *BeanBot) refreshReviewButton() {
+	if b.reviewBtn == nil {
+		return
+	}
+	pending, err := b.proposals.Pending()
+	if err != nil {
+		b.debugLog("Failed to read knowledge base proposals: %v", err)
+		b.reviewBtn.SetText("Review")
+		return
+	}
+	if len(pending) > 0 {
+		b.reviewBtn.SetText(fmt.Sprintf("Review (%d)", len(pending)))
+	} else {
+		b.reviewBtn.SetText("Review")
+	}
+}
+
+// showReviewQueue opens the review dialog for fixes proposed from resolved answers
+func (b *BeanBot) showReviewQueue() {
+	pending, err := b.proposals.Pending()
+	if err != nil {
+		dialog.ShowError(err, b.window)
+		return
+	}
+
+	selected := -1
+	list := widget.NewList(
+		func() int { return len(pending) },
+		func() fyne.CanvasObject { return widget.NewLabel("proposal placeholder") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			item.(*widget.Label).SetText(proposalTitle(pending[id]))
+		},
+	)
+
+	// Editable form for the selected proposal
+	question := widget.NewLabel("")
+	question.Wrapping = fyne.TextWrapWord
+	kind := widget.NewSelect([]string{reviewKindCommonIssue, reviewKindErrorSteps}, nil)
+	issue := widget.NewEntry()
+	code := widget.NewSelect(b.errorCodeNames(), nil)
+	symptoms := widget.NewMultiLineEntry()
+	symptoms.SetPlaceHolder("One symptom per line")
+	steps := widget.NewMultiLineEntry()
+	steps.SetPlaceHolder("One step per line")
+	steps.SetMinRowsVisible(5)
+
+	issueItem := widget.NewFormItem("Issue", issue)
+	symptomsItem := widget.NewFormItem("Symptoms", symptoms)
+	codeItem := widget.NewFormItem("Error code", code)
+	form := widget.NewForm(
+		widget.NewFormItem("Type", kind),
+		issueItem,
+		symptomsItem,
+		codeItem,
+		widget.NewFormItem("Steps / solutions", steps),
+	)
+
+	kind.OnChanged = func(value string) {
+		if value == reviewKindErrorSteps {
+			issue.Disable()
+			symptoms.Disable()
+			code.Enable()
+		} else {
+			issue.Enable()
+			symptoms.Enable()
+			code.Disable()
+		}
+	}
+
+	approveBtn := widget.NewButton("Approve", nil)
+	approveBtn.Importance = widget.HighImportance
+	rejectBtn := widget.NewButton("Reject", nil)
+	approveBtn.Disable()
+	rejectBtn.Disable()
+
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+		proposal := pending[id]
+		question.SetText("Question: " + proposal.Question)
+		issue.SetText(proposal.CommonIssue.Issue)
+		symptoms.SetText(strings.Join(proposal.CommonIssue.Symptoms, "\n"))
+		code.SetSelected(proposal.ErrorCode)
+		if proposal.Kind == knowledge.ProposalErrorCodeSteps {
+			steps.SetText(strings.Join(proposal.Steps, "\n"))
+			kind.SetSelected(reviewKindErrorSteps)
+		} else {
+			steps.SetText(strings.Join(proposal.CommonIssue.Solutions, "\n"))
+			kind.SetSelected(reviewKindCommonIssue)
+		}
+		approveBtn.Enable()
+		rejectBtn.Enable()
+	}
+
+	// finish records the review decision and removes the proposal from the list
+	finish := func(proposal knowledge.Proposal, status knowledge.ProposalStatus) {
+		now := time.Now()
+		proposal.Status = status
+		proposal.ReviewedAt = &now
+		if err := b.proposals.Update(proposal); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.debugLog("Proposal %s %s", proposal.ID, status)
+
+		pending = append(pending[:selected], pending[selected+1:]...)
+		selected = -1
+		list.UnselectAll()
+		list.Refresh()
+		question.SetText("")
+		approveBtn.Disable()
+		rejectBtn.Disable()
+		b.refreshReviewButton()
+	}
+
+	approveBtn.OnTapped = func() {
+		if selected < 0 {
+			return
+		}
+		proposal := pending[selected]
+		if kind.Selected == reviewKindErrorSteps {
+			proposal.Kind = knowledge.ProposalErrorCodeSteps
+			proposal.ErrorCode = code.Selected
+			proposal.Steps = splitLines(steps.Text)
+		} else {
+			proposal.Kind = knowledge.ProposalCommonIssue
+			proposal.CommonIssue.Issue = strings.TrimSpace(issue.Text)
+			proposal.CommonIssue.Symptoms = splitLines(symptoms.Text)
+			proposal.CommonIssue.Solutions = splitLines(steps.Text)
+		}
+
+		if err := b.activeKnowledge().ApplyProposal(proposal); err != nil {
+			dialog.ShowError(fmt.Errorf("could not add to the knowledge base: %w", err), b.window)
+			return
+		}
+		finish(proposal, knowledge.ProposalApproved)
+	}
+	rejectBtn.OnTapped = func() {
+		if selected >= 0 {
+			finish(pending[selected], knowledge.ProposalRejected)
+		}
+	}
+
+	details := container.NewBorder(
+		question,
+		container.NewGridWithColumns(2, approveBtn, rejectBtn),
+		nil, nil,
+		container.NewVScroll(form),
+	)
+	split := container.NewHSplit(list, details)
+	split.Offset = 0.35
+
+	content := container.NewBorder(
+		widget.NewLabel("Fixes proposed from resolved answers. Approved entries are saved to the learned fixes file and used immediately."),
+		nil, nil, nil,
+		split,
+	)
+	if len(pending) == 0 {
+		content = container.NewBorder(widget.NewLabel("No proposals are waiting for review."), nil, nil, nil, split)
+	}
+
+	reviewDialog := dialog.NewCustom("Review Learned Fixes", "Close", content, b.window)
+	reviewDialog.Resize(fyne.NewSize(760, 520))
+	reviewDialog.Show()
+}
+
+// errorCodeNames lists the codes in the knowledge base
+func (b *BeanBot) errorCodeNames() []string {
+	var codes []string
+	for _, errorCode := range b.activeKnowledge().GetData().ErrorCodes {
+		codes = append(codes, errorCode.Code)
+	}
+	return codes
+}
+
+// proposalTitle describes a proposal in the review list
+func proposalTitle(proposal knowledge.Proposal) string {
+	if proposal.Kind == knowledge.ProposalErrorCodeSteps {
+		return fmt.Sprintf("%s: +%d steps", proposal.ErrorCode, len(proposal.Steps))
+	}
+	return proposal.CommonIssue.Issue
+}
+
+// splitLines returns the non-empty trimmed lines of text
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,241 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/session"
+)
+
+// welcomeMessage is shown at the top of a new conversation
+const welcomeMessage = "## 🤖 Hi there! \n\n### What engineering challenge can I help you with today? 💭"
+
+// createSessionSidebar creates the sidebar listing saved conversations with search, rename, export and delete
+func (b *BeanBot) createSessionSidebar() fyne.CanvasObject {
+	b.sessionSearch = widget.NewEntry()
+	b.sessionSearch.SetPlaceHolder("Search conversations")
+	b.sessionSearch.OnChanged = func(string) { b.refreshSessions() }
+
+	b.sessionList = widget.NewList(
+		func() int { return len(b.sessionItems) },
+		func() fyne.CanvasObject {
+			title := widget.NewLabel("")
+			title.TextStyle = fyne.TextStyle{Bold: true}
+			title.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(title, widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			saved := b.sessionItems[id]
+			labels := item.(*fyne.Container).Objects
+			title := saved.Title
+			if saved.ID == b.session.ID {
+				title = "▶ " + title
+			}
+			labels[0].(*widget.Label).SetText(title)
+			labels[1].(*widget.Label).SetText(fmt.Sprintf("%s · %d questions", saved.UpdatedAt.Format("Jan 2 15:04"), len(saved.Turns)))
+		},
+	)
+	b.sessionList.OnSelected = func(id widget.ListItemID) {
+		if id < len(b.sessionItems) && b.sessionItems[id].ID != b.session.ID {
+			b.openSession(b.sessionItems[id].ID)
+		}
+	}
+
+	newBtn := widget.NewButton("➕ New Chat", b.newSession)
+	newBtn.Importance = widget.HighImportance
+	renameBtn := widget.NewButton("Rename", b.renameSession)
+	deleteBtn := widget.NewButton("Delete", b.deleteSession)
+	exportBtn := widget.NewButton("Export", func() { b.showExportDialog(-1) })
+
+	b.refreshSessions()
+	return container.NewBorder(
+		container.NewVBox(newBtn, b.sessionSearch),
+		container.NewGridWithColumns(3, renameBtn, exportBtn, deleteBtn),
+		nil, nil,
+		b.sessionList,
+	)
+}
+
+// refreshSessions reloads the saved sessions that match the search box
+func (b *BeanBot) refreshSessions() {
+	if b.sessionList == nil {
+		return
+	}
+	saved, err := b.sessions.List()
+	if err != nil {
+		b.debugLog("Failed to list sessions: %v", err)
+	}
+
+	b.sessionItems = nil
+	for _, item := range saved {
+		if item.Matches(b.sessionSearch.Text) {
+			b.sessionItems = append(b.sessionItems, item)
+		}
+	}
+	b.sessionList.UnselectAll()
+	b.sessionList.Refresh()
+}
+
+// newSession starts an empty conversation; the previous one is already saved
+func (b *BeanBot) newSession() {
+	kb := b.activeKnowledge()
+	b.session = session.New(kb.Profile().Name)
+	kb.ClearUserUploads()
+	b.refreshUploads()
+	b.history = nil
+	b.hideFeedbackBar()
+	b.transcript.RemoveAll()
+	b.appendMessage(welcomeMessage)
+	b.refreshSessions()
+	b.debugLog("Started new session %s", b.session.ID)
+}
+
+// openSession shows a saved conversation so it can be read or continued. Its attachments are
+// uploaded again from the copies kept with it, or their original locations when the files still exist.
+func (b *BeanBot) openSession(id string) {
+	kb := b.activeKnowledge()
+	saved, err := b.sessions.Load(id)
+	if err != nil {
+		b.debugLog("Failed to open session %s: %v", id, err)
+		dialog.ShowError(fmt.Errorf("could not open the conversation: %w", err), b.window)
+		return
+	}
+
+	b.session = saved
+	kb.ClearUserUploads()
+	b.refreshUploads()
+	b.hideFeedbackBar()
+	b.transcript.RemoveAll()
+	b.history = nil
+	for _, turn := range saved.Turns {
+		b.appendQuestion(turn.Question)
+		b.linkSources(b.appendMessage(turn.Answer), turn.References)
+		b.appendExcerpts(turn.References)
+		b.rememberQuestion(turn.Question)
+	}
+	if saved.Profile != "" && saved.Profile != kb.Profile().Name {
+		b.appendMessage(fmt.Sprintf("*This conversation was started with the %s knowledge base; new answers use %s.*", saved.Profile, kb.Profile().Name))
+	}
+	b.refreshSessions()
+	b.scrollContainer.ScrollToBottom()
+	b.debugLog("Opened session %s with %d turns", saved.ID, len(saved.Turns))
+
+	if len(saved.Attachments) > 0 {
+		go b.restoreAttachments(saved)
+	}
+}
+
+// restoreAttachments uploads a reopened session's files again and reports any that are gone
+func (b *BeanBot) restoreAttachments(saved *session.Session) {
+	var restored, missing []string
+	for _, attachment := range saved.Attachments {
+		source := attachment.Source()
+		if _, err := os.Stat(source); err != nil {
+			missing = append(missing, attachment.Name)
+			continue
+		}
+		if _, err := b.activeKnowledge().ProcessUserUpload(source); err != nil {
+			b.debugLog("Failed to restore attachment %s: %v", source, err)
+			missing = append(missing, attachment.Name)
+			continue
+		}
+		restored = append(restored, attachment.Name)
+	}
+
+	if b.session != saved {
+		return
+	}
+	b.refreshUploads()
+	message := fmt.Sprintf("📎 Attachments restored: %s", strings.Join(restored, ", "))
+	if len(restored) == 0 {
+		message = "📎 No attachments could be restored"
+	}
+	if len(missing) > 0 {
+		message += fmt.Sprintf(" · no longer available: %s", strings.Join(missing, ", "))
+	}
+	b.appendMessage("*" + message + "*")
+}
+
+// renameSession renames the current conversation
+func (b *BeanBot) renameSession() {
+	if len(b.session.Turns) == 0 {
+		dialog.ShowInformation("Rename", "Ask a question first - new conversations are saved with their first answer.", b.window)
+		return
+	}
+
+	title := widget.NewEntry()
+	title.SetText(b.session.Title)
+	dialog.ShowForm("Rename Conversation", "Rename", "Cancel", []*widget.FormItem{widget.NewFormItem("Title", title)}, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := b.sessions.Rename(b.session.ID, title.Text); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.session.Title = strings.TrimSpace(title.Text)
+		b.refreshSessions()
+	}, b.window)
+}
+
+// deleteSession deletes the current conversation after confirmation and starts a new one
+func (b *BeanBot) deleteSession() {
+	if len(b.session.Turns) == 0 {
+		return
+	}
+	dialog.ShowConfirm("Delete Conversation", fmt.Sprintf("Delete %q? This cannot be undone.", b.session.Title), func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := b.sessions.Delete(b.session.ID); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.debugLog("Deleted session %s", b.session.ID)
+		b.newSession()
+	}, b.window)
+}
+
+// saveSession writes a conversation that has at least one answer and refreshes the sidebar
+func (b *BeanBot) saveSession(current *session.Session) {
+	if len(current.Turns) == 0 {
+		return
+	}
+	if b.config.Sessions.KeepUploads {
+		if err := b.sessions.KeepUploads(current); err != nil {
+			b.debugLog("Failed to keep uploads with session %s: %v", current.ID, err)
+		}
+	}
+	if err := b.sessions.Save(current); err != nil {
+		b.debugLog("Failed to save session %s: %v", current.ID, err)
+		b.statusLabel.SetText("🤖 BeanBot AI ⚠️ conversation could not be saved")
+		return
+	}
+	b.refreshSessions()
+}
+
+// appendQuestion adds the user's question to the transcript
+func (b *BeanBot) appendQuestion(question string) {
+	label := widget.NewLabel("🧑 " + strings.TrimSpace(question))
+	label.Wrapping = fyne.TextWrapWord
+	label.TextStyle = fyne.TextStyle{Bold: true}
+	b.transcript.Add(widget.NewSeparator())
+	b.transcript.Add(label)
+}
+
+// appendMessage adds markdown to the transcript and returns it so it can be updated
+func (b *BeanBot) appendMessage(markdown string) *widget.RichText {
+	text := widget.NewRichTextFromMarkdown(markdown)
+	text.Wrapping = fyne.TextWrapWord
+	b.transcript.Add(text)
+	if b.scrollContainer != nil {
+		b.scrollContainer.ScrollToBottom()
+	}
+	return text
+}
@@ -0,0 +1,288 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/config"
+	"github.com/beanspout/2025-beanbot/internal/models"
+)
+
+// defaultsScope is the settings scope that edits the options shared by all models
+const defaultsScope = "Defaults (all models)"
+
+// generationFields holds the entry widgets of the generation settings form
+type generationFields struct {
+	temperature   *widget.Entry
+	topP          *widget.Entry
+	topK          *widget.Entry
+	numCtx        *widget.Entry
+	numPredict    *widget.Entry
+	repeatPenalty *widget.Entry
+	seed          *widget.Entry
+	stop          *widget.Entry
+	keepAlive     *widget.Entry
+}
+
+// showSettings opens the generation settings panel for the defaults and per-model presets
+func (b *BeanBot) showSettings() {
+	fields := newGenerationFields()
+	effective := widget.NewLabel("")
+	effective.Wrapping = fyne.TextWrapWord
+
+	scope := widget.NewSelect(b.settingsScopes(), nil)
+	scope.OnChanged = func(selected string) {
+		fields.load(b.optionsForScope(selected))
+		current := b.ollamaClient.GetCurrentModel()
+		effective.SetText(fmt.Sprintf("Effective options for %s: %s", current, b.ollamaClient.GenerationOptionsFor(current)))
+	}
+	scope.SetSelected(defaultsScope)
+
+	form := widget.NewForm(
+		widget.NewFormItem("Temperature", fields.temperature),
+		widget.NewFormItem("Top P", fields.topP),
+		widget.NewFormItem("Top K", fields.topK),
+		widget.NewFormItem("Context (num_ctx)", fields.numCtx),
+		widget.NewFormItem("Max tokens (num_predict)", fields.numPredict),
+		widget.NewFormItem("Repeat penalty", fields.repeatPenalty),
+		widget.NewFormItem("Seed", fields.seed),
+		widget.NewFormItem("Stop sequences", fields.stop),
+		widget.NewFormItem("Keep alive", fields.keepAlive),
+	)
+
+	templatesLabel := widget.NewLabel(b.templateSummary())
+	templatesLabel.Wrapping = fyne.TextWrapWord
+	reloadBtn := widget.NewButton("Reload Templates", func() {
+		if err := b.templates.Load(); err != nil {
+			b.debugLog("Failed to reload prompt templates: %v", err)
+			dialog.ShowError(err, b.window)
+			return
+		}
+		templatesLabel.SetText(b.templateSummary())
+	})
+
+	keepUploads := widget.NewCheck("Keep a copy of uploaded files with saved conversations", nil)
+	keepUploads.SetChecked(b.config.Sessions.KeepUploads)
+
+	indexLabel := widget.NewLabel(b.activeKnowledge().IndexReport().Summary())
+	indexLabel.Wrapping = fyne.TextWrapWord
+
+	content := container.NewVBox(
+		widget.NewLabelWithStyle("Generation options", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabel("Leave a field empty to inherit it. Model presets are applied on top of the defaults."),
+		container.NewBorder(nil, nil, widget.NewLabel("Applies to:"), nil, scope),
+		form,
+		effective,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Prompt templates", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		templatesLabel,
+		reloadBtn,
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Knowledge base", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		indexLabel,
+		widget.NewButton("Indexing Report", b.showIndexReport),
+		widget.NewSeparator(),
+		widget.NewLabelWithStyle("Conversations", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		keepUploads,
+		widget.NewLabel(fmt.Sprintf("Copies are saved in %s so conversations can be reopened after the originals move.", b.sessions.Dir())),
+	)
+
+	settingsDialog := dialog.NewCustomConfirm("Settings", "Save", "Cancel", container.NewVScroll(content), func(save bool) {
+		if !save {
+			return
+		}
+		options, err := fields.parse()
+		if err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.config.Sessions.KeepUploads = keepUploads.Checked
+		if err := b.saveGenerationOptions(scope.Selected, options); err != nil {
+			dialog.ShowError(err, b.window)
+			return
+		}
+		b.debugLog("Saved generation options for %s: %s", scope.Selected, options)
+	}, b.window)
+	settingsDialog.Resize(fyne.NewSize(520, 600))
+	settingsDialog.Show()
+}
+
+// settingsScopes lists the defaults scope followed by the current model and any configured presets
+func (b *BeanBot) settingsScopes() []string {
+	names := map[string]bool{b.ollamaClient.GetCurrentModel(): true}
+	for model := range b.config.Ollama.ModelPresets {
+		names[model] = true
+	}
+
+	var scoped []string
+	for model := range names {
+		if model != "" {
+			scoped = append(scoped, model)
+		}
+	}
+	sort.Strings(scoped)
+	return append([]string{defaultsScope}, scoped...)
+}
+
+// optionsForScope returns the options configured for a scope (not merged with the defaults)
+func (b *BeanBot) optionsForScope(scope string) models.GenerationOptions {
+	if scope == defaultsScope || scope == "" {
+		return b.config.Ollama.Generation
+	}
+	return b.config.Ollama.ModelPresets[scope]
+}
+
+// saveGenerationOptions stores the options for a scope, applies them to the client and writes config.json
+func (b *BeanBot) saveGenerationOptions(scope string, options models.GenerationOptions) error {
+	if scope == defaultsScope || scope == "" {
+		b.config.Ollama.Generation = options
+	} else {
+		if b.config.Ollama.ModelPresets == nil {
+			b.config.Ollama.ModelPresets = make(map[string]models.GenerationOptions)
+		}
+		if options.String() == "" {
+			delete(b.config.Ollama.ModelPresets, scope)
+		} else {
+			b.config.Ollama.ModelPresets[scope] = options
+		}
+	}
+
+	b.ollamaClient.SetGenerationOptions(b.config.Ollama.Generation, b.config.Ollama.ModelPresets)
+	if err := b.config.Save(config.DefaultPath); err != nil {
+		return fmt.Errorf("options applied but could not be saved: %w", err)
+	}
+	return nil
+}
+
+// templateSummary lists the loaded prompt templates and their versions
+func (b *BeanBot) templateSummary() string {
+	loaded := b.templates.Templates()
+	if len(loaded) == 0 {
+		return fmt.Sprintf("No templates found in %s - using the built-in prompt.", b.templates.Dir())
+	}
+
+	var names []string
+	for _, tmpl := range loaded {
+		names = append(names, fmt.Sprintf("%s v%s", tmpl.Name, tmpl.Version))
+	}
+	return fmt.Sprintf("Loaded from %s: %s", b.templates.Dir(), strings.Join(names, ", "))
+}
+
+// newGenerationFields creates the entries of the generation settings form
+func newGenerationFields() *generationFields {
+	entry := func(placeholder string) *widget.Entry {
+		e := widget.NewEntry()
+		e.SetPlaceHolder(placeholder)
+		return e
+	}
+	return &generationFields{
+		temperature:   entry("e.g. 0.7"),
+		topP:          entry("e.g. 0.9"),
+		topK:          entry("e.g. 40"),
+		numCtx:        entry("e.g. 4096"),
+		numPredict:    entry("e.g. 1000"),
+		repeatPenalty: entry("e.g. 1.1"),
+		seed:          entry("fixed seed for reproducible answers"),
+		stop:          entry("comma separated, e.g. </answer>"),
+		keepAlive:     entry("e.g. 5m"),
+	}
+}
+
+// load fills the form from the given options, leaving unset fields empty
+func (f *generationFields) load(options models.GenerationOptions) {
+	f.temperature.SetText(formatFloatOption(options.Temperature))
+	f.topP.SetText(formatFloatOption(options.TopP))
+	f.topK.SetText(formatIntOption(options.TopK))
+	f.numCtx.SetText(formatIntOption(options.NumCtx))
+	f.numPredict.SetText(formatIntOption(options.NumPredict))
+	f.repeatPenalty.SetText(formatFloatOption(options.RepeatPenalty))
+	f.seed.SetText(formatIntOption(options.Seed))
+	f.stop.SetText(strings.Join(options.Stop, ", "))
+	f.keepAlive.SetText(options.KeepAlive)
+}
+
+// parse validates the form and converts it to generation options
+func (f *generationFields) parse() (models.GenerationOptions, error) {
+	var options models.GenerationOptions
+	var err error
+
+	if options.Temperature, err = parseFloatOption("temperature", f.temperature.Text, 0, 2); err != nil {
+		return options, err
+	}
+	if options.TopP, err = parseFloatOption("top_p", f.topP.Text, 0, 1); err != nil {
+		return options, err
+	}
+	if options.TopK, err = parseIntOption("top_k", f.topK.Text, 1); err != nil {
+		return options, err
+	}
+	if options.NumCtx, err = parseIntOption("num_ctx", f.numCtx.Text, 256); err != nil {
+		return options, err
+	}
+	if options.NumPredict, err = parseIntOption("num_predict", f.numPredict.Text, -2); err != nil {
+		return options, err
+	}
+	if options.RepeatPenalty, err = parseFloatOption("repeat_penalty", f.repeatPenalty.Text, 0, 5); err != nil {
+		return options, err
+	}
+	if options.Seed, err = parseIntOption("seed", f.seed.Text, 0); err != nil {
+		return options, err
+	}
+
+	for _, stop := range strings.Split(f.stop.Text, ",") {
+		if stop = strings.TrimSpace(stop); stop != "" {
+			options.Stop = append(options.Stop, stop)
+		}
+	}
+	options.KeepAlive = strings.TrimSpace(f.keepAlive.Text)
+
+	return options, nil
+}
+
+// parseFloatOption parses an optional float field within [min, max]
+func parseFloatOption(name, text string, min, max float64) (*float64, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value < min || value > max {
+		return nil, fmt.Errorf("%s must be a number between %g and %g", name, min, max)
+	}
+	return &value, nil
+}
+
+// parseIntOption parses an optional integer field that must be at least min
+func parseIntOption(name, text string, min int) (*int, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(text)
+	if err != nil || value < min {
+		return nil, fmt.Errorf("%s must be a whole number of at least %d", name, min)
+	}
+	return &value, nil
+}
+
+// formatFloatOption formats an optional float for display
+func formatFloatOption(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'g', -1, 64)
+}
+
+// formatIntOption formats an optional integer for display
+func formatIntOption(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/knowledge"
+	"github.com/beanspout/2025-beanbot/internal/session"
+)
+
+// ticketLinks shows the tickets a document mentions, each opening the ticket's links, or nil
+// when it mentions none
+func (b *BeanBot) ticketLinks(doc knowledge.Document) fyne.CanvasObject {
+	tickets := b.activeKnowledge().LinkedTickets(doc)
+	if len(tickets) == 0 {
+		return nil
+	}
+	links := container.NewHBox(widget.NewLabel("🎫 Tickets:"))
+	for _, key := range tickets {
+		key := key
+		link := widget.NewHyperlink(key, nil)
+		link.OnTapped = func() { b.showTicket(key) }
+		links.Add(link)
+	}
+	return container.NewHScroll(links)
+}
+
+// showTicket lists the Jira exports containing a ticket and the documents that mention it; each
+// opens in the document viewer at the ticket
+func (b *BeanBot) showTicket(key string) {
+	links := b.activeKnowledge().Ticket(key)
+	content := container.NewVBox()
+
+	if len(links.Exports) == 0 {
+		content.Add(widget.NewLabel("No Jira export of this ticket is in the knowledge base."))
+	}
+	for _, doc := range links.Exports {
+		doc := doc
+		content.Add(b.ticketReferenceLink("📋 "+doc.Path, session.Reference{
+			Source:     fmt.Sprintf("Jira Ticket %s: %s", key, doc.Path),
+			DocumentID: doc.ID,
+			Path:       doc.FullPath,
+			Excerpt:    knowledge.TicketSection(doc, key),
+		}))
+	}
+
+	if len(links.Mentions) > 0 {
+		heading := widget.NewLabel(fmt.Sprintf("Mentioned in %d documents", len(links.Mentions)))
+		heading.TextStyle = fyne.TextStyle{Bold: true}
+		content.Add(heading)
+	}
+	for _, doc := range links.Mentions {
+		doc := doc
+		excerpt := knowledge.MentionExcerpt(doc, key, 300)
+		content.Add(b.ticketReferenceLink("📄 "+doc.Path, session.Reference{
+			Source:     fmt.Sprintf("Mentions %s: %s", key, doc.Path),
+			DocumentID: doc.ID,
+			Path:       doc.FullPath,
+			Excerpt:    excerpt,
+		}))
+		snippet := widget.NewLabel(excerpt)
+		snippet.Wrapping = fyne.TextWrapWord
+		content.Add(snippet)
+	}
+
+	scroll := container.NewVScroll(content)
+	scroll.SetMinSize(fyne.NewSize(560, 360))
+	dialog.ShowCustom("Ticket "+key, "Close", scroll, b.window)
+}
+
+// ticketReferenceLink is a link opening a reference in the document viewer
+func (b *BeanBot) ticketReferenceLink(text string, reference session.Reference) fyne.CanvasObject {
+	link := widget.NewHyperlink(text, nil)
+	link.OnTapped = func() { b.showReference(reference) }
+	return link
+}
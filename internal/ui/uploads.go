@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"github.com/beanspout/2025-beanbot/internal/knowledge"
+)
+
+// maxListedMembers is the number of files of an uploaded archive listed in messages
+const maxListedMembers = 15
+
+// createUploadBar creates the row of uploaded files above the question box; it is hidden until
+// a file is uploaded
+func (b *BeanBot) createUploadBar() fyne.CanvasObject {
+	b.uploadChips = container.NewHBox()
+	b.uploadBar = container.NewBorder(nil, nil, widget.NewLabel("📎 Files:"), nil, container.NewHScroll(b.uploadChips))
+	b.refreshUploads()
+	return b.uploadBar
+}
+
+// refreshUploads shows a chip for each uploaded file
+func (b *BeanBot) refreshUploads() {
+	if b.uploadBar == nil {
+		return
+	}
+	uploads := b.activeKnowledge().UserUploads()
+	b.uploadChips.RemoveAll()
+	for _, upload := range uploads {
+		b.uploadChips.Add(b.uploadChip(upload))
+	}
+	if len(uploads) == 0 {
+		b.uploadBar.Hide()
+	} else {
+		b.uploadBar.Show()
+	}
+	b.uploadBar.Refresh()
+}
+
+// uploadChip shows an uploaded file with buttons to pin and remove it
+func (b *BeanBot) uploadChip(upload knowledge.Upload) fyne.CanvasObject {
+	pinBtn := widget.NewButton("📌", func() { b.pinUpload(upload, !upload.Pinned) })
+	pinBtn.Importance = widget.LowImportance
+	if upload.Pinned {
+		pinBtn.Importance = widget.HighImportance
+	}
+	name := widget.NewHyperlink(upload.Name, nil)
+	name.OnTapped = func() { b.showUploadDetails(upload) }
+	removeBtn := widget.NewButtonWithIcon("", theme.CancelIcon(), func() { b.removeUpload(upload) })
+	removeBtn.Importance = widget.LowImportance
+
+	background := canvas.NewRectangle(theme.InputBackgroundColor())
+	background.CornerRadius = theme.InputRadiusSize()
+	return container.NewStack(background, container.NewHBox(pinBtn, name, removeBtn))
+}
+
+// pinUpload keeps an uploaded file for new conversations, or stops keeping it
+func (b *BeanBot) pinUpload(upload knowledge.Upload, pinned bool) {
+	if b.activeKnowledge().PinUpload(upload.ID, pinned) {
+		b.debugLog("Set pin of upload %s to %v", upload.ID, pinned)
+	}
+	b.refreshUploads()
+}
+
+// removeUpload removes one uploaded file from the knowledge base and the current conversation
+func (b *BeanBot) removeUpload(upload knowledge.Upload) {
+	b.activeKnowledge().RemoveUpload(upload.ID)
+	if attachment, ok := b.session.RemoveAttachment(upload.Name); ok {
+		if err := b.sessions.DeleteUpload(attachment); err != nil {
+			b.debugLog("Failed to delete the kept copy of %s: %v", upload.Name, err)
+		}
+		b.saveSession(b.session)
+	}
+	b.debugLog("Removed upload %s", upload.ID)
+	b.refreshUploads()
+}
+
+// showUploadDetails describes what was extracted from an uploaded file
+func (b *BeanBot) showUploadDetails(upload knowledge.Upload) {
+	pinned := "No - removed when a new chat starts"
+	if upload.Pinned {
+		pinned = "Yes - kept for new chats"
+	}
+	form := widget.NewForm(
+		widget.NewFormItem("File", widget.NewLabel(upload.Path)),
+		widget.NewFormItem("Type", widget.NewLabel(fmt.Sprintf("%s (%s extractor)", upload.Type, upload.Extractor))),
+		widget.NewFormItem("Size", widget.NewLabel(knowledge.FormatBytes(upload.Size))),
+		widget.NewFormItem("Extracted", widget.NewLabel(upload.Summary)),
+		widget.NewFormItem("Uploaded", widget.NewLabel(upload.UploadedAt.Format("2006-01-02 15:04:05"))),
+		widget.NewFormItem("Pinned", widget.NewLabel(pinned)),
+	)
+	if upload.Type == knowledge.DocArchive {
+		contents := container.NewVScroll(widget.NewLabel(strings.Join(archiveContents(upload, len(upload.Members)+len(upload.Skipped)), "\n")))
+		contents.SetMinSize(fyne.NewSize(0, 160))
+		form.Append("Contents", contents)
+	}
+	dialog.ShowCustom(upload.Name, "Close", form, b.window)
+}
+
+// uploadMarkdown describes an uploaded file as a Markdown list item, listing what was found in
+// an archive beneath it
+func uploadMarkdown(upload knowledge.Upload) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "- **%s** - %s\n", upload.Name, upload.Summary)
+	for _, line := range archiveContents(upload, maxListedMembers) {
+		fmt.Fprintf(&out, "  - %s\n", line)
+	}
+	return out.String()
+}
+
+// archiveContents lists up to limit files of an uploaded archive: those extracted, then those
+// skipped with the reason
+func archiveContents(upload knowledge.Upload, limit int) []string {
+	var lines []string
+	for _, member := range upload.Members {
+		lines = append(lines, fmt.Sprintf("✅ %s - %s", strings.TrimPrefix(member.Name, upload.Name+"/"), member.Summary))
+	}
+	for _, skipped := range upload.Skipped {
+		lines = append(lines, fmt.Sprintf("⏭️ %s - %s", skipped.Path, skipped.Reason))
+	}
+	if len(lines) > limit {
+		lines = append(lines[:limit], fmt.Sprintf("...and %d more", len(lines)-limit))
+	}
+	return lines
+}
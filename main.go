@@ -1,33 +1,83 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 
+	"github.com/beanspout/2025-beanbot/internal/config"
+	"github.com/beanspout/2025-beanbot/internal/feedback"
 	"github.com/beanspout/2025-beanbot/internal/knowledge"
 	"github.com/beanspout/2025-beanbot/internal/ollama"
+	"github.com/beanspout/2025-beanbot/internal/prompt"
 	"github.com/beanspout/2025-beanbot/internal/ui"
 )
 
 func main() {
+	exportFeedback := flag.String("export-feedback", "", "write collected answer feedback to a .csv or .json file and exit")
+	negativeOnly := flag.Bool("negative-only", false, "with -export-feedback, export only answers rated not helpful")
+	migrateKB := flag.String("migrate-kb", "", "upgrade an error codes file to the current schema, keeping a .bak backup, and exit")
+	flag.Parse()
+
+	if *migrateKB != "" {
+		notes, err := knowledge.MigrateFile(*migrateKB)
+		for _, note := range notes {
+			fmt.Println(note)
+		}
+		if err != nil {
+			log.Fatal("Failed to migrate knowledge base:", err)
+		}
+		fmt.Printf("%s is at schema version %d\n", *migrateKB, knowledge.SchemaVersion)
+		return
+	}
+
+	// Load application configuration (defaults are used if config.json is missing)
+	cfg, err := config.Load(config.DefaultPath)
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
+	if *exportFeedback != "" {
+		if err := exportFeedbackFile(cfg.Feedback.File, *exportFeedback, *negativeOnly); err != nil {
+			log.Fatal("Failed to export feedback:", err)
+		}
+		return
+	}
+
 	// Initialize Fyne application
 	myApp := app.NewWithID("com.example.beanbot")
 	myWindow := myApp.NewWindow("BeanBot - Engineering Support")
 	myWindow.Resize(fyne.NewSize(450, 700)) // Optimized chat window size
 
-	// Initialize knowledge database
-	kb, err := knowledge.NewKnowledgeDatabase()
+	// Initialize the knowledge database for the active profile
+	kb, err := knowledge.NewKnowledgeDatabase(cfg.KnowledgeBase.Active(), cfg.IndexPolicy())
 	if err != nil {
 		log.Fatal("Failed to initialize knowledge database:", err)
 	}
 
-	// Initialize Ollama client (llama3.2 as default model)
-	ollamaClient := ollama.NewClient("http://localhost:11434", "llama3.2:1b")
+	// Initialize Ollama client with the configured model and fallback policy
+	ollamaClient := ollama.NewClient(cfg.Ollama.BaseURL, cfg.Ollama.Model)
+	ollamaClient.SetFallbackModels(cfg.Ollama.FallbackModels)
+	ollamaClient.SetGenerationOptions(cfg.Ollama.Generation, cfg.Ollama.ModelPresets)
+	if cfg.Ollama.TimeoutSeconds > 0 {
+		ollamaClient.SetTimeout(time.Duration(cfg.Ollama.TimeoutSeconds) * time.Second)
+	}
+	if cfg.Ollama.HealthCheckTTLSeconds > 0 {
+		ollamaClient.SetHealthCheckTTL(time.Duration(cfg.Ollama.HealthCheckTTLSeconds) * time.Second)
+	}
+
+	// Load prompt templates (the built-in prompt is used if none can be loaded)
+	templates := prompt.NewLibrary(cfg.Prompts.Directory)
+	if err := templates.Load(); err != nil {
+		log.Printf("Warning: failed to load prompt templates: %v", err)
+	}
 
 	// Initialize BeanBot UI
-	bot := ui.NewBeanBot(myApp, myWindow, kb, ollamaClient)
+	bot := ui.NewBeanBot(myApp, myWindow, kb, ollamaClient, templates, cfg)
 
 	// Enable debug mode for detailed logging
 	bot.EnableDebugMode()
@@ -36,3 +86,19 @@ func main() {
 	bot.SetupUI()
 	myWindow.ShowAndRun()
 }
+
+// exportFeedbackFile writes the feedback store to a CSV or JSON file for review
+func exportFeedbackFile(storePath, outputPath string, negativeOnly bool) error {
+	entries, err := feedback.NewStore(storePath).Load()
+	if err != nil {
+		return err
+	}
+	if negativeOnly {
+		entries = feedback.Filter(entries, feedback.RatingDown)
+	}
+	if err := feedback.Export(entries, outputPath); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d feedback entries from %s to %s\n", len(entries), storePath, outputPath)
+	return nil
+}
@@ -92,6 +92,7 @@ echo - BeanBot uses AI to provide troubleshooting steps
 echo - Upload PDF documentation for enhanced context
 echo - The knowledge base includes 216+ iTest documentation files
 echo - Supports error codes, screenshots, and DrawIO diagrams
+echo - Download, inspect or remove AI models with the Models button in the footer
 echo.
 echo MODEL: gemma3:1b (optimized for technical troubleshooting)
 echo.